@@ -2,12 +2,17 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"reflect"
+	"strconv"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -24,31 +29,38 @@ import (
 	"achatbot/pkg/consts"
 	"achatbot/pkg/modules/llm"
 	"achatbot/pkg/modules/speech/asr"
+	"achatbot/pkg/modules/speech/audio_dsp"
+	"achatbot/pkg/modules/speech/kws"
+	"achatbot/pkg/modules/speech/lid"
+	"achatbot/pkg/modules/speech/speaker"
 	"achatbot/pkg/modules/speech/tts"
+	"achatbot/pkg/modules/speech/turn_analyzer"
 	"achatbot/pkg/modules/speech/vad_analyzer"
 	"achatbot/pkg/params"
 	achatbot_processors "achatbot/pkg/processors"
 	achatbot_aggregators "achatbot/pkg/processors/aggregators"
 	"achatbot/pkg/processors/llm_processors"
+	"achatbot/pkg/services/auth"
+	"achatbot/pkg/services/metrics"
 	"achatbot/pkg/services/middleware"
+	"achatbot/pkg/services/recording"
+	"achatbot/pkg/services/tracing"
+	"achatbot/pkg/services/transcript"
 	"achatbot/pkg/transports"
 	"achatbot/pkg/types"
 	achatbot_frames "achatbot/pkg/types/frames"
+	"achatbot/pkg/utils"
 )
 
-// Upgrader for upgrading HTTP connections to WebSocket connections
-var upgrader = websocket.Upgrader{
-	CheckOrigin: func(r *http.Request) bool {
-		// Allow connections from any origin in this example
-		// In production, you should be more restrictive
-		return true
-	},
-}
+// Upgrader for upgrading HTTP connections to WebSocket connections,
+// CheckOrigin is set to the authenticator origin allowlist (ALLOWED_ORIGINS) in main
+var upgrader = websocket.Upgrader{}
 
 // Global variables to manage server state
 var (
-	serverMu    sync.Mutex
-	activeTasks = make(map[*pipeline.PipelineTask]bool)
+	serverMu       sync.Mutex
+	activeTasks    = make(map[*pipeline.PipelineTask]bool)
+	activeSessions = make(map[string]*common.Session)
 )
 
 // ExampleIWebSocketConn wraps *websocket.Conn to implement our IWebSocketConn interface
@@ -87,56 +99,426 @@ func (wsc *ExampleIWebSocketConn) Close() error {
 	return wsc.Conn.Close()
 }
 
-func load() (*common.ModuleProviderPool, *common.ModuleProviderPool, *common.ModuleProviderPool) {
-	var err error
+func load() (*common.Pool[*vad_analyzer.SherpaOnnxProvider], *common.Pool[*asr.SherpaOnnxProvider], *common.Pool[*tts.SherpaOnnxProvider]) {
 	// vad
-	vadPoolType := reflect.TypeOf(&vad_analyzer.SherpaOnnxProvider{})
-	common.RegisterNewFunc(vadPoolType, func() (common.IPoolInstance, error) {
+	// model-free pure-Go provider, drop-in replacement without model files and cgo:
+	//vadPool := common.NewPool("vad", 3, func() (*vad_analyzer.EnergyProvider, error) {
+	//	return vad_analyzer.NewEnergyProvider(params.NewEnergyVADArgs()), nil
+	//})
+	vadPool := common.NewPool("vad", 3, func() (*vad_analyzer.SherpaOnnxProvider, error) {
 		sherpaOnnxProvider := vad_analyzer.NewSherpaOnnxProvider(
 			//vad_analyzer.NewDefaultSherpaOnnxVadModelConfig("ten"),
 			vad_analyzer.NewDefaultSherpaOnnxVadModelConfig("silero"),
 			100,
 		)
 		return sherpaOnnxProvider, nil
-	})
-	vadPool := common.NewModuleProviderPool(3, vadPoolType)
-	err = vadPool.Initialize()
-	if err != nil {
+	}).WithMaxSize(maxSessions)
+	if err := vadPool.Initialize(); err != nil {
 		log.Fatal(err)
 	}
 
 	// asr
-	asrPoolType := reflect.TypeOf(&asr.SherpaOnnxProvider{})
-	common.RegisterNewFunc(asrPoolType, func() (common.IPoolInstance, error) {
+	asrPool := common.NewPool("asr", 1, func() (*asr.SherpaOnnxProvider, error) {
 		sherpaOnnxProvider := asr.NewSherpaOnnxProvider(asr.NewDefaultSherpaOnnxOfflineRecognizerConfig())
 		return sherpaOnnxProvider, nil
-	})
-	asrPool := common.NewModuleProviderPool(1, asrPoolType)
-	err = asrPool.Initialize()
-	if err != nil {
+	}).WithMaxSize(maxSessions)
+	if err := asrPool.Initialize(); err != nil {
 		log.Fatal(err)
 	}
 
 	// tts
-	ttsPoolType := reflect.TypeOf(&tts.SherpaOnnxProvider{})
-	common.RegisterNewFunc(ttsPoolType, func() (common.IPoolInstance, error) {
+	ttsPool := common.NewPool("tts", 1, func() (*tts.SherpaOnnxProvider, error) {
 		sherpaOnnxProvider := tts.NewSherpaOnnxProvider(tts.NewDefaultSherpaOnnxOfflineTtsConfig(), tts.KokoroTTS_Speaker_ZM_YunJian, 1.0, "kokoroTTS")
+		// mixed zh/en text is synthesized by segments with the voice of each language
+		sherpaOnnxProvider.WithLanguageSpeaker(types.LanguageZh, tts.KokoroTTS_Speaker_ZM_YunJian).
+			WithLanguageSpeaker(types.LanguageEn, tts.KokoroTTS_Speaker_AM_Michael)
 		return sherpaOnnxProvider, nil
-	})
-	ttsPool := common.NewModuleProviderPool(1, ttsPoolType)
-	err = ttsPool.Initialize()
-	if err != nil {
+	}).WithMaxSize(maxSessions)
+	if err := ttsPool.Initialize(); err != nil {
 		log.Fatal(err)
 	}
 
 	return vadPool, asrPool, ttsPool
 }
 
-var vadPool, asrPool, ttsPool *common.ModuleProviderPool
+var (
+	vadPool *common.Pool[*vad_analyzer.SherpaOnnxProvider]
+	asrPool *common.Pool[*asr.SherpaOnnxProvider]
+	ttsPool *common.Pool[*tts.SherpaOnnxProvider]
+)
+
+const (
+	// maxSessions max concurrent sessions (rate limiter max conns), pools grow up to it
+	maxSessions = 3
+	// maxSessionsPerKey max concurrent sessions of a client (user, api key or ip)
+	maxSessionsPerKey = 2
+	// maxSessionDuration the session is ended after it
+	maxSessionDuration = 30 * time.Minute
+	// poolGetTimeout max wait for a free pool instance
+	poolGetTimeout = 5 * time.Second
+)
+
+// speaker verification shared by sessions, nil if the speaker embedding model is not downloaded
+var speakerVerifier *speaker.SpeakerVerifier
+var voiceprintsPath = filepath.Join(consts.CONFIG_DIR, "voiceprints.json")
+
+func loadSpeakerVerifier() *speaker.SpeakerVerifier {
+	provider := speaker.NewSherpaOnnxProvider(speaker.NewDefaultSherpaOnnxSpeakerEmbeddingExtractorConfig())
+	if provider == nil {
+		return nil
+	}
+	store := speaker.NewVoiceprintStore()
+	if utils.FileExists(voiceprintsPath) {
+		if err := store.Load(voiceprintsPath); err != nil {
+			log.Printf("load voiceprints err: %v", err)
+		}
+	}
+	return speaker.NewSpeakerVerifier(params.NewSpeakerVerifyArgs(), provider, store)
+}
+
+// spoken language identification shared by sessions, english speech is routed to the whisper asr pool;
+// nil if the whisper model is not downloaded
+var lidProvider common.ILanguageIDProvider
+var asrEnPool *common.Pool[*asr.SherpaOnnxProvider]
+
+func loadLanguageID() {
+	provider := lid.NewSherpaOnnxProvider(lid.NewDefaultSherpaOnnxSpokenLanguageIdentificationConfig())
+	if provider == nil {
+		return
+	}
+	lidProvider = provider
+
+	asrEnPool = common.NewPool("asr_en", 1, func() (*asr.SherpaOnnxProvider, error) {
+		return asr.NewSherpaOnnxProvider(asr.NewDefaultSherpaOnnxOfflineWhisperRecognizerConfig()), nil
+	}).WithMaxSize(maxSessions)
+	if err := asrEnPool.Initialize(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// wake word spotters hold per session decoding state, so pooled;
+// nil if the kws model is not downloaded (always listening)
+var kwsPool *common.Pool[*kws.SherpaOnnxProvider]
+
+func loadKeywordSpotter() {
+	config := kws.NewDefaultSherpaOnnxKeywordSpotterConfig()
+	if !utils.FileExists(config.ModelConfig.Tokens) {
+		return
+	}
+	kwsPool = common.NewPool("kws", 1, func() (*kws.SherpaOnnxProvider, error) {
+		provider := kws.NewSherpaOnnxProvider(config, params.NewWakeWordArgs().Keywords...)
+		if provider == nil {
+			return nil, fmt.Errorf("create keyword spotter failed")
+		}
+		return provider, nil
+	}).WithMaxSize(maxSessions)
+	if err := kwsPool.Initialize(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// tts synthesis cache shared by sessions, nil if the cache dir is not available
+var ttsCache *tts.TTSCache
+
+// loadTTSCache creates the tts cache and prewarms the phrases in tts_prewarm.txt (one phrase per line)
+func loadTTSCache() *tts.TTSCache {
+	cache, err := tts.NewTTSCache(params.NewTTSCacheArgs())
+	if err != nil {
+		log.Printf("load tts cache err: %v", err)
+		return nil
+	}
+
+	path := filepath.Join(consts.CONFIG_DIR, "tts_prewarm.txt")
+	if !utils.FileExists(path) {
+		return cache
+	}
+	phrases, err := tts.LoadPrewarmPhrases(path)
+	if err != nil {
+		log.Printf("load tts prewarm phrases err: %v", err)
+		return cache
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), poolGetTimeout)
+	defer cancel()
+	err = ttsPool.Do(ctx, func(ttsProvider *tts.SherpaOnnxProvider) error {
+		tts.NewCachedTTSProvider(ttsProvider, cache).Prewarm(phrases, nil)
+		return nil
+	})
+	if err != nil {
+		log.Printf("Get tts instance from pool err: %v", err)
+	}
+	return cache
+}
+
+// handleTTSCacheStats returns the tts cache stats, GET /tts/cache/stats
+func handleTTSCacheStats(w http.ResponseWriter, r *http.Request) {
+	if ttsCache == nil {
+		http.Error(w, "tts cache is not enabled", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(ttsCache.GetStats()); err != nil {
+		log.Printf("encode tts cache stats err: %v", err)
+	}
+}
+
+// span processor of the session traces, exported to records/traces.jsonl,
+// and to the OTLP collector if OTEL_EXPORTER_OTLP_ENDPOINT is set (e.g. http://localhost:4318)
+var spanProcessor *tracing.BatchSpanProcessor
+
+func loadSpanProcessor() *tracing.BatchSpanProcessor {
+	var exporters tracing.MultiExporter
+	fileExporter, err := tracing.NewJSONFileExporter(filepath.Join(consts.RECORDS_DIR, "traces.jsonl"))
+	if err != nil {
+		log.Printf("create trace file exporter err: %v", err)
+	} else {
+		exporters = append(exporters, fileExporter)
+	}
+	if endpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); endpoint != "" {
+		exporters = append(exporters, tracing.NewOTLPHTTPExporter(strings.TrimRight(endpoint, "/")+"/v1/traces", "achatbot"))
+	}
+	if len(exporters) == 0 {
+		return nil
+	}
+	return tracing.NewBatchSpanProcessor(exporters)
+}
+
+// bot profile selected by the "bot_profile" claim of the session token
+type botProfile struct {
+	SystemPrompt string
+	// default tts voice, the "voice" query param overrides it
+	Voice string
+}
+
+var botProfiles = map[string]botProfile{
+	"":      {SystemPrompt: consts.DefaultLLMSystemPrompt},
+	"tutor": {SystemPrompt: "You are a patient English tutor, answer briefly and correct the user's grammar mistakes."},
+}
+
+func getBotProfile(session *common.Session) botProfile {
+	if profile, ok := botProfiles[session.GetBotProfile()]; ok {
+		return profile
+	}
+	logger.Warn("unknown bot profile, use the default", "bot_profile", session.GetBotProfile())
+	return botProfiles[""]
+}
+
+// loadAuthenticator authenticates the websocket sessions before the upgrade:
+//   - AUTH_KEYS_FILE: json keys file (auth.KeysFile), reloaded when modified to rotate keys without restart;
+//     authentication is disabled if not set
+//   - AUTH_ISSUER, AUTH_AUDIENCE: required iss and aud claims if set
+//   - the exp claim is required, the session ends when the token expires
+//   - ALLOWED_ORIGINS: comma separated origin allowlist (e.g. https://app.example.com,https://*.example.com)
+//
+// the token is read from the Authorization: Bearer header or the ?token= query param
+func loadAuthenticator() (*middleware.Authenticator, *auth.FileKeyProvider) {
+	origins := strings.Split(os.Getenv("ALLOWED_ORIGINS"), ",")
+	keysFile := os.Getenv("AUTH_KEYS_FILE")
+	if keysFile == "" {
+		logger.Warn("AUTH_KEYS_FILE is not set, websocket sessions are not authenticated")
+		// ADMIN_ROUTES_UNAUTHENTICATED=true opts in to unauthenticated admin routes, e.g. for local testing
+		adminRoutesUnauthenticated, _ = strconv.ParseBool(os.Getenv("ADMIN_ROUTES_UNAUTHENTICATED"))
+		if adminRoutesUnauthenticated {
+			logger.Warn("ADMIN_ROUTES_UNAUTHENTICATED is set, unauthenticated callers are admins of the http routes")
+		} else {
+			logger.Warn("the mutating http routes are rejected without authentication, set ADMIN_ROUTES_UNAUTHENTICATED=true to allow them")
+		}
+		return middleware.NewAuthenticator(nil).WithEnable(false).WithAllowedOrigins(origins...), nil
+	}
+	keys, err := auth.NewFileKeyProvider(keysFile, 10*time.Second)
+	if err != nil {
+		log.Fatalf("load auth keys err: %v", err)
+	}
+	verifier := auth.NewVerifier(keys).WithIssuer(os.Getenv("AUTH_ISSUER")).WithAudience(os.Getenv("AUTH_AUDIENCE"))
+	return middleware.NewAuthenticator(verifier).WithAllowedOrigins(origins...), keys
+}
+
+// registerMetrics registers the pools, rate limiter, tts cache and active sessions to the metrics served by GET /metrics,
+// processing time and errors of the processors are recorded by the processors
+func registerMetrics(rateLimiter *middleware.RateLimiter, authenticator *middleware.Authenticator) {
+	registry := metrics.DefaultRegistry
+	registry.RegisterStats("pool", "vad", vadPool.GetStats, metrics.PoolStatsMetrics)
+	registry.RegisterStats("pool", "asr", asrPool.GetStats, metrics.PoolStatsMetrics)
+	registry.RegisterStats("pool", "tts", ttsPool.GetStats, metrics.PoolStatsMetrics)
+	if asrEnPool != nil {
+		registry.RegisterStats("pool", "asr_en", asrEnPool.GetStats, metrics.PoolStatsMetrics)
+	}
+	if kwsPool != nil {
+		registry.RegisterStats("pool", "kws", kwsPool.GetStats, metrics.PoolStatsMetrics)
+	}
+	registry.RegisterStats("server", "websocket", rateLimiter.GetStats, metrics.RateLimiterStatsMetrics)
+	registry.RegisterStats("server", "websocket", authenticator.GetStats, metrics.AuthStatsMetrics)
+	if ttsCache != nil {
+		registry.RegisterStats("cache", "tts", ttsCache.GetStats, []metrics.StatsMetric{
+			{Key: "memory_bytes", Name: "achatbot_cache_memory_bytes", Help: "Bytes of the memory cache.", Type: metrics.GaugeType},
+			{Key: "disk_bytes", Name: "achatbot_cache_disk_bytes", Help: "Bytes of the disk cache.", Type: metrics.GaugeType},
+			{Key: "memory_hits", Name: "achatbot_cache_memory_hits_total", Help: "Number of memory cache hits.", Type: metrics.CounterType},
+			{Key: "disk_hits", Name: "achatbot_cache_disk_hits_total", Help: "Number of disk cache hits.", Type: metrics.CounterType},
+			{Key: "misses", Name: "achatbot_cache_misses_total", Help: "Number of cache misses.", Type: metrics.CounterType},
+		})
+	}
+	registry.NewGaugeFunc("achatbot_active_sessions", "Number of active sessions.", nil, func(emit metrics.EmitFunc) {
+		serverMu.Lock()
+		n := len(activeSessions)
+		serverMu.Unlock()
+		emit(float64(n))
+	})
+}
+
+// pinyin dict for fuzzy correction of chinese asr hotwords, nil if pinyin.txt (pinyin-data format) not found
+var pinyinDict *utils.PinyinDict
+
+func loadPinyinDict() *utils.PinyinDict {
+	path := filepath.Join(consts.CONFIG_DIR, "pinyin.txt")
+	if !utils.FileExists(path) {
+		return nil
+	}
+	dict := utils.NewPinyinDict()
+	if err := dict.Load(path); err != nil {
+		log.Printf("load pinyin dict err: %v", err)
+		return nil
+	}
+	return dict
+}
 
 func init() {
 	logger.InitLoggerWithConfig(logger.NewDefaultLoggerConfig())
 	vadPool, asrPool, ttsPool = load()
+	speakerVerifier = loadSpeakerVerifier()
+	loadLanguageID()
+	loadKeywordSpotter()
+	pinyinDict = loadPinyinDict()
+	ttsCache = loadTTSCache()
+	spanProcessor = loadSpanProcessor()
+}
+
+// adminRoutesUnauthenticated treats the unauthenticated callers as admins when authentication is disabled
+var adminRoutesUnauthenticated bool
+
+// requestIdentity returns the authenticated identity of the request and whether it is an admin;
+// the routes are behind the authenticator, no identity means authentication is disabled (AUTH_KEYS_FILE not set),
+// the caller is not an admin unless ADMIN_ROUTES_UNAUTHENTICATED=true
+func requestIdentity(r *http.Request) (*types.Identity, bool) {
+	identity := middleware.IdentityFromContext(r.Context())
+	if identity == nil {
+		return nil, adminRoutesUnauthenticated
+	}
+	return identity, identity.IsAdmin()
+}
+
+// handleASRContext updates session asr hotwords and replacement dictionary at runtime,
+// POST /asr/context?session_id=xxx with json body {"hotwords":[{"phrase":"智谱","boost":2}],"replacements":{"至普":"智谱"}};
+// without session_id updates the caller's active sessions; only admins may update other users' sessions,
+// or all active sessions with all=true
+func handleASRContext(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	identity, admin := requestIdentity(r)
+	if identity == nil && !admin {
+		http.Error(w, "authentication is required", http.StatusForbidden)
+		return
+	}
+	allSessions := r.URL.Query().Get("all") == "true"
+	if allSessions && !admin {
+		http.Error(w, "updating all sessions requires admin", http.StatusForbidden)
+		return
+	}
+	if identity == nil && !allSessions && r.URL.Query().Get("session_id") == "" {
+		http.Error(w, "session_id or all=true is required", http.StatusBadRequest)
+		return
+	}
+	asrContext := &types.ASRContext{}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(asrContext); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sessionID := r.URL.Query().Get("session_id")
+	serverMu.Lock()
+	defer serverMu.Unlock()
+	updated := 0
+	for id, session := range activeSessions {
+		if sessionID != "" && id != sessionID {
+			continue
+		}
+		owned := identity != nil && session.GetIdentity() != nil && session.GetIdentity().UserID == identity.UserID
+		if !owned && !(admin && (sessionID != "" || allSessions)) {
+			// other users' sessions are reported as not found
+			continue
+		}
+		session.SetHotwords(asrContext.Hotwords)
+		session.SetASRReplacements(asrContext.Replacements)
+		updated++
+	}
+	if sessionID != "" && updated == 0 {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+	fmt.Fprintf(w, "updated %d sessions: %s\n", updated, asrContext)
+}
+
+// handleSpeakerEnroll enrolls user voiceprint, POST /speaker/enroll?user_id=xxx with 16k mono 16bit pcm body;
+// user_id defaults to the caller, only admins may enroll other users
+func handleSpeakerEnroll(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if speakerVerifier == nil {
+		http.Error(w, "speaker verification is not enabled", http.StatusServiceUnavailable)
+		return
+	}
+	identity, admin := requestIdentity(r)
+	if identity == nil && !admin {
+		http.Error(w, "authentication is required", http.StatusForbidden)
+		return
+	}
+	userID := r.URL.Query().Get("user_id")
+	if identity != nil {
+		if userID == "" {
+			userID = identity.UserID
+		}
+		if userID != identity.UserID && !admin {
+			http.Error(w, "enrolling other users requires admin", http.StatusForbidden)
+			return
+		}
+	}
+	if userID == "" {
+		http.Error(w, "user_id is required", http.StatusBadRequest)
+		return
+	}
+	audio, err := io.ReadAll(io.LimitReader(r.Body, 30*consts.DefaultRate*consts.DefaultSampleWidth))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := speakerVerifier.Enroll(userID, audio); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := speakerVerifier.GetStore().Save(voiceprintsPath); err != nil {
+		log.Printf("save voiceprints err: %v", err)
+	}
+	fmt.Fprintf(w, "enrolled %s\n", userID)
+}
+
+// parseTTSOptions parses the session tts options from the websocket query params
+func parseTTSOptions(r *http.Request) *types.TTSOptions {
+	query := r.URL.Query()
+	options := &types.TTSOptions{Speaker: query.Get("voice")}
+	for key, value := range map[string]*float32{"speed": &options.Speed, "pitch": &options.Pitch, "volume": &options.Volume} {
+		if query.Get(key) == "" {
+			continue
+		}
+		f, err := strconv.ParseFloat(query.Get(key), 32)
+		if err != nil {
+			log.Printf("invalid tts %s %q: %v", key, query.Get(key), err)
+			continue
+		}
+		*value = float32(f)
+	}
+	return options
 }
 
 // handleWebSocket handles incoming WebSocket connections
@@ -153,16 +535,30 @@ func handleWebSocket(w http.ResponseWriter, r *http.Request) {
 	clientId := fmt.Sprintf("%s_%s", conn.RemoteAddr().Network(), conn.RemoteAddr().String())
 	chatHistorySize := 2
 	session := common.NewSession(clientId, &chatHistorySize)
-	session.InitChatMessage(map[string]any{"role": "system", "content": consts.DefaultLLMSystemPrompt})
+	// authenticated user and bot profile
+	if identity := middleware.IdentityFromContext(r.Context()); identity != nil {
+		session.SetIdentity(identity)
+		logger.Info("session authenticated", "clientId", clientId, "identity", identity)
+	}
+	profile := getBotProfile(session)
+	session.InitChatMessage(map[string]any{"role": "system", "content": profile.SystemPrompt + " " + consts.TTSMarkupPrompt})
+
+	// wait for a free pool instance at most poolGetTimeout
+	poolCtx, poolCancel := context.WithTimeout(context.Background(), poolGetTimeout)
+	defer poolCancel()
 
 	// vad provider
-	vadPoolInstanceInfo, err := vadPool.Get()
+	// pool instances are returned when the session ends
+	vadProvider, releaseVAD, err := vadPool.Acquire(poolCtx)
 	if err != nil {
 		log.Printf("Get VAD instance from pool err: %v", err)
 		return
 	}
-	vadProvider := vadPoolInstanceInfo.GetInstance().(*vad_analyzer.SherpaOnnxProvider)
-	vadAnalyzer := vad_analyzer.NewVADAnalyzer(params.NewVADAnalyzerArgs(), vadProvider)
+	defer releaseVAD()
+	// 附加开始说话前 0.3s 音频避免首音被截断, 丢弃短于 0.2s 的咳嗽等噪声, 长于 30s 的独白强制切分;
+	// sherpa VAD 置信度为 0/1, 不做阈值校准
+	vadArgs := params.NewVADAnalyzerArgs().WithPreRollSecs(0.3).WithMinSpeechSecs(0.2).WithMaxSpeechSecs(30)
+	vadAnalyzer := vad_analyzer.NewVADAnalyzer(vadArgs, vadProvider)
 
 	// Wrap the connection to implement our interface
 	wsConn := &ExampleIWebSocketConn{Conn: conn}
@@ -174,6 +570,21 @@ func handleWebSocket(w http.ResponseWriter, r *http.Request) {
 	audioCameraParams.AudioVADParams.AudioParams.
 		WithAudioInEnabled(true).WithAudioOutEnabled(true).
 		WithAudioInSampleRate(consts.DefaultRate).WithAudioInSampleWidth(consts.DefaultSampleWidth).WithAudioInChannels(consts.DefaultChannels)
+	// per session input audio DSP (high-pass, noise gate, AGC) before VAD
+	dspParams := params.NewAudioDSPParams()
+	if err := dspParams.Validate(); err != nil {
+		log.Printf("audio dsp params err: %v", err)
+		return
+	}
+	audioCameraParams.AudioVADParams.WithAudioInPreprocessor(
+		audio_dsp.NewAudioInputDSP(dspParams, consts.DefaultRate, consts.DefaultSampleWidth),
+	)
+	// without client AEC, gate bot echo interruptions (EchoStrategyHalfDuplex / EchoStrategyFullDuplex are selectable)
+	echoParams := params.NewEchoParams().WithStrategy(types.EchoStrategyGated)
+	bytesPerMS := consts.DefaultRate * consts.DefaultChannels * consts.DefaultSampleWidth / 1000
+	audioCameraParams.AudioVADParams.WithEchoParams(echoParams).WithEchoReference(
+		utils.NewEchoReferenceBuffer(10*1000*bytesPerMS, echoParams.AECDelayMS*bytesPerMS, consts.DefaultChannels*consts.DefaultSampleWidth),
+	)
 
 	// Create WebSocket server parameters
 	wsParams := &params.WebsocketServerParams{
@@ -182,30 +593,105 @@ func handleWebSocket(w http.ResponseWriter, r *http.Request) {
 	}
 	wsParams.WithAudioOutFrameMS(200).WithAudioOutAddWavHeader(true) //200ms + wav head
 
-	// Set Websocket Transport Writer
-	transportWriter := achatbot_processors.NewWebsocketTransportWriter(wsConn, wsParams)
+	// Set Websocket Transport Writer, record the first bot audio written of each turn
+	turnMetrics := utils.NewTurnMetricsTracker(clientId)
+	// trace of the session, a span per turn with asr, llm, tool and tts child spans (nil tracer if tracing disabled)
+	var tracer *tracing.Tracer
+	if spanProcessor != nil {
+		tracer = tracing.NewSessionTracer(clientId, spanProcessor)
+	}
+	// conversation transcript of the session, records/transcripts/<session>.jsonl
+	transcriptRecorder, err := transcript.NewRecorder(filepath.Join(consts.RECORDS_DIR, "transcripts"), clientId)
+	if err != nil {
+		log.Printf("create transcript recorder err: %v", err)
+		return
+	}
+	defer transcriptRecorder.Close()
+	turnMetricsEvent, _ := strconv.ParseBool(r.URL.Query().Get("turn_metrics"))
+	var transportWriter common.ITransportWriter = achatbot_processors.NewTurnMetricsTransportWriter(
+		achatbot_processors.NewWebsocketTransportWriter(wsConn, wsParams), turnMetrics,
+	)
+	// time-aligned stereo recording of the conversation (user left, bot right), e.g. ws://host/ws?record=true
+	if record, _ := strconv.ParseBool(r.URL.Query().Get("record")); record {
+		conversationRecorder := recording.NewConversationRecorder(
+			filepath.Join(consts.RECORDS_DIR, "conversations"), strings.ReplaceAll(clientId, ":", "-"), consts.DefaultRate,
+			audioCameraParams.GetAudioInFormat(),
+			types.NewAudioFormat(consts.DefaultRate, consts.DefaultChannels, consts.DefaultSampleWidth),
+		).WithRotateInterval(10 * time.Minute)
+		defer conversationRecorder.Close()
+		audioCameraParams.AudioVADParams.WithAudioInTap(conversationRecorder.UserTap())
+		transportWriter = achatbot_processors.NewAudioTapTransportWriter(transportWriter, conversationRecorder.BotTap())
+	}
 	audioCameraParams.WithTransportWriter(transportWriter).WithAudioOutEnabled(true).
 		WithAudioOutSampleWidth(consts.DefaultSampleWidth).WithAudioOutSampleRate(consts.DefaultRate).WithAudioOutChannels(consts.DefaultChannels)
 
+	// Set Wake Word Processor, only open the listening window after the wake word (pass through if no kws model)
+	var spotter common.IKeywordSpotter
+	if kwsPool != nil {
+		kwsSpotter, releaseKWS, err := kwsPool.Acquire(poolCtx)
+		if err != nil {
+			log.Printf("Get KWS instance from pool err: %v", err)
+			return
+		}
+		defer releaseKWS()
+		spotter = kwsSpotter
+	}
+	wakeWordProcessor := achatbot_processors.NewWakeWordProcessor(params.NewWakeWordArgs(), spotter)
+
 	// Set ASR Processor
-	asrPoolInstanceInfo, err := asrPool.Get()
+	asrProvider, releaseASR, err := asrPool.Acquire(poolCtx)
 	if err != nil {
 		log.Printf("Get ASR instance from pool err: %v", err)
 		return
 	}
-	asrProvider := asrPoolInstanceInfo.GetInstance().(*asr.SherpaOnnxProvider)
-	asrProcessor := achatbot_processors.NewASRProcessor(asrProvider)
+	defer releaseASR()
+	// session hotwords (updatable by /asr/context) bias recognition and correct the transcription
+	asrProcessor := achatbot_processors.NewASRProcessor(asrProvider).WithSession(session).WithPinyinDict(pinyinDict).WithTracer(tracer)
+	if asrEnPool != nil {
+		asrEnProvider, releaseASREn, err := asrEnPool.Acquire(poolCtx)
+		if err != nil {
+			log.Printf("Get english ASR instance from pool err: %v", err)
+			return
+		}
+		defer releaseASREn()
+		asrProcessor.WithLanguageProvider(types.LanguageEn, asrEnProvider)
+	}
+	// Set Language ID Processor, annotate the utterance language and route to the asr provider of the language
+	languageIDProcessor := achatbot_processors.NewLanguageIDProcessor(params.NewLanguageIDArgs(), lidProvider)
+
+	// Set Speaker Verify Processor, only respond to enrolled voices (pass through if none enrolled)
+	var verifier common.ISpeakerVerifier
+	if speakerVerifier != nil {
+		verifier = speakerVerifier
+	}
+	speakerVerifyProcessor := achatbot_processors.NewSpeakerVerifyProcessor(params.NewSpeakerVerifyArgs(), verifier)
+
+	// Set Turn Analyzer Processor, decide end of user turn with silence + transcript (+ optional classifier)
+	turnAnalyzerProcessor := achatbot_processors.NewTurnAnalyzerProcessor(
+		turn_analyzer.NewTurnAnalyzer(params.NewTurnAnalyzerArgs(), nil),
+	)
 
 	// Set TTS Processor
-	ttsPoolInstanceInfo, err := ttsPool.Get()
+	sherpaTTSProvider, releaseTTS, err := ttsPool.Acquire(poolCtx)
 	if err != nil {
 		log.Printf("Get tts instance from pool err: %v", err)
 		return
 	}
-	ttsProvider := ttsPoolInstanceInfo.GetInstance().(*tts.SherpaOnnxProvider)
-	ttsProcessor := achatbot_processors.NewTTSProcessor(ttsProvider)
-	outRate, outChannels, outSampleWidth := ttsProvider.GetSampleInfo()
-	audioCameraParams.WithAudioOutSampleWidth(outSampleWidth).WithAudioOutSampleRate(outRate).WithAudioOutChannels(outChannels)
+	defer releaseTTS()
+	var ttsProvider common.ITTSProvider = sherpaTTSProvider
+	if ttsCache != nil {
+		// repeated greetings, fillers and answers are served from the cache
+		ttsProvider = tts.NewCachedTTSProvider(ttsProvider, ttsCache)
+	}
+	// per-session voice and speed, e.g. ws://host/ws?voice=zm_yunjian&speed=1.2
+	ttsOptions := parseTTSOptions(r)
+	if ttsOptions.Speaker == "" {
+		ttsOptions.Speaker = profile.Voice
+	}
+	ttsProcessor := achatbot_processors.NewTTSProcessor(ttsProvider).
+		WithOptions(ttsOptions).
+		WithMarkup(true).
+		WithTracer(tracer)
 
 	// Set LLM Processor
 	//llmProvider := llm.NewOllamaAPIProviderWithoutTools(llm.OllamaAPIProviderName, llm.OllamaAPIProviderModel_QWEN3_0_6, true, nil, nil)
@@ -214,11 +700,23 @@ func handleWebSocket(w http.ResponseWriter, r *http.Request) {
 	llmProvider := llm.NewOpenAIAPIProvider(llm.OllamaAPIProviderName, llm.OllamaAPIProviderBaseUrl, llm.OllamaAPIProviderModel_QWEN3_0_6, []string{"web_search"})
 	//llmProvider := llm.NewOpenAIAPIProvider(llm.OpenAIAPIProviderName, llm.OpenRouterAIAPIProviderBaseUrl, llm.OpenRouterAIAPIProviderModelQwen2_5_72b_free)
 	//llmProvider := llm.NewOpenAIAPIProvider(llm.OpenAIAPIProviderName, llm.OpenRouterAIAPIProviderBaseUrl, llm.OpenRouterAIAPIProviderModelQwen3_235b_free)
-	llmProcessor := llm_processors.NewLLMOpenAIApiProcessor(llmProvider, session, llm_processors.Mode_Chat, true, *types.NewLMGenerateArgs())
+	// SenseVoice recognizes user emotion, hint it to the llm
+	llmProcessor := llm_processors.NewLLMOpenAIApiProcessor(llmProvider, session, llm_processors.Mode_Chat, true, *types.NewLMGenerateArgs()).
+		WithEmotionHint(true).
+		WithTracer(tracer)
 
 	// Set Sentence Processor
 	sentenceProcessor := aggregators.NewSentenceAggregatorWithEnd(reflect.TypeOf(&achatbot_frames.TurnEndFrame{}))
 
+	// Set Text Normalize Processor, make llm sentences speakable, with custom pronunciation lexicon (word=pronunciation per line)
+	textNormalizer := utils.NewTextNormalizer("")
+	if lexiconPath := filepath.Join(consts.CONFIG_DIR, "tts_lexicon.txt"); utils.FileExists(lexiconPath) {
+		if err := textNormalizer.LoadLexicon(lexiconPath); err != nil {
+			log.Printf("load tts lexicon err: %v", err)
+		}
+	}
+	textNormalizeProcessor := achatbot_processors.NewTextNormalizeProcessor(textNormalizer)
+
 	// 1. Create the WebSocket server input processor
 	ws_transport := transports.NewWebsocketTransport(
 		wsConn,
@@ -226,7 +724,8 @@ func handleWebSocket(w http.ResponseWriter, r *http.Request) {
 	)
 
 	// 2. Create a simple pipeline with the async processor
-	myPipeline := pipeline.NewPipelineWithVerbose(
+	// negotiate audio formats, auto insert audio format convert processors (e.g. tts out -> client audio out)
+	pipelineProcessors, err := achatbot_processors.NegotiateAudioFormats(
 		[]processors.IFrameProcessor{
 			processors.NewDefaultFrameLoggerProcessorWithIncludeFrame(
 				[]frames.Frame{&frames.StartFrame{}, &frames.EndFrame{}, &frames.CancelFrame{}},
@@ -234,30 +733,49 @@ func handleWebSocket(w http.ResponseWriter, r *http.Request) {
 			processors.NewDefaultFrameLoggerProcessorWithIncludeFrame([]frames.Frame{&achatbot_frames.BotSpeakingFrame{}}).WithMaxIdToLogs([]uint64{}),
 
 			ws_transport.InputProcessor(),
+			achatbot_processors.NewTurnTracingProcessor(tracer),
+			achatbot_processors.NewTurnMetricsProcessor(turnMetrics, types.TurnStageVADStop),
+			wakeWordProcessor,
 			achatbot_aggregators.NewAudioResponseAggregatorWithAccumulate(
 				reflect.TypeOf(&achatbot_frames.UserStartedSpeakingFrame{}),
 				reflect.TypeOf(&achatbot_frames.UserStoppedSpeakingFrame{}),
 				reflect.TypeOf(&achatbot_frames.VADStateAudioRawFrame{}),
 			),
+			achatbot_processors.NewTurnMetricsProcessor(turnMetrics, types.TurnStageAggregatorFlush),
 			processors.NewDefaultFrameLoggerProcessorWithIncludeFrame([]frames.Frame{&frames.AudioRawFrame{}, &achatbot_frames.VADStateAudioRawFrame{}}),
+			speakerVerifyProcessor,
+			languageIDProcessor,
 			//achatbot_processors.NewAudioSaveProcessor("user_speak", consts.RECORDS_DIR, true),
 			asrProcessor.WithPassRawAudio(false),
-			processors.NewDefaultFrameLoggerProcessorWithIncludeFrame([]frames.Frame{&frames.TextFrame{}}),
+			achatbot_processors.NewTurnMetricsProcessor(turnMetrics, types.TurnStageASRDone),
+			processors.NewDefaultFrameLoggerProcessorWithIncludeFrame([]frames.Frame{&frames.TextFrame{}, &achatbot_frames.TranscriptionFrame{}}),
+			turnAnalyzerProcessor,
+			processors.NewDefaultFrameLoggerProcessorWithIncludeFrame([]frames.Frame{&achatbot_frames.UserEndOfTurnFrame{}}),
+			achatbot_processors.NewUserTranscriptRecorderProcessor(transcriptRecorder),
 			llmProcessor,
+			achatbot_processors.NewTurnMetricsProcessor(turnMetrics, types.TurnStageLLMFirstToken),
 			//processors.NewDefaultFrameLoggerProcessorWithIncludeFrame([]frames.Frame{&achatbot_frames.ThinkTextFrame{}, &frames.TextFrame{}}),
 			sentenceProcessor,
+			achatbot_processors.NewTurnMetricsProcessor(turnMetrics, types.TurnStageFirstSentence),
+			textNormalizeProcessor,
 			processors.NewDefaultFrameLoggerProcessorWithIncludeFrame([]frames.Frame{&frames.TextFrame{}}),
 			ttsProcessor.WithPassText(true),
+			// log the latency breakdown of each turn and the session percentiles, e.g. ws://host/ws?turn_metrics=true to send to the client
+			achatbot_processors.NewTurnMetricsProcessor(turnMetrics, types.TurnStageTTSFirstAudio).WithReport(turnMetricsEvent),
 			processors.NewDefaultFrameLoggerProcessorWithIncludeFrame([]frames.Frame{&frames.AudioRawFrame{}}),
 			//achatbot_processors.NewAudioResampleProcessor(audioCameraParams.AudioOutSampleRate),
 			//processors.NewDefaultFrameLoggerProcessorWithIncludeFrame([]frames.Frame{&frames.AudioRawFrame{}}),
 			//achatbot_processors.NewAudioSaveProcessor("bot_speak", consts.RECORDS_DIR, true),
+			achatbot_processors.NewBotTranscriptRecorderProcessor(transcriptRecorder),
 			processors.NewDefaultFrameLoggerProcessorWithIncludeFrame([]frames.Frame{&frames.AudioRawFrame{}}),
 			ws_transport.OutputProcessor(),
 		},
-		nil, nil,
-		false,
 	)
+	if err != nil {
+		log.Printf("Negotiate pipeline audio formats err: %v", err)
+		return
+	}
+	myPipeline := pipeline.NewPipelineWithVerbose(pipelineProcessors, nil, nil, false)
 	logger.Info(myPipeline.String())
 
 	// In a real application, you would integrate this with your frame processing pipeline
@@ -269,18 +787,33 @@ func handleWebSocket(w http.ResponseWriter, r *http.Request) {
 	// Add task to active tasks map
 	serverMu.Lock()
 	activeTasks[task] = true
+	activeSessions[clientId] = session
 	serverMu.Unlock()
 
 	// Remove task from active tasks when done
 	defer func() {
 		serverMu.Lock()
 		delete(activeTasks, task)
+		delete(activeSessions, clientId)
 		serverMu.Unlock()
+	}()
 
-		// put to pool
-		asrPool.Put(vadPoolInstanceInfo)
-		asrPool.Put(asrPoolInstanceInfo)
-		asrPool.Put(ttsPoolInstanceInfo)
+	// end the session when the request context is done (rate limiter max session duration) or the token expires
+	sessionCtx := r.Context()
+	if identity := session.GetIdentity(); identity != nil && !identity.ExpiresAt.IsZero() {
+		var cancel context.CancelFunc
+		sessionCtx, cancel = context.WithDeadline(sessionCtx, identity.ExpiresAt)
+		defer cancel()
+	}
+	taskDone := make(chan struct{})
+	defer close(taskDone)
+	go func() {
+		select {
+		case <-sessionCtx.Done():
+			logger.Info("session context done, cancel task", "clientId", clientId, "err", sessionCtx.Err())
+			task.Cancel()
+		case <-taskDone:
+		}
 	}()
 
 	task.Run()
@@ -293,8 +826,27 @@ func main() {
 	}
 
 	// Set up the WebSocket endpoint with Rate Limiter middleware, set max one connect for local test
-	rateLimiter := middleware.NewDefaultRateLimiter().WithEnable(true).WithMaxConns(3)
-	http.Handle("/", rateLimiter.Middleware(http.HandlerFunc(handleWebSocket)))
+	// TRUSTED_PROXIES: comma separated CIDRs of the proxies in front of the server whose X-Forwarded-For is used
+	rateLimiter := middleware.NewDefaultRateLimiter().WithEnable(true).WithMaxConns(maxSessions).
+		WithTrustedProxies(strings.Split(os.Getenv("TRUSTED_PROXIES"), ",")...).
+		WithKeyFunc(middleware.UserIDKeyFunc()).
+		WithMaxSessionsPerKey(maxSessionsPerKey).
+		WithMaxSessionDuration(maxSessionDuration).
+		WithBan(10, time.Minute, 5*time.Minute).
+		WithAuthFailureLimit(5, 5)
+	// authenticate before the rate limiter to limit by the authenticated user (client ip if auth is disabled),
+	// failed authentications are limited (5 per minute) and banned by the client ip before the token check
+	authenticator, authKeys := loadAuthenticator()
+	authenticator.WithFailureLimiter(rateLimiter)
+	upgrader.CheckOrigin = authenticator.CheckOrigin
+	http.Handle("/", authenticator.Middleware(rateLimiter.Middleware(http.HandlerFunc(handleWebSocket))))
+	// the http routes are authenticated too, the mutating ones check the caller (requestIdentity):
+	// without authentication they are rejected unless ADMIN_ROUTES_UNAUTHENTICATED=true
+	http.Handle("/speaker/enroll", authenticator.Middleware(http.HandlerFunc(handleSpeakerEnroll)))
+	http.Handle("/asr/context", authenticator.Middleware(http.HandlerFunc(handleASRContext)))
+	http.Handle("/tts/cache/stats", authenticator.Middleware(http.HandlerFunc(handleTTSCacheStats)))
+	registerMetrics(rateLimiter, authenticator)
+	http.Handle("/metrics", authenticator.Middleware(metrics.DefaultRegistry.Handler()))
 
 	// Channel to listen for interrupt signal
 	sigChan := make(chan os.Signal, 1)
@@ -335,9 +887,26 @@ func main() {
 	vadPool.Close()
 	asrPool.Close()
 	ttsPool.Close()
+	if asrEnPool != nil {
+		asrEnPool.Close()
+	}
+	if kwsPool != nil {
+		kwsPool.Close()
+	}
+
+	if authKeys != nil {
+		authKeys.Close()
+	}
 
 	// Wait a bit for tasks to finish cleanup
 	time.Sleep(1 * time.Second)
 
+	// export the remaining spans
+	if spanProcessor != nil {
+		if err := spanProcessor.Shutdown(); err != nil {
+			log.Printf("shutdown span processor err: %v", err)
+		}
+	}
+
 	logger.Info("Server exited gracefully")
 }
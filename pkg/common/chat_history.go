@@ -3,6 +3,8 @@ package common
 import (
 	"maps"
 	"encoding/json"
+	"fmt"
+	"strings"
 )
 
 // ChatHistory buffers the local chat history with limit size to avoid LLM context too long
@@ -71,6 +73,43 @@ func (ch *ChatHistory) Pop(index int) {
 	}
 }
 
+// TruncateLastAssistantMessage replaces the content of the last assistant reply with the spoken text
+// when the user interrupts the bot and only heard part of the reply, the reply is removed if nothing was heard.
+// returns false if there is no assistant reply after the last user message
+func (ch *ChatHistory) TruncateLastAssistantMessage(spokenText string) bool {
+	for i := len(ch.buffer) - 1; i >= 0; i-- {
+		_, role := messageField(ch.buffer[i], "role")
+		if role == "user" {
+			return false
+		}
+		contentKey, content := messageField(ch.buffer[i], "content")
+		if role != "assistant" || content == "" {
+			// tool calls and tool results
+			continue
+		}
+
+		if strings.TrimSpace(spokenText) == "" {
+			ch.Pop(i)
+			return true
+		}
+		ch.buffer[i][contentKey] = spokenText
+		ch.buffer[i]["interrupted"] = true
+		return true
+	}
+	return false
+}
+
+// messageField gets the message field case-insensitively,
+// messages converted from structs use the field names as keys (e.g. Role, Content)
+func messageField(item map[string]any, name string) (string, string) {
+	for key, value := range item {
+		if strings.EqualFold(key, name) && value != nil {
+			return key, fmt.Sprint(value)
+		}
+	}
+	return "", ""
+}
+
 // Init sets the initial chat message
 func (ch *ChatHistory) Init(initChatMessage map[string]any) {
 	ch.initChatMessage = initChatMessage
@@ -99,6 +99,32 @@ func TestChatHistoryPop(t *testing.T) {
 	}
 }
 
+func TestChatHistoryTruncateLastAssistantMessage(t *testing.T) {
+	ch := NewChatHistory(nil, nil, nil)
+	ch.Append(map[string]any{"role": "user", "content": "test1"})
+	ch.Append(map[string]any{"Role": "assistant", "Content": "response1. more"})
+
+	if !ch.TruncateLastAssistantMessage("response1.") {
+		t.Fatal("Expected assistant message truncated")
+	}
+	if ch.buffer[1]["Content"] != "response1." {
+		t.Errorf("Expected content 'response1.', got '%v'", ch.buffer[1]["Content"])
+	}
+
+	// nothing heard, the reply is removed
+	if !ch.TruncateLastAssistantMessage("") {
+		t.Fatal("Expected assistant message removed")
+	}
+	if len(ch.buffer) != 1 {
+		t.Errorf("Expected buffer length 1 after removing, got %d", len(ch.buffer))
+	}
+
+	// no assistant reply after the last user message
+	if ch.TruncateLastAssistantMessage("response") {
+		t.Error("Expected no assistant message to truncate")
+	}
+}
+
 func TestChatHistoryInit(t *testing.T) {
 	ch := NewChatHistory(nil, nil, nil)
 	msg := map[string]any{"role": "system", "content": "You are a helpful assistant"}
@@ -19,6 +19,50 @@ type IPoolInstance interface {
 	Release() error
 }
 
+// IPoolHealthChecker 可选: 池在借出和归还实例时检查实例是否可用, 不可用的实例被释放并替换
+type IPoolHealthChecker interface {
+	// HealthCheck 返回 nil 表示实例可用
+	HealthCheck() error
+}
+
+// IAudioFormatDeclarer 处理器/提供者声明接受的输入音频格式和产生的输出音频格式,
+// 构建 pipeline 时用于自动协商并插入音频格式转换处理器
+type IAudioFormatDeclarer interface {
+	// GetAudioInFormat 返回接受的输入音频格式, nil 表示不关心输入音频格式
+	GetAudioInFormat() *types.AudioFormat
+
+	// GetAudioOutFormat 返回产生的输出音频格式, nil 表示不产生音频(透传上游音频格式)
+	GetAudioOutFormat() *types.AudioFormat
+}
+
+// IAudioPreprocessor 输入音频预处理(降噪/增益等), 在 VAD 分析前处理麦克风音频, 每个会话独立状态
+type IAudioPreprocessor interface {
+	// Process 处理音频, 返回等长音频
+	Process(audio []byte) []byte
+
+	// Reset 重置状态。
+	Reset() error
+}
+
+// IEchoReference 机器人输出音频参考信号(回声消除用),
+// 输出处理器写入已播放的机器人音频, 输入处理器按麦克风音频时间轴读取等长参考信号
+type IEchoReference interface {
+	// WriteReference 写入机器人输出音频(与麦克风输入音频格式一致)
+	WriteReference(audio []byte)
+
+	// ReadReference 读取 n 字节参考信号, 不足部分补静音
+	ReadReference(n int) []byte
+
+	// Reset 清空参考信号(如打断后客户端停止播放)
+	Reset()
+}
+
+// IAudioTap 音频旁路(如会话录音), 接收流经的音频, 不修改音频, 不应阻塞
+type IAudioTap interface {
+	// WriteAudio 写入音频(调用方声明的音频格式)
+	WriteAudio(audio []byte)
+}
+
 // IVADAnalyzer 定义了语音活动检测（VAD）分析器的接口。
 type IVADAnalyzer interface {
 	// AnalyzeAudio 对输入的音频缓冲区进行分析，返回 VAD Frame
@@ -40,6 +84,10 @@ type IVoiceConfidenceProvider interface {
 	// IsActiveSpeech 判断当前音频是否是活跃的语音。
 	IsActiveSpeech(audio []byte) bool
 
+	// GetVoiceConfidence 返回当前音频窗口的语音置信度 [0, 1]。
+	// 与 IsActiveSpeech 一样会推进内部状态, 同一窗口只调用其中之一。
+	GetVoiceConfidence(audio []byte) float32
+
 	// Warmup 预热
 	Warmup()
 
@@ -54,6 +102,41 @@ type IVoiceConfidenceProvider interface {
 	IPoolInstance
 }
 
+// IVoiceConfidenceSegmentResetter 可选: 只重置单段语音状态(如 hangover), 保留会话内学到的自适应状态(噪声底);
+// 会话内候选语音不成立时调用, 未实现时调用 Reset
+type IVoiceConfidenceSegmentResetter interface {
+	ResetSegment()
+}
+
+// IBinaryVoiceConfidence 可选: 置信度只有 0/1(模型内部按阈值判定, 如 sherpa-onnx Silero/TEN VAD),
+// VAD 分析器跳过置信度校准, 滞回阈值不起作用
+type IBinaryVoiceConfidence interface {
+	IsBinaryConfidence() bool
+}
+
+// ------------------------------------------------------------
+
+// ITurnAnalyzer 轮次分析器, 判断用户是否说完(轮次结束)
+type ITurnAnalyzer interface {
+	// AnalyzeEndOfTurn 根据当前轮累计的转录文本, 音频(可为空)和已静音时长判断是否轮次结束
+	AnalyzeEndOfTurn(transcript string, audio []byte, silenceSecs float64) *types.EndOfTurnResult
+
+	// ObservePause 记录用户轮内停顿时长, 用于自适应静音超时
+	ObservePause(pauseSecs float64)
+
+	// Reset 重置状态。
+	Reset() error
+}
+
+// ITurnClassifier 可选的轮次结束分类小模型
+type ITurnClassifier interface {
+	// PredictEndOfTurn 返回用户说完的概率(0~1)
+	PredictEndOfTurn(transcript string, audio []byte) (float64, error)
+
+	// Name 返回分类模型名称。
+	Name() string
+}
+
 // ------------------------------------------------------------
 
 // IASRProvider local语音识别提供者接口
@@ -72,6 +155,82 @@ type IASRProvider interface {
 	IPoolInstance
 }
 
+// IRichASRProvider 可选: 返回带时间戳, 语种, 情绪和音频事件的识别结果
+type IRichASRProvider interface {
+	// TranscribeResult 语音识别, 返回完整识别结果
+	TranscribeResult(audio []byte) *types.ASRResult
+}
+
+// IHotwordsASRProvider 支持热词(上下文偏置)的语音识别提供者, 可选实现
+type IHotwordsASRProvider interface {
+	// SetHotwords 设置热词, 为空时清除; 模型不支持热词时返回错误
+	SetHotwords(hotwords []types.Hotword) error
+}
+
+// ------------------------------------------------------------
+
+// ISpeakerEmbeddingProvider local说话人声纹提取提供者接口
+type ISpeakerEmbeddingProvider interface {
+	// ComputeEmbedding 提取音频的说话人声纹向量
+	ComputeEmbedding(audio []byte) ([]float32, error)
+
+	// Warmup 预热
+	Warmup()
+
+	// Name 返回声纹提取提供者的名称。
+	Name() string
+
+	// Release 释放资源。
+	// Reset 重置状态。
+	IPoolInstance
+}
+
+// ISpeakerVerifier 说话人验证, 注册用户声纹并验证语音是否来自注册用户
+type ISpeakerVerifier interface {
+	// Enroll 使用用户语音注册(多次注册取平均)声纹
+	Enroll(userID string, audio []byte) error
+
+	// Verify 验证语音与注册声纹是否匹配
+	Verify(audio []byte) (*types.SpeakerVerifyResult, error)
+
+	// NumEnrolled 返回已注册的用户数
+	NumEnrolled() int
+}
+
+// ------------------------------------------------------------
+
+// ILanguageIDProvider local语种识别提供者接口
+type ILanguageIDProvider interface {
+	// IdentifyLanguage 识别语音的语种(如 zh, en)
+	IdentifyLanguage(audio []byte) (string, error)
+
+	// Warmup 预热
+	Warmup()
+
+	// Name 返回语种识别提供者的名称。
+	Name() string
+
+	// Release 释放资源。
+	// Reset 重置状态。
+	IPoolInstance
+}
+
+// IKeywordSpotter local唤醒词检测(KWS)提供者接口, 流式检测, 实例持有会话的解码状态
+type IKeywordSpotter interface {
+	// DetectKeyword 流式输入音频, 检测到唤醒词时返回唤醒词, 否则返回空
+	DetectKeyword(audio []byte) (string, error)
+
+	// Warmup 预热
+	Warmup()
+
+	// Name 返回唤醒词检测提供者的名称。
+	Name() string
+
+	// Release 释放资源。
+	// Reset 重置解码状态, 新的语音段重新检测。
+	IPoolInstance
+}
+
 // ------------------------------------------------------------
 
 type OpenAIStreamChatCompletionRespFunc func(*openai.ChatCompletionChunk) error
@@ -121,6 +280,27 @@ type ITTSProvider interface {
 	IPoolInstance
 }
 
+// IMultiLanguageTTSProvider 可选: 按语种选择音色合成语音(如中英混合文本分段合成)
+type IMultiLanguageTTSProvider interface {
+	// SynthesizeLanguage 使用语种对应的音色合成语音, 未配置的语种使用默认音色
+	SynthesizeLanguage(text string, language string) []byte
+
+	// GetLanguages 返回配置了音色的语种, 为空时不按语种分段合成
+	GetLanguages() []string
+}
+
+// ITTSOptionsProvider 可选: 按单次合成选项(音色, 语速, 音高, 音量)合成语音, 池化实例可按会话选择音色
+type ITTSOptionsProvider interface {
+	// SynthesizeWithOptions 按选项合成语音, 零值选项使用默认值
+	SynthesizeWithOptions(text string, options *types.TTSOptions) []byte
+}
+
+// ITTSSpeakerResolver 可选: 解析选项实际使用的音色(默认音色, 语种音色, 音色名或 ID 统一为同一标识), 用于合成缓存键
+type ITTSSpeakerResolver interface {
+	// ResolveSpeaker 返回按选项合成时使用的音色标识, nil 选项为默认音色
+	ResolveSpeaker(options *types.TTSOptions) string
+}
+
 // --------------------------------------------------------------------
 
 // We'll use the standard net/http package for WebSocket support
@@ -11,111 +11,157 @@ import (
 	"github.com/weedge/pipeline-go/pkg/logger"
 )
 
-type NewFunc func() (IPoolInstance, error)
-
-var newFuncMap = make(map[reflect.Type]NewFunc)
-
-// init to RegisterNewFunc/GetNewFunc
-func RegisterNewFunc(poolType reflect.Type, newFunc NewFunc) {
-	newFuncMap[poolType] = newFunc
-}
-func GetNewFunc(poolType reflect.Type) NewFunc {
-	if newFunc, ok := newFuncMap[poolType]; ok {
-		return newFunc
-	}
-	return nil
-}
+const (
+	// DefaultPoolGrowWait 没有空闲实例时, 等待此时长后再创建超出 poolSize 的实例
+	DefaultPoolGrowWait = 100 * time.Millisecond
+	// DefaultPoolIdleTTL 超出 poolSize 的实例空闲此时长后被释放
+	DefaultPoolIdleTTL = 5 * time.Minute
+)
 
-type PoolInstanceInfo struct {
+// PoolItem 池中的实例及其借出状态
+type PoolItem[T IPoolInstance] struct {
 	instanceID int64
 	inUse      int32
 	lastUsed   int64
-	instance   IPoolInstance
+	instance   T
+	pool       *Pool[T]
 }
 
-func (p *PoolInstanceInfo) GetInstance() IPoolInstance {
+func (p *PoolItem[T]) GetInstance() T {
 	return p.instance
 }
 
-// ModuleProviderPool module provider 资源池
-type ModuleProviderPool struct {
-	poolInstances chan *PoolInstanceInfo
+// Pool 类型化的 module provider 资源池, 构造函数直接传入, 无需类型断言
+// - poolSize 常驻实例数, maxSize 最大实例数(空闲 + 借出), 超出 poolSize 的实例空闲 idleTTL 后释放
+// - Get 没有空闲实例时等待, 等待 growWait 后未满 maxSize 则创建新实例, 否则等到有实例归还或 ctx 超时
+// - 实例实现 IPoolHealthChecker 时借出和归还都检查, 不可用(或 Reset 失败)的实例释放并替换
+type Pool[T IPoolInstance] struct {
+	poolInstances chan *PoolItem[T]
 	poolSize      int
-	poolType      reflect.Type
-	newFunc       NewFunc
+	maxSize       int
+	growWait      time.Duration
+	idleTTL       time.Duration
+	name          string
+	newFunc       func() (T, error)
 	stopCh        chan struct{}
+	reaperOnce    sync.Once
 
 	// stats info
-	totalCreated int64 // init create or beyond create +1
-	totalReused  int64 // get from poolInstances channel to reuse +1
-	totalActive  int64 // get from poolInstances channel become active +1 and put to poolInstances channel become disactive -1
+	numInstances   int64 // live instances (idle + in use), reserved before creating
+	totalCreated   int64 // init create or beyond create +1
+	totalReused    int64 // get from poolInstances channel to reuse +1
+	totalActive    int64 // get from poolInstances channel become active +1 and put to poolInstances channel become disactive -1
+	totalReaped    int64 // idle beyond instances released
+	totalUnhealthy int64 // unhealthy instances released and replaced
+	totalGets      int64 // successful Get +1
+	totalTimeouts  int64 // Get canceled or timed out by ctx +1
+	totalWaitNanos int64 // time spent in successful Get
 
 	mu     sync.RWMutex
 	ctx    context.Context
 	cancel context.CancelFunc
 }
 
-// NewModuleProviderPool 创建新的资源池
-func NewModuleProviderPool(poolSize int, poolType reflect.Type) *ModuleProviderPool {
+// NewPool 创建类型化的资源池, name 用于日志, 默认最大实例数为 poolSize
+func NewPool[T IPoolInstance](name string, poolSize int, newFunc func() (T, error)) *Pool[T] {
 	ctx, cancel := context.WithCancel(context.Background())
 
-	pool := &ModuleProviderPool{
-		poolInstances: make(chan *PoolInstanceInfo, poolSize),
+	return &Pool[T]{
+		poolInstances: make(chan *PoolItem[T], poolSize),
 		poolSize:      poolSize,
-		poolType:      poolType,
-		newFunc:       GetNewFunc(poolType),
+		maxSize:       poolSize,
+		growWait:      DefaultPoolGrowWait,
+		idleTTL:       DefaultPoolIdleTTL,
+		name:          name,
+		newFunc:       newFunc,
 		ctx:           ctx,
 		cancel:        cancel,
 		stopCh:        make(chan struct{}),
 	}
+}
+
+// WithMaxSize 设置最大实例数(>= poolSize), 需在 Initialize 之前调用
+func (p *Pool[T]) WithMaxSize(maxSize int) *Pool[T] {
+	if maxSize < p.poolSize {
+		maxSize = p.poolSize
+	}
+	p.maxSize = maxSize
+	p.poolInstances = make(chan *PoolItem[T], maxSize)
+	return p
+}
+
+// WithGrowWait 设置没有空闲实例时创建新实例前的等待时长
+func (p *Pool[T]) WithGrowWait(growWait time.Duration) *Pool[T] {
+	p.growWait = growWait
+	return p
+}
 
-	return pool
+// WithIdleTTL 设置超出 poolSize 的实例的最长空闲时长, <= 0 不释放
+func (p *Pool[T]) WithIdleTTL(idleTTL time.Duration) *Pool[T] {
+	p.idleTTL = idleTTL
+	return p
 }
 
-// createNewInstance 创建新的实例
-func (p *ModuleProviderPool) createNewInstanceInfo() (*PoolInstanceInfo, error) {
+// createNewInstance 创建新的实例, 调用前需已预留实例数
+func (p *Pool[T]) createNewInstanceInfo() (*PoolItem[T], error) {
 	instance, err := p.newFunc()
 	if err != nil {
 		return nil, err
 	}
 
-	instanceInfo := &PoolInstanceInfo{
+	instanceInfo := &PoolItem[T]{
 		instance:   instance,
 		lastUsed:   time.Now().UnixNano(),
 		inUse:      0,
 		instanceID: atomic.AddInt64(&p.totalCreated, 1),
+		pool:       p,
 	}
 
 	logger.Infof("Created New InstanceInfo")
 	return instanceInfo, nil
 }
 
+// reserveInstance 预留一个实例数, 达到 limit 时返回 false
+func (p *Pool[T]) reserveInstance(limit int) bool {
+	for {
+		n := atomic.LoadInt64(&p.numInstances)
+		if n >= int64(limit) {
+			return false
+		}
+		if atomic.CompareAndSwapInt64(&p.numInstances, n, n+1) {
+			return true
+		}
+	}
+}
+
 // Initialize 并行初始化池
-func (p *ModuleProviderPool) Initialize() error {
+func (p *Pool[T]) Initialize() error {
 	logger.Infof("Initializing pool with %d instances...", p.poolSize)
 	if p.newFunc == nil {
-		return fmt.Errorf("no NewFunc registered for pool type %s", p.poolType)
+		return fmt.Errorf("no NewFunc for pool %s", p.name)
 	}
 
 	var initWg sync.WaitGroup
 	errorChan := make(chan error, p.poolSize)
 	for i := 0; i < p.poolSize; i++ {
+		if !p.reserveInstance(p.poolSize) {
+			break
+		}
 		initWg.Add(1)
 		go func(instanceID int) {
 			defer initWg.Done()
 
 			instanceInfo, err := p.createNewInstanceInfo()
 			if err != nil {
+				atomic.AddInt64(&p.numInstances, -1)
 				errorChan <- fmt.Errorf("createNewInstanceInfo err: %s", err.Error())
 				return
 			}
 
-			select {
-			case p.poolInstances <- instanceInfo:
-				logger.Infof("%s Instance#%d Initialized", p.poolType, instanceID)
-			default:
-				instanceInfo.instance.Release()
-				errorChan <- fmt.Errorf("pool queue full, %s instance#%d release", p.poolType, instanceID)
+			if p.putIdle(instanceInfo) {
+				logger.Infof("%s Instance#%d Initialized", p.name, instanceID)
+			} else {
+				errorChan <- fmt.Errorf("pool queue full, %s instance#%d release", p.name, instanceID)
 			}
 		}(i)
 	}
@@ -132,18 +178,34 @@ func (p *ModuleProviderPool) Initialize() error {
 	}
 
 	successCount := p.poolSize - len(initErrors)
-	logger.Infof("pool initialized with %d/%d %s instances", successCount, p.poolSize, p.poolType)
+	logger.Infof("pool initialized with %d/%d %s instances (max %d)", successCount, p.poolSize, p.name, p.maxSize)
 
 	if len(initErrors) > 0 && successCount == 0 {
-		return fmt.Errorf("failed to initialize any %s instances", p.poolType)
+		return fmt.Errorf("failed to initialize any %s instances", p.name)
 	}
 
+	p.startReaper()
 	return nil
 }
 
-// Get 获取实例
-func (p *ModuleProviderPool) Get() (*PoolInstanceInfo, error) {
-	logger.Infof("Attempting to get %s instance from pool (available: %d)", p.poolType, len(p.poolInstances))
+// Get 获取实例, 没有空闲实例且达到最大实例数时阻塞直到有实例归还, ctx 取消或超时返回错误
+func (p *Pool[T]) Get(ctx context.Context) (*PoolItem[T], error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if p.ctx.Err() != nil {
+		return nil, fmt.Errorf("pool is shutting down")
+	}
+	logger.Infof("Attempting to get %s instance from pool (available: %d)", p.name, len(p.poolInstances))
+	start := time.Now()
+
+	// below poolSize (not initialized or unhealthy released), create without waiting
+	growWait := time.Duration(0)
+	if atomic.LoadInt64(&p.numInstances) >= int64(p.poolSize) {
+		growWait = p.growWait
+	}
+	growTimer := time.NewTimer(growWait)
+	defer growTimer.Stop()
 
 	for {
 		select {
@@ -151,88 +213,270 @@ func (p *ModuleProviderPool) Get() (*PoolInstanceInfo, error) {
 			if instanceInfo == nil {
 				return nil, fmt.Errorf("received nil instance from pool")
 			}
-			logger.Infof("Got %s instanceInfoInfo#%d from pool", p.poolType, instanceInfo.instanceID)
+			logger.Infof("Got %s instanceInfoInfo#%d from pool", p.name, instanceInfo.instanceID)
+			if !p.healthy(instanceInfo) {
+				// wait for the replacement or another instance
+				p.replaceInstance(instanceInfo)
+				continue
+			}
 			if atomic.CompareAndSwapInt32(&instanceInfo.inUse, 0, 1) {
 				instanceInfo.lastUsed = time.Now().UnixNano()
 				atomic.AddInt64(&p.totalReused, 1)
 				atomic.AddInt64(&p.totalActive, 1)
-				logger.Infof("%s instance#%d marked as in-use (active: %d)", p.poolType, instanceInfo.instanceID, atomic.LoadInt64(&p.totalActive))
+				logger.Infof("%s instance#%d marked as in-use (active: %d)", p.name, instanceInfo.instanceID, atomic.LoadInt64(&p.totalActive))
+				p.observeGet(start)
 				return instanceInfo, nil
 			}
-			logger.Warnf("%s instance#%d already in use, returning to pool", p.poolType, instanceInfo.instanceID)
-			select {
-			case p.poolInstances <- instanceInfo:
-			default:
+			logger.Warnf("%s instance#%d already in use, returning to pool", p.name, instanceInfo.instanceID)
+			p.putIdle(instanceInfo)
+		case <-growTimer.C:
+			if !p.reserveInstance(p.maxSize) {
+				// pool is full, check again later (instances may be released)
+				growTimer.Reset(p.growWait)
+				continue
 			}
-		case <-time.After(100 * time.Millisecond):
-			logger.Warnf("pool timeout, creating new beyond instance")
+			logger.Warnf("no idle %s instance, creating new beyond instance (instances: %d/%d)",
+				p.name, atomic.LoadInt64(&p.numInstances), p.maxSize)
 			instanceInfo, err := p.createNewInstanceInfo()
 			if err != nil {
+				atomic.AddInt64(&p.numInstances, -1)
 				return nil, fmt.Errorf("createNewInstanceInfo err: %s", err.Error())
 			}
 			instanceInfo.inUse = 1
 			atomic.AddInt64(&p.totalActive, 1)
+			p.startReaper()
+			p.observeGet(start)
 			return instanceInfo, nil
+		case <-ctx.Done():
+			atomic.AddInt64(&p.totalTimeouts, 1)
+			return nil, fmt.Errorf("get %s instance from pool err: %w", p.name, ctx.Err())
 		case <-p.ctx.Done():
 			return nil, fmt.Errorf("pool is shutting down")
 		}
 	}
 }
 
-// Put 归还实例
-func (p *ModuleProviderPool) Put(instanceInfo *PoolInstanceInfo) {
+func (p *Pool[T]) observeGet(start time.Time) {
+	atomic.AddInt64(&p.totalGets, 1)
+	atomic.AddInt64(&p.totalWaitNanos, int64(time.Since(start)))
+}
+
+// Put 归还实例, 拒绝不属于此池或未借出的实例; 不可用的实例释放并替换
+func (p *Pool[T]) Put(instanceInfo *PoolItem[T]) error {
 	if instanceInfo == nil {
 		logger.Warnf("Attempted to put nil instance")
-		return
+		return fmt.Errorf("put nil instance")
+	}
+	if instanceInfo.pool != p {
+		logger.Warnf("Attempted to put instance#%d not belong to pool(%s)", instanceInfo.instanceID, p.name)
+		return fmt.Errorf("instance#%d does not belong to pool(%s)", instanceInfo.instanceID, p.name)
+	}
+
+	logger.Infof("Returning instance#%d to pool(%s)", instanceInfo.instanceID, p.name)
+
+	if !atomic.CompareAndSwapInt32(&instanceInfo.inUse, 1, 0) {
+		logger.Warnf("instance#%d was not in use, cannot return", instanceInfo.instanceID)
+		return fmt.Errorf("instance#%d was not in use", instanceInfo.instanceID)
 	}
+	instanceInfo.lastUsed = time.Now().UnixNano()
+	atomic.AddInt64(&p.totalActive, -1)
 
+	// Close only drains idle instances, the in-use ones are released when they are returned
 	select {
 	case <-p.stopCh:
-		logger.Warnf("poolInstances channel is closed")
-		return
+		logger.Infof("pool(%s) is closed, release instance#%d", p.name, instanceInfo.instanceID)
+		p.releaseInstance(instanceInfo)
+		return nil
 	default:
 	}
 
-	logger.Infof("Returning instance#%d to pool(%s)", instanceInfo.instanceID, p.poolType)
+	// instance reset
+	if err := instanceInfo.instance.Reset(); err != nil {
+		logger.Warnf("Failed to reset instance#%d to pool(%s) err: %v", instanceInfo.instanceID, p.name, err)
+		p.replaceInstance(instanceInfo)
+		return nil
+	}
+	if !p.healthy(instanceInfo) {
+		p.replaceInstance(instanceInfo)
+		return nil
+	}
+
+	if p.putIdle(instanceInfo) {
+		logger.Infof("instance#%d marked as available (active: %d) to pool(%s)", instanceInfo.instanceID, atomic.LoadInt64(&p.totalActive), p.name)
+	}
+	return nil
+}
+
+// Acquire 借出实例, 返回的 release 归还实例(可重复调用), 如 defer release()
+func (p *Pool[T]) Acquire(ctx context.Context) (T, func(), error) {
+	instanceInfo, err := p.Get(ctx)
+	if err != nil {
+		var zero T
+		return zero, func() {}, err
+	}
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			if err := p.Put(instanceInfo); err != nil {
+				logger.Warnf("release instance#%d to pool(%s) err: %v", instanceInfo.instanceID, p.name, err)
+			}
+		})
+	}
+	return instanceInfo.instance, release, nil
+}
+
+// Do 借出实例执行 fn, 结束后(包括 panic)自动归还
+func (p *Pool[T]) Do(ctx context.Context, fn func(instance T) error) error {
+	instance, release, err := p.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return fn(instance)
+}
+
+// healthy checks the instance if it implements IPoolHealthChecker
+func (p *Pool[T]) healthy(instanceInfo *PoolItem[T]) bool {
+	checker, ok := any(instanceInfo.instance).(IPoolHealthChecker)
+	if !ok {
+		return true
+	}
+	if err := checker.HealthCheck(); err != nil {
+		logger.Warnf("%s instance#%d is unhealthy: %v", p.name, instanceInfo.instanceID, err)
+		return false
+	}
+	return true
+}
+
+// putIdle puts the instance back to the idle queue, releases it if the pool is closed or full
+func (p *Pool[T]) putIdle(instanceInfo *PoolItem[T]) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	select {
+	case <-p.stopCh:
+	default:
+		select {
+		case p.poolInstances <- instanceInfo:
+			return true
+		default:
+			logger.Warnf("pool queue full, release instance#%d to pool(%s)", instanceInfo.instanceID, p.name)
+		}
+	}
+	p.releaseInstance(instanceInfo)
+	return false
+}
 
-	if atomic.CompareAndSwapInt32(&instanceInfo.inUse, 1, 0) {
-		instanceInfo.lastUsed = time.Now().UnixNano()
+// releaseInstance releases the instance and frees its place in the pool
+func (p *Pool[T]) releaseInstance(instanceInfo *PoolItem[T]) {
+	atomic.AddInt64(&p.numInstances, -1)
+	if err := instanceInfo.instance.Release(); err != nil {
+		logger.Warnf("Failed to release instance#%d of pool(%s) err: %v", instanceInfo.instanceID, p.name, err)
+	}
+}
 
-		// instance reset
-		if err := instanceInfo.instance.Reset(); err != nil {
-			logger.Warnf("Failed to reset instance#%d to pool(%s) err: %v", instanceInfo.instanceID, p.poolType, err)
+// replaceInstance releases the broken instance and creates a new one in background,
+// so the waiting Get gets the replacement
+func (p *Pool[T]) replaceInstance(instanceInfo *PoolItem[T]) {
+	logger.Warnf("replace unhealthy instance#%d of pool(%s)", instanceInfo.instanceID, p.name)
+	atomic.AddInt64(&p.totalUnhealthy, 1)
+	p.releaseInstance(instanceInfo)
+	if !p.reserveInstance(p.maxSize) {
+		return
+	}
+	go func() {
+		newInstanceInfo, err := p.createNewInstanceInfo()
+		if err != nil {
+			atomic.AddInt64(&p.numInstances, -1)
+			logger.Warnf("replace instance of pool(%s) err: %v", p.name, err)
+			return
 		}
+		p.putIdle(newInstanceInfo)
+	}()
+}
+
+// startReaper starts releasing the idle beyond instances once the pool may grow beyond poolSize
+func (p *Pool[T]) startReaper() {
+	if p.idleTTL <= 0 || p.maxSize <= p.poolSize {
+		return
+	}
+	p.reaperOnce.Do(func() {
+		go func() {
+			ticker := time.NewTicker(max(p.idleTTL/2, time.Second))
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					p.reapIdle()
+				case <-p.ctx.Done():
+					return
+				}
+			}
+		}()
+	})
+}
+
+// reapIdle releases the instances idle longer than idleTTL until the pool shrinks back to poolSize
+func (p *Pool[T]) reapIdle() {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	select {
+	case <-p.stopCh:
+		return
+	default:
+	}
 
+	deadline := time.Now().Add(-p.idleTTL).UnixNano()
+	kept := make([]*PoolItem[T], 0, len(p.poolInstances))
+	for range len(p.poolInstances) {
+		var instanceInfo *PoolItem[T]
+		select {
+		case instanceInfo = <-p.poolInstances:
+		default:
+		}
+		if instanceInfo == nil {
+			break
+		}
+		if atomic.LoadInt64(&p.numInstances) > int64(p.poolSize) && instanceInfo.lastUsed < deadline {
+			logger.Infof("release idle instance#%d of pool(%s)", instanceInfo.instanceID, p.name)
+			p.releaseInstance(instanceInfo)
+			atomic.AddInt64(&p.totalReaped, 1)
+			continue
+		}
+		kept = append(kept, instanceInfo)
+	}
+	for _, instanceInfo := range kept {
 		select {
 		case p.poolInstances <- instanceInfo:
-			atomic.AddInt64(&p.totalActive, -1)
-			logger.Infof("instance#%d marked as available (active: %d) to pool(%s)", instanceInfo.instanceID, atomic.LoadInt64(&p.totalActive), p.poolType)
 		default:
-			logger.Warnf("pool queue full, release instance#%d to pool(%s)", instanceInfo.instanceID, p.poolType)
-			instanceInfo.instance.Release()
-			instanceInfo = nil // gc to free, maybe use sync.pool
+			p.releaseInstance(instanceInfo)
 		}
-	} else {
-		logger.Warnf("instance#%d was not in use, cannot return", instanceInfo.instanceID)
 	}
 }
 
 // GetStats 获取统计信息
-func (p *ModuleProviderPool) GetStats() map[string]any {
+func (p *Pool[T]) GetStats() map[string]any {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
 
 	return map[string]any{
 		"pool_size":       p.poolSize,
+		"max_size":        p.maxSize,
 		"total_instances": len(p.poolInstances),
+		"num_instances":   atomic.LoadInt64(&p.numInstances),
 		"active_count":    atomic.LoadInt64(&p.totalActive),
 		"total_created":   atomic.LoadInt64(&p.totalCreated),
 		"total_reused":    atomic.LoadInt64(&p.totalReused),
+		"total_reaped":    atomic.LoadInt64(&p.totalReaped),
+		"total_unhealthy": atomic.LoadInt64(&p.totalUnhealthy),
+		"total_gets":      atomic.LoadInt64(&p.totalGets),
+		"total_timeouts":  atomic.LoadInt64(&p.totalTimeouts),
+		"total_wait_secs": time.Duration(atomic.LoadInt64(&p.totalWaitNanos)).Seconds(),
 	}
 }
 
 // Shutdown 关闭池
-func (p *ModuleProviderPool) Close() {
+func (p *Pool[T]) Close() {
 	logger.Infof("Pool Closing...")
 
 	p.cancel()
@@ -240,14 +484,75 @@ func (p *ModuleProviderPool) Close() {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	// Close the channel first, then drain. This is safer when combined
-	// with a modified Put method that can handle a closed channel.
+	// Close the channel first, then drain. putIdle holds the read lock and checks stopCh,
+	// so no instance is sent to the closed channel.
 	close(p.stopCh)
 	close(p.poolInstances)
 	for instanceInfo := range p.poolInstances {
-		if instanceInfo != nil && instanceInfo.instance != nil {
-			instanceInfo.instance.Release()
+		if instanceInfo != nil && any(instanceInfo.instance) != nil {
+			p.releaseInstance(instanceInfo)
 		}
 	}
 	logger.Infof("Pool Closed")
 }
+
+// ------------------------------------------------------------
+
+type NewFunc func() (IPoolInstance, error)
+
+var newFuncMap = make(map[reflect.Type]NewFunc)
+
+// init to RegisterNewFunc/GetNewFunc
+func RegisterNewFunc(poolType reflect.Type, newFunc NewFunc) {
+	newFuncMap[poolType] = newFunc
+}
+func GetNewFunc(poolType reflect.Type) NewFunc {
+	if newFunc, ok := newFuncMap[poolType]; ok {
+		return newFunc
+	}
+	return nil
+}
+
+// PoolInstanceInfo ModuleProviderPool 借出的实例
+type PoolInstanceInfo = PoolItem[IPoolInstance]
+
+// ModuleProviderPool module provider 资源池, 按 reflect.Type 注册的 NewFunc 创建实例;
+// 兼容旧接口, 新代码使用类型化的 Pool[T]
+type ModuleProviderPool struct {
+	*Pool[IPoolInstance]
+	poolType reflect.Type
+}
+
+// NewModuleProviderPool 创建新的资源池, 默认最大实例数为 poolSize
+func NewModuleProviderPool(poolSize int, poolType reflect.Type) *ModuleProviderPool {
+	return &ModuleProviderPool{
+		Pool:     NewPool[IPoolInstance](fmt.Sprint(poolType), poolSize, GetNewFunc(poolType)),
+		poolType: poolType,
+	}
+}
+
+// WithMaxSize 设置最大实例数(>= poolSize), 需在 Initialize 之前调用
+func (p *ModuleProviderPool) WithMaxSize(maxSize int) *ModuleProviderPool {
+	p.Pool.WithMaxSize(maxSize)
+	return p
+}
+
+// WithGrowWait 设置没有空闲实例时创建新实例前的等待时长
+func (p *ModuleProviderPool) WithGrowWait(growWait time.Duration) *ModuleProviderPool {
+	p.Pool.WithGrowWait(growWait)
+	return p
+}
+
+// WithIdleTTL 设置超出 poolSize 的实例的最长空闲时长, <= 0 不释放
+func (p *ModuleProviderPool) WithIdleTTL(idleTTL time.Duration) *ModuleProviderPool {
+	p.Pool.WithIdleTTL(idleTTL)
+	return p
+}
+
+// Initialize 并行初始化池
+func (p *ModuleProviderPool) Initialize() error {
+	if p.newFunc == nil {
+		return fmt.Errorf("no NewFunc registered for pool type %s", p.poolType)
+	}
+	return p.Pool.Initialize()
+}
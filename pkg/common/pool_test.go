@@ -1,10 +1,12 @@
 package common
 
 import (
+	"context"
 	"fmt"
 	"reflect"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -165,7 +167,7 @@ func TestGet(t *testing.T) {
 	assert.NoError(t, err)
 
 	// 获取一个实例
-	instanceInfo, err := pool.Get()
+	instanceInfo, err := pool.Get(context.Background())
 	assert.NoError(t, err)
 	assert.NotNil(t, instanceInfo)
 	assert.Equal(t, int32(1), instanceInfo.inUse)
@@ -194,7 +196,7 @@ func TestGetWithTimeout(t *testing.T) {
 	assert.Equal(t, int64(0), pool.totalActive)
 
 	// 在超时前尝试获取实例，应该会创建一个新的超出池范围的实例
-	instanceInfo, err := pool.Get()
+	instanceInfo, err := pool.Get(context.Background())
 	assert.NoError(t, err)
 	assert.NotNil(t, instanceInfo)
 	assert.Equal(t, int32(1), instanceInfo.inUse)
@@ -217,7 +219,7 @@ func TestPut(t *testing.T) {
 	assert.NoError(t, err)
 
 	// 获取一个实例
-	instanceInfo, err := pool.Get()
+	instanceInfo, err := pool.Get(context.Background())
 	assert.NoError(t, err)
 	assert.NotNil(t, instanceInfo)
 
@@ -265,7 +267,7 @@ func TestGetStats(t *testing.T) {
 	assert.Equal(t, int64(0), stats["total_reused"])
 
 	// 获取一个实例并检查统计数据
-	instanceInfo, err := pool.Get()
+	instanceInfo, err := pool.Get(context.Background())
 	assert.NoError(t, err)
 	stats = pool.GetStats()
 	assert.Equal(t, int64(1), stats["active_count"])
@@ -316,7 +318,7 @@ func TestConcurrentAccess(t *testing.T) {
 		go func(workerID int) {
 			defer wg.Done()
 			for j := 0; j < numOperations; j++ {
-				instanceInfo, err := pool.Get()
+				instanceInfo, err := pool.Get(context.Background())
 				assert.NoError(t, err)
 				// 模拟一些工作
 				time.Sleep(time.Microsecond)
@@ -352,7 +354,7 @@ func TestClose(t *testing.T) {
 	pool.Close()
 
 	// 尝试从已关闭的池中获取实例应该失败
-	_, err = pool.Get()
+	_, err = pool.Get(context.Background())
 	assert.Error(t, err)
 	// 错误信息可能因通道关闭而有所不同，所以我们检查它是否包含预期的错误信息之一
 	assert.True(t, strings.Contains(err.Error(), "pool is shutting down") || strings.Contains(err.Error(), "received nil instance from pool"))
@@ -361,3 +363,262 @@ func TestClose(t *testing.T) {
 	stats := pool.GetStats()
 	assert.Equal(t, 3, stats["pool_size"])
 }
+
+func TestPutAfterClose(t *testing.T) {
+	pool := NewPool("typed", 2, func() (*typedPoolInstance, error) {
+		return &typedPoolInstance{}, nil
+	})
+	assert.NoError(t, pool.Initialize())
+
+	instanceInfo, err := pool.Get(context.Background())
+	assert.NoError(t, err)
+	pool.Close()
+
+	// 关闭后归还的使用中实例被释放, 不再占用实例数
+	assert.NoError(t, pool.Put(instanceInfo))
+	assert.True(t, instanceInfo.GetInstance().released)
+	assert.Equal(t, 0, instanceInfo.GetInstance().resets)
+	stats := pool.GetStats()
+	assert.Equal(t, int64(0), stats["active_count"])
+	assert.Equal(t, int64(0), stats["num_instances"])
+
+	// 重复归还报错, 不重复释放
+	assert.Error(t, pool.Put(instanceInfo))
+	assert.Equal(t, int64(0), pool.GetStats()["num_instances"])
+}
+
+// unhealthyPoolInstance 健康检查失败的实例
+type unhealthyPoolInstance struct {
+	MockPoolInstance
+	healthy bool
+}
+
+func (m *unhealthyPoolInstance) Reset() error   { return nil }
+func (m *unhealthyPoolInstance) Release() error { return nil }
+func (m *unhealthyPoolInstance) HealthCheck() error {
+	if !m.healthy {
+		return fmt.Errorf("broken")
+	}
+	return nil
+}
+
+func TestGetMaxSizeWithDeadline(t *testing.T) {
+	// 清理之前注册的函数
+	newFuncMap = make(map[reflect.Type]NewFunc)
+
+	poolType := reflect.TypeOf((*MockPoolInstance)(nil))
+	RegisterNewFunc(poolType, MockNewFunc(0, nil))
+
+	pool := NewModuleProviderPool(1, poolType).WithMaxSize(2).WithGrowWait(time.Millisecond)
+	assert.NoError(t, pool.Initialize())
+	defer pool.Close()
+
+	first, err := pool.Get(context.Background())
+	assert.NoError(t, err)
+	// 超出 poolSize 创建新实例
+	second, err := pool.Get(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), pool.GetStats()["num_instances"])
+
+	// 达到最大实例数, 等到超时
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = pool.Get(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// 有实例归还时等待的 Get 获取到
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		pool.Put(first)
+	}()
+	instanceInfo, err := pool.Get(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, first, instanceInfo)
+	assert.NoError(t, pool.Put(instanceInfo))
+	assert.NoError(t, pool.Put(second))
+}
+
+func TestReapIdle(t *testing.T) {
+	// 清理之前注册的函数
+	newFuncMap = make(map[reflect.Type]NewFunc)
+
+	poolType := reflect.TypeOf((*MockPoolInstance)(nil))
+	RegisterNewFunc(poolType, MockNewFunc(0, nil))
+
+	pool := NewModuleProviderPool(1, poolType).WithMaxSize(3).WithGrowWait(time.Millisecond).WithIdleTTL(time.Hour)
+	assert.NoError(t, pool.Initialize())
+	defer pool.Close()
+
+	instances := make([]*PoolInstanceInfo, 0, 3)
+	for range 3 {
+		instanceInfo, err := pool.Get(context.Background())
+		assert.NoError(t, err)
+		instances = append(instances, instanceInfo)
+	}
+	for _, instanceInfo := range instances {
+		assert.NoError(t, pool.Put(instanceInfo))
+	}
+
+	// 未超过空闲时长不释放
+	pool.reapIdle()
+	assert.Equal(t, int64(3), pool.GetStats()["num_instances"])
+
+	// 超过空闲时长, 收缩回 poolSize
+	for _, instanceInfo := range instances {
+		instanceInfo.lastUsed = time.Now().Add(-2 * time.Hour).UnixNano()
+	}
+	pool.reapIdle()
+	stats := pool.GetStats()
+	assert.Equal(t, int64(1), stats["num_instances"])
+	assert.Equal(t, 1, stats["total_instances"])
+	assert.Equal(t, int64(2), stats["total_reaped"])
+}
+
+func TestHealthCheckReplace(t *testing.T) {
+	// 清理之前注册的函数
+	newFuncMap = make(map[reflect.Type]NewFunc)
+
+	poolType := reflect.TypeOf((*unhealthyPoolInstance)(nil))
+	var created int32
+	RegisterNewFunc(poolType, func() (IPoolInstance, error) {
+		// 第一个实例不可用
+		return &unhealthyPoolInstance{healthy: atomic.AddInt32(&created, 1) > 1}, nil
+	})
+
+	pool := NewModuleProviderPool(1, poolType)
+	assert.NoError(t, pool.Initialize())
+	defer pool.Close()
+
+	instanceInfo, err := pool.Get(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, instanceInfo.GetInstance().(*unhealthyPoolInstance).healthy)
+	stats := pool.GetStats()
+	assert.Equal(t, int64(1), stats["total_unhealthy"])
+	assert.Equal(t, int64(1), stats["num_instances"])
+
+	// 归还时不可用, 替换为新实例
+	instanceInfo.GetInstance().(*unhealthyPoolInstance).healthy = false
+	assert.NoError(t, pool.Put(instanceInfo))
+	replaced, err := pool.Get(context.Background())
+	assert.NoError(t, err)
+	assert.NotEqual(t, instanceInfo, replaced)
+	assert.Equal(t, int64(2), pool.GetStats()["total_unhealthy"])
+}
+
+// handlePoolInstance 与 sherpa 提供者相同: 持有原生句柄, Release 释放并置空句柄, HealthCheck 报告句柄状态
+type handlePoolInstance struct {
+	handle   *int
+	releases int
+}
+
+func (m *handlePoolInstance) Reset() error {
+	if m.handle == nil {
+		return fmt.Errorf("handle is released")
+	}
+	return nil
+}
+func (m *handlePoolInstance) Release() error {
+	if m.handle != nil {
+		m.handle = nil
+		m.releases++
+	}
+	return nil
+}
+func (m *handlePoolInstance) HealthCheck() error {
+	if m.handle == nil {
+		return fmt.Errorf("handle is released")
+	}
+	return nil
+}
+
+func TestHealthCheckReleasedHandle(t *testing.T) {
+	var created int
+	pool := NewPool("handle", 1, func() (*handlePoolInstance, error) {
+		created++
+		return &handlePoolInstance{handle: &created}, nil
+	})
+	assert.NoError(t, pool.Initialize())
+	defer pool.Close()
+
+	// the borrower releases the instance by mistake (e.g. a cancelled pipeline), then returns it
+	instance, release, err := pool.Acquire(context.Background())
+	assert.NoError(t, err)
+	assert.NoError(t, instance.Release())
+	release()
+
+	// the released instance is not handed out again, and not released twice
+	replaced, release, err := pool.Acquire(context.Background())
+	assert.NoError(t, err)
+	defer release()
+	assert.NotSame(t, instance, replaced)
+	assert.NoError(t, replaced.HealthCheck())
+	assert.Equal(t, 1, instance.releases)
+	assert.Equal(t, 2, created)
+	assert.Equal(t, int64(1), pool.GetStats()["total_unhealthy"])
+}
+
+func TestPutForeignInstance(t *testing.T) {
+	// 清理之前注册的函数
+	newFuncMap = make(map[reflect.Type]NewFunc)
+
+	poolType := reflect.TypeOf((*MockPoolInstance)(nil))
+	RegisterNewFunc(poolType, MockNewFunc(0, nil))
+
+	pool := NewModuleProviderPool(1, poolType)
+	other := NewModuleProviderPool(1, poolType)
+	assert.NoError(t, pool.Initialize())
+	assert.NoError(t, other.Initialize())
+
+	instanceInfo, err := other.Get(context.Background())
+	assert.NoError(t, err)
+	assert.Error(t, pool.Put(instanceInfo))
+	assert.Equal(t, 1, len(pool.poolInstances))
+	assert.NoError(t, other.Put(instanceInfo))
+}
+
+// typedPoolInstance 类型化池的测试实例
+type typedPoolInstance struct {
+	id       int
+	resets   int
+	released bool
+}
+
+func (m *typedPoolInstance) Reset() error   { m.resets++; return nil }
+func (m *typedPoolInstance) Release() error { m.released = true; return nil }
+
+func TestTypedPoolAcquireAndDo(t *testing.T) {
+	var created int
+	pool := NewPool("typed", 1, func() (*typedPoolInstance, error) {
+		created++
+		return &typedPoolInstance{id: created}, nil
+	})
+	assert.NoError(t, pool.Initialize())
+
+	// 类型化获取, 无需类型断言
+	instanceInfo, err := pool.Get(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, instanceInfo.GetInstance().id)
+	assert.NoError(t, pool.Put(instanceInfo))
+
+	instance, release, err := pool.Acquire(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, instance.id)
+	release()
+	release() // 重复归还无影响
+	assert.Equal(t, 2, instance.resets)
+	assert.Equal(t, int64(0), pool.GetStats()["active_count"])
+
+	// fn 返回后自动归还
+	err = pool.Do(context.Background(), func(instance *typedPoolInstance) error {
+		assert.Equal(t, int64(1), pool.GetStats()["active_count"])
+		return fmt.Errorf("fn err")
+	})
+	assert.EqualError(t, err, "fn err")
+	assert.Equal(t, int64(0), pool.GetStats()["active_count"])
+	assert.Equal(t, 1, created)
+
+	pool.Close()
+	assert.True(t, instance.released)
+	_, _, err = pool.Acquire(context.Background())
+	assert.Error(t, err)
+}
@@ -1,10 +1,25 @@
 package common
 
+import (
+	"maps"
+	"slices"
+	"sync"
+
+	"achatbot/pkg/types"
+)
+
 // Session represents a chat session with chat history
 type Session struct {
 	chatRound   int
 	sessionID   string
 	chatHistory *ChatHistory
+
+	// asr contextual biasing, updatable at runtime (e.g. from http handler)
+	asrContextMu sync.RWMutex
+	asrContext   types.ASRContext
+
+	// authenticated identity (user and bot profile), nil if not authenticated
+	identity *types.Identity
 }
 
 // NewSession creates a new Session instance
@@ -62,11 +77,63 @@ func (s *Session) GetChatHistory() *ChatHistory {
 	return s.chatHistory
 }
 
+// SetHotwords sets the session asr hotwords, take effect from the next user speech
+func (s *Session) SetHotwords(hotwords []types.Hotword) {
+	s.asrContextMu.Lock()
+	defer s.asrContextMu.Unlock()
+	s.asrContext.Hotwords = slices.Clone(hotwords)
+}
+
+// SetASRReplacements sets the session post-recognition replacement dictionary (wrong -> right)
+func (s *Session) SetASRReplacements(replacements map[string]string) {
+	s.asrContextMu.Lock()
+	defer s.asrContextMu.Unlock()
+	s.asrContext.Replacements = maps.Clone(replacements)
+}
+
+// GetASRContext returns a copy of the session asr context
+func (s *Session) GetASRContext() *types.ASRContext {
+	s.asrContextMu.RLock()
+	defer s.asrContextMu.RUnlock()
+	return &types.ASRContext{
+		Hotwords:     slices.Clone(s.asrContext.Hotwords),
+		Replacements: maps.Clone(s.asrContext.Replacements),
+	}
+}
+
+// SetIdentity attaches the authenticated identity to the session
+func (s *Session) SetIdentity(identity *types.Identity) {
+	s.identity = identity.Copy()
+}
+
+// GetIdentity returns the authenticated identity, nil if not authenticated
+func (s *Session) GetIdentity() *types.Identity {
+	return s.identity
+}
+
+// GetUserID returns the authenticated user id, empty if not authenticated
+func (s *Session) GetUserID() string {
+	if s.identity == nil {
+		return ""
+	}
+	return s.identity.UserID
+}
+
+// GetBotProfile returns the bot profile of the authenticated identity, empty for the default profile
+func (s *Session) GetBotProfile() string {
+	if s.identity == nil {
+		return ""
+	}
+	return s.identity.BotProfile
+}
+
 func (s *Session) Copy() *Session {
 	cpSsession := NewSession(s.sessionID, nil)
 	cpSsession.chatRound = s.chatRound
 	cpSsession.sessionID = s.sessionID
 	cpSsession.chatHistory = s.chatHistory.Copy()
+	cpSsession.asrContext = *s.GetASRContext()
+	cpSsession.identity = s.identity.Copy()
 
 	return cpSsession
 }
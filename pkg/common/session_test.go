@@ -2,6 +2,8 @@ package common
 
 import (
 	"testing"
+
+	"achatbot/pkg/types"
 )
 
 func TestNewSession(t *testing.T) {
@@ -119,3 +121,49 @@ func TestSessionGetters(t *testing.T) {
 		t.Error("Expected chat history, got nil")
 	}
 }
+
+func TestSessionASRContext(t *testing.T) {
+	session := NewSession("test-session", nil)
+	hotwords := []types.Hotword{{Phrase: "智谱", Boost: 2}}
+	session.SetHotwords(hotwords)
+	session.SetASRReplacements(map[string]string{"至普": "智谱"})
+
+	asrContext := session.GetASRContext()
+	if len(asrContext.Hotwords) != 1 || asrContext.Hotwords[0] != hotwords[0] {
+		t.Errorf("Expected hotwords %v, got %v", hotwords, asrContext.Hotwords)
+	}
+	if asrContext.Replacements["至普"] != "智谱" {
+		t.Errorf("Expected replacement 智谱, got %v", asrContext.Replacements)
+	}
+
+	// returned context is a copy
+	asrContext.Hotwords[0].Phrase = "changed"
+	if session.GetASRContext().Hotwords[0].Phrase != "智谱" {
+		t.Error("Expected session hotwords not changed by the copy")
+	}
+	if session.Copy().GetASRContext().Replacements["至普"] != "智谱" {
+		t.Error("Expected copied session keeps asr context")
+	}
+}
+
+func TestSessionIdentity(t *testing.T) {
+	session := NewSession("test", nil)
+	if session.GetIdentity() != nil || session.GetUserID() != "" || session.GetBotProfile() != "" {
+		t.Error("Expected no identity for a new session")
+	}
+
+	identity := &types.Identity{UserID: "alice", BotProfile: "tutor", Claims: map[string]any{"sub": "alice"}}
+	session.SetIdentity(identity)
+	if session.GetUserID() != "alice" || session.GetBotProfile() != "tutor" {
+		t.Errorf("Expected identity alice/tutor, got %v", session.GetIdentity())
+	}
+
+	// the session keeps a copy of the identity
+	identity.Claims["sub"] = "bob"
+	if session.GetIdentity().Claims["sub"] != "alice" {
+		t.Error("Expected session identity not changed by the caller")
+	}
+	if session.Copy().GetUserID() != "alice" {
+		t.Error("Expected copied session keeps identity")
+	}
+}
@@ -2,4 +2,7 @@ package consts
 
 const (
 	DefaultLLMSystemPrompt = `You are a friendly and helpful voice assistant that will call on tools to answer questions. The answers do not contain special characters, do not contain moji symbols, and keep the answers short.`
+
+	// TTSMarkupPrompt 追加到系统提示词, 让 LLM 输出 TTSProcessor 支持的轻量语音标记
+	TTSMarkupPrompt = `You may control the speech with these tags only: <break time="500ms"/> for a pause, <emphasis level="strong">words</emphasis> to stress words, <voice name="zm_yunjian">text</voice> to switch the voice, <say-as interpret-as="characters">ABC</say-as> to spell out letters or digits. Use them sparingly and always close the tags.`
 )
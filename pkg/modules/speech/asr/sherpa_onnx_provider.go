@@ -1,20 +1,30 @@
 package asr
 
 import (
-	"achatbot/pkg/consts"
+	"fmt"
+	"os"
 	"path/filepath"
+	"strings"
 
 	sherpa "github.com/k2-fsa/sherpa-onnx-go/sherpa_onnx"
 	"github.com/weedge/pipeline-go/pkg/logger"
 
+	"achatbot/pkg/consts"
+	"achatbot/pkg/types"
 	"achatbot/pkg/utils"
 )
 
+// DefaultHotwordsScore 热词默认加分
+const DefaultHotwordsScore = 1.5
+
 type SherpaOnnxProvider struct {
 	config     sherpa.OfflineRecognizerConfig
 	recognizer *sherpa.OfflineRecognizer
 	name       string
 	sampleRate int
+
+	// 当前热词写入的临时文件, 没有热词时为空
+	hotwordsFile string
 }
 
 // https://github.com/k2-fsa/sherpa-onnx/blob/v1.12.14/sherpa-onnx/csrc/offline-paraformer-model-config.h
@@ -135,6 +145,21 @@ func NewDefaultSherpaOnnxOfflineRecognizerConfig() sherpa.OfflineRecognizerConfi
 	return conf
 }
 
+// NewDefaultSherpaOnnxOfflineWhisperRecognizerConfig english whisper recognizer, e.g. route english speech by language id
+func NewDefaultSherpaOnnxOfflineWhisperRecognizerConfig() sherpa.OfflineRecognizerConfig {
+	asrConf, tokenPath := NewDefaultSherpaOnnxOfflineWhisperModelConfig()
+	conf := sherpa.OfflineRecognizerConfig{
+		FeatConfig: sherpa.FeatureConfig{SampleRate: consts.DefaultRate, FeatureDim: 80},
+		ModelConfig: sherpa.OfflineModelConfig{
+			Whisper:    asrConf,
+			Tokens:     tokenPath,
+			NumThreads: 1, Debug: 0, Provider: "cpu",
+		},
+		DecodingMethod: "greedy_search",
+	}
+	return conf
+}
+
 func NewSherpaOnnxProvider(config sherpa.OfflineRecognizerConfig) *SherpaOnnxProvider {
 	provider := &SherpaOnnxProvider{
 		config: config,
@@ -153,27 +178,133 @@ func NewSherpaOnnxProvider(config sherpa.OfflineRecognizerConfig) *SherpaOnnxPro
 }
 
 func (p *SherpaOnnxProvider) Transcribe(data []byte) string {
+	return p.TranscribeResult(data).Text
+}
+
+// TranscribeResult returns text with token timestamps, and SenseVoice language/emotion/event tags
+func (p *SherpaOnnxProvider) TranscribeResult(data []byte) *types.ASRResult {
 	samples := utils.SamplesInt16ToFloat(data)
 	stream := sherpa.NewOfflineStream(p.recognizer)
+	defer sherpa.DeleteOfflineStream(stream)
 	stream.AcceptWaveform(p.sampleRate, samples)
 	p.recognizer.Decode(stream)
 	result := stream.GetResult()
-	sherpa.DeleteOfflineStream(stream)
-	return result.Text
+	if result == nil {
+		return &types.ASRResult{}
+	}
+
+	endSecs := float32(len(samples)) / float32(p.sampleRate)
+	return &types.ASRResult{
+		Text:       result.Text,
+		Tokens:     result.Tokens,
+		Timestamps: result.Timestamps,
+		Words:      utils.TokensToWords(result.Tokens, result.Timestamps, endSecs),
+		Language:   utils.NormalizeASRTag(result.Lang),
+		Emotion:    utils.NormalizeASRTag(result.Emotion),
+		Event:      utils.NormalizeASRTag(result.Event),
+	}
+}
+
+// SupportsHotwords sherpa 离线识别只有 transducer 模型支持热词(modified_beam_search 解码)
+// https://k2-fsa.github.io/sherpa/onnx/hotwords/index.html
+func (p *SherpaOnnxProvider) SupportsHotwords() bool {
+	return p.config.ModelConfig.Transducer.Encoder != ""
+}
+
+// SetHotwords 热词写入临时文件(每行 "热词 :加分"), 以新的热词配置重建识别器; 为空时恢复原配置
+func (p *SherpaOnnxProvider) SetHotwords(hotwords []types.Hotword) error {
+	if !p.SupportsHotwords() {
+		return fmt.Errorf("%s model doesn't support hotwords", p.name)
+	}
+
+	config := p.config
+	hotwordsFile := ""
+	if len(hotwords) > 0 {
+		file, err := os.CreateTemp("", "asr_hotwords_*.txt")
+		if err != nil {
+			return err
+		}
+		var sb strings.Builder
+		for _, hotword := range hotwords {
+			sb.WriteString(strings.TrimSpace(hotword.Phrase))
+			if hotword.Boost > 0 {
+				sb.WriteString(fmt.Sprintf(" :%.2f", hotword.Boost))
+			}
+			sb.WriteString("\n")
+		}
+		_, err = file.WriteString(sb.String())
+		file.Close()
+		if err != nil {
+			os.Remove(file.Name())
+			return err
+		}
+
+		hotwordsFile = file.Name()
+		config.HotwordsFile = hotwordsFile
+		config.DecodingMethod = "modified_beam_search"
+		if config.HotwordsScore <= 0 {
+			config.HotwordsScore = DefaultHotwordsScore
+		}
+	}
+
+	recognizer := sherpa.NewOfflineRecognizer(&config)
+	if recognizer == nil {
+		if hotwordsFile != "" {
+			os.Remove(hotwordsFile)
+		}
+		return fmt.Errorf("fail to create ASR with hotwords %v", hotwords)
+	}
+	sherpa.DeleteOfflineRecognizer(p.recognizer)
+	p.recognizer = recognizer
+	p.removeHotwordsFile()
+	p.hotwordsFile = hotwordsFile
+	logger.Infof("ASR %s set %d hotwords", p.name, len(hotwords))
+	return nil
+}
+
+func (p *SherpaOnnxProvider) removeHotwordsFile() {
+	if p.hotwordsFile != "" {
+		os.Remove(p.hotwordsFile)
+		p.hotwordsFile = ""
+	}
 }
 
 func (p *SherpaOnnxProvider) Warmup() {
 }
 
+// Reset 清除会话设置的热词
 func (p *SherpaOnnxProvider) Reset() error {
-	return nil
+	if p.hotwordsFile == "" {
+		return nil
+	}
+	return p.SetHotwords(nil)
 }
 
 func (p *SherpaOnnxProvider) Release() error {
-	sherpa.DeleteOfflineRecognizer(p.recognizer)
+	if p.recognizer != nil {
+		sherpa.DeleteOfflineRecognizer(p.recognizer)
+		p.recognizer = nil
+	}
+	p.removeHotwordsFile()
+	return nil
+}
+
+// HealthCheck 识别器已释放时不可用, 池替换该实例
+func (p *SherpaOnnxProvider) HealthCheck() error {
+	if p.recognizer == nil {
+		return fmt.Errorf("%s recognizer is released", p.name)
+	}
 	return nil
 }
 
 func (p *SherpaOnnxProvider) Name() string {
 	return p.name
 }
+
+func (p *SherpaOnnxProvider) GetAudioInFormat() *types.AudioFormat {
+	return types.NewAudioFormat(p.sampleRate, consts.DefaultChannels, consts.DefaultSampleWidth)
+}
+
+func (p *SherpaOnnxProvider) GetAudioOutFormat() *types.AudioFormat {
+	return nil
+}
@@ -0,0 +1,93 @@
+package audio_dsp
+
+import "math"
+
+// AGC 自动增益控制 + 峰值限幅器
+type AGC struct {
+	target      float64 // 目标 RMS(线性)
+	minGain     float64
+	maxGain     float64
+	gate        float64 // 低于此 RMS 不调整增益
+	limit       float64 // 峰值上限(线性)
+	attackCoef  float64
+	releaseCoef float64
+
+	gain        float64 // 当前 AGC 增益
+	appliedGain float64 // 上一帧末实际应用的增益(含限幅)
+}
+
+// NewAGC 创建自动增益控制, frameSecs 为帧时长, 用于换算 attack/release 时间常数
+func NewAGC(targetDB, minGainDB, maxGainDB, gateDB, limiterDB float64, attackMS, releaseMS int, frameSecs float64) *AGC {
+	return &AGC{
+		target:      dbToLinear(targetDB),
+		minGain:     dbToLinear(minGainDB),
+		maxGain:     dbToLinear(maxGainDB),
+		gate:        dbToLinear(gateDB),
+		limit:       dbToLinear(limiterDB),
+		attackCoef:  timeCoef(attackMS, frameSecs),
+		releaseCoef: timeCoef(releaseMS, frameSecs),
+		gain:        1,
+		appliedGain: 1,
+	}
+}
+
+// Process 原地处理一帧
+func (a *AGC) Process(frame []float64) {
+	if len(frame) == 0 {
+		return
+	}
+
+	var sum, peak float64
+	for _, s := range frame {
+		sum += s * s
+		peak = max(peak, math.Abs(s))
+	}
+	rms := math.Sqrt(sum / float64(len(frame)))
+
+	// 只在有效信号时调整增益, 静音/噪声段保持增益
+	if rms > a.gate {
+		desired := min(max(a.target/rms, a.minGain), a.maxGain)
+		coef := a.releaseCoef
+		if desired < a.gain {
+			coef = a.attackCoef
+		}
+		a.gain = coef*a.gain + (1-coef)*desired
+	}
+
+	// 限幅: 峰值超出上限时本帧立即降低增益
+	frameGain := a.gain
+	if peak*frameGain > a.limit {
+		frameGain = a.limit / peak
+	}
+
+	// 帧内线性插值增益, 避免增益跳变产生咔哒声
+	n := float64(len(frame))
+	for i := range frame {
+		g := a.appliedGain + (frameGain-a.appliedGain)*float64(i+1)/n
+		frame[i] = min(max(frame[i]*g, -a.limit), a.limit)
+	}
+	a.appliedGain = frameGain
+}
+
+// GainDB 当前 AGC 增益(dB)
+func (a *AGC) GainDB() float64 {
+	return 20 * math.Log10(a.gain)
+}
+
+// Reset 重置增益
+func (a *AGC) Reset() {
+	a.gain = 1
+	a.appliedGain = 1
+}
+
+func dbToLinear(db float64) float64 {
+	return math.Pow(10, db/20)
+}
+
+// timeCoef 一阶平滑系数 exp(-frame/tau)
+func timeCoef(ms int, frameSecs float64) float64 {
+	if ms <= 0 {
+		return 0
+	}
+	return math.Exp(-frameSecs / (float64(ms) / 1000))
+}
@@ -0,0 +1,109 @@
+package audio_dsp
+
+import (
+	"math"
+	"sync"
+
+	"achatbot/pkg/params"
+	"achatbot/pkg/utils"
+)
+
+// AudioInputDSP 输入音频 DSP 处理链(高通滤波 -> 频谱噪声门 -> AGC+限幅), 实现 common.IAudioPreprocessor
+// 每个会话创建一个实例, 状态(噪声底, 增益)按会话独立; 仅支持单声道
+type AudioInputDSP struct {
+	mu          sync.Mutex
+	params      *params.AudioDSPParams
+	sampleRate  int
+	sampleWidth int
+
+	frameSamples int
+	highPass     *HighPassFilter
+	noiseGate    *SpectralNoiseGate
+	agc          *AGC
+}
+
+// NewAudioInputDSP 创建输入音频 DSP
+func NewAudioInputDSP(dspParams *params.AudioDSPParams, sampleRate, sampleWidth int) *AudioInputDSP {
+	d := &AudioInputDSP{
+		sampleRate:  sampleRate,
+		sampleWidth: sampleWidth,
+	}
+	d.UpdateParams(dspParams)
+	return d
+}
+
+// UpdateParams 运行时更新参数, 重建处理链(噪声底需重新学习)
+func (d *AudioInputDSP) UpdateParams(dspParams *params.AudioDSPParams) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.params = dspParams
+	d.frameSamples = d.sampleRate * dspParams.FrameMS / 1000
+	frameSecs := float64(dspParams.FrameMS) / 1000
+
+	d.highPass, d.noiseGate, d.agc = nil, nil, nil
+	if dspParams.HighPassEnabled {
+		d.highPass = NewHighPassFilter(d.sampleRate, dspParams.HighPassCutoffHz)
+	}
+	if dspParams.NoiseGateEnabled {
+		learnFrames := int(math.Ceil(dspParams.NoiseLearnSecs / frameSecs))
+		d.noiseGate = NewSpectralNoiseGate(d.frameSamples, dspParams.NoiseGateFloorDB, dspParams.NoiseGateOverSubtraction, learnFrames)
+	}
+	if dspParams.AGCEnabled {
+		d.agc = NewAGC(dspParams.AGCTargetDB, dspParams.AGCMinGainDB, dspParams.AGCMaxGainDB, dspParams.AGCGateDB,
+			dspParams.LimiterDB, dspParams.AGCAttackMS, dspParams.AGCReleaseMS, frameSecs)
+	}
+}
+
+// GetParams 返回当前参数
+func (d *AudioInputDSP) GetParams() *params.AudioDSPParams {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.params
+}
+
+// Process 处理 PCM 音频, 返回等长音频: 高通滤波和噪声门流式处理整段音频(噪声门内部按帧缓存, 输出延迟一帧),
+// AGC 按帧长切帧处理(末尾不足一帧的按短帧处理)
+func (d *AudioInputDSP) Process(audio []byte) []byte {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	samples32 := utils.SamplesToFloat(audio, d.sampleWidth)
+	samples := make([]float64, len(samples32))
+	for i, s := range samples32 {
+		samples[i] = float64(s)
+	}
+
+	if d.highPass != nil {
+		d.highPass.Process(samples)
+	}
+	if d.noiseGate != nil {
+		d.noiseGate.Process(samples)
+	}
+	if d.agc != nil {
+		for start := 0; start < len(samples); start += d.frameSamples {
+			d.agc.Process(samples[start:min(start+d.frameSamples, len(samples))])
+		}
+	}
+
+	for i, s := range samples {
+		samples32[i] = float32(s)
+	}
+	return utils.SamplesFloatTo(samples32, d.sampleWidth)
+}
+
+// Reset 重置处理链状态
+func (d *AudioInputDSP) Reset() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.highPass != nil {
+		d.highPass.Reset()
+	}
+	if d.noiseGate != nil {
+		d.noiseGate.Reset()
+	}
+	if d.agc != nil {
+		d.agc.Reset()
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package audio_dsp
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"achatbot/pkg/params"
+	"achatbot/pkg/utils"
+)
+
+func rms(samples []float64) float64 {
+	var sum float64
+	for _, s := range samples {
+		sum += s * s
+	}
+	return math.Sqrt(sum / float64(len(samples)))
+}
+
+func sine(n, sampleRate int, freq, amp float64) []float64 {
+	out := make([]float64, n)
+	for i := range out {
+		out[i] = amp * math.Sin(2*math.Pi*freq*float64(i)/float64(sampleRate))
+	}
+	return out
+}
+
+func TestHighPassFilter(t *testing.T) {
+	f := NewHighPassFilter(16000, 80)
+
+	// 直流被滤除
+	dc := make([]float64, 16000)
+	for i := range dc {
+		dc[i] = 0.5
+	}
+	f.Process(dc)
+	assert.Less(t, rms(dc[8000:]), 0.01)
+
+	// 1kHz 基本无衰减
+	f.Reset()
+	tone := sine(16000, 16000, 1000, 0.5)
+	f.Process(tone)
+	assert.InDelta(t, 0.5/math.Sqrt2, rms(tone[8000:]), 0.01)
+}
+
+func TestSpectralNoiseGate(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	frameSamples := 320
+	g := NewSpectralNoiseGate(frameSamples, -20, 1.5, 25)
+
+	noise := func() []float64 {
+		frame := make([]float64, frameSamples)
+		for i := range frame {
+			frame[i] = (rng.Float64()*2 - 1) * 0.01
+		}
+		return frame
+	}
+
+	// 学习噪声底后, 平稳噪声被衰减
+	for range 50 {
+		g.Process(noise())
+	}
+	frame := noise()
+	before := rms(frame)
+	g.Process(frame)
+	assert.Less(t, rms(frame), before*0.5)
+
+	// 语音(远高于噪声底的音调)基本保留
+	tone := sine(frameSamples, 16000, 500, 0.3)
+	for i := range tone {
+		tone[i] += (rng.Float64()*2 - 1) * 0.01
+	}
+	before = rms(tone)
+	for range 3 {
+		toneCopy := append([]float64{}, tone...)
+		g.Process(toneCopy)
+		frame = toneCopy
+	}
+	assert.Greater(t, rms(frame), before*0.8)
+}
+
+func TestSpectralNoiseGateFrameBoundaries(t *testing.T) {
+	// 增益为 1 时 overlap-add 完全重建(延迟一帧), 与输入分块方式无关
+	g := NewSpectralNoiseGate(320, -20, 0, 0)
+	tone := sine(16000, 16000, 440, 0.3)
+	out := append([]float64{}, tone...)
+	for start := 0; start < len(out); start += 512 {
+		g.Process(out[start:min(start+512, len(out))])
+	}
+	latency := g.Latency()
+	for i := latency; i < len(out); i++ {
+		assert.InDelta(t, tone[i-latency], out[i], 1e-9)
+	}
+
+	// 学习噪声后增益随帧变化, 平稳音调在帧边界处无跳变(相邻样本差不超过音调本身的最大斜率)
+	rng := rand.New(rand.NewSource(1))
+	g = NewSpectralNoiseGate(320, -20, 1.5, 25)
+	noisy := make([]float64, 32000)
+	for i := range noisy {
+		noisy[i] = (rng.Float64()*2 - 1) * 0.001
+		if i >= 16000 {
+			noisy[i] += 0.3 * math.Sin(2*math.Pi*440*float64(i)/16000)
+		}
+	}
+	for start := 0; start < len(noisy); start += 512 {
+		g.Process(noisy[start:min(start+512, len(noisy))])
+	}
+	maxStep := 2 * math.Pi * 440 / 16000 * 0.3
+	for i := 16000 + 2*latency; i < len(noisy); i++ {
+		assert.LessOrEqual(t, math.Abs(noisy[i]-noisy[i-1]), maxStep*1.1, "discontinuity at %d", i)
+	}
+	assert.Greater(t, rms(noisy[24000:]), 0.3/math.Sqrt2*0.8)
+}
+
+func TestAGC(t *testing.T) {
+	// 小声说话被放大到目标电平附近
+	agc := NewAGC(-20, -12, 24, -50, -1, 20, 400, 0.02)
+	var frame []float64
+	for range 200 {
+		frame = sine(320, 16000, 300, 0.01)
+		agc.Process(frame)
+	}
+	assert.InDelta(t, dbToLinear(-20), rms(frame), 0.02)
+	assert.Greater(t, agc.GainDB(), 10.0)
+
+	// 限幅: 峰值不超过上限
+	loud := sine(320, 16000, 300, 1.0)
+	agc.Process(loud)
+	for _, s := range loud {
+		assert.LessOrEqual(t, math.Abs(s), dbToLinear(-1)+1e-9)
+	}
+
+	// 低于门限的噪声不调整增益
+	agc.Reset()
+	quiet := sine(320, 16000, 300, 0.001)
+	agc.Process(quiet)
+	assert.Equal(t, 0.0, agc.GainDB())
+}
+
+func TestAudioInputDSP(t *testing.T) {
+	dspParams := params.NewAudioDSPParams()
+	assert.NoError(t, dspParams.Validate())
+	d := NewAudioInputDSP(dspParams, 16000, 2)
+
+	// 输出长度与输入一致(包含不足一帧的尾部)
+	samples := sine(512, 16000, 300, 0.05)
+	samples32 := make([]float32, len(samples))
+	for i, s := range samples {
+		samples32[i] = float32(s)
+	}
+	audio := utils.SamplesFloatTo(samples32, 2)
+	out := d.Process(audio)
+	assert.Len(t, out, len(audio))
+
+	// 运行时更新参数
+	d.UpdateParams(params.NewAudioDSPParams().WithAGC(false, -20).WithNoiseGate(false, -18))
+	assert.False(t, d.GetParams().AGCEnabled)
+	assert.Len(t, d.Process(audio), len(audio))
+	assert.NoError(t, d.Reset())
+
+	assert.Error(t, params.NewAudioDSPParams().WithFrameMS(40).Validate())
+}
@@ -0,0 +1,39 @@
+package audio_dsp
+
+import "math"
+
+// HighPassFilter 二阶 Butterworth 高通滤波器(biquad), 去除直流偏移和低频噪声
+type HighPassFilter struct {
+	b0, b1, b2, a1, a2 float64
+	x1, x2, y1, y2     float64
+}
+
+// NewHighPassFilter 创建高通滤波器, 系数参考 RBJ Audio EQ Cookbook
+func NewHighPassFilter(sampleRate int, cutoffHz float64) *HighPassFilter {
+	w0 := 2 * math.Pi * cutoffHz / float64(sampleRate)
+	cosW0 := math.Cos(w0)
+	alpha := math.Sin(w0) / math.Sqrt2 // sin(w0)/(2Q), Q = 1/sqrt(2)
+	a0 := 1 + alpha
+	return &HighPassFilter{
+		b0: (1 + cosW0) / 2 / a0,
+		b1: -(1 + cosW0) / a0,
+		b2: (1 + cosW0) / 2 / a0,
+		a1: -2 * cosW0 / a0,
+		a2: (1 - alpha) / a0,
+	}
+}
+
+// Process 原地滤波
+func (f *HighPassFilter) Process(samples []float64) {
+	for i, x := range samples {
+		y := f.b0*x + f.b1*f.x1 + f.b2*f.x2 - f.a1*f.y1 - f.a2*f.y2
+		f.x2, f.x1 = f.x1, x
+		f.y2, f.y1 = f.y1, y
+		samples[i] = y
+	}
+}
+
+// Reset 重置滤波器状态
+func (f *HighPassFilter) Reset() {
+	f.x1, f.x2, f.y1, f.y2 = 0, 0, 0, 0
+}
@@ -0,0 +1,156 @@
+package audio_dsp
+
+import (
+	"math"
+	"math/cmplx"
+
+	"achatbot/pkg/utils"
+)
+
+// SpectralNoiseGate 频谱噪声门: 学习每个频点的噪声底, 按信噪比计算频点增益(谱减), 并在时间上平滑增益;
+// 流式处理: sqrt-Hann 窗 50% 重叠分析/合成(overlap-add), 增益变化在帧间平滑过渡, 输出延迟一帧(Latency)
+type SpectralNoiseGate struct {
+	frameSamples    int
+	hop             int
+	fftSize         int
+	floorGain       float64
+	overSubtraction float64
+	learnFrames     int
+
+	window    []float64 // sqrt-Hann 分析/合成窗, 50% 重叠时平方和为 1
+	windowSum float64
+
+	framesSeen int
+	noise      []float64 // 每个频点噪声幅度
+	gains      []float64 // 上一帧频点增益
+	buf        []complex128
+	in         []float64 // 分析窗输入, 后 hop 个样本为待填充的新样本
+	inFill     int
+	out        []float64 // overlap-add 输出, 前 hop 个样本已完成
+}
+
+// NewSpectralNoiseGate 创建噪声门, frameSamples 为分析帧样本数(帧移为半帧), floorDB 为噪声频点最大衰减,
+// learnFrames 为初始噪声学习帧数
+func NewSpectralNoiseGate(frameSamples int, floorDB, overSubtraction float64, learnFrames int) *SpectralNoiseGate {
+	hop := max(frameSamples/2, 1)
+	frameSamples = 2 * hop
+	fftSize := utils.NextPowerOfTwo(frameSamples)
+	bins := fftSize/2 + 1
+	window := make([]float64, frameSamples)
+	windowSum := 0.0
+	for i := range window {
+		window[i] = math.Sqrt(0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(frameSamples)))
+		windowSum += window[i]
+	}
+	g := &SpectralNoiseGate{
+		frameSamples:    frameSamples,
+		hop:             hop,
+		fftSize:         fftSize,
+		floorGain:       math.Pow(10, floorDB/20),
+		overSubtraction: overSubtraction,
+		// 每半帧分析一次
+		learnFrames: learnFrames * 2,
+		window:      window,
+		windowSum:   windowSum,
+		noise:       make([]float64, bins),
+		gains:       make([]float64, bins),
+		buf:         make([]complex128, fftSize),
+		in:          make([]float64, frameSamples),
+		out:         make([]float64, frameSamples),
+	}
+	g.Reset()
+	return g
+}
+
+// Latency 输出相对输入的延迟样本数
+func (g *SpectralNoiseGate) Latency() int {
+	return g.frameSamples
+}
+
+// Process 原地处理任意长度的连续音频, 不足半帧的样本缓存到下次处理, 输出延迟 Latency 个样本
+func (g *SpectralNoiseGate) Process(samples []float64) {
+	for i, x := range samples {
+		g.in[g.frameSamples-g.hop+g.inFill] = x
+		samples[i] = g.out[g.inFill]
+		g.inFill++
+		if g.inFill == g.hop {
+			g.processFrame()
+			g.inFill = 0
+		}
+	}
+}
+
+// processFrame 分析加窗帧, 按频点增益滤波, 合成加窗后 overlap-add 到输出
+func (g *SpectralNoiseGate) processFrame() {
+	for i := range g.buf {
+		if i < g.frameSamples {
+			g.buf[i] = complex(g.in[i]*g.window[i], 0)
+		} else {
+			g.buf[i] = 0
+		}
+	}
+	utils.FFT(g.buf, false)
+
+	learning := g.framesSeen < g.learnFrames
+	for k := range g.noise {
+		mag := cmplx.Abs(g.buf[k]) / g.windowSum
+
+		// 噪声底估计: 学习期取均值, 之后快降慢升跟踪
+		switch {
+		case learning:
+			g.noise[k] += (mag - g.noise[k]) / float64(g.framesSeen+1)
+		case mag < g.noise[k]:
+			g.noise[k] = 0.9*g.noise[k] + 0.1*mag
+		default:
+			g.noise[k] = 0.998*g.noise[k] + 0.002*mag
+		}
+
+		gain := 1.0
+		if mag > 0 {
+			gain = 1 - g.overSubtraction*g.noise[k]/mag
+		}
+		gain = max(gain, g.floorGain)
+		// 增益上升快(保护语音起始), 下降慢(减少音乐噪声)
+		if gain > g.gains[k] {
+			gain = 0.3*g.gains[k] + 0.7*gain
+		} else {
+			gain = 0.7*g.gains[k] + 0.3*gain
+		}
+		g.gains[k] = gain
+
+		g.buf[k] *= complex(gain, 0)
+		if k > 0 && k < g.fftSize-k {
+			g.buf[g.fftSize-k] *= complex(gain, 0)
+		}
+	}
+	g.framesSeen++
+
+	utils.FFT(g.buf, true)
+	copy(g.out, g.out[g.hop:])
+	clear(g.out[g.frameSamples-g.hop:])
+	for i := range g.out {
+		g.out[i] += real(g.buf[i]) * g.window[i]
+	}
+	copy(g.in, g.in[g.hop:])
+}
+
+// NoiseFloorDB 当前噪声底平均电平(dBFS), 用于观测
+func (g *SpectralNoiseGate) NoiseFloorDB() float64 {
+	var sum float64
+	for _, v := range g.noise {
+		sum += v * v
+	}
+	return 10 * math.Log10(sum/float64(len(g.noise))+1e-12)
+}
+
+// Reset 重置噪声底, 增益和缓存的音频, 重新学习
+func (g *SpectralNoiseGate) Reset() {
+	g.framesSeen = 0
+	g.inFill = 0
+	clear(g.in)
+	clear(g.out)
+	for k := range g.noise {
+		g.noise[k] = 0
+		g.gains[k] = 1
+	}
+}
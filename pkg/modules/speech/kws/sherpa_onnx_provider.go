@@ -0,0 +1,153 @@
+package kws
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	sherpa "github.com/k2-fsa/sherpa-onnx-go/sherpa_onnx"
+	"github.com/weedge/pipeline-go/pkg/logger"
+
+	"achatbot/pkg/consts"
+	"achatbot/pkg/types"
+	"achatbot/pkg/utils"
+)
+
+const SherpaOnnxProviderName = "SherpaOnnxKeywordSpotter"
+
+type SherpaOnnxProvider struct {
+	config  sherpa.KeywordSpotterConfig
+	spotter *sherpa.KeywordSpotter
+	stream  *sherpa.OnlineStream
+	// 自定义唤醒词, 以 "/" 分隔, 为空时使用 config.KeywordsFile
+	keywords   string
+	sampleRate int
+}
+
+// https://github.com/k2-fsa/sherpa-onnx/blob/v1.12.14/sherpa-onnx/csrc/keyword-spotter.h
+// https://k2-fsa.github.io/sherpa/onnx/kws/index.html
+// keywords.txt 每行一个唤醒词, 按 tokens.txt 的建模单元(拼音声韵母)切分, 如: x iǎo ài t óng x ué @小爱同学
+func NewDefaultSherpaOnnxKeywordSpotterConfig() sherpa.KeywordSpotterConfig {
+	modelDir := filepath.Join(consts.MODELS_DIR, "csukuangfj/sherpa-onnx-kws-zipformer-wenetspeech-3.3M-2024-01-01")
+	return sherpa.KeywordSpotterConfig{
+		FeatConfig: sherpa.FeatureConfig{SampleRate: consts.DefaultRate, FeatureDim: 80},
+		ModelConfig: sherpa.OnlineModelConfig{
+			Transducer: sherpa.OnlineTransducerModelConfig{
+				Encoder: filepath.Join(modelDir, "encoder-epoch-12-avg-2-chunk-16-left-64.int8.onnx"),
+				Decoder: filepath.Join(modelDir, "decoder-epoch-12-avg-2-chunk-16-left-64.onnx"),
+				Joiner:  filepath.Join(modelDir, "joiner-epoch-12-avg-2-chunk-16-left-64.int8.onnx"),
+			},
+			Tokens:     filepath.Join(modelDir, "tokens.txt"),
+			NumThreads: 1,
+			Provider:   "cpu",
+			Debug:      0,
+		},
+		MaxActivePaths:    4,
+		KeywordsFile:      filepath.Join(modelDir, "keywords.txt"),
+		KeywordsScore:     1.0,
+		KeywordsThreshold: 0.25,
+		NumTrailingBlanks: 1,
+	}
+}
+
+// NewSherpaOnnxProvider keywords 为空时使用 config.KeywordsFile 中的唤醒词
+func NewSherpaOnnxProvider(config sherpa.KeywordSpotterConfig, keywords ...string) *SherpaOnnxProvider {
+	provider := &SherpaOnnxProvider{
+		config:     config,
+		keywords:   strings.Join(keywords, "/"),
+		sampleRate: config.FeatConfig.SampleRate,
+	}
+	provider.spotter = sherpa.NewKeywordSpotter(&config)
+	if provider.spotter == nil {
+		logger.Error("Fail to create keyword spotter")
+		return nil
+	}
+	provider.stream = provider.newStream()
+	if provider.stream == nil {
+		logger.Errorf("Fail to create keyword stream, keywords: %s", provider.keywords)
+		sherpa.DeleteKeywordSpotter(provider.spotter)
+		return nil
+	}
+
+	logger.Info("KWS NewSherpaOnnxProvider Done")
+
+	return provider
+}
+
+func (p *SherpaOnnxProvider) newStream() *sherpa.OnlineStream {
+	if p.keywords == "" {
+		return sherpa.NewKeywordStream(p.spotter)
+	}
+	return sherpa.NewKeywordStreamWithKeywords(p.spotter, p.keywords)
+}
+
+// DetectKeyword 流式输入音频(16k 单声道 16bit), 检测到唤醒词时返回唤醒词
+func (p *SherpaOnnxProvider) DetectKeyword(audio []byte) (string, error) {
+	if p.stream == nil {
+		return "", fmt.Errorf("keyword stream is released")
+	}
+	p.stream.AcceptWaveform(p.sampleRate, utils.SamplesInt16ToFloat(audio))
+	for p.spotter.IsReady(p.stream) {
+		p.spotter.Decode(p.stream)
+		result := p.spotter.GetResult(p.stream)
+		if result != nil && result.Keyword != "" {
+			// 检测到唤醒词后需要重置解码状态, 否则会重复触发
+			p.spotter.Reset(p.stream)
+			return result.Keyword, nil
+		}
+	}
+	return "", nil
+}
+
+func (p *SherpaOnnxProvider) Warmup() {
+}
+
+func (p *SherpaOnnxProvider) Name() string {
+	return SherpaOnnxProviderName
+}
+
+// Reset 重建 stream, 丢弃上一段语音的解码状态
+func (p *SherpaOnnxProvider) Reset() error {
+	if p.spotter == nil {
+		return fmt.Errorf("keyword spotter is released")
+	}
+	if p.stream != nil {
+		sherpa.DeleteOnlineStream(p.stream)
+	}
+	p.stream = p.newStream()
+	if p.stream == nil {
+		return fmt.Errorf("fail to create keyword stream")
+	}
+	return nil
+}
+
+func (p *SherpaOnnxProvider) Release() error {
+	if p.stream != nil {
+		sherpa.DeleteOnlineStream(p.stream)
+		p.stream = nil
+	}
+	if p.spotter != nil {
+		sherpa.DeleteKeywordSpotter(p.spotter)
+		p.spotter = nil
+	}
+	return nil
+}
+
+// HealthCheck 唤醒词检测器已释放或 stream 创建失败时不可用, 池替换该实例
+func (p *SherpaOnnxProvider) HealthCheck() error {
+	if p.spotter == nil {
+		return fmt.Errorf("keyword spotter is released")
+	}
+	if p.stream == nil {
+		return fmt.Errorf("keyword stream is not created")
+	}
+	return nil
+}
+
+func (p *SherpaOnnxProvider) GetAudioInFormat() *types.AudioFormat {
+	return types.NewAudioFormat(p.sampleRate, consts.DefaultChannels, consts.DefaultSampleWidth)
+}
+
+func (p *SherpaOnnxProvider) GetAudioOutFormat() *types.AudioFormat {
+	return nil
+}
@@ -0,0 +1,91 @@
+package lid
+
+import (
+	"fmt"
+	"path/filepath"
+
+	sherpa "github.com/k2-fsa/sherpa-onnx-go/sherpa_onnx"
+	"github.com/weedge/pipeline-go/pkg/logger"
+
+	"achatbot/pkg/consts"
+	"achatbot/pkg/types"
+	"achatbot/pkg/utils"
+)
+
+const SherpaOnnxProviderName = "SherpaOnnxSpokenLanguageID"
+
+type SherpaOnnxProvider struct {
+	config     sherpa.SpokenLanguageIdentificationConfig
+	slid       *sherpa.SpokenLanguageIdentification
+	sampleRate int
+}
+
+// https://github.com/k2-fsa/sherpa-onnx/blob/v1.12.14/sherpa-onnx/csrc/spoken-language-identification.h
+// https://k2-fsa.github.io/sherpa/onnx/spoken-language-identification/index.html
+// use multilingual whisper models (not *.en)
+func NewDefaultSherpaOnnxSpokenLanguageIdentificationConfig() sherpa.SpokenLanguageIdentificationConfig {
+	return sherpa.SpokenLanguageIdentificationConfig{
+		Whisper: sherpa.SpokenLanguageIdentificationWhisperConfig{
+			Encoder:      filepath.Join(consts.MODELS_DIR, "csukuangfj/sherpa-onnx-whisper-tiny/tiny-encoder.int8.onnx"),
+			Decoder:      filepath.Join(consts.MODELS_DIR, "csukuangfj/sherpa-onnx-whisper-tiny/tiny-decoder.int8.onnx"),
+			TailPaddings: -1,
+		},
+		NumThreads: 1,
+		Debug:      0,
+		Provider:   "cpu",
+	}
+}
+
+func NewSherpaOnnxProvider(config sherpa.SpokenLanguageIdentificationConfig) *SherpaOnnxProvider {
+	provider := &SherpaOnnxProvider{
+		config:     config,
+		sampleRate: consts.DefaultRate, // whisper uses 16000 samples
+	}
+	provider.slid = sherpa.NewSpokenLanguageIdentification(&config)
+	if provider.slid == nil {
+		logger.Error("Fail to create spoken language identification")
+		return nil
+	}
+
+	logger.Info("LID NewSherpaOnnxProvider Done")
+
+	return provider
+}
+
+// IdentifyLanguage 识别语音(16k 单声道 16bit)的语种
+func (p *SherpaOnnxProvider) IdentifyLanguage(audio []byte) (string, error) {
+	samples := utils.SamplesInt16ToFloat(audio)
+	stream := p.slid.CreateStream()
+	defer sherpa.DeleteOfflineStream(stream)
+
+	stream.AcceptWaveform(p.sampleRate, samples)
+	result := p.slid.Compute(stream)
+	if result == nil || result.Lang == "" {
+		return "", fmt.Errorf("identify language failed: %d samples", len(samples))
+	}
+	return result.Lang, nil
+}
+
+func (p *SherpaOnnxProvider) Warmup() {
+}
+
+func (p *SherpaOnnxProvider) Reset() error {
+	return nil
+}
+
+func (p *SherpaOnnxProvider) Release() error {
+	sherpa.DeleteSpokenLanguageIdentification(p.slid)
+	return nil
+}
+
+func (p *SherpaOnnxProvider) Name() string {
+	return SherpaOnnxProviderName
+}
+
+func (p *SherpaOnnxProvider) GetAudioInFormat() *types.AudioFormat {
+	return types.NewAudioFormat(p.sampleRate, consts.DefaultChannels, consts.DefaultSampleWidth)
+}
+
+func (p *SherpaOnnxProvider) GetAudioOutFormat() *types.AudioFormat {
+	return nil
+}
@@ -0,0 +1,87 @@
+package speaker
+
+import (
+	"fmt"
+	"path/filepath"
+
+	sherpa "github.com/k2-fsa/sherpa-onnx-go/sherpa_onnx"
+	"github.com/weedge/pipeline-go/pkg/logger"
+
+	"achatbot/pkg/consts"
+	"achatbot/pkg/types"
+	"achatbot/pkg/utils"
+)
+
+const SherpaOnnxProviderName = "SherpaOnnxSpeakerEmbedding"
+
+type SherpaOnnxProvider struct {
+	config     sherpa.SpeakerEmbeddingExtractorConfig
+	extractor  *sherpa.SpeakerEmbeddingExtractor
+	sampleRate int
+}
+
+// https://github.com/k2-fsa/sherpa-onnx/blob/v1.12.14/sherpa-onnx/csrc/speaker-embedding-extractor.h
+// https://github.com/k2-fsa/sherpa-onnx/releases/tag/speaker-recongition-models
+// https://github.com/modelscope/3D-Speaker
+func NewDefaultSherpaOnnxSpeakerEmbeddingExtractorConfig() sherpa.SpeakerEmbeddingExtractorConfig {
+	return sherpa.SpeakerEmbeddingExtractorConfig{
+		Model:      filepath.Join(consts.MODELS_DIR, "3dspeaker_speech_eres2net_base_sv_zh-cn_3dspeaker_16k.onnx"),
+		NumThreads: 1,
+		Debug:      0,
+		Provider:   "cpu",
+	}
+}
+
+func NewSherpaOnnxProvider(config sherpa.SpeakerEmbeddingExtractorConfig) *SherpaOnnxProvider {
+	provider := &SherpaOnnxProvider{
+		config:     config,
+		sampleRate: consts.DefaultRate, // speaker embedding models are trained with 16000 samples
+	}
+	provider.extractor = sherpa.NewSpeakerEmbeddingExtractor(&config)
+	if provider.extractor == nil {
+		logger.Error("Fail to create speaker embedding extractor")
+		return nil
+	}
+
+	logger.Info("Speaker NewSherpaOnnxProvider Done", "dim", provider.extractor.Dim())
+
+	return provider
+}
+
+// ComputeEmbedding 提取音频(16k 单声道 16bit)的说话人声纹向量
+func (p *SherpaOnnxProvider) ComputeEmbedding(audio []byte) ([]float32, error) {
+	samples := utils.SamplesInt16ToFloat(audio)
+	stream := p.extractor.CreateStream()
+	defer sherpa.DeleteOnlineStream(stream)
+
+	stream.AcceptWaveform(p.sampleRate, samples)
+	stream.InputFinished()
+	if !p.extractor.IsReady(stream) {
+		return nil, fmt.Errorf("audio too short to compute speaker embedding: %d samples", len(samples))
+	}
+	return p.extractor.Compute(stream), nil
+}
+
+func (p *SherpaOnnxProvider) Warmup() {
+}
+
+func (p *SherpaOnnxProvider) Reset() error {
+	return nil
+}
+
+func (p *SherpaOnnxProvider) Release() error {
+	sherpa.DeleteSpeakerEmbeddingExtractor(p.extractor)
+	return nil
+}
+
+func (p *SherpaOnnxProvider) Name() string {
+	return SherpaOnnxProviderName
+}
+
+func (p *SherpaOnnxProvider) GetAudioInFormat() *types.AudioFormat {
+	return types.NewAudioFormat(p.sampleRate, consts.DefaultChannels, consts.DefaultSampleWidth)
+}
+
+func (p *SherpaOnnxProvider) GetAudioOutFormat() *types.AudioFormat {
+	return nil
+}
@@ -0,0 +1,65 @@
+package speaker
+
+import (
+	"fmt"
+
+	"achatbot/pkg/common"
+	"achatbot/pkg/params"
+	"achatbot/pkg/types"
+)
+
+// SpeakerVerifier 说话人验证: 声纹提取 + 注册声纹比对, 多个会话可共享
+type SpeakerVerifier struct {
+	args     *params.SpeakerVerifyArgs
+	provider common.ISpeakerEmbeddingProvider
+	store    *VoiceprintStore
+}
+
+func NewSpeakerVerifier(args *params.SpeakerVerifyArgs, provider common.ISpeakerEmbeddingProvider, store *VoiceprintStore) *SpeakerVerifier {
+	return &SpeakerVerifier{
+		args:     args,
+		provider: provider,
+		store:    store,
+	}
+}
+
+// GetStore 返回注册声纹存储
+func (v *SpeakerVerifier) GetStore() *VoiceprintStore {
+	return v.store
+}
+
+// Enroll 使用用户语音注册声纹
+func (v *SpeakerVerifier) Enroll(userID string, audio []byte) error {
+	embedding, err := v.provider.ComputeEmbedding(audio)
+	if err != nil {
+		return fmt.Errorf("enroll %s: %w", userID, err)
+	}
+	return v.store.Add(userID, embedding)
+}
+
+// Verify 验证语音是否来自注册用户
+func (v *SpeakerVerifier) Verify(audio []byte) (*types.SpeakerVerifyResult, error) {
+	embedding, err := v.provider.ComputeEmbedding(audio)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, score := v.store.Identify(embedding)
+	result := &types.SpeakerVerifyResult{Score: score}
+	switch {
+	case userID == "":
+		result.Reason = "no enrolled speaker"
+	case score < v.args.Threshold:
+		result.Reason = fmt.Sprintf("nearest %s below threshold %.2f", userID, v.args.Threshold)
+	default:
+		result.SpeakerID = userID
+		result.Verified = true
+		result.Reason = "matched"
+	}
+	return result, nil
+}
+
+// NumEnrolled 返回已注册的用户数
+func (v *SpeakerVerifier) NumEnrolled() int {
+	return v.store.Len()
+}
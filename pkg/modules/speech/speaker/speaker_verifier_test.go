@@ -0,0 +1,95 @@
+package speaker
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"achatbot/pkg/common"
+	"achatbot/pkg/params"
+)
+
+var _ common.ISpeakerVerifier = (*SpeakerVerifier)(nil)
+
+// fakeProvider 音频首字节决定声纹方向: 1 -> x 轴, 2 -> y 轴, 3 -> 靠近 x 轴
+type fakeProvider struct{}
+
+func (p *fakeProvider) ComputeEmbedding(audio []byte) ([]float32, error) {
+	if len(audio) == 0 {
+		return nil, fmt.Errorf("empty audio")
+	}
+	switch audio[0] {
+	case 1:
+		return []float32{2, 0, 0}, nil
+	case 2:
+		return []float32{0, 1, 0}, nil
+	default:
+		return []float32{0.9, 0.1, 0.1}, nil
+	}
+}
+func (p *fakeProvider) Warmup()        {}
+func (p *fakeProvider) Name() string   { return "fake" }
+func (p *fakeProvider) Reset() error   { return nil }
+func (p *fakeProvider) Release() error { return nil }
+
+func TestCosineSimilarity(t *testing.T) {
+	assert.InDelta(t, 1, CosineSimilarity([]float32{1, 2}, []float32{2, 4}), 1e-6)
+	assert.InDelta(t, 0, CosineSimilarity([]float32{1, 0}, []float32{0, 1}), 1e-6)
+	assert.InDelta(t, -1, CosineSimilarity([]float32{1, 0}, []float32{-1, 0}), 1e-6)
+	assert.Equal(t, float32(0), CosineSimilarity([]float32{1}, []float32{1, 0}))
+}
+
+func TestSpeakerVerifier(t *testing.T) {
+	args := params.NewSpeakerVerifyArgs().WithThreshold(0.8)
+	assert.NoError(t, args.Validate())
+	verifier := NewSpeakerVerifier(args, &fakeProvider{}, NewVoiceprintStore())
+
+	// 没有注册声纹
+	result, err := verifier.Verify([]byte{1})
+	assert.NoError(t, err)
+	assert.False(t, result.Verified)
+
+	assert.NoError(t, verifier.Enroll("alice", []byte{1}))
+	assert.NoError(t, verifier.Enroll("alice", []byte{1}))
+	assert.NoError(t, verifier.Enroll("bob", []byte{2}))
+	assert.Error(t, verifier.Enroll("", []byte{1}))
+	assert.Error(t, verifier.Enroll("carol", nil))
+	assert.Equal(t, 2, verifier.NumEnrolled())
+	assert.Equal(t, []string{"alice", "bob"}, verifier.GetStore().UserIDs())
+
+	result, err = verifier.Verify([]byte{3})
+	assert.NoError(t, err)
+	assert.True(t, result.Verified)
+	assert.Equal(t, "alice", result.SpeakerID)
+	assert.Greater(t, result.Score, float32(0.8))
+
+	// 低于阈值
+	verifier = NewSpeakerVerifier(params.NewSpeakerVerifyArgs().WithThreshold(0.999), &fakeProvider{}, verifier.GetStore())
+	result, err = verifier.Verify([]byte{3})
+	assert.NoError(t, err)
+	assert.False(t, result.Verified)
+	assert.Empty(t, result.SpeakerID)
+}
+
+func TestVoiceprintStoreSaveLoad(t *testing.T) {
+	store := NewVoiceprintStore()
+	assert.NoError(t, store.Add("alice", []float32{1, 0}))
+	assert.NoError(t, store.Add("alice", []float32{0, 1}))
+	assert.Error(t, store.Add("alice", []float32{1, 0, 0}))
+	assert.Error(t, store.Add("bob", []float32{0, 0}))
+
+	path := filepath.Join(t.TempDir(), "voiceprints.json")
+	assert.NoError(t, store.Save(path))
+
+	loaded := NewVoiceprintStore()
+	assert.NoError(t, loaded.Load(path))
+	id, score := loaded.Identify([]float32{1, 1})
+	assert.Equal(t, "alice", id)
+	assert.InDelta(t, 1, score, 1e-6)
+
+	loaded.Remove("alice")
+	id, _ = loaded.Identify([]float32{1, 1})
+	assert.Empty(t, id)
+}
@@ -0,0 +1,175 @@
+package speaker
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"sync"
+)
+
+// Voiceprint 用户声纹, 多次注册的声纹向量(归一化后)取平均
+type Voiceprint struct {
+	UserID    string    `json:"user_id"`
+	Embedding []float32 `json:"embedding"`
+	Count     int       `json:"count"`
+}
+
+// VoiceprintStore 注册用户声纹存储, 并发安全, 可持久化为 json 文件
+type VoiceprintStore struct {
+	mu          sync.RWMutex
+	voiceprints map[string]*Voiceprint
+}
+
+func NewVoiceprintStore() *VoiceprintStore {
+	return &VoiceprintStore{
+		voiceprints: make(map[string]*Voiceprint),
+	}
+}
+
+// Add 注册用户声纹, 已注册用户与之前的声纹累计平均
+func (s *VoiceprintStore) Add(userID string, embedding []float32) error {
+	if userID == "" {
+		return fmt.Errorf("empty user id")
+	}
+	embedding = normalize(embedding)
+	if embedding == nil {
+		return fmt.Errorf("invalid speaker embedding")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	vp, ok := s.voiceprints[userID]
+	if !ok {
+		s.voiceprints[userID] = &Voiceprint{UserID: userID, Embedding: embedding, Count: 1}
+		return nil
+	}
+	if len(vp.Embedding) != len(embedding) {
+		return fmt.Errorf("speaker embedding dim mismatch: %d != %d", len(embedding), len(vp.Embedding))
+	}
+	for i := range vp.Embedding {
+		vp.Embedding[i] = (vp.Embedding[i]*float32(vp.Count) + embedding[i]) / float32(vp.Count+1)
+	}
+	vp.Count++
+	return nil
+}
+
+// Remove 删除用户声纹
+func (s *VoiceprintStore) Remove(userID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.voiceprints, userID)
+}
+
+// Len 返回已注册的用户数
+func (s *VoiceprintStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.voiceprints)
+}
+
+// UserIDs 返回已注册的用户 ID(排序)
+func (s *VoiceprintStore) UserIDs() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ids := make([]string, 0, len(s.voiceprints))
+	for id := range s.voiceprints {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Identify 返回与声纹最相似的注册用户及余弦相似度, 没有注册用户时返回空
+func (s *VoiceprintStore) Identify(embedding []float32) (string, float32) {
+	embedding = normalize(embedding)
+	if embedding == nil {
+		return "", 0
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	bestID, bestScore := "", float32(-1)
+	for id, vp := range s.voiceprints {
+		score := CosineSimilarity(embedding, vp.Embedding)
+		if score > bestScore || (score == bestScore && id < bestID) {
+			bestID, bestScore = id, score
+		}
+	}
+	if bestID == "" {
+		return "", 0
+	}
+	return bestID, bestScore
+}
+
+// Save 持久化声纹到 json 文件
+func (s *VoiceprintStore) Save(path string) error {
+	s.mu.RLock()
+	list := make([]*Voiceprint, 0, len(s.voiceprints))
+	for _, vp := range s.voiceprints {
+		list = append(list, vp)
+	}
+	data, err := json.Marshal(list)
+	s.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// Load 从 json 文件加载声纹, 覆盖同名用户
+func (s *VoiceprintStore) Load(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	list := make([]*Voiceprint, 0)
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, vp := range list {
+		if vp.UserID == "" || len(vp.Embedding) == 0 {
+			continue
+		}
+		s.voiceprints[vp.UserID] = vp
+	}
+	return nil
+}
+
+// CosineSimilarity 余弦相似度, 维度不一致返回 0
+func CosineSimilarity(a, b []float32) float32 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return float32(dot / math.Sqrt(na*nb))
+}
+
+// normalize L2 归一化(返回新切片), 零向量返回 nil
+func normalize(embedding []float32) []float32 {
+	var sum float64
+	for _, v := range embedding {
+		sum += float64(v) * float64(v)
+	}
+	if sum == 0 {
+		return nil
+	}
+	norm := float32(math.Sqrt(sum))
+	res := make([]float32, len(embedding))
+	for i, v := range embedding {
+		res[i] = v / norm
+	}
+	return res
+}
@@ -0,0 +1,172 @@
+package tts
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/weedge/pipeline-go/pkg/logger"
+
+	"achatbot/pkg/common"
+	"achatbot/pkg/types"
+	"achatbot/pkg/utils"
+)
+
+// CachedTTSProvider 带合成缓存的 TTS 提供者, 包装池化的提供者实例, 多个会话共享同一个 TTSCache;
+// 问候语, 填充词, 出错致歉等重复文本直接返回缓存的音频
+type CachedTTSProvider struct {
+	provider common.ITTSProvider
+	cache    *TTSCache
+	// 设置了 prompt audio 后音色不再由缓存键决定, 不使用缓存
+	promptAudio bool
+}
+
+func NewCachedTTSProvider(provider common.ITTSProvider, cache *TTSCache) *CachedTTSProvider {
+	return &CachedTTSProvider{
+		provider: provider,
+		cache:    cache,
+	}
+}
+
+func (p *CachedTTSProvider) Synthesize(text string) []byte {
+	return p.cached(text, nil, func() []byte {
+		return p.provider.Synthesize(text)
+	})
+}
+
+// SynthesizeLanguage synthesizes with the speaker of the language if the provider supports
+func (p *CachedTTSProvider) SynthesizeLanguage(text string, language string) []byte {
+	multiLanguageProvider, ok := p.provider.(common.IMultiLanguageTTSProvider)
+	if !ok {
+		return p.Synthesize(text)
+	}
+	return p.cached(text, &types.TTSOptions{Language: language}, func() []byte {
+		return multiLanguageProvider.SynthesizeLanguage(text, language)
+	})
+}
+
+// GetLanguages returns the languages with speaker configured of the provider
+func (p *CachedTTSProvider) GetLanguages() []string {
+	if multiLanguageProvider, ok := p.provider.(common.IMultiLanguageTTSProvider); ok {
+		return multiLanguageProvider.GetLanguages()
+	}
+	return nil
+}
+
+// SynthesizeWithOptions synthesizes with the options if the provider supports, otherwise with the voice of the language
+func (p *CachedTTSProvider) SynthesizeWithOptions(text string, options *types.TTSOptions) []byte {
+	optionsProvider, ok := p.provider.(common.ITTSOptionsProvider)
+	if !ok {
+		language := ""
+		if options != nil {
+			language = options.Language
+		}
+		return p.SynthesizeLanguage(text, language)
+	}
+	return p.cached(text, options, func() []byte {
+		return optionsProvider.SynthesizeWithOptions(text, options)
+	})
+}
+
+// Prewarm synthesizes the not cached phrases with the options (nil for the provider default voice),
+// phrases are split by language as TTSProcessor does (unless the speaker is specified), so the keys match at runtime;
+// returns the number of synthesized segments
+func (p *CachedTTSProvider) Prewarm(phrases []string, options *types.TTSOptions) int {
+	splitLanguage := len(p.GetLanguages()) > 0 && (options == nil || options.Speaker == "")
+	synthesized := 0
+	for _, phrase := range phrases {
+		segments := []types.LanguageSegment{{Text: phrase}}
+		if splitLanguage {
+			segments = utils.SplitTextByLanguage(phrase, 2)
+		}
+		for _, segment := range segments {
+			segmentOptions := options.Merge(&types.TTSOptions{Language: segment.Language})
+			if !p.cache.Cacheable(segment.Text) || p.cache.Contains(p.cacheKey(segmentOptions, segment.Text)) {
+				continue
+			}
+			p.SynthesizeWithOptions(segment.Text, segmentOptions)
+			synthesized++
+		}
+	}
+	logger.Info("TTS cache prewarm Done", "phrases", len(phrases), "synthesized", synthesized)
+	return synthesized
+}
+
+// cached returns the cached audio of the text, otherwise synthesizes and caches it
+func (p *CachedTTSProvider) cached(text string, options *types.TTSOptions, synthesize func() []byte) []byte {
+	if p.promptAudio || !p.cache.Cacheable(text) {
+		return synthesize()
+	}
+
+	key := p.cacheKey(options, text)
+	if audio, ok := p.cache.Get(key); ok {
+		return audio
+	}
+	audio := synthesize()
+	rate, channels, sampleWidth := p.provider.GetSampleInfo()
+	p.cache.Put(key, audio, rate, channels, sampleWidth)
+	return audio
+}
+
+// cacheKey keys on the resolved speaker if the provider supports, so the default voice and the same voice
+// specified by name or ID share the entry, and pooled instances with different default voices do not
+func (p *CachedTTSProvider) cacheKey(options *types.TTSOptions, text string) string {
+	if resolver, ok := p.provider.(common.ITTSSpeakerResolver); ok {
+		resolved := options.Merge(nil)
+		resolved.Speaker = resolver.ResolveSpeaker(options)
+		options = resolved
+	}
+	return TTSCacheKey(p.Name(), options, text)
+}
+
+func (p *CachedTTSProvider) Warmup() {
+	p.provider.Warmup()
+}
+
+func (p *CachedTTSProvider) GetSampleInfo() (int, int, int) {
+	return p.provider.GetSampleInfo()
+}
+
+func (p *CachedTTSProvider) SetPromptAudio(text string, audio []byte) error {
+	if err := p.provider.SetPromptAudio(text, audio); err != nil {
+		return err
+	}
+	p.promptAudio = true
+	return nil
+}
+
+func (p *CachedTTSProvider) Name() string {
+	return p.provider.Name()
+}
+
+func (p *CachedTTSProvider) Reset() error {
+	return p.provider.Reset()
+}
+
+func (p *CachedTTSProvider) Release() error {
+	return p.provider.Release()
+}
+
+// LoadPrewarmPhrases loads the phrases to prewarm, one phrase per line, lines starting with # are comments
+func LoadPrewarmPhrases(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open tts prewarm phrases %s: %w", path, err)
+	}
+	defer file.Close()
+
+	phrases := make([]string, 0)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		phrases = append(phrases, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read tts prewarm phrases %s: %w", path, err)
+	}
+	return phrases, nil
+}
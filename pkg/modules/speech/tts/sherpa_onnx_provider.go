@@ -1,14 +1,18 @@
 package tts
 
 import (
-	"achatbot/pkg/consts"
-	"achatbot/pkg/utils"
+	"fmt"
 	"math"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	sherpa "github.com/k2-fsa/sherpa-onnx-go/sherpa_onnx"
 	"github.com/weedge/pipeline-go/pkg/logger"
+
+	"achatbot/pkg/consts"
+	"achatbot/pkg/types"
+	"achatbot/pkg/utils"
 )
 
 type SherpaOnnxProvider struct {
@@ -18,6 +22,8 @@ type SherpaOnnxProvider struct {
 	sid        int     // Speaker ID (multi-speaker models only)
 	speed      float32 // Speech speed. larger->faster; smaller->slower
 	sampleRate int
+
+	languageSids map[string]int // per language speaker ID, e.g. zh -> zm_yunjian, en -> am_michael
 }
 
 // https://github.com/k2-fsa/sherpa-onnx/blob/v1.12.14/sherpa-onnx/csrc/offline-tts-kokoro-model-config.h
@@ -54,6 +60,11 @@ func NewDefaultSherpaOnnxOfflineTtsConfig() sherpa.OfflineTtsConfig {
 }
 
 const (
+	//kokoroTTS: 3->af_heart, 11->am_adam, 16->am_michael
+	KokoroTTS_Speaker_AF_Heart   = 3
+	KokoroTTS_Speaker_AM_Adam    = 11
+	KokoroTTS_Speaker_AM_Michael = 16
+
 	//kokoroTTS: 45->zf_xiaobei, 46->zf_xiaoni, 47->zf_xiaoxiao, 48->zf_xiaoyi, 49->zm_yunjian, 50->zm_yunxi, 51->zm_yunxia, 52->zm_yunyang
 	KokoroTTS_Speaker_ZF_XiaoBei  = 45
 	KokoroTTS_Speaker_ZF_XiaoNi   = 46
@@ -61,9 +72,25 @@ const (
 	KokoroTTS_Speaker_ZF_XiaoYi   = 48
 	KokoroTTS_Speaker_ZM_YunJian  = 49
 	KokoroTTS_Speaker_ZM_YunXi    = 50
+	KokoroTTS_Speaker_ZM_YunXia   = 51
 	KokoroTTS_Speaker_ZM_YunYang  = 52
 )
 
+// KokoroTTSSpeakers speaker name -> speaker ID, for TTSOptions.Speaker and markup voice switch
+var KokoroTTSSpeakers = map[string]int{
+	"af_heart":    KokoroTTS_Speaker_AF_Heart,
+	"am_adam":     KokoroTTS_Speaker_AM_Adam,
+	"am_michael":  KokoroTTS_Speaker_AM_Michael,
+	"zf_xiaobei":  KokoroTTS_Speaker_ZF_XiaoBei,
+	"zf_xiaoni":   KokoroTTS_Speaker_ZF_XiaoNi,
+	"zf_xiaoxiao": KokoroTTS_Speaker_ZF_XiaoXiao,
+	"zf_xiaoyi":   KokoroTTS_Speaker_ZF_XiaoYi,
+	"zm_yunjian":  KokoroTTS_Speaker_ZM_YunJian,
+	"zm_yunxi":    KokoroTTS_Speaker_ZM_YunXi,
+	"zm_yunxia":   KokoroTTS_Speaker_ZM_YunXia,
+	"zm_yunyang":  KokoroTTS_Speaker_ZM_YunYang,
+}
+
 func NewSherpaOnnxProvider(config sherpa.OfflineTtsConfig, sid int, speed float32, name string) *SherpaOnnxProvider {
 	provider := &SherpaOnnxProvider{
 		config: config,
@@ -87,8 +114,101 @@ func NewSherpaOnnxProvider(config sherpa.OfflineTtsConfig, sid int, speed float3
 	return provider
 }
 
+// WithLanguageSpeaker sets the speaker ID used to synthesize text of the language
+func (p *SherpaOnnxProvider) WithLanguageSpeaker(language string, sid int) *SherpaOnnxProvider {
+	if p.languageSids == nil {
+		p.languageSids = make(map[string]int)
+	}
+	p.languageSids[language] = sid
+	return p
+}
+
 func (p *SherpaOnnxProvider) Synthesize(text string) []byte {
-	generateAudio := p.tts.Generate(text, p.sid, float32(math.Max(float64(p.speed), 1e-6)))
+	return p.generate(text, p.sid)
+}
+
+// SynthesizeLanguage synthesizes with the speaker of the language, fallback to the default speaker
+func (p *SherpaOnnxProvider) SynthesizeLanguage(text string, language string) []byte {
+	if sid, ok := p.languageSids[language]; ok {
+		return p.generate(text, sid)
+	}
+	return p.generate(text, p.sid)
+}
+
+// GetLanguages returns the languages with speaker configured
+func (p *SherpaOnnxProvider) GetLanguages() []string {
+	languages := make([]string, 0, len(p.languageSids))
+	for language := range p.languageSids {
+		languages = append(languages, language)
+	}
+	return languages
+}
+
+// SynthesizeWithOptions synthesizes with the speaker (ID or name in KokoroTTSSpeakers) and speed of the options,
+// pitch shifts by synthesizing slower/faster then resampling back to the original duration, volume scales the samples
+func (p *SherpaOnnxProvider) SynthesizeWithOptions(text string, options *types.TTSOptions) []byte {
+	sid, ok := p.resolveSid(options)
+	if !ok {
+		logger.Warnf("TTS %s unknown speaker %s, use %d", p.name, options.Speaker, sid)
+	}
+	speed := p.speed
+	if options.Speed > 0 {
+		speed = options.Speed
+	}
+	pitchFactor := 1.0
+	if options.Pitch != 0 {
+		pitchFactor = math.Pow(2, float64(options.Pitch)/12)
+		speed = float32(float64(speed) / pitchFactor)
+	}
+
+	generateAudio := p.tts.Generate(text, sid, float32(math.Max(float64(speed), 1e-6)))
+	p.sampleRate = generateAudio.SampleRate
+	samples := generateAudio.Samples
+	if pitchFactor != 1 {
+		samples = utils.Resample(samples, int(float64(p.sampleRate)*pitchFactor), p.sampleRate)
+	}
+	if options.Volume > 0 && options.Volume != 1 {
+		samples = utils.ScaleSamples(samples, options.Volume)
+	}
+	return utils.SamplesFloatToInt16(samples)
+}
+
+// ResolveSpeaker returns the speaker ID used to synthesize with the options, e.g. the default speaker and "49" resolve the same
+func (p *SherpaOnnxProvider) ResolveSpeaker(options *types.TTSOptions) string {
+	sid, _ := p.resolveSid(options)
+	return strconv.Itoa(sid)
+}
+
+// resolveSid returns the speaker ID of the options: the speaker, else the speaker of the language, else the default;
+// false if the speaker is unknown
+func (p *SherpaOnnxProvider) resolveSid(options *types.TTSOptions) (int, bool) {
+	sid := p.sid
+	if options == nil {
+		return sid, true
+	}
+	if languageSid, ok := p.languageSids[options.Language]; ok {
+		sid = languageSid
+	}
+	if options.Speaker == "" {
+		return sid, true
+	}
+	if speakerSid, ok := p.parseSpeaker(options.Speaker); ok {
+		return speakerSid, true
+	}
+	return sid, false
+}
+
+// parseSpeaker parses speaker ID or name
+func (p *SherpaOnnxProvider) parseSpeaker(speaker string) (int, bool) {
+	if sid, err := strconv.Atoi(speaker); err == nil && sid >= 0 {
+		return sid, true
+	}
+	sid, ok := KokoroTTSSpeakers[strings.ToLower(speaker)]
+	return sid, ok
+}
+
+func (p *SherpaOnnxProvider) generate(text string, sid int) []byte {
+	generateAudio := p.tts.Generate(text, sid, float32(math.Max(float64(p.speed), 1e-6)))
 	p.sampleRate = generateAudio.SampleRate
 	return utils.SamplesFloatToInt16(generateAudio.Samples)
 }
@@ -102,6 +222,14 @@ func (p *SherpaOnnxProvider) GetSampleInfo() (int, int, int) {
 	return p.sampleRate, consts.DefaultChannels, consts.DefaultSampleWidth
 }
 
+func (p *SherpaOnnxProvider) GetAudioInFormat() *types.AudioFormat {
+	return nil
+}
+
+func (p *SherpaOnnxProvider) GetAudioOutFormat() *types.AudioFormat {
+	return types.NewAudioFormat(p.GetSampleInfo())
+}
+
 func (p *SherpaOnnxProvider) SetPromptAudio(string, []byte) error {
 	return nil
 }
@@ -111,7 +239,18 @@ func (p *SherpaOnnxProvider) Reset() error {
 }
 
 func (p *SherpaOnnxProvider) Release() error {
-	sherpa.DeleteOfflineTts(p.tts)
+	if p.tts != nil {
+		sherpa.DeleteOfflineTts(p.tts)
+		p.tts = nil
+	}
+	return nil
+}
+
+// HealthCheck 合成器已释放时不可用, 池替换该实例
+func (p *SherpaOnnxProvider) HealthCheck() error {
+	if p.tts == nil {
+		return fmt.Errorf("%s tts is released", p.name)
+	}
 	return nil
 }
 
@@ -0,0 +1,316 @@
+package tts
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/weedge/pipeline-go/pkg/logger"
+
+	"achatbot/pkg/params"
+	"achatbot/pkg/types"
+	"achatbot/pkg/utils"
+)
+
+const ttsCacheFileExt = ".wav"
+
+// TTSCacheKey 缓存键: 提供者名称, 音色, 语速(音高, 音量, 语种)和归一化文本(合并空白)
+func TTSCacheKey(providerName string, options *types.TTSOptions, text string) string {
+	if options == nil {
+		options = &types.TTSOptions{}
+	}
+	raw := fmt.Sprintf("%s|%s|%.2f|%.2f|%.2f|%s|%s", providerName, options.Speaker, options.Speed,
+		options.Pitch, options.Volume, options.Language, NormalizeCacheText(text))
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:16])
+}
+
+// NormalizeCacheText 去掉首尾空白并合并连续空白, 空白差异不影响合成结果
+func NormalizeCacheText(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+type memoryCacheEntry struct {
+	key   string
+	audio []byte
+}
+
+type diskCacheEntry struct {
+	key  string
+	size int64
+}
+
+// TTSCache TTS 合成缓存, 内存 LRU + 磁盘 WAV 两级, 按字节数上限淘汰最久未使用的条目, 并发安全;
+// 磁盘命中的条目提升到内存, 重启后从磁盘目录恢复(按修改时间排序)
+type TTSCache struct {
+	args *params.TTSCacheArgs
+
+	mu          sync.Mutex
+	memoryList  *list.List // front is the most recently used
+	memoryIndex map[string]*list.Element
+	memoryBytes int
+	diskList    *list.List
+	diskIndex   map[string]*list.Element
+	diskBytes   int64
+	tmpSeq      atomic.Int64 // unique temp file names of concurrent writes
+
+	// stats
+	memoryHits      int64
+	diskHits        int64
+	misses          int64
+	memoryEvictions int64
+	diskEvictions   int64
+}
+
+// NewTTSCache 创建 TTS 缓存, 加载磁盘目录已有的缓存
+func NewTTSCache(args *params.TTSCacheArgs) (*TTSCache, error) {
+	if err := args.Validate(); err != nil {
+		return nil, err
+	}
+	c := &TTSCache{
+		args:        args,
+		memoryList:  list.New(),
+		memoryIndex: make(map[string]*list.Element),
+		diskList:    list.New(),
+		diskIndex:   make(map[string]*list.Element),
+	}
+	if args.DiskMaxBytes > 0 {
+		if err := c.loadDisk(); err != nil {
+			return nil, err
+		}
+	}
+	logger.Info("NewTTSCache Done", "args", args.String(), "disk_entries", c.diskList.Len())
+	return c, nil
+}
+
+// Cacheable 文本是否可缓存, 长句很少重复不缓存
+func (c *TTSCache) Cacheable(text string) bool {
+	text = NormalizeCacheText(text)
+	return text != "" && len([]rune(text)) <= c.args.MaxTextRunes
+}
+
+// Contains 是否已缓存, 不影响淘汰顺序和统计
+func (c *TTSCache) Contains(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, inMemory := c.memoryIndex[key]
+	_, onDisk := c.diskIndex[key]
+	return inMemory || onDisk
+}
+
+// Get 获取缓存的音频, 先查内存再查磁盘; 磁盘读取在锁外进行, 不阻塞其他会话的内存命中
+func (c *TTSCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	if elem, ok := c.memoryIndex[key]; ok {
+		c.memoryList.MoveToFront(elem)
+		c.memoryHits++
+		audio := bytes.Clone(elem.Value.(*memoryCacheEntry).audio)
+		c.mu.Unlock()
+		return audio, true
+	}
+	_, onDisk := c.diskIndex[key]
+	if !onDisk {
+		c.misses++
+		c.mu.Unlock()
+		return nil, false
+	}
+	c.mu.Unlock()
+
+	audio, err := utils.ReadAudioFile(c.diskPath(key))
+
+	c.mu.Lock()
+	elem, onDisk := c.diskIndex[key]
+	if err != nil {
+		// the file may be evicted by another session after the index lookup
+		var removed []string
+		if onDisk {
+			logger.Warnf("read tts cache %s err: %v", key, err)
+			removed = append(removed, c.unlinkDisk(elem))
+		}
+		c.misses++
+		c.mu.Unlock()
+		c.removeDiskFiles(removed)
+		return nil, false
+	}
+	if onDisk {
+		c.diskList.MoveToFront(elem)
+	}
+	c.diskHits++
+	c.putMemory(key, audio)
+	c.mu.Unlock()
+
+	now := time.Now()
+	_ = os.Chtimes(c.diskPath(key), now, now) // keep the recency after restart
+	return bytes.Clone(audio), true
+}
+
+// Put 缓存合成的音频, 采样信息用于写磁盘 WAV 头; 磁盘写入在锁外进行, 锁内只更新索引
+func (c *TTSCache) Put(key string, audio []byte, sampleRate, channels, sampleWidth int) {
+	if len(audio) == 0 {
+		return
+	}
+	audio = bytes.Clone(audio)
+
+	c.mu.Lock()
+	c.putMemory(key, audio)
+	_, onDisk := c.diskIndex[key]
+	if onDisk {
+		c.diskList.MoveToFront(c.diskIndex[key])
+	}
+	c.mu.Unlock()
+
+	if onDisk || int64(len(audio)) > c.args.DiskMaxBytes {
+		return
+	}
+	if !c.writeDisk(key, audio, sampleRate, channels, sampleWidth) {
+		return
+	}
+
+	c.mu.Lock()
+	var removed []string
+	if elem, ok := c.diskIndex[key]; ok {
+		// written by another session concurrently, the file is replaced with the same audio
+		c.diskList.MoveToFront(elem)
+	} else {
+		c.diskIndex[key] = c.diskList.PushFront(&diskCacheEntry{key: key, size: int64(len(audio))})
+		c.diskBytes += int64(len(audio))
+		for c.diskBytes > c.args.DiskMaxBytes {
+			removed = append(removed, c.unlinkDisk(c.diskList.Back()))
+			c.diskEvictions++
+		}
+	}
+	c.mu.Unlock()
+	c.removeDiskFiles(removed)
+}
+
+// GetStats 获取统计信息
+func (c *TTSCache) GetStats() map[string]any {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	hitRate := 0.0
+	if total := c.memoryHits + c.diskHits + c.misses; total > 0 {
+		hitRate = float64(c.memoryHits+c.diskHits) / float64(total)
+	}
+	return map[string]any{
+		"memory_entries":   c.memoryList.Len(),
+		"memory_bytes":     c.memoryBytes,
+		"disk_entries":     c.diskList.Len(),
+		"disk_bytes":       c.diskBytes,
+		"memory_hits":      c.memoryHits,
+		"disk_hits":        c.diskHits,
+		"misses":           c.misses,
+		"hit_rate":         hitRate,
+		"memory_evictions": c.memoryEvictions,
+		"disk_evictions":   c.diskEvictions,
+	}
+}
+
+func (c *TTSCache) putMemory(key string, audio []byte) {
+	if len(audio) > c.args.MemoryMaxBytes {
+		return
+	}
+	if elem, ok := c.memoryIndex[key]; ok {
+		entry := elem.Value.(*memoryCacheEntry)
+		c.memoryBytes += len(audio) - len(entry.audio)
+		entry.audio = audio
+		c.memoryList.MoveToFront(elem)
+	} else {
+		c.memoryIndex[key] = c.memoryList.PushFront(&memoryCacheEntry{key: key, audio: audio})
+		c.memoryBytes += len(audio)
+	}
+
+	for c.memoryBytes > c.args.MemoryMaxBytes {
+		elem := c.memoryList.Back()
+		entry := elem.Value.(*memoryCacheEntry)
+		c.memoryList.Remove(elem)
+		delete(c.memoryIndex, entry.key)
+		c.memoryBytes -= len(entry.audio)
+		c.memoryEvictions++
+	}
+}
+
+// writeDisk writes the temp file then renames, a reader never sees the partial file
+func (c *TTSCache) writeDisk(key string, audio []byte, sampleRate, channels, sampleWidth int) bool {
+	tmpName := fmt.Sprintf("%s%s.%d.tmp", key, ttsCacheFileExt, c.tmpSeq.Add(1))
+	tmpPath, err := utils.SaveAudioToFile(audio, tmpName, utils.WithAudioDir(c.args.DiskDir),
+		utils.WithSampleRate(sampleRate), utils.WithChannels(channels), utils.WithSampleWidth(sampleWidth))
+	if err != nil {
+		logger.Warnf("write tts cache %s err: %v", key, err)
+		return false
+	}
+	if err := os.Rename(tmpPath, c.diskPath(key)); err != nil {
+		logger.Warnf("write tts cache %s err: %v", key, err)
+		_ = os.Remove(tmpPath)
+		return false
+	}
+	return true
+}
+
+// unlinkDisk removes the entry from the disk index (with c.mu held), returns the file path to remove after unlock
+func (c *TTSCache) unlinkDisk(elem *list.Element) string {
+	entry := elem.Value.(*diskCacheEntry)
+	c.diskList.Remove(elem)
+	delete(c.diskIndex, entry.key)
+	c.diskBytes -= entry.size
+	return c.diskPath(entry.key)
+}
+
+func (c *TTSCache) removeDiskFiles(paths []string) {
+	for _, path := range paths {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			logger.Warnf("remove tts cache %s err: %v", path, err)
+		}
+	}
+}
+
+// loadDisk 加载磁盘缓存目录, 最近使用的在前, 超出上限的淘汰
+func (c *TTSCache) loadDisk() error {
+	if err := os.MkdirAll(c.args.DiskDir, 0755); err != nil {
+		return fmt.Errorf("failed to create tts cache dir %s: %w", c.args.DiskDir, err)
+	}
+	dirEntries, err := os.ReadDir(c.args.DiskDir)
+	if err != nil {
+		return fmt.Errorf("failed to read tts cache dir %s: %w", c.args.DiskDir, err)
+	}
+
+	files := make([]os.FileInfo, 0, len(dirEntries))
+	for _, dirEntry := range dirEntries {
+		if dirEntry.IsDir() || !strings.HasSuffix(dirEntry.Name(), ttsCacheFileExt) {
+			continue
+		}
+		info, err := dirEntry.Info()
+		if err != nil || info.Size() < 44 {
+			continue
+		}
+		files = append(files, info)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].ModTime().Before(files[j].ModTime()) })
+
+	for _, info := range files {
+		key := strings.TrimSuffix(info.Name(), ttsCacheFileExt)
+		size := info.Size() - 44 // WAV header
+		c.diskIndex[key] = c.diskList.PushFront(&diskCacheEntry{key: key, size: size})
+		c.diskBytes += size
+	}
+	var removed []string
+	for c.diskBytes > c.args.DiskMaxBytes {
+		removed = append(removed, c.unlinkDisk(c.diskList.Back()))
+		c.diskEvictions++
+	}
+	c.removeDiskFiles(removed)
+	return nil
+}
+
+func (c *TTSCache) diskPath(key string) string {
+	return filepath.Join(c.args.DiskDir, key+ttsCacheFileExt)
+}
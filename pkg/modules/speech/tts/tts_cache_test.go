@@ -0,0 +1,171 @@
+package tts
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"achatbot/pkg/common"
+	"achatbot/pkg/params"
+	"achatbot/pkg/types"
+)
+
+var (
+	_ common.ITTSProvider              = (*CachedTTSProvider)(nil)
+	_ common.IMultiLanguageTTSProvider = (*CachedTTSProvider)(nil)
+	_ common.ITTSOptionsProvider       = (*CachedTTSProvider)(nil)
+	_ common.ITTSSpeakerResolver       = (*SherpaOnnxProvider)(nil)
+	_ common.IPoolHealthChecker        = (*SherpaOnnxProvider)(nil)
+)
+
+// fakeTTSProvider 合成音频为文本字节, 记录合成次数
+type fakeTTSProvider struct {
+	calls int
+}
+
+func (p *fakeTTSProvider) Synthesize(text string) []byte {
+	p.calls++
+	return []byte(text)
+}
+func (p *fakeTTSProvider) SynthesizeWithOptions(text string, options *types.TTSOptions) []byte {
+	p.calls++
+	return []byte(options.Speaker + text)
+}
+func (p *fakeTTSProvider) Warmup()                             {}
+func (p *fakeTTSProvider) GetSampleInfo() (int, int, int)      { return 16000, 1, 2 }
+func (p *fakeTTSProvider) SetPromptAudio(string, []byte) error { return nil }
+func (p *fakeTTSProvider) Name() string                        { return "fake" }
+func (p *fakeTTSProvider) Reset() error                        { return nil }
+func (p *fakeTTSProvider) Release() error                      { return nil }
+
+// fakeResolverTTSProvider 默认音色为 am_adam
+type fakeResolverTTSProvider struct {
+	fakeTTSProvider
+}
+
+func (p *fakeResolverTTSProvider) ResolveSpeaker(options *types.TTSOptions) string {
+	if options == nil || options.Speaker == "" {
+		return "am_adam"
+	}
+	return options.Speaker
+}
+
+func TestTTSCacheKey(t *testing.T) {
+	assert.Equal(t, TTSCacheKey("fake", nil, " 你好,  世界 "), TTSCacheKey("fake", &types.TTSOptions{}, "你好, 世界"))
+	assert.NotEqual(t, TTSCacheKey("fake", nil, "hello"), TTSCacheKey("other", nil, "hello"))
+	assert.NotEqual(t, TTSCacheKey("fake", nil, "hello"), TTSCacheKey("fake", &types.TTSOptions{Speaker: "am_adam"}, "hello"))
+	assert.NotEqual(t, TTSCacheKey("fake", nil, "hello"), TTSCacheKey("fake", &types.TTSOptions{Speed: 1.2}, "hello"))
+}
+
+func TestTTSCacheMemoryAndDisk(t *testing.T) {
+	dir := t.TempDir()
+	args := params.NewTTSCacheArgs().WithMemoryMaxBytes(10).WithDiskMaxBytes(12).WithDiskDir(dir)
+	cache, err := NewTTSCache(args)
+	assert.NoError(t, err)
+
+	cache.Put("a", []byte("aaaaa"), 16000, 1, 2)
+	cache.Put("b", []byte("bbbbb"), 16000, 1, 2)
+	audio, ok := cache.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("aaaaa"), audio)
+
+	// memory evicts b (least recently used), disk evicts a (least recently written)
+	cache.Put("c", []byte("ccccc"), 16000, 1, 2)
+	stats := cache.GetStats()
+	assert.EqualValues(t, 1, stats["memory_evictions"])
+	assert.EqualValues(t, 1, stats["disk_evictions"])
+	audio, ok = cache.Get("b")
+	assert.True(t, ok, "disk hit")
+	assert.Equal(t, []byte("bbbbb"), audio)
+	_, ok = cache.Get("x")
+	assert.False(t, ok)
+
+	stats = cache.GetStats()
+	assert.EqualValues(t, 1, stats["memory_hits"])
+	assert.EqualValues(t, 1, stats["disk_hits"])
+	assert.EqualValues(t, 1, stats["misses"])
+
+	// restart loads the disk cache
+	cache, err = NewTTSCache(args)
+	assert.NoError(t, err)
+	audio, ok = cache.Get("c")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("ccccc"), audio)
+	assert.False(t, cache.Contains("a"))
+}
+
+func TestCachedTTSProvider(t *testing.T) {
+	cache, err := NewTTSCache(params.NewTTSCacheArgs().WithDiskMaxBytes(0).WithMaxTextRunes(10))
+	assert.NoError(t, err)
+	provider := &fakeTTSProvider{}
+	cachedProvider := NewCachedTTSProvider(provider, cache)
+
+	assert.Equal(t, []byte("hello"), cachedProvider.Synthesize("hello"))
+	assert.Equal(t, []byte("hello"), cachedProvider.Synthesize(" hello "))
+	assert.Equal(t, 1, provider.calls)
+
+	// different speaker is another entry
+	options := &types.TTSOptions{Speaker: "am_adam"}
+	assert.Equal(t, []byte("am_adamhello"), cachedProvider.SynthesizeWithOptions("hello", options))
+	assert.Equal(t, 2, provider.calls)
+
+	// long text is not cached
+	cachedProvider.Synthesize("a long sentence")
+	cachedProvider.Synthesize("a long sentence")
+	assert.Equal(t, 4, provider.calls)
+
+	// prewarm skips the cached phrases
+	assert.Equal(t, 1, cachedProvider.Prewarm([]string{"hello", "hi"}, options))
+	cachedProvider.SynthesizeWithOptions("hi", options)
+	assert.Equal(t, 5, provider.calls)
+}
+
+func TestCachedTTSProviderResolvedSpeaker(t *testing.T) {
+	cache, err := NewTTSCache(params.NewTTSCacheArgs().WithDiskMaxBytes(0))
+	assert.NoError(t, err)
+	provider := &fakeResolverTTSProvider{}
+	cachedProvider := NewCachedTTSProvider(provider, cache)
+
+	// the default voice and the same voice by name share the entry
+	cachedProvider.SynthesizeWithOptions("hello", &types.TTSOptions{})
+	cachedProvider.SynthesizeWithOptions("hello", &types.TTSOptions{Speaker: "am_adam"})
+	assert.Equal(t, 1, provider.calls)
+	cachedProvider.SynthesizeWithOptions("hello", &types.TTSOptions{Speaker: "zf_xiaobei"})
+	assert.Equal(t, 2, provider.calls)
+}
+
+func TestTTSCacheConcurrent(t *testing.T) {
+	args := params.NewTTSCacheArgs().WithMemoryMaxBytes(16).WithDiskMaxBytes(32).WithDiskDir(t.TempDir())
+	cache, err := NewTTSCache(args)
+	assert.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				key := fmt.Sprintf("k%d", (i+j)%6)
+				if audio, ok := cache.Get(key); ok {
+					assert.Equal(t, []byte(key+"-audio"), audio)
+				} else {
+					cache.Put(key, []byte(key+"-audio"), 16000, 1, 2)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	stats := cache.GetStats()
+	assert.LessOrEqual(t, stats["memory_bytes"].(int), args.MemoryMaxBytes)
+	assert.LessOrEqual(t, stats["disk_bytes"].(int64), args.DiskMaxBytes)
+	entries, err := os.ReadDir(args.DiskDir)
+	assert.NoError(t, err)
+	for _, entry := range entries {
+		assert.True(t, strings.HasSuffix(entry.Name(), ttsCacheFileExt), "no leaked temp file %s", entry.Name())
+	}
+}
@@ -0,0 +1,120 @@
+package turn_analyzer
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	// 英文句尾出现说明话没说完的连词/介词/冠词
+	enTrailingWords = map[string]bool{
+		"and": true, "but": true, "or": true, "so": true, "because": true, "cause": true,
+		"then": true, "if": true, "when": true, "while": true, "although": true, "though": true,
+		"unless": true, "since": true, "that": true, "which": true, "who": true,
+		"with": true, "to": true, "of": true, "for": true, "in": true, "on": true, "at": true, "about": true,
+		"the": true, "a": true, "an": true, "my": true, "your": true, "is": true, "are": true,
+	}
+	// 英文填充词
+	enFillers = map[string]bool{
+		"um": true, "umm": true, "uh": true, "uhm": true, "er": true, "erm": true,
+		"hmm": true, "mm": true, "ah": true, "like": true, "well": true,
+	}
+	enFillerPhrases = []string{"you know", "i mean", "kind of", "sort of"}
+
+	// 中文句尾出现说明话没说完的连词
+	zhTrailingWords = []string{
+		"然后", "但是", "可是", "不过", "因为", "所以", "而且", "还有", "或者", "如果", "那么",
+		"并且", "以及", "的话", "还是", "比如", "另外", "接着", "于是", "虽然", "就是说", "和", "跟", "与", "或",
+	}
+	// 中文填充词
+	zhFillers = []string{"嗯", "呃", "额", "那个", "这个", "就是", "怎么说", "然后呢"}
+	// 中文句末语气词
+	zhFinalParticles = []string{"吗", "呢", "吧", "了", "啦", "呀", "嘛", "哦"}
+)
+
+// TranscriptEndOfTurnProbability 根据转录文本的结尾特征(标点, 连词, 填充词, 语气词)估计用户说完的概率(0~1)
+func TranscriptEndOfTurnProbability(text string) (float64, string) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return 0, "empty transcript"
+	}
+
+	if strings.HasSuffix(text, "...") || strings.HasSuffix(text, "…") {
+		return 0.25, "trailing ellipsis"
+	}
+	last, _ := utf8.DecodeLastRuneInString(text)
+	switch {
+	case strings.ContainsRune("?？!！。", last):
+		return 0.95, "sentence-final punctuation"
+	case last == '.':
+		return 0.85, "sentence-final punctuation"
+	case strings.ContainsRune(",，、;；:：-—", last):
+		return 0.2, "continuation punctuation"
+	}
+
+	core := strings.TrimRightFunc(text, func(r rune) bool {
+		return unicode.IsPunct(r) || unicode.IsSpace(r)
+	})
+	if core == "" {
+		return 0.5, "punctuation only"
+	}
+	lower := strings.ToLower(core)
+	if isFillerOnly(lower) {
+		return 0.3, "filler only"
+	}
+
+	coreLast, _ := utf8.DecodeLastRuneInString(core)
+	if unicode.Is(unicode.Han, coreLast) {
+		for _, w := range zhTrailingWords {
+			if strings.HasSuffix(core, w) {
+				return 0.15, "trailing conjunction: " + w
+			}
+		}
+		for _, w := range zhFillers {
+			if strings.HasSuffix(core, w) {
+				return 0.25, "trailing filler: " + w
+			}
+		}
+		for _, w := range zhFinalParticles {
+			if strings.HasSuffix(core, w) {
+				return 0.85, "sentence-final particle: " + w
+			}
+		}
+		return 0.6, "no end marker"
+	}
+
+	words := strings.Fields(lower)
+	lastWord := strings.TrimFunc(words[len(words)-1], unicode.IsPunct)
+	if enTrailingWords[lastWord] {
+		return 0.15, "trailing conjunction: " + lastWord
+	}
+	if enFillers[lastWord] {
+		return 0.25, "trailing filler: " + lastWord
+	}
+	for _, phrase := range enFillerPhrases {
+		if strings.HasSuffix(lower, phrase) {
+			return 0.25, "trailing filler: " + phrase
+		}
+	}
+	return 0.6, "no end marker"
+}
+
+// isFillerOnly 文本是否只有填充词
+func isFillerOnly(lower string) bool {
+	rest := lower
+	for _, w := range zhFillers {
+		rest = strings.ReplaceAll(rest, w, " ")
+	}
+	for _, phrase := range enFillerPhrases {
+		rest = strings.ReplaceAll(rest, phrase, " ")
+	}
+	for _, word := range strings.FieldsFunc(rest, func(r rune) bool {
+		return unicode.IsPunct(r) || unicode.IsSpace(r)
+	}) {
+		if !enFillers[word] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,95 @@
+package turn_analyzer
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/weedge/pipeline-go/pkg/logger"
+
+	"achatbot/pkg/common"
+	"achatbot/pkg/params"
+	"achatbot/pkg/types"
+)
+
+// TurnAnalyzer 轮次分析器, 融合静音时长, 转录文本启发式规则和可选分类模型判断用户是否说完
+type TurnAnalyzer struct {
+	args       *params.TurnAnalyzerArgs
+	classifier common.ITurnClassifier
+
+	mu       sync.Mutex
+	pauseEMA float64 // 用户轮内停顿时长 EMA(秒)
+}
+
+// NewTurnAnalyzer 创建新的轮次分析器, classifier 可为 nil
+func NewTurnAnalyzer(args *params.TurnAnalyzerArgs, classifier common.ITurnClassifier) *TurnAnalyzer {
+	return &TurnAnalyzer{
+		args:       args,
+		classifier: classifier,
+	}
+}
+
+// AnalyzeEndOfTurn 判断是否轮次结束
+func (a *TurnAnalyzer) AnalyzeEndOfTurn(transcript string, audio []byte, silenceSecs float64) *types.EndOfTurnResult {
+	prob, reason := TranscriptEndOfTurnProbability(transcript)
+	if a.classifier != nil && transcript != "" {
+		classifierProb, err := a.classifier.PredictEndOfTurn(transcript, audio)
+		if err != nil {
+			logger.Warnf("TurnAnalyzer classifier %s predict error: %v", a.classifier.Name(), err)
+		} else {
+			prob = (1-a.args.ClassifierWeight)*prob + a.args.ClassifierWeight*classifierProb
+			reason = fmt.Sprintf("%s, %s: %.2f", reason, a.classifier.Name(), classifierProb)
+		}
+	}
+
+	result := &types.EndOfTurnResult{
+		State:       types.TurnIncomplete,
+		Probability: prob,
+		WaitSecs:    a.waitSecs(prob),
+		Reason:      reason,
+	}
+	if transcript == "" {
+		return result
+	}
+	if prob >= a.args.CompleteThreshold {
+		result.State = types.TurnComplete
+	} else if silenceSecs >= result.WaitSecs {
+		result.State = types.TurnComplete
+		result.Reason += ", silence timeout"
+	}
+	return result
+}
+
+// waitSecs 按说完概率在 [MinWaitSecs, MaxWaitSecs] 间插值, 并按用户停顿习惯自适应
+func (a *TurnAnalyzer) waitSecs(prob float64) float64 {
+	wait := a.args.MinWaitSecs + (1-prob)*(a.args.MaxWaitSecs-a.args.MinWaitSecs)
+
+	a.mu.Lock()
+	pauseEMA := a.pauseEMA
+	a.mu.Unlock()
+	if a.args.AdaptivePause && pauseEMA > 0 {
+		wait = max(wait, pauseEMA*a.args.PauseFactor)
+	}
+	return min(max(wait, a.args.MinWaitSecs), a.args.MaxWaitSecs)
+}
+
+// ObservePause 记录用户轮内停顿时长
+func (a *TurnAnalyzer) ObservePause(pauseSecs float64) {
+	if pauseSecs <= 0 {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.pauseEMA == 0 {
+		a.pauseEMA = pauseSecs
+		return
+	}
+	a.pauseEMA = a.args.PauseEMAAlpha*pauseSecs + (1-a.args.PauseEMAAlpha)*a.pauseEMA
+}
+
+// Reset 重置状态
+func (a *TurnAnalyzer) Reset() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.pauseEMA = 0
+	return nil
+}
@@ -0,0 +1,84 @@
+package turn_analyzer
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"achatbot/pkg/params"
+	"achatbot/pkg/types"
+)
+
+func TestTranscriptEndOfTurnProbability(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		complete bool
+	}{
+		{name: "Empty", text: "  ", complete: false},
+		{name: "English question", text: "What is the weather today?", complete: true},
+		{name: "English period", text: "I want to book a flight.", complete: true},
+		{name: "English trailing conjunction", text: "I want to book a flight and", complete: false},
+		{name: "English trailing filler", text: "I think um", complete: false},
+		{name: "English filler phrase", text: "it's kind of, you know", complete: false},
+		{name: "English comma", text: "first of all,", complete: false},
+		{name: "Ellipsis", text: "let me think...", complete: false},
+		{name: "Filler only", text: "uh um", complete: false},
+		{name: "Chinese full stop", text: "今天天气怎么样。", complete: true},
+		{name: "Chinese question particle", text: "你能帮我订机票吗", complete: true},
+		{name: "Chinese trailing conjunction", text: "我想订一张机票然后", complete: false},
+		{name: "Chinese trailing filler", text: "我觉得那个", complete: false},
+		{name: "Chinese comma", text: "首先，", complete: false},
+		{name: "Chinese filler only", text: "嗯，呃", complete: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prob, reason := TranscriptEndOfTurnProbability(tt.text)
+			if tt.complete {
+				assert.GreaterOrEqual(t, prob, 0.8, reason)
+			} else {
+				assert.Less(t, prob, 0.5, reason)
+			}
+		})
+	}
+}
+
+type mockTurnClassifier struct {
+	prob float64
+	err  error
+}
+
+func (m *mockTurnClassifier) PredictEndOfTurn(string, []byte) (float64, error) { return m.prob, m.err }
+func (m *mockTurnClassifier) Name() string                                     { return "mock" }
+
+func TestTurnAnalyzer(t *testing.T) {
+	args := params.NewTurnAnalyzerArgs()
+	analyzer := NewTurnAnalyzer(args, nil)
+
+	// 完整句子直接结束
+	result := analyzer.AnalyzeEndOfTurn("What time is it?", nil, 0)
+	assert.Equal(t, types.TurnComplete, result.State)
+
+	// 未说完: 等待时长接近最长等待, 静音超时后结束
+	result = analyzer.AnalyzeEndOfTurn("I want to go to", nil, 0)
+	assert.Equal(t, types.TurnIncomplete, result.State)
+	assert.Greater(t, result.WaitSecs, args.MinWaitSecs)
+	assert.LessOrEqual(t, result.WaitSecs, args.MaxWaitSecs)
+	result = analyzer.AnalyzeEndOfTurn("I want to go to", nil, args.MaxWaitSecs)
+	assert.Equal(t, types.TurnComplete, result.State)
+
+	// 用户停顿习惯较长时自适应拉长等待时长
+	noMarker := analyzer.AnalyzeEndOfTurn("I want to go home", nil, 0).WaitSecs
+	analyzer.ObservePause(1.5)
+	assert.Greater(t, analyzer.AnalyzeEndOfTurn("I want to go home", nil, 0).WaitSecs, noMarker)
+	assert.NoError(t, analyzer.Reset())
+	assert.Equal(t, noMarker, analyzer.AnalyzeEndOfTurn("I want to go home", nil, 0).WaitSecs)
+
+	// 分类模型融合
+	classified := NewTurnAnalyzer(args, &mockTurnClassifier{prob: 1})
+	assert.Equal(t, types.TurnComplete, classified.AnalyzeEndOfTurn("I want to go home", nil, 0).State)
+	failed := NewTurnAnalyzer(args, &mockTurnClassifier{err: errors.New("boom")})
+	assert.Equal(t, types.TurnIncomplete, failed.AnalyzeEndOfTurn("I want to go home", nil, 0).State)
+}
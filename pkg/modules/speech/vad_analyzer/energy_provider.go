@@ -0,0 +1,181 @@
+package vad_analyzer
+
+import (
+	"math"
+	"math/cmplx"
+
+	"achatbot/pkg/consts"
+	"achatbot/pkg/params"
+	"achatbot/pkg/types"
+	"achatbot/pkg/utils"
+)
+
+const EnergyProviderName = "EnergyVad"
+
+// EnergyProvider 无模型的语音置信度提供者, 基于 RMS 能量, 过零率和频谱平坦度,
+// 自适应跟踪噪声底; 纯 Go 实现, 无需模型文件和 cgo, 可替换 SherpaOnnxProvider
+type EnergyProvider struct {
+	args *params.EnergyVADArgs
+
+	fftBuf        []complex128
+	noiseFloorDB  float64
+	initialized   bool
+	hangoverCount int
+}
+
+// NewEnergyProvider 创建能量语音置信度提供者
+func NewEnergyProvider(args *params.EnergyVADArgs) *EnergyProvider {
+	return &EnergyProvider{
+		args:   args,
+		fftBuf: make([]complex128, utils.NextPowerOfTwo(args.WindowSize)),
+	}
+}
+
+// IsActiveSpeech 判断当前音频窗口是否是活跃的语音
+func (p *EnergyProvider) IsActiveSpeech(audio []byte) bool {
+	return p.GetVoiceConfidence(audio) >= 0.5
+}
+
+// GetVoiceConfidence 返回当前音频窗口的语音置信度:
+// 信噪比经 sigmoid 映射, 信噪比等于 SpeechSNRDB 时为 0.5;
+// 低于绝对能量下限或判为宽带噪声时压低到 0.5 以下, hangover 帧内不低于 0.5
+func (p *EnergyProvider) GetVoiceConfidence(audio []byte) float32 {
+	samples := utils.SamplesInt16ToFloat(audio)
+	if len(samples) == 0 {
+		return 0
+	}
+
+	energyDB := p.energyDB(samples)
+	if !p.initialized {
+		p.noiseFloorDB = max(energyDB, p.args.MinEnergyDB)
+		p.initialized = true
+	}
+
+	snrDB := energyDB - p.noiseFloorDB
+	confidence := 1 / (1 + math.Exp(-(snrDB-p.args.SpeechSNRDB)/3))
+
+	// 能量足够高于噪声底, 且不是宽带噪声(过零率高且频谱平坦)
+	speech := energyDB >= p.args.MinEnergyDB && snrDB >= p.args.SpeechSNRDB
+	if energyDB < p.args.MinEnergyDB {
+		confidence *= 0.3
+	} else if speech && zeroCrossingRate(samples) > p.args.MaxZCR && p.spectralFlatness(samples) > p.args.MaxSpectralFlatness {
+		speech = false
+		confidence *= 0.3
+	}
+
+	p.trackNoiseFloor(energyDB, speech)
+
+	if speech {
+		p.hangoverCount = p.args.HangoverFrames
+		return float32(confidence)
+	}
+	if p.hangoverCount > 0 {
+		p.hangoverCount--
+		return float32(max(confidence, 0.5))
+	}
+	return float32(confidence)
+}
+
+// trackNoiseFloor 非语音帧向当前能量平滑, 低于噪声底时快速下降, 语音帧缓慢上升以适应环境变化
+func (p *EnergyProvider) trackNoiseFloor(energyDB float64, speech bool) {
+	switch {
+	case energyDB < p.noiseFloorDB:
+		p.noiseFloorDB = 0.5*p.noiseFloorDB + 0.5*energyDB
+	case !speech:
+		p.noiseFloorDB += p.args.NoiseFloorAlpha * (energyDB - p.noiseFloorDB)
+	default:
+		p.noiseFloorDB += p.args.NoiseFloorRiseDB
+	}
+	p.noiseFloorDB = max(p.noiseFloorDB, p.args.MinEnergyDB-20)
+}
+
+func (p *EnergyProvider) energyDB(samples []float32) float64 {
+	var sum float64
+	for _, s := range samples {
+		sum += float64(s) * float64(s)
+	}
+	return 10 * math.Log10(sum/float64(len(samples))+1e-12)
+}
+
+// zeroCrossingRate 每样本过零次数
+func zeroCrossingRate(samples []float32) float64 {
+	if len(samples) < 2 {
+		return 0
+	}
+	crossings := 0
+	for i := 1; i < len(samples); i++ {
+		if (samples[i-1] >= 0) != (samples[i] >= 0) {
+			crossings++
+		}
+	}
+	return float64(crossings) / float64(len(samples)-1)
+}
+
+// spectralFlatness 语音频带(100~4000Hz)功率谱几何均值/算术均值
+func (p *EnergyProvider) spectralFlatness(samples []float32) float64 {
+	n := len(p.fftBuf)
+	for i := range p.fftBuf {
+		if i < len(samples) {
+			p.fftBuf[i] = complex(float64(samples[i]), 0)
+		} else {
+			p.fftBuf[i] = 0
+		}
+	}
+	utils.FFT(p.fftBuf, false)
+
+	binHz := float64(p.args.SampleRate) / float64(n)
+	lo := max(1, int(100/binHz))
+	hi := min(n/2, int(4000/binHz))
+	if hi <= lo {
+		return 1
+	}
+	var logSum, sum float64
+	for k := lo; k <= hi; k++ {
+		power := math.Pow(cmplx.Abs(p.fftBuf[k]), 2) + 1e-12
+		logSum += math.Log(power)
+		sum += power
+	}
+	count := float64(hi - lo + 1)
+	return math.Exp(logSum/count) / (sum / count)
+}
+
+// NoiseFloorDB 当前噪声底(dBFS)
+func (p *EnergyProvider) NoiseFloorDB() float64 {
+	return p.noiseFloorDB
+}
+
+func (p *EnergyProvider) Warmup() {
+}
+
+func (p *EnergyProvider) Name() string {
+	return EnergyProviderName
+}
+
+// Reset 重置全部自适应状态(噪声底重新学习), 如池化实例用于新的会话
+func (p *EnergyProvider) Reset() error {
+	p.noiseFloorDB = 0
+	p.initialized = false
+	p.hangoverCount = 0
+	return nil
+}
+
+// ResetSegment 重置单段语音状态, 保留学到的噪声底
+func (p *EnergyProvider) ResetSegment() {
+	p.hangoverCount = 0
+}
+
+func (p *EnergyProvider) Release() error {
+	return nil
+}
+
+func (p *EnergyProvider) GetSampleInfo() (int, int) {
+	return p.args.SampleRate, p.args.WindowSize
+}
+
+func (p *EnergyProvider) GetAudioInFormat() *types.AudioFormat {
+	return types.NewAudioFormat(p.args.SampleRate, consts.DefaultChannels, consts.DefaultSampleWidth)
+}
+
+func (p *EnergyProvider) GetAudioOutFormat() *types.AudioFormat {
+	return nil
+}
@@ -0,0 +1,111 @@
+package vad_analyzer
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"achatbot/pkg/common"
+	"achatbot/pkg/params"
+	"achatbot/pkg/types"
+	"achatbot/pkg/utils"
+)
+
+var _ common.IVoiceConfidenceProvider = (*EnergyProvider)(nil)
+
+func noiseWindow(rng *rand.Rand, n int, amp float64) []byte {
+	samples := make([]float32, n)
+	for i := range samples {
+		samples[i] = float32((rng.Float64()*2 - 1) * amp)
+	}
+	return utils.SamplesFloatToInt16(samples)
+}
+
+// voiceWindow 模拟浊音: 基频 200Hz 带谐波, 叠加底噪
+func voiceWindow(rng *rand.Rand, n int, amp float64) []byte {
+	samples := make([]float32, n)
+	for i := range samples {
+		t := float64(i) / 16000
+		v := math.Sin(2*math.Pi*200*t) + 0.5*math.Sin(2*math.Pi*400*t) + 0.25*math.Sin(2*math.Pi*600*t)
+		samples[i] = float32(amp*v/1.75 + (rng.Float64()*2-1)*0.002)
+	}
+	return utils.SamplesFloatToInt16(samples)
+}
+
+func TestEnergyProvider(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	args := params.NewEnergyVADArgs()
+	assert.NoError(t, args.Validate())
+	p := NewEnergyProvider(args)
+
+	sr, windowSize := p.GetSampleInfo()
+	assert.Equal(t, 16000, sr)
+	assert.Equal(t, 512, windowSize)
+
+	// 底噪学习
+	for range 20 {
+		assert.False(t, p.IsActiveSpeech(noiseWindow(rng, windowSize, 0.002)))
+	}
+	// 语音
+	assert.True(t, p.IsActiveSpeech(voiceWindow(rng, windowSize, 0.2)))
+	// 语音结束后 hangover 帧内仍判为语音, 之后恢复静音
+	for range args.HangoverFrames {
+		assert.True(t, p.IsActiveSpeech(noiseWindow(rng, windowSize, 0.002)))
+	}
+	assert.False(t, p.IsActiveSpeech(noiseWindow(rng, windowSize, 0.002)))
+
+	// 持续的宽带噪声(过零率高, 频谱平坦)不判为语音
+	assert.False(t, p.IsActiveSpeech(noiseWindow(rng, windowSize, 0.2)))
+
+	assert.NoError(t, p.Reset())
+	assert.NoError(t, p.Release())
+}
+
+func TestEnergyProviderWithVADAnalyzer(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	p := NewEnergyProvider(params.NewEnergyVADArgs())
+	analyzer := NewVADAnalyzer(params.NewVADAnalyzerArgs(), p)
+	windowSize := analyzer.GetWindowSize()
+
+	for range 10 {
+		frame := analyzer.AnalyzeAudio(noiseWindow(rng, windowSize, 0.002))
+		assert.Equal(t, types.Quiet, frame.State)
+	}
+	frame := analyzer.AnalyzeAudio(voiceWindow(rng, windowSize, 0.3))
+	assert.Equal(t, types.Speaking, frame.State)
+
+	// 静音持续 StopSecs 后回到 Quiet
+	for range 20 {
+		frame = analyzer.AnalyzeAudio(noiseWindow(rng, windowSize, 0.002))
+	}
+	assert.Equal(t, types.Quiet, frame.State)
+	assert.True(t, frame.IsFinal)
+}
+
+func TestEnergyProviderReset(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	args := params.NewEnergyVADArgs()
+	p := NewEnergyProvider(args)
+	var _ common.IVoiceConfidenceSegmentResetter = p
+	_, windowSize := p.GetSampleInfo()
+
+	// 嘈杂环境学到较高的噪声底
+	for range 50 {
+		p.IsActiveSpeech(noiseWindow(rng, windowSize, 0.05))
+	}
+	noisyFloor := p.NoiseFloorDB()
+
+	// 单段重置保留噪声底
+	p.ResetSegment()
+	assert.Equal(t, noisyFloor, p.NoiseFloorDB())
+
+	// 池化实例用于新会话: 重置后在安静环境重新学习噪声底
+	assert.NoError(t, p.Reset())
+	assert.False(t, p.IsActiveSpeech(noiseWindow(rng, windowSize, 0.002)))
+	// 第一帧即按安静环境初始化噪声底(未重置时只能从嘈杂噪声底逐步下降)
+	assert.LessOrEqual(t, p.NoiseFloorDB(), args.MinEnergyDB)
+	assert.Less(t, p.NoiseFloorDB(), noisyFloor-20)
+	assert.True(t, p.IsActiveSpeech(voiceWindow(rng, windowSize, 0.05)))
+}
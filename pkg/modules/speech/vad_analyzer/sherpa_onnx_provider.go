@@ -1,12 +1,14 @@
 package vad_analyzer
 
 import (
+	"fmt"
 	"path/filepath"
 
 	sherpa "github.com/k2-fsa/sherpa-onnx-go/sherpa_onnx"
 	"github.com/weedge/pipeline-go/pkg/logger"
 
 	"achatbot/pkg/consts"
+	"achatbot/pkg/types"
 	"achatbot/pkg/utils"
 )
 
@@ -61,6 +63,13 @@ func NewDefaultSherpaOnnxVadModelConfig(name string) sherpa.VadModelConfig {
 	return conf
 }
 
+// WithSherpaOnnxVadThreshold 设置 Silero/Ten VAD 模型内部的语音概率阈值(默认 0.5)
+func WithSherpaOnnxVadThreshold(conf sherpa.VadModelConfig, threshold float32) sherpa.VadModelConfig {
+	conf.SileroVad.Threshold = threshold
+	conf.TenVad.Threshold = threshold
+	return conf
+}
+
 // https://github.com/k2-fsa/sherpa-onnx/blob/v1.12.14/sherpa-onnx/csrc/vad-model-config.h
 // https://github.com/k2-fsa/sherpa-onnx/blob/v1.12.14/sherpa-onnx/csrc/voice-activity-detector.cc
 func NewSherpaOnnxProvider(config sherpa.VadModelConfig, bufferSizeInSeconds float32) *SherpaOnnxProvider {
@@ -111,6 +120,20 @@ func (s *SherpaOnnxProvider) IsActiveSpeech(audio []byte) bool {
 	return s.vad.IsSpeech()
 }
 
+// GetVoiceConfidence sherpa-onnx go 接口只暴露模型阈值判定后的结果, 置信度为 0 或 1,
+// 阈值通过 WithSherpaOnnxVadThreshold 调整
+func (s *SherpaOnnxProvider) GetVoiceConfidence(audio []byte) float32 {
+	if s.IsActiveSpeech(audio) {
+		return 1
+	}
+	return 0
+}
+
+// IsBinaryConfidence 置信度只有 0 或 1
+func (s *SherpaOnnxProvider) IsBinaryConfidence() bool {
+	return true
+}
+
 func (s *SherpaOnnxProvider) Warmup() {
 }
 
@@ -119,15 +142,37 @@ func (s *SherpaOnnxProvider) Name() string {
 }
 
 func (s *SherpaOnnxProvider) Reset() error {
+	if err := s.HealthCheck(); err != nil {
+		return err
+	}
 	s.vad.Reset()
 	return nil
 }
 
 func (s *SherpaOnnxProvider) Release() error {
-	sherpa.DeleteVoiceActivityDetector(s.vad)
+	if s.vad != nil {
+		sherpa.DeleteVoiceActivityDetector(s.vad)
+		s.vad = nil
+	}
+	return nil
+}
+
+// HealthCheck 检测器未创建或已释放时不可用, 池替换该实例
+func (s *SherpaOnnxProvider) HealthCheck() error {
+	if s.vad == nil {
+		return fmt.Errorf("%s voice activity detector is released", s.name)
+	}
 	return nil
 }
 
 func (s *SherpaOnnxProvider) GetSampleInfo() (int, int) {
 	return s.config.SampleRate, s.windowSize
 }
+
+func (s *SherpaOnnxProvider) GetAudioInFormat() *types.AudioFormat {
+	return types.NewAudioFormat(s.config.SampleRate, consts.DefaultChannels, consts.DefaultSampleWidth)
+}
+
+func (s *SherpaOnnxProvider) GetAudioOutFormat() *types.AudioFormat {
+	return nil
+}
@@ -30,6 +30,28 @@ type VADAnalyzer struct {
 	curAtS                   float64
 	endAtS                   float64
 
+	// 语音段填充与时长限制, 单位为 VAD 窗口数
+	preRollNumBytes      int
+	vadPostRollFrames    int
+	vadMinSpeechFrames   int
+	vadMaxSpeechFrames   int
+	preRollBuffer        []byte // Quiet 时最近的音频, 用于语音段前填充
+	startingBuffer       []byte // Starting 阶段的候选语音音频
+	startingAtBytes      int    // 候选语音开始位置(累计字节)
+	startingSpeechFrames int    // Starting 阶段累计的语音窗口数
+	startingSilentFrames int    // Starting 阶段连续的静音窗口数
+	startConfirmed       bool   // Starting 阶段是否已满足 StartSecs 连续语音
+	segmentFrames        int    // 当前语音段已输出的窗口数
+	continueSpeech       bool   // 强制切分后, 下一个语音窗口直接进入 Speaking
+
+	// 滞回阈值(可能经自适应校准提高)与校准统计
+	startThreshold     float32
+	stopThreshold      float32
+	calibrationFrames  int
+	calibrationCount   int
+	calibrationSum     float64
+	calibrationSquares float64
+
 	// 语音置信度提供者
 	IVoiceConfidenceProvider common.IVoiceConfidenceProvider
 }
@@ -49,6 +71,22 @@ func NewVADAnalyzer(args *params.VADAnalyzerArgs, vcp common.IVoiceConfidencePro
 	vadFramesPerSec := float64(analyzer.vadFrames) / float64(analyzer.args.SampleRate)
 	analyzer.vadStartFrames = int(math.Round(analyzer.args.StartSecs / vadFramesPerSec))
 	analyzer.vadStopFrames = int(math.Round(analyzer.args.StopSecs / vadFramesPerSec))
+	analyzer.vadPostRollFrames = int(math.Round(analyzer.args.PostRollSecs / vadFramesPerSec))
+	analyzer.vadMinSpeechFrames = int(math.Round(analyzer.args.MinSpeechSecs / vadFramesPerSec))
+	analyzer.vadMaxSpeechFrames = int(math.Round(analyzer.args.MaxSpeechSecs / vadFramesPerSec))
+	analyzer.preRollNumBytes = int(math.Round(analyzer.args.PreRollSecs/vadFramesPerSec)) * analyzer.vadFramesNumBytes
+	analyzer.calibrationFrames = int(math.Round(analyzer.args.CalibrationSecs / vadFramesPerSec))
+	// 二值置信度(0/1)无法按阈值区分噪声水平, 跳过校准和滞回
+	if binary, ok := vcp.(common.IBinaryVoiceConfidence); ok && binary.IsBinaryConfidence() {
+		if analyzer.calibrationFrames > 0 {
+			logger.Warnf("VADAnalyzer: %s confidence is binary, skip calibration", vcp.Name())
+			analyzer.calibrationFrames = 0
+		}
+		if args.StartThreshold != args.StopThreshold {
+			logger.Infof("VADAnalyzer: %s confidence is binary, hysteresis thresholds %.2f/%.2f have no effect",
+				vcp.Name(), args.StartThreshold, args.StopThreshold)
+		}
+	}
 
 	analyzer.Reset()
 	return analyzer
@@ -65,10 +103,61 @@ func (b *VADAnalyzer) Reset() error {
 	b.startAtS = 0.0
 	b.curAtS = 0.0
 	b.endAtS = 0.0
+	b.preRollBuffer = b.preRollBuffer[:0]
+	b.resetStarting()
+	b.segmentFrames = 0
+	b.continueSpeech = false
+	b.startThreshold = b.args.StartThreshold
+	b.stopThreshold = b.args.StopThreshold
+	b.calibrationCount = 0
+	b.calibrationSum = 0
+	b.calibrationSquares = 0
 
 	return b.IVoiceConfidenceProvider.Reset()
 }
 
+// resetStarting 清空 Starting 阶段的候选语音状态
+func (b *VADAnalyzer) resetStarting() {
+	b.vadStartingCount = 0
+	b.startingBuffer = b.startingBuffer[:0]
+	b.startingAtBytes = 0
+	b.startingSpeechFrames = 0
+	b.startingSilentFrames = 0
+	b.startConfirmed = false
+}
+
+// appendPreRoll 追加音频到前填充缓冲, 只保留最近 PreRollSecs 的音频
+func (b *VADAnalyzer) appendPreRoll(audio []byte) {
+	if b.preRollNumBytes <= 0 {
+		return
+	}
+	b.preRollBuffer = append(b.preRollBuffer, audio...)
+	if over := len(b.preRollBuffer) - b.preRollNumBytes; over > 0 {
+		b.preRollBuffer = append(b.preRollBuffer[:0], b.preRollBuffer[over:]...)
+	}
+}
+
+// abortStarting 候选语音不成立(过短或被静音打断), 回到 Quiet, 候选音频作为后续的前填充
+func (b *VADAnalyzer) abortStarting() {
+	if b.vadMinSpeechFrames > 0 && b.startingSpeechFrames > 0 {
+		logger.Debugf("VADAnalyzer: discard short speech %d frames < min %d frames",
+			b.startingSpeechFrames, b.vadMinSpeechFrames)
+	}
+	b.appendPreRoll(b.startingBuffer)
+	b.resetStarting()
+	b.vadState = types.Quiet
+	if resetter, ok := b.IVoiceConfidenceProvider.(common.IVoiceConfidenceSegmentResetter); ok {
+		resetter.ResetSegment()
+	} else {
+		b.IVoiceConfidenceProvider.Reset()
+	}
+}
+
+// secs 累计字节位置转换为秒
+func (b *VADAnalyzer) secs(numBytes int) float64 {
+	return math.Round(float64(numBytes)/float64(b.sampleNumBytes)*1000) / 1000
+}
+
 func (b *VADAnalyzer) GetSampleRate() int {
 	sr, _ := b.IVoiceConfidenceProvider.GetSampleInfo()
 	return sr
@@ -84,39 +173,111 @@ func (b *VADAnalyzer) GetWindowSize() int {
 	return windowSize
 }
 
-// isActiveSpeech 判断是否是活跃的语音
-func (b *VADAnalyzer) isActiveSpeech(audio []byte) bool {
-	return b.IVoiceConfidenceProvider.IsActiveSpeech(audio)
+// GetAudioInFormat 返回 VAD 分析需要的输入音频格式
+func (b *VADAnalyzer) GetAudioInFormat() *types.AudioFormat {
+	return types.NewAudioFormat(b.GetSampleRate(), b.args.NumChannels, b.args.SampleWidth)
+}
+
+// GetAudioOutFormat VAD 输出的音频格式与输入一致
+func (b *VADAnalyzer) GetAudioOutFormat() *types.AudioFormat {
+	return b.GetAudioInFormat()
+}
+
+// GetThresholds 返回当前开始/停止说话的置信度阈值
+func (b *VADAnalyzer) GetThresholds() (float32, float32) {
+	return b.startThreshold, b.stopThreshold
+}
+
+// isActiveSpeech 按滞回阈值判断是否是活跃的语音: 说话中使用较低的停止阈值, 避免语音内部置信度波动导致断句
+func (b *VADAnalyzer) isActiveSpeech(confidence float32) bool {
+	if b.vadState == types.Speaking || b.vadState == types.Stopping {
+		return confidence >= b.stopThreshold
+	}
+	return confidence >= b.startThreshold
+}
+
+// calibrate 会话开始阶段统计静音窗口的置信度, 环境噪声较大时提高阈值
+func (b *VADAnalyzer) calibrate(confidence float32) {
+	if b.calibrationCount >= b.calibrationFrames || b.vadState != types.Quiet {
+		return
+	}
+	b.calibrationCount++
+	b.calibrationSum += float64(confidence)
+	b.calibrationSquares += float64(confidence) * float64(confidence)
+	if b.calibrationCount < b.calibrationFrames {
+		return
+	}
+
+	n := float64(b.calibrationCount)
+	mean := b.calibrationSum / n
+	std := math.Sqrt(max(b.calibrationSquares/n-mean*mean, 0))
+	noise := float32(mean + 2*std)
+	startThreshold := min(noise+b.args.CalibrationMargin, 0.95)
+	if startThreshold <= b.args.StartThreshold {
+		logger.Debugf("VADAnalyzer: calibration noise confidence %.2f, keep thresholds %.2f/%.2f",
+			noise, b.startThreshold, b.stopThreshold)
+		return
+	}
+	b.startThreshold = startThreshold
+	b.stopThreshold = max(startThreshold-(b.args.StartThreshold-b.args.StopThreshold), noise)
+	logger.Infof("VADAnalyzer: calibration noise confidence %.2f, thresholds -> %.2f/%.2f",
+		noise, b.startThreshold, b.stopThreshold)
 }
 
 // AnalyzeAudio 分析音频
+// 进入 Speaking 的帧会带上前填充音频(PreRollSecs)和 Starting 阶段的候选音频, 避免首音被截断;
+// 结束判定后继续附加 PostRollSecs 的音频再进入 Quiet;
+// 语音窗口累计不足 MinSpeechSecs 的候选语音被丢弃, 超过 MaxSpeechSecs 的语音段强制切分(IsFinal)
 func (b *VADAnalyzer) AnalyzeAudio(buffer []byte) *localframes.VADStateAudioRawFrame {
 	if len(buffer) != b.vadFramesNumBytes {
 		logger.Warnf("VADAnalyzer: buffer size MisMatch: %d != %d", len(buffer), b.vadFramesNumBytes)
 		return nil
 	}
 
-	b.curAtS = math.Round(float64(b.accumulateSpeechBytesLen)/float64(b.sampleNumBytes)*1000) / 1000
+	b.curAtS = b.secs(b.accumulateSpeechBytesLen)
 	b.accumulateSpeechBytesLen += len(buffer)
 
-	speaking := b.isActiveSpeech(buffer)
+	audio := buffer
+	confidence := b.IVoiceConfidenceProvider.GetVoiceConfidence(buffer)
+	b.calibrate(confidence)
+	speaking := b.isActiveSpeech(confidence)
+	continueSpeech := b.continueSpeech
+	b.continueSpeech = false
 	if speaking {
 		switch b.vadState {
 		case types.Quiet:
+			if continueSpeech {
+				// 强制切分后仍在说话, 直接开始新的语音段
+				b.startingBuffer = append(b.startingBuffer, buffer...)
+				audio = b.startSpeech(b.accumulateSpeechBytesLen - len(buffer))
+				break
+			}
 			b.vadState = types.Starting
 			b.vadStartingCount = 1
+			b.startingAtBytes = b.accumulateSpeechBytesLen - len(buffer)
+			b.startingSpeechFrames = 1
+			b.startingBuffer = append(b.startingBuffer, buffer...)
 		case types.Starting:
 			b.vadStartingCount++
+			b.startingSpeechFrames++
+			b.startingSilentFrames = 0
+			b.startingBuffer = append(b.startingBuffer, buffer...)
 		case types.Stopping:
 			b.vadState = types.Speaking
 			b.vadStoppingCount = 0
 		}
 	} else {
 		switch b.vadState {
+		case types.Quiet:
+			b.appendPreRoll(buffer)
 		case types.Starting:
-			b.vadState = types.Quiet
 			b.vadStartingCount = 0
-			b.Reset()
+			b.startingSilentFrames++
+			b.startingBuffer = append(b.startingBuffer, buffer...)
+			// 未设置最短时长时保持原有行为: 候选语音被静音打断即回到 Quiet
+			if b.vadMinSpeechFrames <= b.vadStartFrames || b.startingSilentFrames >= b.vadStopFrames {
+				b.abortStarting()
+			}
 		case types.Speaking:
 			b.vadState = types.Stopping
 			b.vadStoppingCount = 1
@@ -126,33 +287,37 @@ func (b *VADAnalyzer) AnalyzeAudio(buffer []byte) *localframes.VADStateAudioRawF
 	}
 
 	// 检查是否开始说话
-	if b.vadState == types.Starting && b.vadStartingCount >= b.vadStartFrames {
-		b.vadState = types.Speaking
-		b.vadStartingCount = 0
-
-		b.speechID++
-		b.isFinal = false
-		b.startAtS = math.Round(float64(b.accumulateSpeechBytesLen-len(buffer))/float64(b.sampleNumBytes)*1000) / 1000
-		b.endAtS = 0.0
+	if b.vadState == types.Starting {
+		if b.vadStartingCount >= b.vadStartFrames {
+			b.startConfirmed = true
+		}
+		if b.startConfirmed && b.startingSpeechFrames >= b.vadMinSpeechFrames {
+			audio = b.startSpeech(b.startingAtBytes)
+		}
 	}
 
-	// 检查是否结束说话
-	if b.vadState == types.Stopping && b.vadStoppingCount >= b.vadStopFrames {
-		b.vadState = types.Quiet
-		b.vadStoppingCount = 0
+	if b.vadState == types.Speaking || b.vadState == types.Stopping {
+		b.segmentFrames++
+	}
 
-		b.isFinal = true
-		b.endAtS = math.Round(float64(b.accumulateSpeechBytesLen)/float64(b.sampleNumBytes)*1000) / 1000
+	// 检查是否结束说话, 结束判定后继续附加后填充音频
+	if b.vadState == types.Stopping && b.vadStoppingCount >= b.vadStopFrames+b.vadPostRollFrames {
+		b.stopSpeech()
+	} else if b.vadMaxSpeechFrames > 0 && b.segmentFrames >= b.vadMaxSpeechFrames &&
+		(b.vadState == types.Speaking || b.vadState == types.Stopping) {
+		logger.Debugf("VADAnalyzer: speech %d reach max %d frames, force split", b.speechID, b.vadMaxSpeechFrames)
+		b.continueSpeech = b.vadState == types.Speaking
+		b.stopSpeech()
 	}
 
 	vadStateAudioRawFrame := &localframes.VADStateAudioRawFrame{
 		AudioRawFrame: &pipelineframes.AudioRawFrame{
 			DataFrame:   pipelineframes.NewDataFrameWithName("VADStateAudioRawFrame"),
-			Audio:       buffer,
+			Audio:       audio,
 			SampleRate:  b.args.SampleRate,
 			NumChannels: b.args.NumChannels,
 			SampleWidth: b.args.SampleWidth,
-			NumFrames:   len(buffer) / (b.args.NumChannels * b.args.SampleWidth),
+			NumFrames:   len(audio) / (b.args.NumChannels * b.args.SampleWidth),
 		},
 		State:    b.vadState,
 		SpeechID: b.speechID,
@@ -160,6 +325,37 @@ func (b *VADAnalyzer) AnalyzeAudio(buffer []byte) *localframes.VADStateAudioRawF
 		StartAtS: b.startAtS,
 		CurAtS:   b.curAtS,
 		EndAtS:   b.endAtS,
+
+		Confidence: confidence,
 	}
 	return vadStateAudioRawFrame
 }
+
+// startSpeech 进入 Speaking, 返回前填充音频 + 候选语音音频(已包含当前窗口)
+func (b *VADAnalyzer) startSpeech(startAtBytes int) []byte {
+	audio := make([]byte, 0, len(b.preRollBuffer)+len(b.startingBuffer))
+	audio = append(audio, b.preRollBuffer...)
+	audio = append(audio, b.startingBuffer...)
+
+	b.vadState = types.Speaking
+	b.speechID++
+	b.isFinal = false
+	b.startAtS = b.secs(startAtBytes - len(b.preRollBuffer))
+	b.endAtS = 0.0
+	// 候选语音窗口计入语音段时长, 当前窗口在 AnalyzeAudio 中计数
+	b.segmentFrames = len(b.startingBuffer)/b.vadFramesNumBytes - 1
+
+	b.preRollBuffer = b.preRollBuffer[:0]
+	b.resetStarting()
+	return audio
+}
+
+// stopSpeech 结束当前语音段, 进入 Quiet
+func (b *VADAnalyzer) stopSpeech() {
+	b.vadState = types.Quiet
+	b.vadStoppingCount = 0
+	b.segmentFrames = 0
+
+	b.isFinal = true
+	b.endAtS = b.secs(b.accumulateSpeechBytesLen)
+}
@@ -0,0 +1,225 @@
+package vad_analyzer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"achatbot/pkg/common"
+	"achatbot/pkg/params"
+	"achatbot/pkg/types"
+	localframes "achatbot/pkg/types/frames"
+)
+
+// scriptProvider 窗口首字节/100 即为语音置信度
+type scriptProvider struct{}
+
+func (p *scriptProvider) IsActiveSpeech(audio []byte) bool { return p.GetVoiceConfidence(audio) >= 0.5 }
+func (p *scriptProvider) GetVoiceConfidence(audio []byte) float32 {
+	return float32(audio[0]) / 100
+}
+func (p *scriptProvider) Warmup()                   {}
+func (p *scriptProvider) GetSampleInfo() (int, int) { return 16000, 512 }
+func (p *scriptProvider) Name() string              { return "script" }
+func (p *scriptProvider) Reset() error              { return nil }
+func (p *scriptProvider) Release() error            { return nil }
+
+const windowBytes = 512 * 2
+
+func window(confidence byte) []byte {
+	buf := make([]byte, windowBytes)
+	buf[0] = confidence
+	return buf
+}
+
+func feed(analyzer *VADAnalyzer, speech bool, n int) []*localframes.VADStateAudioRawFrame {
+	if speech {
+		return feedConfidence(analyzer, 100, n)
+	}
+	return feedConfidence(analyzer, 0, n)
+}
+
+func feedConfidence(analyzer *VADAnalyzer, confidence byte, n int) (res []*localframes.VADStateAudioRawFrame) {
+	for range n {
+		res = append(res, analyzer.AnalyzeAudio(window(confidence)))
+	}
+	return
+}
+
+func TestVADAnalyzerPreRoll(t *testing.T) {
+	// 32ms 窗口: StartSecs 3 窗口, PreRollSecs 4 窗口
+	args := params.NewVADAnalyzerArgs().WithStartSecs(0.096).WithPreRollSecs(0.128)
+	analyzer := NewVADAnalyzer(args, &scriptProvider{})
+
+	feed(analyzer, false, 10)
+	res := feed(analyzer, true, 3)
+	assert.Equal(t, types.Starting, res[0].State)
+	assert.Equal(t, types.Starting, res[1].State)
+	assert.Equal(t, types.Speaking, res[2].State)
+	// 进入 Speaking 的帧带上前填充和 Starting 阶段的音频
+	assert.Len(t, res[2].Audio, (4+3)*windowBytes)
+	assert.Equal(t, 1, res[2].SpeechID)
+	assert.InDelta(t, 0.192, res[2].StartAtS, 0.001)
+
+	res = feed(analyzer, false, 10)
+	assert.Equal(t, types.Quiet, res[9].State)
+	assert.True(t, res[9].IsFinal)
+
+	// 进入 Speaking 前已输出为 Quiet 的音频不足前填充时长时只附加已有的音频
+	analyzer.Reset()
+	res = feed(analyzer, true, 3)
+	assert.Len(t, res[2].Audio, 3*windowBytes)
+}
+
+func TestVADAnalyzerDefaultNoPreRoll(t *testing.T) {
+	// 默认不附加前填充, 进入 Speaking 的帧只带 Starting 阶段的音频
+	analyzer := NewVADAnalyzer(params.NewVADAnalyzerArgs().WithStartSecs(0.096), &scriptProvider{})
+
+	feed(analyzer, false, 10)
+	res := feed(analyzer, true, 3)
+	assert.Equal(t, types.Speaking, res[2].State)
+	assert.Len(t, res[2].Audio, 3*windowBytes)
+}
+
+func TestVADAnalyzerPostRoll(t *testing.T) {
+	args := params.NewVADAnalyzerArgs().WithPreRollSecs(0).WithPostRollSecs(0.16)
+	analyzer := NewVADAnalyzer(args, &scriptProvider{})
+
+	feed(analyzer, true, 5)
+	// StopSecs 10 窗口 + PostRollSecs 5 窗口
+	res := feed(analyzer, false, 15)
+	assert.Equal(t, types.Stopping, res[13].State)
+	assert.Equal(t, types.Quiet, res[14].State)
+	assert.True(t, res[14].IsFinal)
+}
+
+func TestVADAnalyzerMinSpeech(t *testing.T) {
+	args := params.NewVADAnalyzerArgs().WithPreRollSecs(0.3).WithMinSpeechSecs(0.16)
+	analyzer := NewVADAnalyzer(args, &scriptProvider{})
+
+	// 咳嗽: 3 窗口语音后静音, 丢弃, 不进入 Speaking
+	res := append(feed(analyzer, true, 3), feed(analyzer, false, 10)...)
+	for _, frame := range res {
+		assert.NotEqual(t, types.Speaking, frame.State)
+	}
+	assert.Equal(t, types.Quiet, res[len(res)-1].State)
+
+	// 短暂停顿不打断候选语音, 累计 5 窗口语音后进入 Speaking
+	res = append(feed(analyzer, true, 3), feed(analyzer, false, 2)...)
+	res = append(res, feed(analyzer, true, 2)...)
+	assert.Equal(t, types.Starting, res[5].State)
+	assert.Equal(t, types.Speaking, res[6].State)
+	assert.Equal(t, 1, res[6].SpeechID)
+	// 前填充(9 窗口) + 候选语音(7 窗口)
+	assert.Len(t, res[6].Audio, (9+7)*windowBytes)
+}
+
+func TestVADAnalyzerMaxSpeech(t *testing.T) {
+	args := params.NewVADAnalyzerArgs().WithMaxSpeechSecs(0.32)
+	analyzer := NewVADAnalyzer(args, &scriptProvider{})
+
+	res := feed(analyzer, true, 25)
+	// 每 10 窗口强制切分, 下一个语音窗口直接开始新的语音段
+	assert.Equal(t, types.Quiet, res[9].State)
+	assert.True(t, res[9].IsFinal)
+	assert.Equal(t, 1, res[9].SpeechID)
+	assert.Equal(t, types.Speaking, res[10].State)
+	assert.False(t, res[10].IsFinal)
+	assert.Equal(t, 2, res[10].SpeechID)
+	assert.Len(t, res[10].Audio, windowBytes)
+	assert.Equal(t, types.Quiet, res[19].State)
+	assert.Equal(t, 3, res[20].SpeechID)
+}
+
+func TestVADAnalyzerHysteresis(t *testing.T) {
+	analyzer := NewVADAnalyzer(params.NewVADAnalyzerArgs().WithThresholds(0.6, 0.3), &scriptProvider{})
+
+	// 低于开始阈值不开始说话
+	res := feedConfidence(analyzer, 50, 5)
+	assert.Equal(t, types.Quiet, res[4].State)
+	assert.InDelta(t, 0.5, res[4].Confidence, 0.001)
+
+	res = feedConfidence(analyzer, 70, 1)
+	assert.Equal(t, types.Speaking, res[0].State)
+	assert.InDelta(t, 0.7, res[0].Confidence, 0.001)
+	// 说话中置信度回落到开始阈值以下但高于停止阈值, 仍为说话
+	res = feedConfidence(analyzer, 40, 20)
+	assert.Equal(t, types.Speaking, res[19].State)
+	res = feedConfidence(analyzer, 20, 10)
+	assert.Equal(t, types.Stopping, res[0].State)
+	assert.Equal(t, types.Quiet, res[9].State)
+}
+
+func TestVADAnalyzerCalibration(t *testing.T) {
+	// 校准 10 窗口
+	args := params.NewVADAnalyzerArgs().WithCalibrationSecs(0.32)
+	analyzer := NewVADAnalyzer(args, &scriptProvider{})
+
+	start, stop := analyzer.GetThresholds()
+	assert.InDelta(t, 0.5, start, 0.001)
+	assert.InDelta(t, 0.35, stop, 0.001)
+
+	// 环境噪声置信度 0.4, 阈值提高到 0.4 + 0.2
+	res := feedConfidence(analyzer, 40, 10)
+	assert.Equal(t, types.Quiet, res[9].State)
+	start, stop = analyzer.GetThresholds()
+	assert.InDelta(t, 0.6, start, 0.001)
+	assert.InDelta(t, 0.45, stop, 0.001)
+
+	res = feedConfidence(analyzer, 55, 3)
+	assert.Equal(t, types.Quiet, res[2].State)
+	res = feedConfidence(analyzer, 65, 1)
+	assert.Equal(t, types.Speaking, res[0].State)
+
+	// 新会话重新校准
+	analyzer.Reset()
+	start, _ = analyzer.GetThresholds()
+	assert.InDelta(t, 0.5, start, 0.001)
+}
+
+// binaryProvider 置信度只有 0/1 的提供者(如 sherpa-onnx VAD)
+type binaryProvider struct{ scriptProvider }
+
+func (p *binaryProvider) IsBinaryConfidence() bool { return true }
+
+func TestVADAnalyzerBinaryConfidenceSkipsCalibration(t *testing.T) {
+	args := params.NewVADAnalyzerArgs().WithCalibrationSecs(0.32)
+	assert.Equal(t, 10, NewVADAnalyzer(args, &scriptProvider{}).calibrationFrames)
+
+	analyzer := NewVADAnalyzer(args, &binaryProvider{})
+	assert.Equal(t, 0, analyzer.calibrationFrames)
+	feed(analyzer, false, 20)
+	start, stop := analyzer.GetThresholds()
+	assert.InDelta(t, 0.5, start, 0.001)
+	assert.InDelta(t, 0.35, stop, 0.001)
+}
+
+var _ common.IPoolHealthChecker = (*SherpaOnnxProvider)(nil)
+
+func TestSherpaOnnxProviderPoolHealthCheck(t *testing.T) {
+	probe := NewSherpaOnnxProvider(NewDefaultSherpaOnnxVadModelConfig("silero"), 5)
+	if probe == nil {
+		t.Skip("vad model not found")
+	}
+	assert.NoError(t, probe.Release())
+	pool := common.NewPool("vad", 1, func() (*SherpaOnnxProvider, error) {
+		return NewSherpaOnnxProvider(NewDefaultSherpaOnnxVadModelConfig("silero"), 5), nil
+	})
+	assert.NoError(t, pool.Initialize())
+	defer pool.Close()
+
+	provider, release, err := pool.Acquire(context.Background())
+	assert.NoError(t, err)
+	assert.NoError(t, provider.HealthCheck())
+	assert.NoError(t, provider.Release())
+	assert.Error(t, provider.HealthCheck())
+	assert.Error(t, provider.Reset())
+	release()
+
+	replaced, release, err := pool.Acquire(context.Background())
+	assert.NoError(t, err)
+	defer release()
+	assert.NotSame(t, provider, replaced)
+	assert.NoError(t, replaced.HealthCheck())
+}
@@ -0,0 +1,132 @@
+package params
+
+import "fmt"
+
+// AudioDSPParams 输入音频 DSP 参数(高通滤波, 频谱噪声门, 自动增益+限幅), 每个会话可独立配置
+type AudioDSPParams struct {
+	// 处理帧长(毫秒), 10~32ms
+	FrameMS int `json:"frame_ms"`
+
+	// 高通滤波, 去除直流和低频嗡嗡声
+	HighPassEnabled  bool    `json:"high_pass_enabled"`
+	HighPassCutoffHz float64 `json:"high_pass_cutoff_hz"`
+
+	// 频谱噪声门, 按学习到的每个频点噪声底衰减噪声
+	NoiseGateEnabled bool `json:"noise_gate_enabled"`
+	// 噪声频点最大衰减(dB, 负数)
+	NoiseGateFloorDB float64 `json:"noise_gate_floor_db"`
+	// 噪声过减系数
+	NoiseGateOverSubtraction float64 `json:"noise_gate_over_subtraction"`
+	// 会话开始时学习噪声底的时长(秒)
+	NoiseLearnSecs float64 `json:"noise_learn_secs"`
+
+	// 自动增益控制
+	AGCEnabled   bool    `json:"agc_enabled"`
+	AGCTargetDB  float64 `json:"agc_target_db"`   // 目标 RMS 电平(dBFS)
+	AGCMaxGainDB float64 `json:"agc_max_gain_db"` // 最大增益
+	AGCMinGainDB float64 `json:"agc_min_gain_db"` // 最小增益
+	AGCGateDB    float64 `json:"agc_gate_db"`     // 低于此电平(dBFS)不调整增益, 避免放大噪声
+	AGCAttackMS  int     `json:"agc_attack_ms"`   // 增益下降时间常数
+	AGCReleaseMS int     `json:"agc_release_ms"`  // 增益上升时间常数
+	// 限幅器峰值上限(dBFS)
+	LimiterDB float64 `json:"limiter_db"`
+}
+
+// NewAudioDSPParams 创建一个新的AudioDSPParams实例，带有默认值
+func NewAudioDSPParams() *AudioDSPParams {
+	return &AudioDSPParams{
+		FrameMS:                  20,
+		HighPassEnabled:          true,
+		HighPassCutoffHz:         80,
+		NoiseGateEnabled:         true,
+		NoiseGateFloorDB:         -18,
+		NoiseGateOverSubtraction: 1.5,
+		NoiseLearnSecs:           0.5,
+		AGCEnabled:               true,
+		AGCTargetDB:              -20,
+		AGCMaxGainDB:             24,
+		AGCMinGainDB:             -12,
+		AGCGateDB:                -50,
+		AGCAttackMS:              20,
+		AGCReleaseMS:             400,
+		LimiterDB:                -1,
+	}
+}
+
+// WithFrameMS 设置处理帧长
+func (p *AudioDSPParams) WithFrameMS(frameMS int) *AudioDSPParams {
+	p.FrameMS = frameMS
+	return p
+}
+
+// WithHighPass 设置高通滤波开关和截止频率
+func (p *AudioDSPParams) WithHighPass(enabled bool, cutoffHz float64) *AudioDSPParams {
+	p.HighPassEnabled = enabled
+	p.HighPassCutoffHz = cutoffHz
+	return p
+}
+
+// WithNoiseGate 设置噪声门开关和最大衰减
+func (p *AudioDSPParams) WithNoiseGate(enabled bool, floorDB float64) *AudioDSPParams {
+	p.NoiseGateEnabled = enabled
+	p.NoiseGateFloorDB = floorDB
+	return p
+}
+
+// WithNoiseGateOverSubtraction 设置噪声过减系数
+func (p *AudioDSPParams) WithNoiseGateOverSubtraction(overSubtraction float64) *AudioDSPParams {
+	p.NoiseGateOverSubtraction = overSubtraction
+	return p
+}
+
+// WithNoiseLearnSecs 设置噪声底学习时长
+func (p *AudioDSPParams) WithNoiseLearnSecs(secs float64) *AudioDSPParams {
+	p.NoiseLearnSecs = secs
+	return p
+}
+
+// WithAGC 设置自动增益开关和目标电平
+func (p *AudioDSPParams) WithAGC(enabled bool, targetDB float64) *AudioDSPParams {
+	p.AGCEnabled = enabled
+	p.AGCTargetDB = targetDB
+	return p
+}
+
+// WithAGCGainRange 设置自动增益范围
+func (p *AudioDSPParams) WithAGCGainRange(minGainDB, maxGainDB float64) *AudioDSPParams {
+	p.AGCMinGainDB = minGainDB
+	p.AGCMaxGainDB = maxGainDB
+	return p
+}
+
+// WithLimiterDB 设置限幅器峰值上限
+func (p *AudioDSPParams) WithLimiterDB(limiterDB float64) *AudioDSPParams {
+	p.LimiterDB = limiterDB
+	return p
+}
+
+// Validate validates the audio DSP parameters
+func (p *AudioDSPParams) Validate() error {
+	if p.FrameMS < 10 || p.FrameMS > 32 {
+		return fmt.Errorf("frame_ms must be in [10, 32], got %d", p.FrameMS)
+	}
+	if p.HighPassEnabled && p.HighPassCutoffHz <= 0 {
+		return fmt.Errorf("high_pass_cutoff_hz must be positive, got %f", p.HighPassCutoffHz)
+	}
+	if p.NoiseGateEnabled && p.NoiseGateFloorDB > 0 {
+		return fmt.Errorf("noise_gate_floor_db must be non-positive, got %f", p.NoiseGateFloorDB)
+	}
+	if p.AGCEnabled && p.AGCMinGainDB > p.AGCMaxGainDB {
+		return fmt.Errorf("agc_min_gain_db %f greater than agc_max_gain_db %f", p.AGCMinGainDB, p.AGCMaxGainDB)
+	}
+	if p.LimiterDB > 0 {
+		return fmt.Errorf("limiter_db must be non-positive, got %f", p.LimiterDB)
+	}
+	return nil
+}
+
+func (p *AudioDSPParams) String() string {
+	return fmt.Sprintf("AudioDSPParams{FrameMS: %d, HighPass: %t(%.0fHz), NoiseGate: %t(%.1fdB), AGC: %t(%.1fdBFS, %.1f~%.1fdB), LimiterDB: %.1f}",
+		p.FrameMS, p.HighPassEnabled, p.HighPassCutoffHz, p.NoiseGateEnabled, p.NoiseGateFloorDB,
+		p.AGCEnabled, p.AGCTargetDB, p.AGCMinGainDB, p.AGCMaxGainDB, p.LimiterDB)
+}
@@ -3,6 +3,7 @@ package params
 import (
 	"achatbot/pkg/common"
 	"achatbot/pkg/consts"
+	"achatbot/pkg/types"
 	"fmt"
 )
 
@@ -101,6 +102,16 @@ type AudioVADParams struct {
 	VADEnabled          bool `json:"vad_enabled"`
 	VADAudioPassthrough bool `json:"vad_audio_passthrough"`
 	VADAnalyzer         common.IVADAnalyzer
+
+	// 机器人回声处理
+	EchoParams    *EchoParams `json:"echo_params"`
+	EchoReference common.IEchoReference
+
+	// VAD 分析前的输入音频预处理(降噪/增益)
+	AudioInPreprocessor common.IAudioPreprocessor
+
+	// 输入音频旁路(如会话录音), 接收客户端原始输入音频
+	AudioInTap common.IAudioTap
 }
 
 // NewAudioVADParams creates a new AudioVADParams with default values
@@ -110,6 +121,8 @@ func NewAudioVADParams() *AudioVADParams {
 		VADEnabled:          false,
 		VADAudioPassthrough: false,
 		VADAnalyzer:         nil,
+		EchoParams:          NewEchoParams(),
+		EchoReference:       nil,
 	}
 }
 
@@ -137,6 +150,30 @@ func (p *AudioVADParams) WithVADAnalyzer(analyzer common.IVADAnalyzer) *AudioVAD
 	return p
 }
 
+// WithEchoParams sets echo handling parameters
+func (p *AudioVADParams) WithEchoParams(echoParams *EchoParams) *AudioVADParams {
+	p.EchoParams = echoParams
+	return p
+}
+
+// WithEchoReference sets the bot output audio reference shared by output and input processors
+func (p *AudioVADParams) WithEchoReference(echoReference common.IEchoReference) *AudioVADParams {
+	p.EchoReference = echoReference
+	return p
+}
+
+// WithAudioInPreprocessor sets the input audio preprocessor applied before VAD analysis
+func (p *AudioVADParams) WithAudioInPreprocessor(preprocessor common.IAudioPreprocessor) *AudioVADParams {
+	p.AudioInPreprocessor = preprocessor
+	return p
+}
+
+// WithAudioInTap sets the tap of the raw input audio received from the client, before echo handling and VAD
+func (p *AudioVADParams) WithAudioInTap(tap common.IAudioTap) *AudioVADParams {
+	p.AudioInTap = tap
+	return p
+}
+
 // GetAudioOutSampleRate returns audio output sample rate
 func (p *AudioParams) GetAudioOutSampleRate() int {
 	return p.AudioOutSampleRate
@@ -167,6 +204,16 @@ func (p *AudioParams) GetAudioInSampleWidth() int {
 	return p.AudioInSampleWidth
 }
 
+// GetAudioInFormat returns audio input format
+func (p *AudioParams) GetAudioInFormat() *types.AudioFormat {
+	return types.NewAudioFormat(p.AudioInSampleRate, p.AudioInChannels, p.AudioInSampleWidth)
+}
+
+// GetAudioOutFormat returns audio output format
+func (p *AudioParams) GetAudioOutFormat() *types.AudioFormat {
+	return types.NewAudioFormat(p.AudioOutSampleRate, p.AudioOutChannels, p.AudioOutSampleWidth)
+}
+
 // IsAudioOutEnabled checks if audio output is enabled
 func (p *AudioParams) IsAudioOutEnabled() bool {
 	return p.AudioOutEnabled
@@ -197,6 +244,14 @@ func (p *AudioVADParams) GetVADAnalyzer() common.IVADAnalyzer {
 	return p.VADAnalyzer
 }
 
+// GetEchoStrategy returns the echo handling strategy
+func (p *AudioVADParams) GetEchoStrategy() types.EchoStrategy {
+	if p.EchoParams == nil {
+		return types.EchoStrategyNone
+	}
+	return p.EchoParams.Strategy
+}
+
 // Validate validates the audio parameters
 func (p *AudioParams) Validate() error {
 	if p.AudioOutSampleRate <= 0 {
@@ -233,6 +288,36 @@ func (p *AudioVADParams) Validate() error {
 		return fmt.Errorf("VAD is enabled but no VAD analyzer provided")
 	}
 
+	// VAD 在输入处理器内部分析客户端音频, 无法插入格式转换, 格式不一致直接报错
+	if declarer, ok := p.VADAnalyzer.(common.IAudioFormatDeclarer); ok && p.VADEnabled {
+		vadInFormat := declarer.GetAudioInFormat()
+		if vadInFormat != nil && !vadInFormat.Equal(p.GetAudioInFormat()) {
+			return fmt.Errorf("client audio in format %s mismatch VAD analyzer %T expected %s",
+				p.GetAudioInFormat(), p.VADAnalyzer, vadInFormat)
+		}
+	}
+
+	if p.EchoParams != nil {
+		if err := p.EchoParams.Validate(); err != nil {
+			return err
+		}
+	}
+	if p.GetEchoStrategy() == types.EchoStrategyFullDuplex {
+		if p.EchoReference == nil {
+			return fmt.Errorf("echo strategy %s needs echo reference", p.GetEchoStrategy())
+		}
+		if p.AudioInChannels != 1 {
+			return fmt.Errorf("echo strategy %s only support mono audio in, got %s",
+				p.GetEchoStrategy(), p.GetAudioInFormat())
+		}
+		switch p.AudioInSampleWidth {
+		case 1, 2, 4:
+		default:
+			return fmt.Errorf("echo strategy %s only support 8/16/32 bit audio in, got %s",
+				p.GetEchoStrategy(), p.GetAudioInFormat())
+		}
+	}
+
 	return nil
 }
 
@@ -263,6 +348,6 @@ func (p *AudioVADParams) String() string {
 	if p.VADAnalyzer != nil {
 		vadAnalyzerStr = fmt.Sprintf("%T", p.VADAnalyzer)
 	}
-	return fmt.Sprintf("AudioVADParams{%s, VADEnabled: %t, VADPassThrough: %t, VADAnalyzer: %s}",
-		p.AudioParams.String(), p.VADEnabled, p.VADAudioPassthrough, vadAnalyzerStr)
+	return fmt.Sprintf("AudioVADParams{%s, VADEnabled: %t, VADPassThrough: %t, VADAnalyzer: %s, EchoStrategy: %s}",
+		p.AudioParams.String(), p.VADEnabled, p.VADAudioPassthrough, vadAnalyzerStr, p.GetEchoStrategy())
 }
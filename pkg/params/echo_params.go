@@ -0,0 +1,105 @@
+package params
+
+import (
+	"fmt"
+
+	"achatbot/pkg/types"
+)
+
+// EchoParams 回声处理参数
+type EchoParams struct {
+	Strategy types.EchoStrategy `json:"strategy"`
+	// 机器人停止说话后仍视为说话中的拖尾时长(客户端播放缓冲+房间混响)
+	TailSecs float64 `json:"tail_secs"`
+	// 门控模式: 机器人说话期间用户语音需持续的时长才触发打断
+	BargeInSecs float64 `json:"barge_in_secs"`
+	// 门控模式: 有参考信号时, 麦克风能量需达到参考信号能量的倍数才触发打断
+	BargeInEnergyRatio float64 `json:"barge_in_energy_ratio"`
+	// 全双工模式: NLMS 自适应滤波器阶数(样本数)和步长
+	AECFilterLen int     `json:"aec_filter_len"`
+	AECStepSize  float64 `json:"aec_step_size"`
+	// 参考信号相对麦克风的延迟(网络+播放), 毫秒
+	AECDelayMS int `json:"aec_delay_ms"`
+}
+
+// NewEchoParams 创建一个新的EchoParams实例，带有默认值
+func NewEchoParams() *EchoParams {
+	return &EchoParams{
+		Strategy:           types.EchoStrategyNone,
+		TailSecs:           0.5,
+		BargeInSecs:        0.6,
+		BargeInEnergyRatio: 1.5,
+		AECFilterLen:       512, // 32ms for 16000 samples
+		AECStepSize:        0.1,
+		AECDelayMS:         100,
+	}
+}
+
+// WithStrategy 设置回声处理策略
+func (p *EchoParams) WithStrategy(strategy types.EchoStrategy) *EchoParams {
+	p.Strategy = strategy
+	return p
+}
+
+// WithTailSecs 设置拖尾时长
+func (p *EchoParams) WithTailSecs(secs float64) *EchoParams {
+	p.TailSecs = secs
+	return p
+}
+
+// WithBargeInSecs 设置门控打断需持续的时长
+func (p *EchoParams) WithBargeInSecs(secs float64) *EchoParams {
+	p.BargeInSecs = secs
+	return p
+}
+
+// WithBargeInEnergyRatio 设置门控打断能量比
+func (p *EchoParams) WithBargeInEnergyRatio(ratio float64) *EchoParams {
+	p.BargeInEnergyRatio = ratio
+	return p
+}
+
+// WithAECFilterLen 设置回声消除滤波器阶数
+func (p *EchoParams) WithAECFilterLen(filterLen int) *EchoParams {
+	p.AECFilterLen = filterLen
+	return p
+}
+
+// WithAECStepSize 设置回声消除步长
+func (p *EchoParams) WithAECStepSize(stepSize float64) *EchoParams {
+	p.AECStepSize = stepSize
+	return p
+}
+
+// WithAECDelayMS 设置参考信号延迟
+func (p *EchoParams) WithAECDelayMS(delayMS int) *EchoParams {
+	p.AECDelayMS = delayMS
+	return p
+}
+
+// Validate validates the echo parameters
+func (p *EchoParams) Validate() error {
+	if p.TailSecs < 0 {
+		return fmt.Errorf("tail_secs must be non-negative, got %f", p.TailSecs)
+	}
+	if p.BargeInSecs < 0 {
+		return fmt.Errorf("barge_in_secs must be non-negative, got %f", p.BargeInSecs)
+	}
+	if p.Strategy == types.EchoStrategyFullDuplex {
+		if p.AECFilterLen <= 0 {
+			return fmt.Errorf("aec_filter_len must be positive, got %d", p.AECFilterLen)
+		}
+		if p.AECStepSize <= 0 || p.AECStepSize >= 2 {
+			return fmt.Errorf("aec_step_size must be in (0, 2), got %f", p.AECStepSize)
+		}
+		if p.AECDelayMS < 0 {
+			return fmt.Errorf("aec_delay_ms must be non-negative, got %d", p.AECDelayMS)
+		}
+	}
+	return nil
+}
+
+func (p *EchoParams) String() string {
+	return fmt.Sprintf("EchoParams{Strategy: %s, TailSecs: %.3f, BargeInSecs: %.3f, BargeInEnergyRatio: %.2f, AECFilterLen: %d, AECStepSize: %.3f, AECDelayMS: %d}",
+		p.Strategy, p.TailSecs, p.BargeInSecs, p.BargeInEnergyRatio, p.AECFilterLen, p.AECStepSize, p.AECDelayMS)
+}
@@ -0,0 +1,97 @@
+package params
+
+import (
+	"fmt"
+
+	"achatbot/pkg/consts"
+)
+
+// EnergyVADArgs 基于能量/过零率/频谱平坦度的语音置信度参数
+type EnergyVADArgs struct {
+	SampleRate int `json:"sample_rate"`
+	WindowSize int `json:"window_size"`
+	// 帧能量高于噪声底的信噪比阈值(dB)
+	SpeechSNRDB float64 `json:"speech_snr_db"`
+	// 绝对能量下限(dBFS), 低于此电平一律视为静音
+	MinEnergyDB float64 `json:"min_energy_db"`
+	// 过零率上限(每样本), 超出视为噪声/摩擦音
+	MaxZCR float64 `json:"max_zcr"`
+	// 频谱平坦度上限(0~1), 白噪声接近 1, 浊音语音较低
+	MaxSpectralFlatness float64 `json:"max_spectral_flatness"`
+	// 噪声底跟踪系数: 非语音帧更新, 语音帧缓慢上升
+	NoiseFloorAlpha  float64 `json:"noise_floor_alpha"`
+	NoiseFloorRiseDB float64 `json:"noise_floor_rise_db"`
+	// 语音判定后保持的帧数, 避免词间短暂能量下降被切断
+	HangoverFrames int `json:"hangover_frames"`
+}
+
+// NewEnergyVADArgs 创建一个新的EnergyVADArgs实例，带有默认值
+func NewEnergyVADArgs() *EnergyVADArgs {
+	return &EnergyVADArgs{
+		SampleRate:          consts.DefaultRate,
+		WindowSize:          512, // 32ms for 16000 samples, the same as SileroVAD
+		SpeechSNRDB:         9,
+		MinEnergyDB:         -55,
+		MaxZCR:              0.35,
+		MaxSpectralFlatness: 0.55,
+		NoiseFloorAlpha:     0.05,
+		NoiseFloorRiseDB:    0.02,
+		HangoverFrames:      3,
+	}
+}
+
+// WithSampleRate 设置采样率
+func (args *EnergyVADArgs) WithSampleRate(sampleRate int) *EnergyVADArgs {
+	args.SampleRate = sampleRate
+	return args
+}
+
+// WithWindowSize 设置窗口大小
+func (args *EnergyVADArgs) WithWindowSize(windowSize int) *EnergyVADArgs {
+	args.WindowSize = windowSize
+	return args
+}
+
+// WithSpeechSNRDB 设置语音信噪比阈值
+func (args *EnergyVADArgs) WithSpeechSNRDB(snrDB float64) *EnergyVADArgs {
+	args.SpeechSNRDB = snrDB
+	return args
+}
+
+// WithMinEnergyDB 设置绝对能量下限
+func (args *EnergyVADArgs) WithMinEnergyDB(minEnergyDB float64) *EnergyVADArgs {
+	args.MinEnergyDB = minEnergyDB
+	return args
+}
+
+// WithMaxZCR 设置过零率上限
+func (args *EnergyVADArgs) WithMaxZCR(maxZCR float64) *EnergyVADArgs {
+	args.MaxZCR = maxZCR
+	return args
+}
+
+// WithMaxSpectralFlatness 设置频谱平坦度上限
+func (args *EnergyVADArgs) WithMaxSpectralFlatness(maxFlatness float64) *EnergyVADArgs {
+	args.MaxSpectralFlatness = maxFlatness
+	return args
+}
+
+// WithHangoverFrames 设置语音保持帧数
+func (args *EnergyVADArgs) WithHangoverFrames(frames int) *EnergyVADArgs {
+	args.HangoverFrames = frames
+	return args
+}
+
+// Validate validates the energy VAD arguments
+func (args *EnergyVADArgs) Validate() error {
+	if args.SampleRate <= 0 {
+		return fmt.Errorf("sample_rate must be positive, got %d", args.SampleRate)
+	}
+	if args.WindowSize <= 0 {
+		return fmt.Errorf("window_size must be positive, got %d", args.WindowSize)
+	}
+	if args.NoiseFloorAlpha <= 0 || args.NoiseFloorAlpha > 1 {
+		return fmt.Errorf("noise_floor_alpha must be in (0, 1], got %f", args.NoiseFloorAlpha)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package params
+
+import (
+	"fmt"
+	"slices"
+
+	"achatbot/pkg/consts"
+	"achatbot/pkg/types"
+)
+
+// LanguageIDArgs 语种识别参数
+type LanguageIDArgs struct {
+	// 服务支持的语种, 识别结果不在其中时沿用上一次结果(或默认语种)
+	Languages       []string `json:"languages"`
+	DefaultLanguage string   `json:"default_language"`
+	// 短于此时长的语音语种识别不可靠, 沿用会话上一次的识别结果
+	MinAudioSecs float64 `json:"min_audio_secs"`
+	SampleRate   int     `json:"sample_rate"`
+	SampleWidth  int     `json:"sample_width"`
+}
+
+// NewLanguageIDArgs 创建一个新的LanguageIDArgs实例，带有默认值
+func NewLanguageIDArgs() *LanguageIDArgs {
+	return &LanguageIDArgs{
+		Languages:       []string{types.LanguageZh, types.LanguageEn},
+		DefaultLanguage: types.LanguageZh,
+		MinAudioSecs:    1.0,
+		SampleRate:      consts.DefaultRate,
+		SampleWidth:     consts.DefaultSampleWidth,
+	}
+}
+
+// WithLanguages 设置支持的语种
+func (args *LanguageIDArgs) WithLanguages(languages ...string) *LanguageIDArgs {
+	args.Languages = languages
+	return args
+}
+
+// WithDefaultLanguage 设置默认语种
+func (args *LanguageIDArgs) WithDefaultLanguage(language string) *LanguageIDArgs {
+	args.DefaultLanguage = language
+	return args
+}
+
+// WithMinAudioSecs 设置可识别的最短语音时长
+func (args *LanguageIDArgs) WithMinAudioSecs(secs float64) *LanguageIDArgs {
+	args.MinAudioSecs = secs
+	return args
+}
+
+// IsSupported 语种是否在支持列表中
+func (args *LanguageIDArgs) IsSupported(language string) bool {
+	return slices.Contains(args.Languages, language)
+}
+
+// Validate 校验参数
+func (args *LanguageIDArgs) Validate() error {
+	if len(args.Languages) == 0 {
+		return fmt.Errorf("language id languages is empty")
+	}
+	if !args.IsSupported(args.DefaultLanguage) {
+		return fmt.Errorf("default language %q not in languages %v", args.DefaultLanguage, args.Languages)
+	}
+	if args.MinAudioSecs < 0 {
+		return fmt.Errorf("language id min_audio_secs %.2f must >= 0", args.MinAudioSecs)
+	}
+	return nil
+}
+
+func (args *LanguageIDArgs) String() string {
+	return fmt.Sprintf("LanguageIDArgs(languages: %v default: %s min_audio_secs: %.2f)",
+		args.Languages, args.DefaultLanguage, args.MinAudioSecs)
+}
@@ -0,0 +1,67 @@
+package params
+
+import (
+	"fmt"
+
+	"achatbot/pkg/consts"
+	"achatbot/pkg/types"
+)
+
+// SpeakerVerifyArgs 说话人验证参数
+type SpeakerVerifyArgs struct {
+	// 与注册声纹的余弦相似度阈值, 低于阈值视为非注册说话人
+	Threshold float32                   `json:"threshold"`
+	Action    types.SpeakerVerifyAction `json:"action"`
+	// 短于此时长的语音声纹不可靠, 沿用会话上一次的验证结果
+	MinAudioSecs float64 `json:"min_audio_secs"`
+	SampleRate   int     `json:"sample_rate"`
+	SampleWidth  int     `json:"sample_width"`
+}
+
+// NewSpeakerVerifyArgs 创建一个新的SpeakerVerifyArgs实例，带有默认值
+func NewSpeakerVerifyArgs() *SpeakerVerifyArgs {
+	return &SpeakerVerifyArgs{
+		Threshold:    0.5,
+		Action:       types.SpeakerVerifyActionDrop,
+		MinAudioSecs: 0.5,
+		SampleRate:   consts.DefaultRate,
+		SampleWidth:  consts.DefaultSampleWidth,
+	}
+}
+
+// WithThreshold 设置相似度阈值
+func (args *SpeakerVerifyArgs) WithThreshold(threshold float32) *SpeakerVerifyArgs {
+	args.Threshold = threshold
+	return args
+}
+
+// WithAction 设置验证未通过时的处理方式
+func (args *SpeakerVerifyArgs) WithAction(action types.SpeakerVerifyAction) *SpeakerVerifyArgs {
+	args.Action = action
+	return args
+}
+
+// WithMinAudioSecs 设置可验证的最短语音时长
+func (args *SpeakerVerifyArgs) WithMinAudioSecs(secs float64) *SpeakerVerifyArgs {
+	args.MinAudioSecs = secs
+	return args
+}
+
+// Validate 校验参数
+func (args *SpeakerVerifyArgs) Validate() error {
+	if args.Threshold <= -1 || args.Threshold >= 1 {
+		return fmt.Errorf("speaker verify threshold %.2f out of range (-1, 1)", args.Threshold)
+	}
+	if args.MinAudioSecs < 0 {
+		return fmt.Errorf("speaker verify min_audio_secs %.2f must >= 0", args.MinAudioSecs)
+	}
+	if args.SampleRate <= 0 || args.SampleWidth <= 0 {
+		return fmt.Errorf("speaker verify invalid sample_rate %d sample_width %d", args.SampleRate, args.SampleWidth)
+	}
+	return nil
+}
+
+func (args *SpeakerVerifyArgs) String() string {
+	return fmt.Sprintf("SpeakerVerifyArgs(threshold: %.2f action: %s min_audio_secs: %.2f)",
+		args.Threshold, args.Action, args.MinAudioSecs)
+}
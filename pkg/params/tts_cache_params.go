@@ -0,0 +1,70 @@
+package params
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"achatbot/pkg/consts"
+)
+
+// TTSCacheArgs TTS 合成缓存参数: 内存 LRU + 磁盘 WAV 两级缓存
+type TTSCacheArgs struct {
+	// 内存缓存音频总字节数上限, <= 0 不使用内存缓存
+	MemoryMaxBytes int `json:"memory_max_bytes"`
+	// 磁盘缓存音频总字节数上限, <= 0 不使用磁盘缓存
+	DiskMaxBytes int64 `json:"disk_max_bytes"`
+	// 磁盘缓存目录
+	DiskDir string `json:"disk_dir"`
+	// 只缓存不长于此字符数的文本(问候语, 填充词, 常见回复), 长句很少重复
+	MaxTextRunes int `json:"max_text_runes"`
+}
+
+// NewTTSCacheArgs 创建一个新的TTSCacheArgs实例，带有默认值
+func NewTTSCacheArgs() *TTSCacheArgs {
+	return &TTSCacheArgs{
+		MemoryMaxBytes: 64 << 20,
+		DiskMaxBytes:   512 << 20,
+		DiskDir:        filepath.Join(consts.RESOURCES_DIR, "tts_cache"),
+		MaxTextRunes:   100,
+	}
+}
+
+// WithMemoryMaxBytes 设置内存缓存字节数上限
+func (args *TTSCacheArgs) WithMemoryMaxBytes(maxBytes int) *TTSCacheArgs {
+	args.MemoryMaxBytes = maxBytes
+	return args
+}
+
+// WithDiskMaxBytes 设置磁盘缓存字节数上限
+func (args *TTSCacheArgs) WithDiskMaxBytes(maxBytes int64) *TTSCacheArgs {
+	args.DiskMaxBytes = maxBytes
+	return args
+}
+
+// WithDiskDir 设置磁盘缓存目录
+func (args *TTSCacheArgs) WithDiskDir(dir string) *TTSCacheArgs {
+	args.DiskDir = dir
+	return args
+}
+
+// WithMaxTextRunes 设置缓存文本的最大字符数
+func (args *TTSCacheArgs) WithMaxTextRunes(maxTextRunes int) *TTSCacheArgs {
+	args.MaxTextRunes = maxTextRunes
+	return args
+}
+
+// Validate 校验参数
+func (args *TTSCacheArgs) Validate() error {
+	if args.DiskMaxBytes > 0 && args.DiskDir == "" {
+		return fmt.Errorf("tts cache disk_dir is empty with disk_max_bytes %d", args.DiskMaxBytes)
+	}
+	if args.MaxTextRunes <= 0 {
+		return fmt.Errorf("tts cache max_text_runes %d must > 0", args.MaxTextRunes)
+	}
+	return nil
+}
+
+func (args *TTSCacheArgs) String() string {
+	return fmt.Sprintf("TTSCacheArgs(memory_max_bytes: %d disk_max_bytes: %d disk_dir: %s max_text_runes: %d)",
+		args.MemoryMaxBytes, args.DiskMaxBytes, args.DiskDir, args.MaxTextRunes)
+}
@@ -0,0 +1,72 @@
+package params
+
+// TurnAnalyzerArgs 轮次分析器参数
+type TurnAnalyzerArgs struct {
+	// VAD 判断停止说话后的最短/最长继续等待静音时长(秒), 实际等待时长在两者间按说完概率自适应
+	MinWaitSecs float64 `json:"min_wait_secs"`
+	MaxWaitSecs float64 `json:"max_wait_secs"`
+	// 说完概率达到阈值直接判定轮次结束
+	CompleteThreshold float64 `json:"complete_threshold"`
+	// 分类模型概率在融合中的权重(0~1), 无分类模型时忽略
+	ClassifierWeight float64 `json:"classifier_weight"`
+	// 按用户轮内停顿时长自适应等待时长
+	AdaptivePause bool `json:"adaptive_pause"`
+	// 停顿时长 EMA 平滑系数, 以及等待时长相对停顿 EMA 的倍数
+	PauseEMAAlpha float64 `json:"pause_ema_alpha"`
+	PauseFactor   float64 `json:"pause_factor"`
+}
+
+// NewTurnAnalyzerArgs 创建一个新的TurnAnalyzerArgs实例，带有默认值
+func NewTurnAnalyzerArgs() *TurnAnalyzerArgs {
+	return &TurnAnalyzerArgs{
+		MinWaitSecs:       0.2,
+		MaxWaitSecs:       2.0,
+		CompleteThreshold: 0.8,
+		ClassifierWeight:  0.6,
+		AdaptivePause:     true,
+		PauseEMAAlpha:     0.3,
+		PauseFactor:       1.2,
+	}
+}
+
+// WithMinWaitSecs 设置最短等待时长
+func (args *TurnAnalyzerArgs) WithMinWaitSecs(secs float64) *TurnAnalyzerArgs {
+	args.MinWaitSecs = secs
+	return args
+}
+
+// WithMaxWaitSecs 设置最长等待时长
+func (args *TurnAnalyzerArgs) WithMaxWaitSecs(secs float64) *TurnAnalyzerArgs {
+	args.MaxWaitSecs = secs
+	return args
+}
+
+// WithCompleteThreshold 设置说完概率阈值
+func (args *TurnAnalyzerArgs) WithCompleteThreshold(threshold float64) *TurnAnalyzerArgs {
+	args.CompleteThreshold = threshold
+	return args
+}
+
+// WithClassifierWeight 设置分类模型权重
+func (args *TurnAnalyzerArgs) WithClassifierWeight(weight float64) *TurnAnalyzerArgs {
+	args.ClassifierWeight = weight
+	return args
+}
+
+// WithAdaptivePause 设置是否按停顿自适应等待时长
+func (args *TurnAnalyzerArgs) WithAdaptivePause(adaptive bool) *TurnAnalyzerArgs {
+	args.AdaptivePause = adaptive
+	return args
+}
+
+// WithPauseEMAAlpha 设置停顿时长 EMA 平滑系数
+func (args *TurnAnalyzerArgs) WithPauseEMAAlpha(alpha float64) *TurnAnalyzerArgs {
+	args.PauseEMAAlpha = alpha
+	return args
+}
+
+// WithPauseFactor 设置等待时长相对停顿 EMA 的倍数
+func (args *TurnAnalyzerArgs) WithPauseFactor(factor float64) *TurnAnalyzerArgs {
+	args.PauseFactor = factor
+	return args
+}
@@ -10,6 +10,19 @@ type VADAnalyzerArgs struct {
 	StartSecs   float64 `json:"start_secs"`
 	StopSecs    float64 `json:"stop_secs"`
 	BufferSecs  int     `json:"buffer_secs"`
+	// 语音段前后填充: 开始说话前的音频(含判定开始的 Starting 窗口)和结束判定后继续附加的音频时长
+	PreRollSecs  float64 `json:"pre_roll_secs"`
+	PostRollSecs float64 `json:"post_roll_secs"`
+	// 语音段最短时长(短于此丢弃, 如咳嗽), 最长时长(超出强制切分, IsFinal), 0 表示不限制
+	MinSpeechSecs float64 `json:"min_speech_secs"`
+	MaxSpeechSecs float64 `json:"max_speech_secs"`
+	// 滞回阈值: 置信度 >= StartThreshold 判为开始说话, 说话中置信度 >= StopThreshold 仍判为说话
+	StartThreshold float32 `json:"start_threshold"`
+	StopThreshold  float32 `json:"stop_threshold"`
+	// 自适应校准: 会话开始 CalibrationSecs 内静音窗口的置信度统计噪声水平,
+	// 噪声水平 + CalibrationMargin 高于阈值时提高阈值; 0 表示不校准
+	CalibrationSecs   float64 `json:"calibration_secs"`
+	CalibrationMargin float32 `json:"calibration_margin"`
 }
 
 // NewVADAnalyzerArgs 创建一个新的VADAnalyzerArgs实例，带有默认值
@@ -21,6 +34,16 @@ func NewVADAnalyzerArgs() *VADAnalyzerArgs {
 		StartSecs:   0.032, // default use SileroVAD 32ms start once for 16000 samples, 512 frames per second, accumulate 1 times
 		StopSecs:    0.32,  // default use SileroVAD  32ms stop once for 16000 samples, 512 frames per second, accumulate 10 times
 		BufferSecs:  3,     // buffer samples size = BufferSecs * SampleRate
+
+		PreRollSecs:   0,
+		PostRollSecs:  0,
+		MinSpeechSecs: 0,
+		MaxSpeechSecs: 0,
+
+		StartThreshold:    0.5,
+		StopThreshold:     0.35,
+		CalibrationSecs:   0,
+		CalibrationMargin: 0.2,
 	}
 }
 
@@ -53,3 +76,46 @@ func (args *VADAnalyzerArgs) WithStopSecs(stopSecs float64) *VADAnalyzerArgs {
 	args.StopSecs = stopSecs
 	return args
 }
+
+// WithPreRollSecs 设置语音段前填充时长
+func (args *VADAnalyzerArgs) WithPreRollSecs(preRollSecs float64) *VADAnalyzerArgs {
+	args.PreRollSecs = preRollSecs
+	return args
+}
+
+// WithPostRollSecs 设置语音段后填充时长
+func (args *VADAnalyzerArgs) WithPostRollSecs(postRollSecs float64) *VADAnalyzerArgs {
+	args.PostRollSecs = postRollSecs
+	return args
+}
+
+// WithMinSpeechSecs 设置语音段最短时长
+func (args *VADAnalyzerArgs) WithMinSpeechSecs(minSpeechSecs float64) *VADAnalyzerArgs {
+	args.MinSpeechSecs = minSpeechSecs
+	return args
+}
+
+// WithMaxSpeechSecs 设置语音段最长时长
+func (args *VADAnalyzerArgs) WithMaxSpeechSecs(maxSpeechSecs float64) *VADAnalyzerArgs {
+	args.MaxSpeechSecs = maxSpeechSecs
+	return args
+}
+
+// WithThresholds 设置开始/停止说话的置信度阈值
+func (args *VADAnalyzerArgs) WithThresholds(startThreshold, stopThreshold float32) *VADAnalyzerArgs {
+	args.StartThreshold = startThreshold
+	args.StopThreshold = stopThreshold
+	return args
+}
+
+// WithCalibrationSecs 设置自适应校准时长
+func (args *VADAnalyzerArgs) WithCalibrationSecs(calibrationSecs float64) *VADAnalyzerArgs {
+	args.CalibrationSecs = calibrationSecs
+	return args
+}
+
+// WithCalibrationMargin 设置自适应校准时阈值高于噪声水平的余量
+func (args *VADAnalyzerArgs) WithCalibrationMargin(calibrationMargin float32) *VADAnalyzerArgs {
+	args.CalibrationMargin = calibrationMargin
+	return args
+}
@@ -0,0 +1,72 @@
+package params
+
+import (
+	"fmt"
+
+	"achatbot/pkg/consts"
+)
+
+// WakeWordArgs 唤醒词门控参数
+type WakeWordArgs struct {
+	// 唤醒词, 需按 KWS 模型的建模单元切分好(如 "x iǎo ài t óng x ué @小爱同学"), 为空时使用模型目录的 keywords.txt
+	Keywords []string `json:"keywords"`
+	// 唤醒后等待用户开始说话的最长时长, 超时回到休眠
+	ListenTimeoutSecs float64 `json:"listen_timeout_secs"`
+	// 用户说完一轮(UserStoppedSpeakingFrame)后是否立即回到休眠, 否则等待下一轮直到超时
+	CloseAfterTurn bool `json:"close_after_turn"`
+	// 唤醒词所在语音段中唤醒词之后的语音不短于此时长时, 视为一轮指令(如 "小爱同学, 现在几点")
+	MinCommandSecs float64 `json:"min_command_secs"`
+	SampleRate     int     `json:"sample_rate"`
+	SampleWidth    int     `json:"sample_width"`
+}
+
+// NewWakeWordArgs 创建一个新的WakeWordArgs实例，带有默认值
+func NewWakeWordArgs() *WakeWordArgs {
+	return &WakeWordArgs{
+		ListenTimeoutSecs: 8.0,
+		CloseAfterTurn:    true,
+		MinCommandSecs:    0.5,
+		SampleRate:        consts.DefaultRate,
+		SampleWidth:       consts.DefaultSampleWidth,
+	}
+}
+
+// WithKeywords 设置唤醒词
+func (args *WakeWordArgs) WithKeywords(keywords ...string) *WakeWordArgs {
+	args.Keywords = keywords
+	return args
+}
+
+// WithListenTimeoutSecs 设置唤醒后的监听超时时长
+func (args *WakeWordArgs) WithListenTimeoutSecs(secs float64) *WakeWordArgs {
+	args.ListenTimeoutSecs = secs
+	return args
+}
+
+// WithCloseAfterTurn 设置一轮结束后是否回到休眠
+func (args *WakeWordArgs) WithCloseAfterTurn(closeAfterTurn bool) *WakeWordArgs {
+	args.CloseAfterTurn = closeAfterTurn
+	return args
+}
+
+// WithMinCommandSecs 设置唤醒词之后视为指令的最短语音时长
+func (args *WakeWordArgs) WithMinCommandSecs(secs float64) *WakeWordArgs {
+	args.MinCommandSecs = secs
+	return args
+}
+
+// Validate 校验参数
+func (args *WakeWordArgs) Validate() error {
+	if args.ListenTimeoutSecs <= 0 {
+		return fmt.Errorf("wake word listen_timeout_secs %.2f must > 0", args.ListenTimeoutSecs)
+	}
+	if args.MinCommandSecs < 0 {
+		return fmt.Errorf("wake word min_command_secs %.2f must >= 0", args.MinCommandSecs)
+	}
+	return nil
+}
+
+func (args *WakeWordArgs) String() string {
+	return fmt.Sprintf("WakeWordArgs(keywords: %v listen_timeout_secs: %.2f close_after_turn: %t min_command_secs: %.2f)",
+		args.Keywords, args.ListenTimeoutSecs, args.CloseAfterTurn, args.MinCommandSecs)
+}
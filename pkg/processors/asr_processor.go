@@ -1,17 +1,41 @@
 package processors
 
 import (
+	"slices"
+	"time"
+
 	"github.com/weedge/pipeline-go/pkg/frames"
 	"github.com/weedge/pipeline-go/pkg/logger"
 	"github.com/weedge/pipeline-go/pkg/processors"
 
 	"achatbot/pkg/common"
+	"achatbot/pkg/services/metrics"
+	"achatbot/pkg/services/tracing"
+	"achatbot/pkg/types"
 	achatbot_frames "achatbot/pkg/types/frames"
+	"achatbot/pkg/utils"
 )
 
 type ASRProcessor struct {
 	*processors.AsyncFrameProcessor
 	provider common.IASRProvider
+
+	// speaker verification result of the following audio
+	speaker *achatbot_frames.SpeakerVerifiedFrame
+
+	// language routing: detected language of the following audio -> asr provider
+	languageProviders map[string]common.IASRProvider
+	language          string
+
+	// session asr contextual biasing: hotwords applied to the providers and post-recognition correction
+	session    *common.Session
+	pinyinDict *utils.PinyinDict
+	hotwords   []types.Hotword
+
+	// last transcribed speech id
+	speechID int
+
+	tracer *tracing.Tracer
 }
 
 func NewASRProcessor(provider common.IASRProvider) *ASRProcessor {
@@ -26,6 +50,48 @@ func (p *ASRProcessor) WithPassRawAudio(passRawAudio bool) *ASRProcessor {
 	return p
 }
 
+// WithLanguageProvider routes audio of the detected language (LanguageDetectedFrame) to the provider,
+// other languages use the default provider
+func (p *ASRProcessor) WithLanguageProvider(language string, provider common.IASRProvider) *ASRProcessor {
+	if p.languageProviders == nil {
+		p.languageProviders = make(map[string]common.IASRProvider)
+	}
+	p.languageProviders[language] = provider
+	return p
+}
+
+// WithSession biases recognition with the session hotwords and replacement dictionary,
+// which are updatable at runtime and take effect from the next user speech
+func (p *ASRProcessor) WithSession(session *common.Session) *ASRProcessor {
+	p.session = session
+	return p
+}
+
+// WithTracer traces each transcription as a child span of the turn propagated by the audio frame
+func (p *ASRProcessor) WithTracer(tracer *tracing.Tracer) *ASRProcessor {
+	p.tracer = tracer
+	return p
+}
+
+// WithPinyinDict enables pinyin-aware fuzzy correction of chinese hotwords
+func (p *ASRProcessor) WithPinyinDict(dict *utils.PinyinDict) *ASRProcessor {
+	p.pinyinDict = dict
+	return p
+}
+
+// GetAudioInFormat returns the audio format which asr provider expects
+func (p *ASRProcessor) GetAudioInFormat() *types.AudioFormat {
+	if declarer, ok := p.provider.(common.IAudioFormatDeclarer); ok {
+		return declarer.GetAudioInFormat()
+	}
+	return nil
+}
+
+// GetAudioOutFormat asr outputs text, pass raw audio through
+func (p *ASRProcessor) GetAudioOutFormat() *types.AudioFormat {
+	return nil
+}
+
 func (p *ASRProcessor) Start(frame *frames.StartFrame) {
 	logger.Info("ASRProcessor Start")
 }
@@ -34,8 +100,8 @@ func (p *ASRProcessor) Stop(frame *frames.EndFrame) {
 	logger.Info("ASRProcessor Stop")
 }
 
+// Cancel the providers are owned by the caller (e.g. returned to the pool when the session ends), not released here
 func (p *ASRProcessor) Cancel(frame *frames.CancelFrame) {
-	p.provider.Release()
 	logger.Info("ASRProcessor Cancel")
 }
 
@@ -58,22 +124,125 @@ func (p *ASRProcessor) ProcessFrame(frame frames.Frame, direction processors.Fra
 		if p.PassRawAudio() {
 			p.QueueFrame(f, direction)
 		}
-		text := p.provider.Transcribe(f.Audio)
-		p.PushDownstreamFrame(frames.NewTextFrame(text))
+		p.transcribeFrame(f, f.Audio, 0)
 	case *achatbot_frames.VADStateAudioRawFrame:
 		if p.PassRawAudio() {
 			p.QueueFrame(f, direction)
 		}
-		text := p.provider.Transcribe(f.Audio)
-		p.PushDownstreamFrame(frames.NewTextFrame(text))
+		p.transcribeFrame(f, f.Audio, f.SpeechID)
+	case *achatbot_frames.SpeakerVerifiedFrame:
+		p.speaker = f
+		p.QueueFrame(f, direction)
+	case *achatbot_frames.LanguageDetectedFrame:
+		p.language = f.Language
+		p.QueueFrame(f, direction)
 	case *achatbot_frames.AnimationAudioRawFrame:
 		if p.PassRawAudio() {
 			p.QueueFrame(f, direction)
 		}
-		text := p.provider.Transcribe(f.Audio)
-		p.PushDownstreamFrame(frames.NewTextFrame(text))
+		p.transcribeFrame(f, f.Audio, 0)
 	default:
 		p.QueueFrame(f, direction)
 	}
+}
+
+// transcribeFrame transcribes the audio of the frame in a span, the trace context is propagated to the transcription
+func (p *ASRProcessor) transcribeFrame(frame frames.Frame, audio []byte, speechID int) {
+	parent := p.tracer.ParentContext(frame)
+	span := p.tracer.StartSpan(tracing.SpanASR, parent)
+	result := p.transcribe(audio)
+	span.SetAttribute("asr.audio_bytes", len(audio))
+	span.SetAttribute("asr.text", result.Text)
+	span.SetAttribute("asr.language", result.Language)
+	span.End()
+
+	p.pushTranscription(result, speechID, frame, parent)
+}
+
+// transcribe transcribes with the provider of the detected language, then corrects with the session asr context
+func (p *ASRProcessor) transcribe(audio []byte) *types.ASRResult {
+	defer metrics.ObserveProcessing(metrics.ProcessorASR, time.Now())
+
+	asrContext := p.applyASRContext()
+
+	provider := p.provider
+	if languageProvider, ok := p.languageProviders[p.language]; ok {
+		provider = languageProvider
+	}
+	var result *types.ASRResult
+	if richProvider, ok := provider.(common.IRichASRProvider); ok {
+		result = richProvider.TranscribeResult(audio)
+	} else {
+		result = &types.ASRResult{Text: provider.Transcribe(audio)}
+	}
+
+	if asrContext != nil {
+		result.Text = utils.CorrectHotwords(result.Text, asrContext.Hotwords, asrContext.Replacements, p.pinyinDict)
+	}
+	return result
+}
+
+// applyASRContext sets the updated session hotwords to the providers which support hotwords
+func (p *ASRProcessor) applyASRContext() *types.ASRContext {
+	if p.session == nil {
+		return nil
+	}
+	asrContext := p.session.GetASRContext()
+	if slices.Equal(asrContext.Hotwords, p.hotwords) {
+		return asrContext
+	}
 
+	p.hotwords = asrContext.Hotwords
+	providers := []common.IASRProvider{p.provider}
+	for _, provider := range p.languageProviders {
+		providers = append(providers, provider)
+	}
+	for _, provider := range providers {
+		hotwordsProvider, ok := provider.(common.IHotwordsASRProvider)
+		if !ok {
+			continue
+		}
+		if err := hotwordsProvider.SetHotwords(asrContext.Hotwords); err != nil {
+			logger.Warnf("%s set hotwords to %s err: %v, only correct after recognition", p.Name(), provider.Name(), err)
+		}
+	}
+	return asrContext
+}
+
+// pushTranscription pushes TranscriptionFrame with the verified speaker and detected language,
+// empty and noise-only (e.g. only punctuation) results are dropped;
+// speechID is the VAD speech id of the audio, 0 for aggregated audio which counts speech by the processor;
+// the metadata of the source audio frame is propagated to the transcription
+func (p *ASRProcessor) pushTranscription(result *types.ASRResult, speechID int, source frames.Frame, traceContext tracing.SpanContext) {
+	speaker, language := p.speaker, p.language
+	p.speaker = nil
+	p.language = ""
+
+	if !utils.IsMeaningfulText(result.Text) {
+		logger.Debugf("%s drop empty transcription: %s", p.Name(), result)
+		return
+	}
+	if speechID == 0 {
+		speechID = p.speechID + 1
+	}
+	p.speechID = speechID
+
+	frame := achatbot_frames.NewTranscriptionFrame(result.Text, "", 0)
+	frame.Words = result.Words
+	frame.Language = result.Language
+	if language != "" {
+		frame.Language = language
+	}
+	frame.Emotion, frame.Event = result.Emotion, result.Event
+	frame.SpeechID = speechID
+	if p.session != nil {
+		frame.UserID = p.session.GetSessionID()
+	}
+	if speaker != nil {
+		frame.SpeakerID, frame.SpeakerScore = speaker.SpeakerID, speaker.Score
+	}
+	// e.g. the saved audio file of the speech
+	achatbot_frames.CopyFrameMetadata(frame, source)
+	tracing.InjectFrame(frame, traceContext)
+	p.PushDownstreamFrame(frame)
 }
@@ -0,0 +1,70 @@
+package processors
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weedge/pipeline-go/pkg/frames"
+	"github.com/weedge/pipeline-go/pkg/processors"
+
+	"achatbot/pkg/common"
+	"achatbot/pkg/types"
+)
+
+// fakeRichASRProvider 返回固定识别结果, 记录设置的热词
+type fakeRichASRProvider struct {
+	result   types.ASRResult
+	hotwords []types.Hotword
+	released bool
+}
+
+func (p *fakeRichASRProvider) Transcribe(audio []byte) string { return p.result.Text }
+func (p *fakeRichASRProvider) TranscribeResult(audio []byte) *types.ASRResult {
+	result := p.result
+	return &result
+}
+func (p *fakeRichASRProvider) SetHotwords(hotwords []types.Hotword) error {
+	p.hotwords = hotwords
+	return nil
+}
+func (p *fakeRichASRProvider) Warmup()      {}
+func (p *fakeRichASRProvider) Name() string { return "fake" }
+func (p *fakeRichASRProvider) Reset() error { return nil }
+func (p *fakeRichASRProvider) Release() error {
+	p.released = true
+	return nil
+}
+
+func TestASRProcessorTranscribe(t *testing.T) {
+	provider := &fakeRichASRProvider{result: types.ASRResult{
+		Text:    "我在用蓝天的模型",
+		Words:   []types.WordTimestamp{{Word: "我", Start: 0, End: 0.2}},
+		Emotion: "happy",
+	}}
+	session := common.NewSession("s1", nil)
+	p := NewASRProcessor(provider).WithSession(session)
+
+	result := p.transcribe([]byte{0})
+	assert.Equal(t, "我在用蓝天的模型", result.Text)
+	assert.Equal(t, "happy", result.Emotion)
+	assert.Len(t, result.Words, 1)
+	assert.Nil(t, provider.hotwords)
+
+	// 会话运行时更新热词和替换词典, 下一段语音生效
+	hotwords := []types.Hotword{{Phrase: "澜天", Boost: 2}}
+	session.SetHotwords(hotwords)
+	session.SetASRReplacements(map[string]string{"蓝天": "澜天"})
+	result = p.transcribe([]byte{0})
+	assert.Equal(t, "我在用澜天的模型", result.Text)
+	assert.Equal(t, hotwords, provider.hotwords)
+}
+
+func TestASRProcessorCancelKeepsPooledProviders(t *testing.T) {
+	provider, enProvider := &fakeRichASRProvider{}, &fakeRichASRProvider{}
+	p := NewASRProcessor(provider).WithLanguageProvider(types.LanguageEn, enProvider)
+
+	// 取消会话(如会话超时)后, 池化的提供者归还到池中复用, 不能被处理器释放
+	p.ProcessFrame(frames.NewCancelFrame(), processors.FrameDirectionDownstream)
+	assert.False(t, provider.released)
+	assert.False(t, enProvider.released)
+}
@@ -2,7 +2,9 @@ package processors
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -13,10 +15,25 @@ import (
 	"achatbot/pkg/common"
 	"achatbot/pkg/consts"
 	"achatbot/pkg/params"
+	"achatbot/pkg/types"
 	achatbot_frames "achatbot/pkg/types/frames"
 	"achatbot/pkg/utils"
 )
 
+// audioOutChunk is the audio chunk to write out with the text spoken in it,
+// turnEnd marks the end of the bot reply (no audio)
+type audioOutChunk struct {
+	audio   []byte
+	text    string
+	turnEnd bool
+}
+
+// playbackTimer emits the chunk text when the client starts playing the chunk
+type playbackTimer struct {
+	timer   *time.Timer
+	turnEnd bool
+}
+
 // AudioCameraOutputProcessor processes audio and camera output
 type AudioCameraOutputProcessor struct {
 	*processors.AsyncFrameProcessor
@@ -24,7 +41,7 @@ type AudioCameraOutputProcessor struct {
 
 	ctx            context.Context
 	cancel         context.CancelFunc
-	audioOutQueue  chan []byte
+	audioOutQueue  chan *audioOutChunk
 	audioOutTask   *sync.WaitGroup
 	cameraOutQueue chan frames.Frame
 	cameraOutTask  *sync.WaitGroup
@@ -53,6 +70,15 @@ type AudioCameraOutputProcessor struct {
 
 	// Transport writer
 	transportWriter common.ITransportWriter
+
+	// Client playback clock: audio written out is played back to back,
+	// the spoken text is emitted as its audio is played, the not played text is dropped on interruption
+	playbackMu     sync.Mutex
+	playbackEnd    time.Time
+	playbackSeq    int
+	playbackTimers map[int]*playbackTimer
+	// played text of the current bot reply
+	spokenText strings.Builder
 }
 
 // NewAudioCameraOutputProcessor creates a new AudioCameraOutputProcessor
@@ -64,7 +90,7 @@ func NewAudioCameraOutputProcessor(name string, params *params.AudioCameraParams
 		params:                     params,
 		ctx:                        ctx,
 		cancel:                     cancel,
-		audioOutQueue:              make(chan []byte, 100), // buffer size
+		audioOutQueue:              make(chan *audioOutChunk, 100), // buffer size
 		audioOutTask:               &sync.WaitGroup{},
 		cameraOutQueue:             make(chan frames.Frame, 100), // buffer size
 		cameraOutTask:              &sync.WaitGroup{},
@@ -74,6 +100,7 @@ func NewAudioCameraOutputProcessor(name string, params *params.AudioCameraParams
 		transportWriter:            params.TransportWriter,
 		botSpeakingSendPeriodMS:    consts.DefaultBotSpeakingSendPeriodMS,
 		botSpeakingSendPeriodMSAcc: 0,
+		playbackTimers:             make(map[int]*playbackTimer),
 	}
 
 	p.botSpeakingSendPeriodMSBuf = p.botSpeakingSendPeriodMS * params.AudioOutChannels * params.AudioOutSampleRate * params.AudioOutSampleWidth / 1000
@@ -87,6 +114,19 @@ func NewAudioCameraOutputProcessor(name string, params *params.AudioCameraParams
 	return p
 }
 
+// GetAudioInFormat returns the audio format written to the transport
+func (p *AudioCameraOutputProcessor) GetAudioInFormat() *types.AudioFormat {
+	if !p.params.AudioOutEnabled {
+		return nil
+	}
+	return p.params.GetAudioOutFormat()
+}
+
+// GetAudioOutFormat output processor is the pipeline sink
+func (p *AudioCameraOutputProcessor) GetAudioOutFormat() *types.AudioFormat {
+	return nil
+}
+
 // Start starts the processor
 func (p *AudioCameraOutputProcessor) Start(frame *frames.StartFrame) {
 	// Create media threads queues and task
@@ -167,7 +207,12 @@ func (p *AudioCameraOutputProcessor) ProcessFrame(frame frames.Frame, direction
 			logger.Error(fmt.Sprintf("Error Write %T", f), "error", err)
 		}
 	case *frames.AudioRawFrame:
-		p.handleAudio(f)
+		p.handleAudio(f, nil)
+	case *achatbot_frames.TTSAudioRawFrame:
+		p.handleAudio(f.AudioRawFrame, f.Words)
+	case *achatbot_frames.TurnEndFrame:
+		p.handleTurnEnd()
+		p.QueueFrame(f, direction)
 	case *frames.ImageRawFrame:
 		p.handleImage(f)
 	case *achatbot_frames.SpriteFrame:
@@ -194,6 +239,16 @@ func (p *AudioCameraOutputProcessor) handleInterruptions(frame frames.Frame) {
 		if p.botSpeaking {
 			p.botStoppedSpeaking()
 		}
+		// bot audio is interrupted, drop the not played echo reference
+		if p.params.EchoReference != nil {
+			p.params.EchoReference.Reset()
+		}
+		// the user only heard the played part of the bot reply
+		if spokenText, interrupted := p.interruptPlayback(); interrupted {
+			logger.Infof("%s bot speech interrupted, spoken text: %s", p.Name(), spokenText)
+			p.PushFrame(achatbot_frames.NewBotSpeechInterruptedFrame(spokenText), processors.FrameDirectionUpstream)
+			p.writeBotTranscriptEvent(spokenText, true)
+		}
 	}
 }
 
@@ -211,16 +266,25 @@ func (p *AudioCameraOutputProcessor) botStoppedSpeaking() {
 	p.PushFrame(achatbot_frames.NewBotStoppedSpeakingFrame(), processors.FrameDirectionUpstream)
 }
 
-// handleAudio handles audio frames
-func (p *AudioCameraOutputProcessor) handleAudio(frame *frames.AudioRawFrame) {
+// handleAudio handles audio frames, words (relative to the start of the audio) are the text spoken in the audio
+func (p *AudioCameraOutputProcessor) handleAudio(frame *frames.AudioRawFrame, words []types.WordTimestamp) {
 	if !p.params.AudioOutEnabled {
 		return
 	}
 
 	audio := frame.Audio
+	bytesPerSec := float32(frame.SampleRate * frame.NumChannels * frame.SampleWidth)
+	wordIdx := 0
 	for i := 0; i < len(audio); i += p.audioChunkSize {
 		end := min(i+p.audioChunkSize, len(audio))
-		chunk := audio[i:end]
+		chunk := &audioOutChunk{audio: audio[i:end]}
+
+		// the words start in the chunk, the last chunk takes the rest
+		var text strings.Builder
+		for ; wordIdx < len(words) && (end == len(audio) || bytesPerSec <= 0 || words[wordIdx].Start*bytesPerSec < float32(end)); wordIdx++ {
+			text.WriteString(words[wordIdx].Word)
+		}
+		chunk.text = text.String()
 
 		// Add chunk to queue
 		select {
@@ -229,7 +293,7 @@ func (p *AudioCameraOutputProcessor) handleAudio(frame *frames.AudioRawFrame) {
 			return
 		}
 
-		p.botSpeakingSendPeriodMSAcc += len(chunk)
+		p.botSpeakingSendPeriodMSAcc += len(chunk.audio)
 		if p.botSpeakingSendPeriodMSAcc >= p.botSpeakingSendPeriodMSBuf {
 			p.botSpeakingSendPeriodMSAcc -= p.botSpeakingSendPeriodMSBuf
 			// Push bot speaking frame upstream if bot is speaking,
@@ -239,6 +303,17 @@ func (p *AudioCameraOutputProcessor) handleAudio(frame *frames.AudioRawFrame) {
 	}
 }
 
+// handleTurnEnd queues the bot reply end after the reply audio
+func (p *AudioCameraOutputProcessor) handleTurnEnd() {
+	if !p.params.AudioOutEnabled {
+		return
+	}
+	select {
+	case p.audioOutQueue <- &audioOutChunk{turnEnd: true}:
+	case <-p.ctx.Done():
+	}
+}
+
 // audioOutTaskHandler handles audio output task
 func (p *AudioCameraOutputProcessor) audioOutTaskHandler() {
 	defer p.audioOutTask.Done()
@@ -249,10 +324,15 @@ func (p *AudioCameraOutputProcessor) audioOutTaskHandler() {
 			if chunk == nil {
 				return
 			}
-			err := p.transportWriter.WriteRawAudio(chunk)
-			if err != nil {
-				logger.Error(fmt.Sprintf("%s audio_out_task_handler error", p.Name()), "error", err)
+			if len(chunk.audio) > 0 {
+				err := p.transportWriter.WriteRawAudio(chunk.audio)
+				if err != nil {
+					logger.Error(fmt.Sprintf("%s audio_out_task_handler error", p.Name()), "error", err)
+					continue
+				}
+				p.writeEchoReference(chunk.audio)
 			}
+			p.schedulePlayback(chunk)
 		case <-p.ctx.Done():
 			logger.Info(fmt.Sprintf("%s audio_out_task_handler cancelled", p.Name()))
 			return
@@ -260,6 +340,116 @@ func (p *AudioCameraOutputProcessor) audioOutTaskHandler() {
 	}
 }
 
+// schedulePlayback estimates when the client plays the written chunk (back to back after the previous chunks),
+// the chunk text is emitted when its audio starts playing
+func (p *AudioCameraOutputProcessor) schedulePlayback(chunk *audioOutChunk) {
+	p.playbackMu.Lock()
+	defer p.playbackMu.Unlock()
+
+	now := time.Now()
+	if p.playbackEnd.Before(now) {
+		p.playbackEnd = now
+	}
+	playAt := p.playbackEnd
+	if bytesPerSec := p.params.GetAudioOutFormat().BytesPerSecond(); bytesPerSec > 0 {
+		p.playbackEnd = p.playbackEnd.Add(time.Duration(len(chunk.audio)) * time.Second / time.Duration(bytesPerSec))
+	}
+	if chunk.text == "" && !chunk.turnEnd {
+		return
+	}
+
+	p.playbackSeq++
+	seq := p.playbackSeq
+	p.playbackTimers[seq] = &playbackTimer{
+		timer:   time.AfterFunc(playAt.Sub(now), func() { p.played(seq, chunk) }),
+		turnEnd: chunk.turnEnd,
+	}
+}
+
+// played emits the spoken text of the played chunk, the bot reply is finished at the turn end
+func (p *AudioCameraOutputProcessor) played(seq int, chunk *audioOutChunk) {
+	p.playbackMu.Lock()
+	if _, ok := p.playbackTimers[seq]; !ok {
+		// cancelled by interruption
+		p.playbackMu.Unlock()
+		return
+	}
+	delete(p.playbackTimers, seq)
+	if chunk.turnEnd {
+		p.spokenText.Reset()
+	} else {
+		p.spokenText.WriteString(chunk.text)
+	}
+	p.playbackMu.Unlock()
+
+	if chunk.text != "" {
+		p.PushFrame(achatbot_frames.NewBotSpokenTextFrame(chunk.text), processors.FrameDirectionUpstream)
+		p.writeBotTranscriptEvent(chunk.text, false)
+	}
+}
+
+// interruptPlayback drops the not played audio and text, returns the played text of the current bot reply
+// and whether the reply is cut off
+func (p *AudioCameraOutputProcessor) interruptPlayback() (string, bool) {
+	interrupted := false
+	for drained := false; !drained; {
+		select {
+		case chunk := <-p.audioOutQueue:
+			if chunk == nil {
+				drained = true
+			} else if chunk.text != "" {
+				interrupted = true
+			}
+		default:
+			drained = true
+		}
+	}
+
+	p.playbackMu.Lock()
+	defer p.playbackMu.Unlock()
+	for seq, pending := range p.playbackTimers {
+		if pending.timer.Stop() && !pending.turnEnd {
+			interrupted = true
+		}
+		delete(p.playbackTimers, seq)
+	}
+	p.playbackEnd = time.Now()
+	spokenText := p.spokenText.String()
+	p.spokenText.Reset()
+	return spokenText, interrupted
+}
+
+// writeBotTranscriptEvent sends the bot spoken text event to the client
+func (p *AudioCameraOutputProcessor) writeBotTranscriptEvent(text string, interrupted bool) {
+	message, err := json.Marshal(types.NewBotTranscriptEvent(text, interrupted))
+	if err != nil {
+		logger.Error(fmt.Sprintf("%s marshal bot transcript event error", p.Name()), "error", err)
+		return
+	}
+	if err := p.transportWriter.WriteFrame(achatbot_frames.NewTransportMessageFrame(message)); err != nil {
+		logger.Error(fmt.Sprintf("%s write bot transcript event error", p.Name()), "error", err)
+	}
+}
+
+// writeEchoReference writes the bot output audio as echo reference for the input processor
+func (p *AudioCameraOutputProcessor) writeEchoReference(chunk []byte) {
+	if p.params.EchoReference == nil {
+		return
+	}
+
+	// reference must be the same format as client audio input
+	inFormat, outFormat := p.params.GetAudioInFormat(), p.params.GetAudioOutFormat()
+	if !inFormat.Equal(outFormat) {
+		converted, err := utils.ConvertAudioFormat(chunk, outFormat, inFormat)
+		if err != nil {
+			logger.Error(fmt.Sprintf("%s convert echo reference error", p.Name()), "error", err)
+			return
+		}
+		chunk = converted
+	}
+	p.params.EchoReference.WriteReference(chunk)
+}
+
 // handleImage handles image frames
 func (p *AudioCameraOutputProcessor) handleImage(frame *frames.ImageRawFrame) {
 	if !p.params.CameraOutEnabled {
@@ -0,0 +1,79 @@
+package processors
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weedge/pipeline-go/pkg/frames"
+
+	"achatbot/pkg/params"
+	"achatbot/pkg/types"
+)
+
+// fakeTransportWriter 记录写出的音频和帧
+type fakeTransportWriter struct {
+	mu     sync.Mutex
+	audio  []byte
+	frames []frames.Frame
+}
+
+func (w *fakeTransportWriter) WriteRawAudio(data []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.audio = append(w.audio, data...)
+	return nil
+}
+
+func (w *fakeTransportWriter) WriteFrame(frame frames.Frame) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.frames = append(w.frames, frame)
+	return nil
+}
+
+func newTestAudioOutputProcessor(writer *fakeTransportWriter) *AudioCameraOutputProcessor {
+	p := params.NewAudioCameraParams().WithTransportWriter(writer)
+	p.WithAudioOutEnabled(true).WithAudioOutSampleRate(16000).WithAudioOutChannels(1).
+		WithAudioOutSampleWidth(2).WithAudioOut10msChunks(10)
+	return NewAudioCameraOutputProcessor("output", p)
+}
+
+func TestAudioCameraOutputProcessorSpokenText(t *testing.T) {
+	writer := &fakeTransportWriter{}
+	p := newTestAudioOutputProcessor(writer)
+
+	// 1s 音频切成 10 个 100ms 分片, 单词按开始时间落入分片
+	words := []types.WordTimestamp{
+		{Word: "hello ", Start: 0, End: 0.5},
+		{Word: "world", Start: 0.5, End: 1},
+	}
+	p.handleAudio(frames.NewAudioRawFrame(make([]byte, 32000), 16000, 1, 2), words)
+	assert.Len(t, p.audioOutQueue, 10)
+	first := <-p.audioOutQueue
+	assert.Equal(t, "hello ", first.text)
+
+	// 第一个分片立即播放, 其余未播放的文本在打断时丢弃
+	p.schedulePlayback(first)
+	assert.Eventually(t, func() bool {
+		p.playbackMu.Lock()
+		defer p.playbackMu.Unlock()
+		return p.spokenText.String() == "hello "
+	}, time.Second, 10*time.Millisecond)
+
+	spokenText, interrupted := p.interruptPlayback()
+	assert.True(t, interrupted)
+	assert.Equal(t, "hello ", spokenText)
+	assert.Len(t, p.audioOutQueue, 0)
+
+	// 回复播放完成后不算打断
+	p.schedulePlayback(&audioOutChunk{turnEnd: true})
+	assert.Eventually(t, func() bool {
+		p.playbackMu.Lock()
+		defer p.playbackMu.Unlock()
+		return len(p.playbackTimers) == 0
+	}, time.Second, 10*time.Millisecond)
+	_, interrupted = p.interruptPlayback()
+	assert.False(t, interrupted)
+}
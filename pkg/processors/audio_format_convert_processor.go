@@ -0,0 +1,71 @@
+package processors
+
+import (
+	"github.com/weedge/pipeline-go/pkg/frames"
+	"github.com/weedge/pipeline-go/pkg/logger"
+	"github.com/weedge/pipeline-go/pkg/processors"
+
+	"achatbot/pkg/types"
+	"achatbot/pkg/utils"
+)
+
+// AudioFormatConvertProcessor 将音频帧转换为指定的输出格式(采样率, 通道数, 采样宽度),
+// 一般由 NegotiateAudioFormats 在格式不一致的处理器之间自动插入
+type AudioFormatConvertProcessor struct {
+	*processors.AsyncFrameProcessor
+	outFormat *types.AudioFormat
+}
+
+func NewAudioFormatConvertProcessor(outFormat *types.AudioFormat) *AudioFormatConvertProcessor {
+	return &AudioFormatConvertProcessor{
+		AsyncFrameProcessor: processors.NewAsyncFrameProcessor("AudioFormatConvertProcessor"),
+		outFormat:           outFormat,
+	}
+}
+
+// GetAudioInFormat accepts any audio format
+func (p *AudioFormatConvertProcessor) GetAudioInFormat() *types.AudioFormat {
+	return nil
+}
+
+// GetAudioOutFormat returns the converted audio format
+func (p *AudioFormatConvertProcessor) GetAudioOutFormat() *types.AudioFormat {
+	return p.outFormat
+}
+
+// ProcessFrame processes a frame
+func (p *AudioFormatConvertProcessor) ProcessFrame(frame frames.Frame, direction processors.FrameDirection) {
+	// call frame processor to init star frame init
+	p.AsyncFrameProcessor.WithPorcessFrameAllowPush(false).ProcessFrame(frame, direction)
+
+	switch f := frame.(type) {
+	case *frames.StartFrame:
+		p.PushFrame(f, direction)
+	case *frames.EndFrame:
+		p.PushFrame(f, direction)
+	case *frames.CancelFrame:
+		p.PushFrame(f, direction)
+	default:
+		if audioFrame := utils.GetAudioRawFrame(frame); audioFrame != nil {
+			p.convert(audioFrame)
+		}
+		p.QueueFrame(frame, direction)
+	}
+}
+
+func (p *AudioFormatConvertProcessor) convert(frame *frames.AudioRawFrame) {
+	inFormat := types.NewAudioFormat(frame.SampleRate, frame.NumChannels, frame.SampleWidth)
+	if inFormat.Equal(p.outFormat) {
+		return
+	}
+	audio, err := utils.ConvertAudioFormat(frame.Audio, inFormat, p.outFormat)
+	if err != nil {
+		logger.Errorf("%s convert %s -> %s error: %v", p.Name(), inFormat, p.outFormat, err)
+		return
+	}
+	frame.Audio = audio
+	frame.SampleRate = p.outFormat.SampleRate
+	frame.NumChannels = p.outFormat.NumChannels
+	frame.SampleWidth = p.outFormat.SampleWidth
+	frame.NumFrames = len(audio) / (p.outFormat.NumChannels * p.outFormat.SampleWidth)
+}
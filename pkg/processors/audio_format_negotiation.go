@@ -0,0 +1,55 @@
+package processors
+
+import (
+	"fmt"
+
+	"github.com/weedge/pipeline-go/pkg/logger"
+	"github.com/weedge/pipeline-go/pkg/processors"
+
+	"achatbot/pkg/common"
+	"achatbot/pkg/types"
+)
+
+// audioFormatValidator 构建 pipeline 前需要校验自身音频格式配置的处理器
+type audioFormatValidator interface {
+	ValidateAudioFormat() error
+}
+
+// NegotiateAudioFormats 按顺序遍历 pipeline 处理器声明的输入/输出音频格式(common.IAudioFormatDeclarer),
+// 上游输出格式与下游期望输入格式不一致时自动插入 AudioFormatConvertProcessor;
+// 处理器自身格式配置不合法(如客户端输入格式与 VAD 不一致)时直接返回错误
+func NegotiateAudioFormats(procs []processors.IFrameProcessor) ([]processors.IFrameProcessor, error) {
+	var curFormat *types.AudioFormat
+	negotiated := make([]processors.IFrameProcessor, 0, len(procs))
+	for _, proc := range procs {
+		if validator, ok := proc.(audioFormatValidator); ok {
+			if err := validator.ValidateAudioFormat(); err != nil {
+				return nil, fmt.Errorf("%T audio format invalid: %w", proc, err)
+			}
+		}
+
+		declarer, ok := proc.(common.IAudioFormatDeclarer)
+		if !ok {
+			negotiated = append(negotiated, proc)
+			continue
+		}
+
+		inFormat := declarer.GetAudioInFormat()
+		if inFormat != nil && curFormat != nil && !curFormat.Equal(inFormat) {
+			if err := curFormat.Validate(); err != nil {
+				return nil, fmt.Errorf("unsupported audio format %s before %T: %w", curFormat, proc, err)
+			}
+			if err := inFormat.Validate(); err != nil {
+				return nil, fmt.Errorf("%T unsupported audio in format %s: %w", proc, inFormat, err)
+			}
+			logger.Infof("insert AudioFormatConvertProcessor %s -> %s before %T", curFormat, inFormat, proc)
+			negotiated = append(negotiated, NewAudioFormatConvertProcessor(inFormat))
+		}
+		negotiated = append(negotiated, proc)
+
+		if outFormat := declarer.GetAudioOutFormat(); outFormat != nil {
+			curFormat = outFormat
+		}
+	}
+	return negotiated, nil
+}
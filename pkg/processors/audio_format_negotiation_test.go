@@ -0,0 +1,64 @@
+package processors
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weedge/pipeline-go/pkg/frames"
+	"github.com/weedge/pipeline-go/pkg/processors"
+
+	"achatbot/pkg/types"
+)
+
+// formatSinkProcessor 只声明输入音频格式的测试处理器
+type formatSinkProcessor struct {
+	*processors.AsyncFrameProcessor
+	inFormat *types.AudioFormat
+}
+
+func (p *formatSinkProcessor) GetAudioInFormat() *types.AudioFormat  { return p.inFormat }
+func (p *formatSinkProcessor) GetAudioOutFormat() *types.AudioFormat { return nil }
+
+func TestNegotiateAudioFormats(t *testing.T) {
+	source := NewAudioFormatConvertProcessor(types.NewAudioFormat(24000, 1, 2))
+	sink := &formatSinkProcessor{
+		AsyncFrameProcessor: processors.NewAsyncFrameProcessor("sink"),
+		inFormat:            types.NewAudioFormat(16000, 1, 2),
+	}
+
+	procs, err := NegotiateAudioFormats([]processors.IFrameProcessor{source, sink})
+	assert.NoError(t, err)
+	assert.Len(t, procs, 3)
+	converter, ok := procs[1].(*AudioFormatConvertProcessor)
+	assert.True(t, ok)
+	assert.True(t, converter.GetAudioOutFormat().Equal(sink.inFormat))
+
+	// 格式一致无需插入
+	sink.inFormat = types.NewAudioFormat(24000, 1, 2)
+	procs, err = NegotiateAudioFormats([]processors.IFrameProcessor{source, sink})
+	assert.NoError(t, err)
+	assert.Len(t, procs, 2)
+
+	// 不支持的格式
+	sink.inFormat = types.NewAudioFormat(16000, 1, 3)
+	_, err = NegotiateAudioFormats([]processors.IFrameProcessor{source, sink})
+	assert.Error(t, err)
+}
+
+func TestFillAudioFrameFormat(t *testing.T) {
+	inFormat := types.NewAudioFormat(16000, 1, 2)
+
+	// unset fields use the declared format
+	frame := frames.NewAudioRawFrame(make([]byte, 320), 16000, 0, 0)
+	assert.NoError(t, fillAudioFrameFormat(frame, inFormat))
+	assert.Equal(t, 1, frame.NumChannels)
+	assert.Equal(t, 2, frame.SampleWidth)
+	frame = frames.NewAudioRawFrame(make([]byte, 320), 0, 0, 0)
+	assert.NoError(t, fillAudioFrameFormat(frame, inFormat))
+	assert.Equal(t, 16000, frame.SampleRate)
+
+	// explicit conflicts
+	assert.Error(t, fillAudioFrameFormat(frames.NewAudioRawFrame(nil, 24000, 1, 2), inFormat))
+	assert.Error(t, fillAudioFrameFormat(frames.NewAudioRawFrame(nil, 16000, 2, 0), inFormat))
+	assert.Error(t, fillAudioFrameFormat(frames.NewAudioRawFrame(nil, 0, 1, 4), inFormat))
+}
@@ -0,0 +1,166 @@
+package processors
+
+import (
+	"math"
+	"time"
+
+	"github.com/weedge/pipeline-go/pkg/frames"
+	"github.com/weedge/pipeline-go/pkg/logger"
+
+	"achatbot/pkg/types"
+	achatbot_frames "achatbot/pkg/types/frames"
+	"achatbot/pkg/utils"
+)
+
+// echo handling for AudioVADInputProcessor, bot TTS audio played through speakers
+// comes back to the mic without client side AEC and should not interrupt the bot itself.
+
+// setBotSpeaking updates bot speaking state from upstream bot speaking frames
+func (p *AudioVADInputProcessor) setBotSpeaking(speaking bool) {
+	p.botSpeaking.Store(speaking)
+	p.botSpeakingAt.Store(time.Now().UnixNano())
+}
+
+// isBotSpeaking bot is speaking or still in echo tail after stopped
+func (p *AudioVADInputProcessor) isBotSpeaking() bool {
+	if p.botSpeaking.Load() {
+		return true
+	}
+	tail := time.Duration(p.params.EchoParams.TailSecs * float64(time.Second))
+	return time.Since(time.Unix(0, p.botSpeakingAt.Load())) < tail
+}
+
+// readEchoReference reads the bot output reference aligned with the mic chunk
+func (p *AudioVADInputProcessor) readEchoReference(n int) []byte {
+	switch p.params.GetEchoStrategy() {
+	case types.EchoStrategyGated, types.EchoStrategyFullDuplex:
+		if p.params.EchoReference != nil {
+			return p.params.EchoReference.ReadReference(n)
+		}
+	}
+	return nil
+}
+
+// dropEchoAudio half duplex: drop mic audio while bot is speaking,
+// end the user speech which is in progress
+func (p *AudioVADInputProcessor) dropEchoAudio(vadState *types.VADState) bool {
+	if p.params.GetEchoStrategy() != types.EchoStrategyHalfDuplex || !p.isBotSpeaking() {
+		return false
+	}
+
+	if *vadState != types.Quiet {
+		if p.vadAnalyzer != nil {
+			p.vadAnalyzer.Reset()
+		}
+		*vadState = types.Quiet
+		p.handleInterruptions(achatbot_frames.NewUserStoppedSpeakingFrame(), true)
+	}
+	return true
+}
+
+// cancelEcho full duplex: subtract the estimated bot echo from the mic audio
+func (p *AudioVADInputProcessor) cancelEcho(chunk, ref []byte) []byte {
+	if p.echoCanceller == nil || ref == nil {
+		return chunk
+	}
+	return p.echoCanceller.ProcessBytes(chunk, ref)
+}
+
+// gateEcho gated: while bot is speaking, hold user started speaking until the speech lasts
+// BargeInSecs and its energy is higher than the bot reference, otherwise drop it as echo.
+// return true if the vad frame and interruption frame are handled (held or dropped)
+func (p *AudioVADInputProcessor) gateEcho(
+	vadStateFrame *achatbot_frames.VADStateAudioRawFrame,
+	userInterruptionFrame frames.Frame,
+	chunk, ref []byte,
+) bool {
+	if p.params.GetEchoStrategy() != types.EchoStrategyGated {
+		return false
+	}
+
+	_, isStarted := userInterruptionFrame.(*achatbot_frames.UserStartedSpeakingFrame)
+	_, isStopped := userInterruptionFrame.(*achatbot_frames.UserStoppedSpeakingFrame)
+
+	switch {
+	case p.echoGateSuppressed:
+		if isStopped {
+			p.echoGateSuppressed = false
+			logger.Infof("%s echo gate: suppressed speech ended", p.Name())
+		}
+		return true
+	case p.echoGatePending != nil:
+		if isStopped {
+			logger.Infof("%s echo gate: drop %.3fs speech as bot echo", p.Name(), p.echoGatePendingSecs())
+			p.resetEchoGate()
+			return true
+		}
+		p.holdEchoGate(vadStateFrame, chunk, ref)
+		if !p.isBotSpeaking() {
+			p.releaseEchoGate()
+			return true
+		}
+		if p.echoGatePendingSecs() >= p.params.EchoParams.BargeInSecs {
+			if p.echoGateEnergyRatio() >= p.params.EchoParams.BargeInEnergyRatio {
+				logger.Infof("%s echo gate: barge in after %.3fs", p.Name(), p.echoGatePendingSecs())
+				p.releaseEchoGate()
+			} else {
+				logger.Infof("%s echo gate: suppress speech, energy ratio %.2f < %.2f", p.Name(),
+					p.echoGateEnergyRatio(), p.params.EchoParams.BargeInEnergyRatio)
+				p.resetEchoGate()
+				p.echoGateSuppressed = true
+			}
+		}
+		return true
+	case isStarted && p.isBotSpeaking():
+		p.echoGatePending = []*achatbot_frames.VADStateAudioRawFrame{}
+		p.holdEchoGate(vadStateFrame, chunk, ref)
+		return true
+	}
+	return false
+}
+
+// holdEchoGate holds the vad frame and accumulates mic/reference energy
+func (p *AudioVADInputProcessor) holdEchoGate(vadStateFrame *achatbot_frames.VADStateAudioRawFrame, chunk, ref []byte) {
+	if vadStateFrame != nil {
+		p.echoGatePending = append(p.echoGatePending, vadStateFrame)
+	}
+	p.echoGateBytes += len(chunk)
+	p.echoGateMicEnergy += math.Pow(utils.RMS(chunk, p.params.AudioInSampleWidth), 2)
+	if ref != nil {
+		p.echoGateRefEnergy += math.Pow(utils.RMS(ref, p.params.AudioInSampleWidth), 2)
+	}
+}
+
+// releaseEchoGate user barge in, push user started speaking and held audio
+func (p *AudioVADInputProcessor) releaseEchoGate() {
+	pending := p.echoGatePending
+	p.resetEchoGate()
+
+	p.handleInterruptions(achatbot_frames.NewUserStartedSpeakingFrame(), true)
+	if p.params.VADAudioPassthrough {
+		for _, frame := range pending {
+			if len(frame.Audio) > 0 {
+				p.PushDownstreamFrame(frame)
+			}
+		}
+	}
+}
+
+func (p *AudioVADInputProcessor) resetEchoGate() {
+	p.echoGatePending = nil
+	p.echoGateBytes = 0
+	p.echoGateMicEnergy = 0
+	p.echoGateRefEnergy = 0
+}
+
+func (p *AudioVADInputProcessor) echoGatePendingSecs() float64 {
+	return float64(p.echoGateBytes) / float64(p.params.GetAudioInFormat().BytesPerSecond())
+}
+
+// echoGateEnergyRatio mic/reference rms ratio, no reference is treated as no echo
+func (p *AudioVADInputProcessor) echoGateEnergyRatio() float64 {
+	if p.echoGateRefEnergy <= 0 {
+		return math.Inf(1)
+	}
+	return math.Sqrt(p.echoGateMicEnergy / p.echoGateRefEnergy)
+}
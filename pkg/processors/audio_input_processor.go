@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/weedge/pipeline-go/pkg/frames"
@@ -28,6 +29,16 @@ type AudioVADInputProcessor struct {
 	audioTask        *sync.WaitGroup
 	vadAnalyzer      common.IVADAnalyzer
 	vadRingBuffer    *utils.RingBuffer // buffer audio byte
+
+	// bot echo handling
+	botSpeaking        atomic.Bool
+	botSpeakingAt      atomic.Int64 // last bot speaking frame unix nano
+	echoCanceller      *utils.NLMSEchoCanceller
+	echoGatePending    []*achatbot_frames.VADStateAudioRawFrame
+	echoGateBytes      int
+	echoGateMicEnergy  float64
+	echoGateRefEnergy  float64
+	echoGateSuppressed bool
 }
 
 // NewAudioVADInputProcessor creates a new AudioVADInputProcessor
@@ -59,8 +70,42 @@ func (p *AudioVADInputProcessor) SetVADAnalyzer(analyzer common.IVADAnalyzer) {
 	p.vadAnalyzer = analyzer
 }
 
+// GetAudioInFormat returns the client audio input format
+func (p *AudioVADInputProcessor) GetAudioInFormat() *types.AudioFormat {
+	return p.params.GetAudioInFormat()
+}
+
+// GetAudioOutFormat input audio (and VAD state audio) is pushed downstream as client audio input format
+func (p *AudioVADInputProcessor) GetAudioOutFormat() *types.AudioFormat {
+	return p.params.GetAudioInFormat()
+}
+
+// ValidateAudioFormat checks the client audio input format matches the VAD analyzer expectation
+func (p *AudioVADInputProcessor) ValidateAudioFormat() error {
+	if p.params.VADEnabled && p.vadAnalyzer != p.params.VADAnalyzer {
+		// analyzer may be replaced by SetVADAnalyzer
+		params := p.params.Clone().WithVADAnalyzer(p.vadAnalyzer)
+		return params.Validate()
+	}
+	return p.params.Validate()
+}
+
 // Start starts the processor
 func (p *AudioVADInputProcessor) Start(frame *frames.StartFrame) {
+	if p.params.GetEchoStrategy() == types.EchoStrategyFullDuplex {
+		echoCanceller, err := utils.NewPCMEchoCanceller(p.params.EchoParams.AECFilterLen, p.params.EchoParams.AECStepSize,
+			p.params.AudioInSampleWidth)
+		if err != nil {
+			// rejected by ValidateAudioFormat when the pipeline is built
+			logger.Errorf("%s echo canceller disabled: %v", p.Name(), err)
+		} else {
+			p.echoCanceller = echoCanceller
+		}
+	}
+	if p.params.GetEchoStrategy() != types.EchoStrategyNone {
+		logger.Infof("%s echo handling with %s", p.Name(), p.params.EchoParams)
+	}
+
 	if p.params.AudioInEnabled || p.params.VADEnabled {
 		p.audioTask.Add(1)
 		go p.audioTaskHandler()
@@ -99,6 +144,12 @@ func (p *AudioVADInputProcessor) PushAudioFrame(frame *frames.AudioRawFrame) err
 			logger.Warnf("%s audioInQueue is nil, cannot push frame %s", p.Name(), frame.String())
 			return nil
 		}
+		if err := fillAudioFrameFormat(frame, p.params.GetAudioInFormat()); err != nil {
+			return fmt.Errorf("%s %w", p.Name(), err)
+		}
+		if p.params.AudioInTap != nil {
+			p.params.AudioInTap.WriteAudio(frame.Audio)
+		}
 		if p.isPushAudioBlock {
 			select {
 			case p.audioInQueue <- frame:
@@ -120,6 +171,27 @@ func (p *AudioVADInputProcessor) PushAudioFrame(frame *frames.AudioRawFrame) err
 	return nil
 }
 
+// fillAudioFrameFormat fills the unset (0) format fields of the frame with the declared format,
+// e.g. clients not setting num_channels; only explicit non-zero conflicts are a mismatch
+func fillAudioFrameFormat(frame *frames.AudioRawFrame, inFormat *types.AudioFormat) error {
+	if (frame.SampleRate > 0 && frame.SampleRate != inFormat.SampleRate) ||
+		(frame.NumChannels > 0 && frame.NumChannels != inFormat.NumChannels) ||
+		(frame.SampleWidth > 0 && frame.SampleWidth != inFormat.SampleWidth) {
+		return fmt.Errorf("audio frame format (sample_rate: %d, num_channels: %d, sample_width: %d) mismatch declared %s",
+			frame.SampleRate, frame.NumChannels, frame.SampleWidth, inFormat)
+	}
+	if frame.SampleRate == 0 {
+		frame.SampleRate = inFormat.SampleRate
+	}
+	if frame.NumChannels == 0 {
+		frame.NumChannels = inFormat.NumChannels
+	}
+	if frame.SampleWidth == 0 {
+		frame.SampleWidth = inFormat.SampleWidth
+	}
+	return nil
+}
+
 // audioTaskHandler handles audio processing
 func (p *AudioVADInputProcessor) audioTaskHandler() {
 	defer p.audioTask.Done()
@@ -140,6 +212,18 @@ func (p *AudioVADInputProcessor) audioTaskHandler() {
 			for p.vadRingBuffer.Size() >= bytesChunkSize {
 				chunkBytes := p.vadRingBuffer.PopBytes(bytesChunkSize)
 
+				// Handle bot echo before VAD
+				refBytes := p.readEchoReference(len(chunkBytes))
+				if p.dropEchoAudio(&vadState) {
+					continue
+				}
+				chunkBytes = p.cancelEcho(chunkBytes, refBytes)
+
+				// Preprocess mic audio (noise suppression, AGC) before VAD
+				if p.params.AudioInPreprocessor != nil {
+					chunkBytes = p.params.AudioInPreprocessor.Process(chunkBytes)
+				}
+
 				var vadStateFrame *achatbot_frames.VADStateAudioRawFrame
 				var userInterruptionFrame frames.Frame
 
@@ -151,6 +235,11 @@ func (p *AudioVADInputProcessor) audioTaskHandler() {
 					}
 				}
 
+				// Gate user speech while bot is speaking
+				if p.gateEcho(vadStateFrame, userInterruptionFrame, chunkBytes, refBytes) {
+					continue
+				}
+
 				// Handle user started speaking
 				if _, ok := userInterruptionFrame.(*achatbot_frames.UserStartedSpeakingFrame); ok {
 					p.handleInterruptions(userInterruptionFrame, true)
@@ -275,6 +364,15 @@ func (p *AudioVADInputProcessor) ProcessFrame(frame frames.Frame, direction proc
 		p.startInterruption()
 	case *frames.StopInterruptionFrame:
 		p.stopInterruption()
+	case *achatbot_frames.BotStartedSpeakingFrame:
+		p.setBotSpeaking(true)
+		p.QueueFrame(f, direction)
+	case *achatbot_frames.BotSpeakingFrame:
+		p.botSpeakingAt.Store(time.Now().UnixNano())
+		p.QueueFrame(f, direction)
+	case *achatbot_frames.BotStoppedSpeakingFrame:
+		p.setBotSpeaking(false)
+		p.QueueFrame(f, direction)
 	default:
 		p.QueueFrame(f, direction)
 	}
@@ -1,10 +1,11 @@
 package processors
 
 import (
-	"achatbot/pkg/utils"
-
 	"github.com/weedge/pipeline-go/pkg/frames"
 	"github.com/weedge/pipeline-go/pkg/processors"
+
+	achatbot_frames "achatbot/pkg/types/frames"
+	"achatbot/pkg/utils"
 )
 
 type AudioResampleProcessor struct {
@@ -32,16 +33,23 @@ func (p *AudioResampleProcessor) ProcessFrame(frame frames.Frame, direction proc
 	case *frames.CancelFrame:
 		p.PushFrame(f, direction)
 	case *frames.AudioRawFrame:
-		if f.SampleRate != p.outRate {
-			f.Audio = utils.ResampleBytes(f.Audio, f.SampleRate, p.outRate)
-			f.SampleRate = p.outRate
-			if f.NumChannels > 0 && f.SampleWidth > 0 {
-				f.NumFrames = len(f.Audio) / (f.NumChannels * f.SampleWidth)
-			}
-		}
+		p.resample(f)
+		p.QueueFrame(f, direction)
+	case *achatbot_frames.TTSAudioRawFrame:
+		p.resample(f.AudioRawFrame)
 		p.QueueFrame(f, direction)
 	default:
 		p.QueueFrame(f, direction)
 	}
 
 }
+
+func (p *AudioResampleProcessor) resample(f *frames.AudioRawFrame) {
+	if f.SampleRate != p.outRate {
+		f.Audio = utils.ResampleBytes(f.Audio, f.SampleRate, p.outRate)
+		f.SampleRate = p.outRate
+		if f.NumChannels > 0 && f.SampleWidth > 0 {
+			f.NumFrames = len(f.Audio) / (f.NumChannels * f.SampleWidth)
+		}
+	}
+}
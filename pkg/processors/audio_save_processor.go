@@ -58,12 +58,15 @@ func (p *AudioSaveProcessor) ProcessFrame(frame frames.Frame, direction processo
 		return
 	}
 
+	// link the saved audio file to the frame, e.g. transcript records
+	achatbot_frames.SetFrameMetadata(frame, achatbot_frames.AudioPathMetadataKey, filePath)
 	if p.passRawAudio {
 		p.PushFrame(frame, direction)
 	} else {
 		// Create a new frame with the file path
 		pathFrame := achatbot_frames.NewPathAudioRawFrame(
 			audioFrame.Audio, audioFrame.SampleRate, audioFrame.NumChannels, audioFrame.SampleWidth, filePath)
+		achatbot_frames.CopyFrameMetadata(pathFrame, frame)
 		p.PushFrame(pathFrame, direction)
 	}
 }
@@ -0,0 +1,26 @@
+package processors
+
+import (
+	"achatbot/pkg/common"
+)
+
+// AudioTapTransportWriter 包装输出处理器的 ITransportWriter, 将写入客户端的音频同时写入旁路(如会话录音)
+type AudioTapTransportWriter struct {
+	common.ITransportWriter
+	tap common.IAudioTap
+}
+
+func NewAudioTapTransportWriter(writer common.ITransportWriter, tap common.IAudioTap) *AudioTapTransportWriter {
+	return &AudioTapTransportWriter{
+		ITransportWriter: writer,
+		tap:              tap,
+	}
+}
+
+func (w *AudioTapTransportWriter) WriteRawAudio(data []byte) error {
+	if err := w.ITransportWriter.WriteRawAudio(data); err != nil {
+		return err
+	}
+	w.tap.WriteAudio(data)
+	return nil
+}
@@ -0,0 +1,108 @@
+package processors
+
+import (
+	"github.com/weedge/pipeline-go/pkg/frames"
+	"github.com/weedge/pipeline-go/pkg/logger"
+	"github.com/weedge/pipeline-go/pkg/processors"
+
+	"achatbot/pkg/common"
+	"achatbot/pkg/consts"
+	"achatbot/pkg/params"
+	"achatbot/pkg/types"
+	achatbot_frames "achatbot/pkg/types/frames"
+	"achatbot/pkg/utils"
+)
+
+// LanguageIDProcessor 语种识别处理器, 放在用户语音聚合器之后, ASR 之前:
+// 识别每段用户语音的语种, 在语音前推送 LanguageDetectedFrame, ASR 按语种路由到对应的识别模型;
+// provider 为 nil 时不做识别, 直接透传
+type LanguageIDProcessor struct {
+	*processors.AsyncFrameProcessor
+	args     *params.LanguageIDArgs
+	provider common.ILanguageIDProvider
+
+	// 会话上一次的识别结果, 语音过短或识别失败时沿用
+	language string
+}
+
+func NewLanguageIDProcessor(args *params.LanguageIDArgs, provider common.ILanguageIDProvider) *LanguageIDProcessor {
+	return &LanguageIDProcessor{
+		AsyncFrameProcessor: processors.NewAsyncFrameProcessor("LanguageIDProcessor"),
+		args:                args,
+		provider:            provider,
+		language:            args.DefaultLanguage,
+	}
+}
+
+// GetAudioInFormat returns the audio format which language identification expects
+func (p *LanguageIDProcessor) GetAudioInFormat() *types.AudioFormat {
+	if declarer, ok := p.provider.(common.IAudioFormatDeclarer); ok {
+		return declarer.GetAudioInFormat()
+	}
+	return types.NewAudioFormat(p.args.SampleRate, consts.DefaultChannels, p.args.SampleWidth)
+}
+
+// GetAudioOutFormat language identification pass raw audio through
+func (p *LanguageIDProcessor) GetAudioOutFormat() *types.AudioFormat {
+	return nil
+}
+
+func (p *LanguageIDProcessor) Start(frame *frames.StartFrame) {
+	logger.Infof("LanguageIDProcessor Start %s", p.args)
+}
+
+func (p *LanguageIDProcessor) Stop(frame *frames.EndFrame) {
+	logger.Info("LanguageIDProcessor Stop")
+}
+
+func (p *LanguageIDProcessor) Cancel(frame *frames.CancelFrame) {
+	logger.Info("LanguageIDProcessor Cancel")
+}
+
+// ProcessFrame processes a frame
+func (p *LanguageIDProcessor) ProcessFrame(frame frames.Frame, direction processors.FrameDirection) {
+	// call frame processor to init star frame init
+	p.AsyncFrameProcessor.WithPorcessFrameAllowPush(false).ProcessFrame(frame, direction)
+
+	switch f := frame.(type) {
+	case *frames.StartFrame:
+		p.PushFrame(f, direction)
+		p.Start(f)
+	case *frames.EndFrame:
+		p.PushFrame(f, direction)
+		p.Stop(f)
+	case *frames.CancelFrame:
+		p.PushFrame(f, direction)
+		p.Cancel(f)
+	default:
+		audioFrame := utils.GetAudioRawFrame(frame)
+		if p.provider == nil || audioFrame == nil || direction != processors.FrameDirectionDownstream || len(audioFrame.Audio) == 0 {
+			p.QueueFrame(f, direction)
+			return
+		}
+
+		p.QueueFrame(achatbot_frames.NewLanguageDetectedFrame(p.identify(audioFrame.Audio)), direction)
+		p.QueueFrame(f, direction)
+	}
+}
+
+// identify identifies the utterance language, fallback to the last session language
+func (p *LanguageIDProcessor) identify(audio []byte) string {
+	bytesPerSec := float64(p.args.SampleRate * p.args.SampleWidth)
+	if float64(len(audio))/bytesPerSec < p.args.MinAudioSecs {
+		return p.language
+	}
+
+	language, err := p.provider.IdentifyLanguage(audio)
+	if err != nil {
+		logger.Warnf("%s identify err: %v, use %s", p.Name(), err, p.language)
+		return p.language
+	}
+	if !p.args.IsSupported(language) {
+		logger.Debugf("%s unsupported language %s, use %s", p.Name(), language, p.language)
+		return p.language
+	}
+	logger.Debugf("%s language: %s", p.Name(), language)
+	p.language = language
+	return language
+}
@@ -0,0 +1,50 @@
+package processors
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"achatbot/pkg/params"
+	"achatbot/pkg/types"
+)
+
+// fakeLanguageIDProvider 音频首字节: 1 -> en, 2 -> ja, 其他识别失败
+type fakeLanguageIDProvider struct{}
+
+func (p *fakeLanguageIDProvider) IdentifyLanguage(audio []byte) (string, error) {
+	switch audio[0] {
+	case 1:
+		return types.LanguageEn, nil
+	case 2:
+		return "ja", nil
+	default:
+		return "", fmt.Errorf("unknown")
+	}
+}
+func (p *fakeLanguageIDProvider) Warmup()        {}
+func (p *fakeLanguageIDProvider) Name() string   { return "fake" }
+func (p *fakeLanguageIDProvider) Reset() error   { return nil }
+func (p *fakeLanguageIDProvider) Release() error { return nil }
+
+func TestLanguageIDProcessorIdentify(t *testing.T) {
+	args := params.NewLanguageIDArgs().WithMinAudioSecs(0.5)
+	assert.NoError(t, args.Validate())
+	p := NewLanguageIDProcessor(args, &fakeLanguageIDProvider{})
+
+	audio := func(b byte, secs float64) []byte {
+		buf := make([]byte, int(secs*float64(args.SampleRate*args.SampleWidth)))
+		buf[0] = b
+		return buf
+	}
+
+	// 识别失败使用默认语种
+	assert.Equal(t, types.LanguageZh, p.identify(audio(0, 1)))
+	assert.Equal(t, types.LanguageEn, p.identify(audio(1, 1)))
+	// 不支持的语种和过短语音沿用上一次结果
+	assert.Equal(t, types.LanguageEn, p.identify(audio(2, 1)))
+	assert.Equal(t, types.LanguageEn, p.identify(audio(0, 0.2)))
+
+	assert.Error(t, params.NewLanguageIDArgs().WithDefaultLanguage("ja").Validate())
+}
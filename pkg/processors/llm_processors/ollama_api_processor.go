@@ -2,6 +2,8 @@ package llm_processors
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/go-viper/mapstructure/v2"
 	"github.com/google/uuid"
@@ -13,14 +15,18 @@ import (
 	"achatbot/pkg/common"
 	"achatbot/pkg/modules/functions"
 	"achatbot/pkg/modules/llm"
+	"achatbot/pkg/services/metrics"
+	"achatbot/pkg/services/tracing"
 	achatbot_frames "achatbot/pkg/types/frames"
 )
 
 type LLMOllamaApiProcessor struct {
 	*processors.AsyncFrameProcessor
-	provider *llm.OllamaAPIProvider
-	session  *common.Session
-	mode     string
+	provider    *llm.OllamaAPIProvider
+	session     *common.Session
+	mode        string
+	emotionHint bool
+	tracer      *tracing.Tracer
 }
 
 const (
@@ -42,6 +48,18 @@ func NewLLMOllamaApiProcessor(provider *llm.OllamaAPIProvider, session *common.S
 	return p
 }
 
+// WithEmotionHint prefixes the recognized user emotion (e.g. SenseVoice) to the user message as the prompt hint
+func (p *LLMOllamaApiProcessor) WithEmotionHint(emotionHint bool) *LLMOllamaApiProcessor {
+	p.emotionHint = emotionHint
+	return p
+}
+
+// WithTracer traces each chat as a child span of the turn, with the llm streams and tool executions as its child spans
+func (p *LLMOllamaApiProcessor) WithTracer(tracer *tracing.Tracer) *LLMOllamaApiProcessor {
+	p.tracer = tracer
+	return p
+}
+
 // ProcessFrame processes a frame
 func (p *LLMOllamaApiProcessor) ProcessFrame(frame frames.Frame, direction processors.FrameDirection) {
 	// call frame processor to init star frame init
@@ -59,10 +77,23 @@ func (p *LLMOllamaApiProcessor) ProcessFrame(frame frames.Frame, direction proce
 	case *frames.TextFrame:
 		switch p.mode {
 		case "chat":
-			p.chat(f, direction)
+			p.chat(f, nil, direction)
 		case "generate":
 			p.generate(f, direction)
 		}
+	case *achatbot_frames.TranscriptionFrame:
+		switch p.mode {
+		case "chat":
+			p.chat(f.TextFrame, f, direction)
+		case "generate":
+			p.generate(f.TextFrame, direction)
+		}
+	case *achatbot_frames.BotSpeechInterruptedFrame:
+		// keep only the reply the user really heard
+		if p.session.GetChatHistory().TruncateLastAssistantMessage(f.SpokenText) {
+			logger.Infof("%s truncate the interrupted reply to: %s", p.Name(), f.SpokenText)
+		}
+		p.QueueFrame(f, direction)
 	default:
 		p.QueueFrame(f, direction)
 	}
@@ -81,14 +112,23 @@ func (p *LLMOllamaApiProcessor) appendHistoryChatMessages(msgs []api.Message) {
 	}
 }
 
-func (p *LLMOllamaApiProcessor) chat(frame *frames.TextFrame, direction processors.FrameDirection) {
+// chat transcription is the user speech transcription of the text (nil for plain text),
+// its speaker and emotion are recorded in chat history
+func (p *LLMOllamaApiProcessor) chat(frame *frames.TextFrame, transcription *achatbot_frames.TranscriptionFrame, direction processors.FrameDirection) {
+	defer metrics.ObserveProcessing(metrics.ProcessorLLM, time.Now())
+	turnContext := p.tracer.ParentContext(frame)
+	chatSpan := p.tracer.StartSpan(tracing.SpanLLMChat, turnContext)
+	chatSpan.SetAttribute("llm.provider", p.provider.Name())
+	defer chatSpan.End()
+
 	chatHistory := p.session.GetChatHistory()
-	chatHistory.Append(map[string]any{"role": "user", "content": frame.Text})
+	chatHistory.Append(newUserMessage(frame.Text, transcription, p.emotionHint))
 	historyList := chatHistory.ToListWithoutTools() // init tools in provider
 	messages := make([]api.Message, 0)
 	err := mapstructure.Decode(historyList, &messages) // history list([]map[string]any) to messages([]api.Message)
 	if err != nil {
 		logger.Error("chat", "err", err)
+		metrics.IncError(metrics.ProcessorLLM)
 	}
 
 	isToolCalls := true
@@ -96,10 +136,14 @@ func (p *LLMOllamaApiProcessor) chat(frame *frames.TextFrame, direction processo
 	for isToolCalls {
 		if cnToolCalls > 3 {
 			logger.Error("chat", "err", "too many tool calls")
+			metrics.IncError(metrics.ProcessorLLM)
+			chatSpan.RecordError(errors.New("too many tool calls"))
 			break
 		}
 		cnToolCalls++
 		genThinking, genContent := "", ""
+		streamSpan := p.tracer.StartSpan(tracing.SpanLLMStream, chatSpan.Context())
+		streamSpan.SetAttribute("llm.round", cnToolCalls)
 		p.provider.Chat(context.Background(), messages, func(resp api.ChatResponse) error {
 			if resp.Done {
 				logger.Debugf("DoneReason: %s", resp.DoneReason)
@@ -108,9 +152,16 @@ func (p *LLMOllamaApiProcessor) chat(frame *frames.TextFrame, direction processo
 			if resp.Message.ToolCalls != nil { //tool_calls
 				toolMsgs := []api.Message{}
 				for _, toolCall := range resp.Message.ToolCalls {
+					p.QueueFrame(achatbot_frames.NewFunctionCallFrame("", toolCall.Function.Name, toolCall.Function.Arguments, toolCall.Function.Index), direction)
+					toolSpan := p.tracer.StartSpan(tracing.SpanTool, streamSpan.Context())
+					toolSpan.SetAttribute("tool.name", toolCall.Function.Name)
 					result, err := functions.RegisterFuncs.Execute(toolCall.Function.Name, toolCall.Function.Arguments)
+					toolSpan.RecordError(err)
+					toolSpan.End()
+					p.QueueFrame(achatbot_frames.NewFunctionCallResultFrame("", toolCall.Function.Name, result, err), direction)
 					if err != nil {
 						logger.Error("Execute", "err", err, "funcName", toolCall.Function.Name, "funcArgs", toolCall.Function.Arguments)
+						metrics.IncError(metrics.ProcessorLLM)
 						continue
 					}
 					toolMsgs = append(toolMsgs, api.Message{
@@ -118,7 +169,6 @@ func (p *LLMOllamaApiProcessor) chat(frame *frames.TextFrame, direction processo
 						Content:  result,
 						ToolName: toolCall.Function.Name,
 					})
-					p.QueueFrame(achatbot_frames.NewFunctionCallFrame("", toolCall.Function.Name, toolCall.Function.Arguments, toolCall.Function.Index), direction)
 				}
 				if len(toolMsgs) > 0 {
 					messages = append(messages, resp.Message)
@@ -133,12 +183,13 @@ func (p *LLMOllamaApiProcessor) chat(frame *frames.TextFrame, direction processo
 				genThinking += resp.Message.Thinking
 			}
 			if resp.Message.Content != "" {
-				p.QueueFrame(frames.NewTextFrame(resp.Message.Content), direction)
+				p.QueueFrame(newTracedTextFrame(resp.Message.Content, turnContext), direction)
 				genContent += resp.Message.Content
 				isToolCalls = false // if llm gen call tools, no content
 			}
 			return nil
 		})
+		streamSpan.End()
 		msg := api.Message{Role: "assistant"}
 		if genThinking != "" {
 			msg.Thinking = genThinking
@@ -150,7 +201,9 @@ func (p *LLMOllamaApiProcessor) chat(frame *frames.TextFrame, direction processo
 		}
 	}
 
-	p.QueueFrame(achatbot_frames.NewTurnEndFrame(), direction)
+	turnEndFrame := achatbot_frames.NewTurnEndFrame()
+	tracing.InjectFrame(turnEndFrame, turnContext)
+	p.QueueFrame(turnEndFrame, direction)
 	logger.Infof("ChatHistory: %+v", p.session.GetChatHistory().ToList())
 	p.session.IncrementChatRound()
 }
@@ -3,7 +3,9 @@ package llm_processors
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"strings"
+	"time"
 
 	"github.com/go-viper/mapstructure/v2"
 	"github.com/google/uuid"
@@ -14,6 +16,8 @@ import (
 
 	"achatbot/pkg/common"
 	"achatbot/pkg/modules/functions"
+	"achatbot/pkg/services/metrics"
+	"achatbot/pkg/services/tracing"
 	"achatbot/pkg/types"
 	achatbot_frames "achatbot/pkg/types/frames"
 )
@@ -26,6 +30,8 @@ type LLMOpenAIApiProcessor struct {
 	stream         bool
 	args           types.LMGenerateArgs
 	isHistoryThink bool
+	emotionHint    bool
+	tracer         *tracing.Tracer
 }
 
 func NewLLMOpenAIApiProcessor(
@@ -53,6 +59,18 @@ func (p *LLMOpenAIApiProcessor) WithIsHistoryThink(isHistoryThink bool) *LLMOpen
 	return p
 }
 
+// WithEmotionHint prefixes the recognized user emotion (e.g. SenseVoice) to the user message as the prompt hint
+func (p *LLMOpenAIApiProcessor) WithEmotionHint(emotionHint bool) *LLMOpenAIApiProcessor {
+	p.emotionHint = emotionHint
+	return p
+}
+
+// WithTracer traces each chat as a child span of the turn, with the llm requests and tool executions as its child spans
+func (p *LLMOpenAIApiProcessor) WithTracer(tracer *tracing.Tracer) *LLMOpenAIApiProcessor {
+	p.tracer = tracer
+	return p
+}
+
 // ProcessFrame processes a frame
 func (p *LLMOpenAIApiProcessor) ProcessFrame(frame frames.Frame, direction processors.FrameDirection) {
 	// call frame processor to init star frame init
@@ -70,10 +88,23 @@ func (p *LLMOpenAIApiProcessor) ProcessFrame(frame frames.Frame, direction proce
 	case *frames.TextFrame:
 		switch p.mode {
 		case "chat":
-			p.chat(f, direction)
+			p.chat(f, nil, direction)
 		case "generate":
 			p.generate(f, direction)
 		}
+	case *achatbot_frames.TranscriptionFrame:
+		switch p.mode {
+		case "chat":
+			p.chat(f.TextFrame, f, direction)
+		case "generate":
+			p.generate(f.TextFrame, direction)
+		}
+	case *achatbot_frames.BotSpeechInterruptedFrame:
+		// keep only the reply the user really heard
+		if p.session.GetChatHistory().TruncateLastAssistantMessage(f.SpokenText) {
+			logger.Infof("%s truncate the interrupted reply to: %s", p.Name(), f.SpokenText)
+		}
+		p.QueueFrame(f, direction)
 	default:
 		p.QueueFrame(f, direction)
 	}
@@ -92,14 +123,24 @@ func (p *LLMOpenAIApiProcessor) appendHistoryChatMessages(msgs []types.Message)
 	}
 }
 
-func (p *LLMOpenAIApiProcessor) chat(frame *frames.TextFrame, direction processors.FrameDirection) {
+// chat transcription is the user speech transcription of the text (nil for plain text),
+// its speaker and emotion are recorded in chat history
+func (p *LLMOpenAIApiProcessor) chat(frame *frames.TextFrame, transcription *achatbot_frames.TranscriptionFrame, direction processors.FrameDirection) {
+	defer metrics.ObserveProcessing(metrics.ProcessorLLM, time.Now())
+	turnContext := p.tracer.ParentContext(frame)
+	chatSpan := p.tracer.StartSpan(tracing.SpanLLMChat, turnContext)
+	chatSpan.SetAttribute("llm.provider", p.provider.Name())
+	chatSpan.SetAttribute("llm.stream", p.stream)
+	defer chatSpan.End()
+
 	chatHistory := p.session.GetChatHistory()
-	chatHistory.Append(map[string]any{"role": "user", "content": frame.Text})
+	chatHistory.Append(newUserMessage(frame.Text, transcription, p.emotionHint))
 	historyList := chatHistory.ToListWithoutTools() // init tools in provider
 	messages := make([]types.Message, 0)
 	err := mapstructure.Decode(historyList, &messages)
 	if err != nil {
 		logger.Error("chat", "err", err)
+		metrics.IncError(metrics.ProcessorLLM)
 	}
 
 	isToolCalls := true
@@ -107,10 +148,14 @@ func (p *LLMOpenAIApiProcessor) chat(frame *frames.TextFrame, direction processo
 	for isToolCalls {
 		if cnToolCalls > 3 {
 			logger.Error("chat", "err", "too many tool calls")
+			metrics.IncError(metrics.ProcessorLLM)
+			chatSpan.RecordError(errors.New("too many tool calls"))
 			break
 		}
 		cnToolCalls++
 		if !p.stream {
+			requestSpan := p.tracer.StartSpan(tracing.SpanLLMRequest, chatSpan.Context())
+			requestSpan.SetAttribute("llm.round", cnToolCalls)
 			p.provider.Chat(context.Background(), p.args, messages, func(resp *openai.ChatCompletion) error {
 				toolMsgs := []types.Message{}
 				for i, toolCall := range resp.Choices[0].Message.ToolCalls {
@@ -122,18 +167,25 @@ func (p *LLMOpenAIApiProcessor) chat(frame *frames.TextFrame, direction processo
 					err := json.Unmarshal([]byte(funcArgs), &args)
 					if err != nil {
 						logger.Errorf("Failed to unmarshal function arguments: %v err: %v", funcArgs, err)
+						metrics.IncError(metrics.ProcessorLLM)
 						continue
 					}
+					p.QueueFrame(achatbot_frames.NewFunctionCallFrame(toolCall.ID, toolCall.Function.Name, args, i), direction)
+					toolSpan := p.tracer.StartSpan(tracing.SpanTool, requestSpan.Context())
+					toolSpan.SetAttribute("tool.name", toolCall.Function.Name)
 					result, err := functions.RegisterFuncs.Execute(toolCall.Function.Name, args)
+					toolSpan.RecordError(err)
+					toolSpan.End()
+					p.QueueFrame(achatbot_frames.NewFunctionCallResultFrame(toolCall.ID, toolCall.Function.Name, result, err), direction)
 					if err != nil {
 						logger.Errorf("Failed to execute function: %v err: %v", toolCall.Function.Name, err)
+						metrics.IncError(metrics.ProcessorLLM)
 						continue
 					}
 					toolMsgs = append(toolMsgs, types.Message{
 						ChatCompletionMessage: openai.ChatCompletionMessage{Role: "tool", Content: result},
 						ToolCallID:            toolCall.ID,
 					})
-					p.QueueFrame(achatbot_frames.NewFunctionCallFrame(toolCall.ID, toolCall.Function.Name, args, i), direction)
 				}
 				// If there is a was a function call, continue the conversation
 				if len(toolMsgs) > 0 { //call_tools
@@ -158,11 +210,14 @@ func (p *LLMOpenAIApiProcessor) chat(frame *frames.TextFrame, direction processo
 					msg := types.Message{ChatCompletionMessage: resp.Choices[0].Message}
 					messages = append(messages, msg)
 					p.appendHistoryChatMessages([]types.Message{msg})
-					p.QueueFrame(frames.NewTextFrame(resp.Choices[0].Message.Content), direction)
+					p.QueueFrame(newTracedTextFrame(resp.Choices[0].Message.Content, turnContext), direction)
 				}
 				return nil
 			})
+			requestSpan.End()
 		} else { //stream
+			streamSpan := p.tracer.StartSpan(tracing.SpanLLMStream, chatSpan.Context())
+			streamSpan.SetAttribute("llm.round", cnToolCalls)
 			acc := openai.ChatCompletionAccumulator{}
 			toolMsgs := []types.Message{}
 			p.provider.ChatStream(context.Background(), p.args, messages, func(chunk *openai.ChatCompletionChunk) error {
@@ -175,7 +230,7 @@ func (p *LLMOpenAIApiProcessor) chat(frame *frames.TextFrame, direction processo
 					p.QueueFrame(achatbot_frames.NewThinkTextFrame(chunk.Choices[0].Delta.Reasoning), direction)
 				}
 				if chunk.Choices[0].Delta.Content != "" {
-					p.QueueFrame(frames.NewTextFrame(chunk.Choices[0].Delta.Content), direction)
+					p.QueueFrame(newTracedTextFrame(chunk.Choices[0].Delta.Content, turnContext), direction)
 				}
 
 				if chunk.Choices[0].Delta.ToolCalls != nil {
@@ -185,22 +240,30 @@ func (p *LLMOpenAIApiProcessor) chat(frame *frames.TextFrame, direction processo
 						err := json.Unmarshal([]byte(tool.Function.Arguments), &args)
 						if err != nil {
 							logger.Errorf("Failed to Unmarshal err: %v", err)
+							metrics.IncError(metrics.ProcessorLLM)
 							continue
 						}
+						p.QueueFrame(achatbot_frames.NewFunctionCallFrame(tool.ID, tool.Function.Name, args, int(tool.Index)), direction)
+						toolSpan := p.tracer.StartSpan(tracing.SpanTool, streamSpan.Context())
+						toolSpan.SetAttribute("tool.name", tool.Function.Name)
 						result, err := functions.RegisterFuncs.Execute(tool.Function.Name, args)
+						toolSpan.RecordError(err)
+						toolSpan.End()
+						p.QueueFrame(achatbot_frames.NewFunctionCallResultFrame(tool.ID, tool.Function.Name, result, err), direction)
 						if err != nil {
 							logger.Error("Execute", "err", err, "funcName", tool.Function.Name, "funcArgs", tool.Function.Arguments)
+							metrics.IncError(metrics.ProcessorLLM)
 							continue
 						}
 						toolMsgs = append(toolMsgs, types.Message{
 							ChatCompletionMessage: openai.ChatCompletionMessage{Role: "tool", Content: result},
 							ToolCallID:            tool.ID,
 						})
-						p.QueueFrame(achatbot_frames.NewFunctionCallFrame(tool.ID, tool.Function.Name, args, int(tool.Index)), direction)
 					}
 				}
 				return nil
 			})
+			streamSpan.End()
 			// If there is a was a function call, continue the conversation
 			if len(toolMsgs) > 0 { //call_tools
 				if !p.isHistoryThink {
@@ -227,7 +290,9 @@ func (p *LLMOpenAIApiProcessor) chat(frame *frames.TextFrame, direction processo
 		} //end stream
 	} //end call
 
-	p.QueueFrame(achatbot_frames.NewTurnEndFrame(), direction)
+	turnEndFrame := achatbot_frames.NewTurnEndFrame()
+	tracing.InjectFrame(turnEndFrame, turnContext)
+	p.QueueFrame(turnEndFrame, direction)
 	logger.Infof("ChatHistory: %+v", p.session.GetChatHistory().ToList())
 	p.session.IncrementChatRound()
 }
@@ -0,0 +1,14 @@
+package llm_processors
+
+import (
+	"github.com/weedge/pipeline-go/pkg/frames"
+
+	"achatbot/pkg/services/tracing"
+)
+
+// newTracedTextFrame creates the llm output text frame which propagates the trace context of the turn
+func newTracedTextFrame(text string, traceContext tracing.SpanContext) *frames.TextFrame {
+	frame := frames.NewTextFrame(text)
+	tracing.InjectFrame(frame, traceContext)
+	return frame
+}
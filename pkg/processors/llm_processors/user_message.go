@@ -0,0 +1,29 @@
+package llm_processors
+
+import (
+	"fmt"
+
+	"achatbot/pkg/types"
+	achatbot_frames "achatbot/pkg/types/frames"
+)
+
+// newUserMessage builds the user chat history message,
+// records the verified speaker and emotion of the transcription (nil for plain text);
+// with emotionHint, the non neutral emotion is prefixed to the content as the prompt hint
+func newUserMessage(text string, transcription *achatbot_frames.TranscriptionFrame, emotionHint bool) map[string]any {
+	userMsg := map[string]any{"role": "user", "content": text}
+	if transcription == nil {
+		return userMsg
+	}
+
+	if transcription.SpeakerID != "" {
+		userMsg["speaker_id"] = transcription.SpeakerID
+	}
+	if transcription.Emotion != "" {
+		userMsg["emotion"] = transcription.Emotion
+		if emotionHint && transcription.Emotion != types.EmotionNeutral {
+			userMsg["content"] = fmt.Sprintf("[user emotion: %s] %s", transcription.Emotion, text)
+		}
+	}
+	return userMsg
+}
@@ -0,0 +1,119 @@
+package processors
+
+import (
+	"github.com/weedge/pipeline-go/pkg/frames"
+	"github.com/weedge/pipeline-go/pkg/logger"
+	"github.com/weedge/pipeline-go/pkg/processors"
+
+	"achatbot/pkg/common"
+	"achatbot/pkg/consts"
+	"achatbot/pkg/params"
+	"achatbot/pkg/types"
+	achatbot_frames "achatbot/pkg/types/frames"
+	"achatbot/pkg/utils"
+)
+
+// SpeakerVerifyProcessor 说话人验证处理器, 放在用户语音聚合器之后, ASR 之前:
+// 验证每段用户语音是否来自注册声纹, 未通过时按配置丢弃或仅标记;
+// 通过(或标记)的语音前推送 SpeakerVerifiedFrame, ASR 将说话人附加到转录帧;
+// verifier 为 nil 或未注册任何声纹时不做验证, 直接透传
+type SpeakerVerifyProcessor struct {
+	*processors.AsyncFrameProcessor
+	args     *params.SpeakerVerifyArgs
+	verifier common.ISpeakerVerifier
+
+	// 会话上一次的验证结果, 语音过短时沿用
+	lastResult *types.SpeakerVerifyResult
+}
+
+func NewSpeakerVerifyProcessor(args *params.SpeakerVerifyArgs, verifier common.ISpeakerVerifier) *SpeakerVerifyProcessor {
+	return &SpeakerVerifyProcessor{
+		AsyncFrameProcessor: processors.NewAsyncFrameProcessor("SpeakerVerifyProcessor"),
+		args:                args,
+		verifier:            verifier,
+	}
+}
+
+// GetAudioInFormat returns the audio format which speaker embedding expects
+func (p *SpeakerVerifyProcessor) GetAudioInFormat() *types.AudioFormat {
+	return types.NewAudioFormat(p.args.SampleRate, consts.DefaultChannels, p.args.SampleWidth)
+}
+
+// GetAudioOutFormat verification pass raw audio through
+func (p *SpeakerVerifyProcessor) GetAudioOutFormat() *types.AudioFormat {
+	return nil
+}
+
+func (p *SpeakerVerifyProcessor) Start(frame *frames.StartFrame) {
+	logger.Infof("SpeakerVerifyProcessor Start %s", p.args)
+}
+
+func (p *SpeakerVerifyProcessor) Stop(frame *frames.EndFrame) {
+	logger.Info("SpeakerVerifyProcessor Stop")
+}
+
+func (p *SpeakerVerifyProcessor) Cancel(frame *frames.CancelFrame) {
+	logger.Info("SpeakerVerifyProcessor Cancel")
+}
+
+// ProcessFrame processes a frame
+func (p *SpeakerVerifyProcessor) ProcessFrame(frame frames.Frame, direction processors.FrameDirection) {
+	// call frame processor to init star frame init
+	p.AsyncFrameProcessor.WithPorcessFrameAllowPush(false).ProcessFrame(frame, direction)
+
+	switch f := frame.(type) {
+	case *frames.StartFrame:
+		p.PushFrame(f, direction)
+		p.Start(f)
+	case *frames.EndFrame:
+		p.PushFrame(f, direction)
+		p.Stop(f)
+	case *frames.CancelFrame:
+		p.PushFrame(f, direction)
+		p.Cancel(f)
+	default:
+		audioFrame := utils.GetAudioRawFrame(frame)
+		if audioFrame == nil || direction != processors.FrameDirectionDownstream || len(audioFrame.Audio) == 0 {
+			p.QueueFrame(f, direction)
+			return
+		}
+
+		result := p.verify(audioFrame.Audio)
+		if result == nil {
+			// 未注册任何声纹, 不做验证
+			p.QueueFrame(f, direction)
+			return
+		}
+		if !result.Verified && p.args.Action == types.SpeakerVerifyActionDrop {
+			logger.Infof("%s drop unverified speech: %s", p.Name(), result)
+			return
+		}
+		p.QueueFrame(achatbot_frames.NewSpeakerVerifiedFrame(result.SpeakerID, result.Score, result.Verified), direction)
+		p.QueueFrame(f, direction)
+	}
+}
+
+// verify verifies the utterance, short utterance reuses the last session result
+func (p *SpeakerVerifyProcessor) verify(audio []byte) *types.SpeakerVerifyResult {
+	if p.verifier == nil || p.verifier.NumEnrolled() == 0 {
+		return nil
+	}
+
+	bytesPerSec := float64(p.args.SampleRate * p.args.SampleWidth)
+	if float64(len(audio))/bytesPerSec < p.args.MinAudioSecs {
+		if p.lastResult != nil {
+			logger.Debugf("%s audio too short, reuse last result: %s", p.Name(), p.lastResult)
+			return p.lastResult
+		}
+		return &types.SpeakerVerifyResult{Reason: "audio too short"}
+	}
+
+	result, err := p.verifier.Verify(audio)
+	if err != nil {
+		logger.Warnf("%s verify err: %v", p.Name(), err)
+		return &types.SpeakerVerifyResult{Reason: err.Error()}
+	}
+	logger.Debugf("%s %s", p.Name(), result)
+	p.lastResult = result
+	return result
+}
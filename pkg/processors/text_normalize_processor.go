@@ -0,0 +1,70 @@
+package processors
+
+import (
+	"github.com/weedge/pipeline-go/pkg/frames"
+	"github.com/weedge/pipeline-go/pkg/logger"
+	"github.com/weedge/pipeline-go/pkg/processors"
+
+	achatbot_frames "achatbot/pkg/types/frames"
+	"achatbot/pkg/utils"
+)
+
+// TextNormalizeProcessor TTS 文本规整处理器, 放在句子聚合器之后, TTSProcessor 之前:
+// 去掉 LLM 输出中的 markdown, 链接和 emoji, 将数字, 货币, 日期, 时间, 单位和缩写展开为可读的文字;
+// 规整后为空的句子(如代码块)不再下发
+type TextNormalizeProcessor struct {
+	*processors.AsyncFrameProcessor
+	normalizer *utils.TextNormalizer
+}
+
+func NewTextNormalizeProcessor(normalizer *utils.TextNormalizer) *TextNormalizeProcessor {
+	return &TextNormalizeProcessor{
+		AsyncFrameProcessor: processors.NewAsyncFrameProcessor("TextNormalizeProcessor"),
+		normalizer:          normalizer,
+	}
+}
+
+func (p *TextNormalizeProcessor) Start(frame *frames.StartFrame) {
+	logger.Info("TextNormalizeProcessor Start")
+}
+
+func (p *TextNormalizeProcessor) Stop(frame *frames.EndFrame) {
+	logger.Info("TextNormalizeProcessor Stop")
+}
+
+func (p *TextNormalizeProcessor) Cancel(frame *frames.CancelFrame) {
+	logger.Info("TextNormalizeProcessor Cancel")
+}
+
+// ProcessFrame processes a frame
+func (p *TextNormalizeProcessor) ProcessFrame(frame frames.Frame, direction processors.FrameDirection) {
+	// call frame processor to init star frame init
+	p.AsyncFrameProcessor.WithPorcessFrameAllowPush(false).ProcessFrame(frame, direction)
+
+	switch f := frame.(type) {
+	case *frames.StartFrame:
+		p.PushFrame(f, direction)
+		p.Start(f)
+	case *frames.EndFrame:
+		p.PushFrame(f, direction)
+		p.Stop(f)
+	case *frames.CancelFrame:
+		p.PushFrame(f, direction)
+		p.Cancel(f)
+	case *frames.TextFrame:
+		text := p.normalizer.Normalize(f.Text)
+		if text == "" {
+			logger.Debugf("TextNormalizeProcessor drop text %q", f.Text)
+			return
+		}
+		p.QueueFrame(frames.NewTextFrame(text), direction)
+	case *frames.StartInterruptionFrame:
+		p.normalizer.Reset()
+		p.QueueFrame(f, direction)
+	case *achatbot_frames.TurnEndFrame:
+		p.normalizer.Reset()
+		p.QueueFrame(f, direction)
+	default:
+		p.QueueFrame(f, direction)
+	}
+}
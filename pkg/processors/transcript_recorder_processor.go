@@ -0,0 +1,135 @@
+package processors
+
+import (
+	"github.com/weedge/pipeline-go/pkg/frames"
+	"github.com/weedge/pipeline-go/pkg/logger"
+	"github.com/weedge/pipeline-go/pkg/processors"
+
+	"achatbot/pkg/services/transcript"
+	achatbot_frames "achatbot/pkg/types/frames"
+)
+
+// TranscriptRecorderProcessor 会话记录的采集点, 帧透传:
+// 用户侧放在轮次判定之后 LLM 之前, 记录用户转写; 机器人侧放在 TTS 之后, 记录回复文本, 思考文本,
+// 工具调用和结果, 保存的音频文件及输出处理器上行的打断
+type TranscriptRecorderProcessor struct {
+	*processors.AsyncFrameProcessor
+	recorder *transcript.Recorder
+	bot      bool
+}
+
+// NewUserTranscriptRecorderProcessor records the user transcriptions
+func NewUserTranscriptRecorderProcessor(recorder *transcript.Recorder) *TranscriptRecorderProcessor {
+	return &TranscriptRecorderProcessor{
+		AsyncFrameProcessor: processors.NewAsyncFrameProcessor("UserTranscriptRecorderProcessor"),
+		recorder:            recorder,
+	}
+}
+
+// NewBotTranscriptRecorderProcessor records the bot replies
+func NewBotTranscriptRecorderProcessor(recorder *transcript.Recorder) *TranscriptRecorderProcessor {
+	return &TranscriptRecorderProcessor{
+		AsyncFrameProcessor: processors.NewAsyncFrameProcessor("BotTranscriptRecorderProcessor"),
+		recorder:            recorder,
+		bot:                 true,
+	}
+}
+
+func (p *TranscriptRecorderProcessor) Start(frame *frames.StartFrame) {
+	logger.Info(p.Name()+" Start", "path", p.recorder.Path())
+}
+
+func (p *TranscriptRecorderProcessor) Stop(frame *frames.EndFrame) {
+	if p.bot {
+		p.recorder.EndBotReply()
+	}
+	logger.Info(p.Name() + " Stop")
+}
+
+func (p *TranscriptRecorderProcessor) Cancel(frame *frames.CancelFrame) {
+	if p.bot {
+		p.recorder.EndBotReply()
+	}
+	logger.Info(p.Name() + " Cancel")
+}
+
+// ProcessFrame processes a frame
+func (p *TranscriptRecorderProcessor) ProcessFrame(frame frames.Frame, direction processors.FrameDirection) {
+	// call frame processor to init star frame init
+	p.AsyncFrameProcessor.WithPorcessFrameAllowPush(false).ProcessFrame(frame, direction)
+
+	switch f := frame.(type) {
+	case *frames.StartFrame:
+		p.PushFrame(f, direction)
+		p.Start(f)
+	case *frames.EndFrame:
+		p.PushFrame(f, direction)
+		p.Stop(f)
+	case *frames.CancelFrame:
+		p.PushFrame(f, direction)
+		p.Cancel(f)
+	default:
+		if p.bot {
+			p.recordBot(f, direction)
+		} else if direction == processors.FrameDirectionDownstream {
+			p.recordUser(f)
+		}
+		p.QueueFrame(f, direction)
+	}
+}
+
+func (p *TranscriptRecorderProcessor) recordUser(frame frames.Frame) {
+	switch f := frame.(type) {
+	case *achatbot_frames.UserStartedSpeakingFrame:
+		p.recorder.UserStartedSpeaking()
+	case *achatbot_frames.TranscriptionFrame:
+		p.recorder.RecordUser(&transcript.Entry{
+			Text:       f.Text,
+			Language:   f.Language,
+			Emotion:    f.Emotion,
+			SpeakerID:  f.SpeakerID,
+			SpeechID:   f.SpeechID,
+			AudioPaths: audioPaths(f),
+		})
+	}
+}
+
+func (p *TranscriptRecorderProcessor) recordBot(frame frames.Frame, direction processors.FrameDirection) {
+	if direction == processors.FrameDirectionUpstream {
+		if f, ok := frame.(*achatbot_frames.BotSpeechInterruptedFrame); ok {
+			p.recorder.RecordInterruption(f.SpokenText)
+		}
+		return
+	}
+
+	switch f := frame.(type) {
+	case *frames.TextFrame:
+		p.recorder.AppendBotText(f.Text)
+	case *achatbot_frames.ThinkTextFrame:
+		p.recorder.AppendThink(f.Text)
+	case *achatbot_frames.FunctionCallFrame:
+		p.recorder.RecordToolCall(f.ToolCallID, f.FunctionName, f.Arguments)
+	case *achatbot_frames.FunctionCallResultFrame:
+		p.recorder.RecordToolResult(f.ToolCallID, f.FunctionName, f.Result, f.Error)
+	case *achatbot_frames.TurnEndFrame:
+		p.recorder.EndBotReply()
+	default:
+		// bot audio saved by AudioSaveProcessor
+		for _, path := range audioPaths(f) {
+			p.recorder.AppendBotAudio(path)
+		}
+	}
+}
+
+// audioPaths returns the saved audio file linked by AudioSaveProcessor
+func audioPaths(frame frames.Frame) []string {
+	if path, ok := achatbot_frames.GetFrameMetadata(frame, achatbot_frames.AudioPathMetadataKey); ok {
+		if path, ok := path.(string); ok && path != "" {
+			return []string{path}
+		}
+	}
+	if f, ok := frame.(*achatbot_frames.PathAudioRawFrame); ok && f.Path != "" {
+		return []string{f.Path}
+	}
+	return nil
+}
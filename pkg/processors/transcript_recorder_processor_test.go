@@ -0,0 +1,50 @@
+package processors
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weedge/pipeline-go/pkg/frames"
+	"github.com/weedge/pipeline-go/pkg/processors"
+
+	"achatbot/pkg/services/transcript"
+	achatbot_frames "achatbot/pkg/types/frames"
+)
+
+func TestTranscriptRecorderProcessor(t *testing.T) {
+	dir := t.TempDir()
+	recorder, err := transcript.NewRecorder(dir, "session")
+	assert.NoError(t, err)
+	user := NewUserTranscriptRecorderProcessor(recorder)
+	bot := NewBotTranscriptRecorderProcessor(recorder)
+
+	audio := frames.NewAudioRawFrame([]byte{0, 0}, 16000, 1, 2)
+	achatbot_frames.SetFrameMetadata(audio, achatbot_frames.AudioPathMetadataKey, "user_speak.wav")
+	transcription := achatbot_frames.NewTranscriptionFrame("hi", "", 0)
+	achatbot_frames.CopyFrameMetadata(transcription, audio)
+
+	user.ProcessFrame(achatbot_frames.NewUserStartedSpeakingFrame(), processors.FrameDirectionDownstream)
+	user.ProcessFrame(transcription, processors.FrameDirectionDownstream)
+	// bot frames are recorded by the bot side
+	user.ProcessFrame(frames.NewTextFrame("ignored"), processors.FrameDirectionDownstream)
+	bot.ProcessFrame(achatbot_frames.NewFunctionCallFrame("call_1", "web_search", map[string]any{"query": "hi"}, 0), processors.FrameDirectionDownstream)
+	bot.ProcessFrame(achatbot_frames.NewFunctionCallResultFrame("call_1", "web_search", "", errors.New("timeout")), processors.FrameDirectionDownstream)
+	bot.ProcessFrame(frames.NewTextFrame("hello "), processors.FrameDirectionDownstream)
+	bot.ProcessFrame(frames.NewTextFrame("there"), processors.FrameDirectionDownstream)
+	bot.ProcessFrame(achatbot_frames.NewTurnEndFrame(), processors.FrameDirectionDownstream)
+	bot.ProcessFrame(achatbot_frames.NewBotSpeechInterruptedFrame("hello"), processors.FrameDirectionUpstream)
+	assert.NoError(t, recorder.Close())
+
+	session, err := transcript.LoadSession(dir, "session")
+	assert.NoError(t, err)
+	turns := session.Turns()
+	assert.Len(t, turns, 1)
+	assert.Len(t, turns[0].Entries, 4)
+	assert.Equal(t, "hi", turns[0].UserText())
+	assert.Equal(t, []string{"user_speak.wav"}, turns[0].Entries[0].AudioPaths)
+	assert.Equal(t, "web_search", turns[0].Entries[1].ToolName)
+	assert.Equal(t, "timeout", turns[0].Entries[2].Error)
+	assert.Equal(t, "hello there", turns[0].Entries[3].Text)
+	assert.Equal(t, "hello", turns[0].BotText())
+}
@@ -1,16 +1,35 @@
 package processors
 
 import (
+	"errors"
+	"math"
+	"strings"
+	"time"
+
 	"github.com/weedge/pipeline-go/pkg/frames"
 	"github.com/weedge/pipeline-go/pkg/logger"
 	"github.com/weedge/pipeline-go/pkg/processors"
 
 	"achatbot/pkg/common"
+	"achatbot/pkg/services/metrics"
+	"achatbot/pkg/services/tracing"
+	"achatbot/pkg/types"
+	achatbot_frames "achatbot/pkg/types/frames"
+	"achatbot/pkg/utils"
 )
 
 type TTSProcessor struct {
 	*processors.AsyncFrameProcessor
 	provider common.ITTSProvider
+
+	// session synthesis options, applied by the provider which supports options
+	options *types.TTSOptions
+	// parses the llm markup (break, emphasis, voice, say-as) into synthesis segments, nil if disabled
+	markupParser *utils.TTSMarkupParser
+
+	tracer *tracing.Tracer
+	// trace context of the text being synthesized
+	traceContext tracing.SpanContext
 }
 
 func NewTTSProcessor(provider common.ITTSProvider) *TTSProcessor {
@@ -25,6 +44,37 @@ func (p *TTSProcessor) WithPassText(passText bool) *TTSProcessor {
 	return p
 }
 
+// WithOptions sets the session synthesis options (voice, speed, pitch, volume)
+func (p *TTSProcessor) WithOptions(options *types.TTSOptions) *TTSProcessor {
+	p.options = options
+	return p
+}
+
+// WithMarkup enables the lightweight SSML-like markup of the text, see utils.TTSMarkupParser
+func (p *TTSProcessor) WithMarkup(markup bool) *TTSProcessor {
+	p.markupParser = nil
+	if markup {
+		p.markupParser = utils.NewTTSMarkupParser()
+	}
+	return p
+}
+
+// WithTracer traces each synthesis as a child span of the turn
+func (p *TTSProcessor) WithTracer(tracer *tracing.Tracer) *TTSProcessor {
+	p.tracer = tracer
+	return p
+}
+
+// GetAudioInFormat tts consumes text
+func (p *TTSProcessor) GetAudioInFormat() *types.AudioFormat {
+	return nil
+}
+
+// GetAudioOutFormat returns the synthesized audio format
+func (p *TTSProcessor) GetAudioOutFormat() *types.AudioFormat {
+	return types.NewAudioFormat(p.provider.GetSampleInfo())
+}
+
 func (p *TTSProcessor) Start(frame *frames.StartFrame) {
 	logger.Info("TTSProcessor Start")
 }
@@ -33,8 +83,8 @@ func (p *TTSProcessor) Stop(frame *frames.EndFrame) {
 	logger.Info("TTSProcessor Stop")
 }
 
+// Cancel the provider is owned by the caller (e.g. returned to the pool when the session ends), not released here
 func (p *TTSProcessor) Cancel(frame *frames.CancelFrame) {
-	p.provider.Release()
 	logger.Info("TTSProcessor Cancel")
 }
 
@@ -54,14 +104,131 @@ func (p *TTSProcessor) ProcessFrame(frame frames.Frame, direction processors.Fra
 		p.PushFrame(f, direction)
 		p.Cancel(f)
 	case *frames.TextFrame:
+		p.traceContext = p.tracer.ParentContext(f)
+		segments := p.parseSegments(f.Text)
 		if p.PassText() {
-			p.QueueFrame(f, direction)
+			// client shows the text without markup
+			if p.markupParser != nil {
+				p.QueueFrame(frames.NewTextFrame(segmentsText(segments)), direction)
+			} else {
+				p.QueueFrame(f, direction)
+			}
 		}
-		audio := p.provider.Synthesize(f.Text)
-		rate, channels, sampleWidth := p.provider.GetSampleInfo()
-		p.PushDownstreamFrame(frames.NewAudioRawFrame(audio, rate, channels, sampleWidth))
+		p.synthesize(segments)
+	case *frames.StartInterruptionFrame:
+		p.resetMarkup()
+		p.QueueFrame(f, direction)
+	case *achatbot_frames.TurnEndFrame:
+		p.resetMarkup()
+		p.QueueFrame(f, direction)
 	default:
 		p.QueueFrame(f, direction)
 	}
+}
+
+// parseSegments parses the markup text into synthesis segments, the whole text is one segment if markup disabled
+func (p *TTSProcessor) parseSegments(text string) []types.TTSSegment {
+	if p.markupParser == nil {
+		return []types.TTSSegment{{Text: text, SpeedScale: 1}}
+	}
+	return p.markupParser.Parse(text)
+}
+
+func segmentsText(segments []types.TTSSegment) string {
+	var sb strings.Builder
+	for _, segment := range segments {
+		sb.WriteString(segment.Text)
+	}
+	return sb.String()
+}
 
+// synthesize segments: breaks are pushed as silence,
+// others are synthesized with the segment speaker and speed scale
+func (p *TTSProcessor) synthesize(segments []types.TTSSegment) {
+	for _, segment := range segments {
+		if segment.BreakSecs > 0 {
+			p.pushSilence(segment.BreakSecs)
+			continue
+		}
+		options := p.options.Merge(&types.TTSOptions{Speaker: segment.Speaker})
+		if segment.SpeedScale > 0 && segment.SpeedScale != 1 {
+			speed := options.Speed
+			if speed <= 0 {
+				speed = 1
+			}
+			options.Speed = speed * segment.SpeedScale
+		}
+		p.synthesizeSegment(segment.Text, options)
+	}
+}
+
+// synthesizeSegment multi-language provider synthesizes each language segment with the voice of the language,
+// unless the speaker is specified
+func (p *TTSProcessor) synthesizeSegment(text string, options *types.TTSOptions) {
+	multiLanguageProvider, ok := p.provider.(common.IMultiLanguageTTSProvider)
+	if !ok || len(multiLanguageProvider.GetLanguages()) == 0 || (options != nil && options.Speaker != "") {
+		p.pushAudio(p.synthesizeText(text, "", options), text)
+		return
+	}
+
+	for _, segment := range utils.SplitTextByLanguage(text, 2) {
+		if strings.TrimSpace(segment.Text) == "" {
+			continue
+		}
+		p.pushAudio(p.synthesizeText(segment.Text, segment.Language, options), segment.Text)
+	}
+}
+
+// synthesizeText synthesizes with options if the provider supports, otherwise with the voice of the language
+func (p *TTSProcessor) synthesizeText(text, language string, options *types.TTSOptions) (audio []byte) {
+	start := time.Now()
+	span := p.tracer.StartSpan(tracing.SpanTTS, p.traceContext)
+	span.SetAttribute("tts.text", text)
+	span.SetAttribute("tts.language", language)
+	defer func() {
+		metrics.ObserveProcessing(metrics.ProcessorTTS, start)
+		if len(audio) == 0 {
+			logger.Warn("TTS synthesized empty audio", "text", text, "language", language)
+			metrics.IncError(metrics.ProcessorTTS)
+			span.RecordError(errors.New("empty audio"))
+		}
+		span.SetAttribute("tts.audio_bytes", len(audio))
+		span.End()
+	}()
+
+	if optionsProvider, ok := p.provider.(common.ITTSOptionsProvider); ok && options != nil {
+		return optionsProvider.SynthesizeWithOptions(text, options.Merge(&types.TTSOptions{Language: language}))
+	}
+	if multiLanguageProvider, ok := p.provider.(common.IMultiLanguageTTSProvider); ok && language != "" {
+		return multiLanguageProvider.SynthesizeLanguage(text, language)
+	}
+	return p.provider.Synthesize(text)
+}
+
+// pushSilence pushes the markup break as silence audio, at most utils.MaxTTSBreakSecs
+func (p *TTSProcessor) pushSilence(secs float64) {
+	if math.IsNaN(secs) || secs <= 0 {
+		return
+	}
+	secs = min(secs, utils.MaxTTSBreakSecs)
+	rate, channels, sampleWidth := p.provider.GetSampleInfo()
+	p.pushAudio(make([]byte, int(secs*float64(rate))*channels*sampleWidth), "")
+}
+
+func (p *TTSProcessor) resetMarkup() {
+	if p.markupParser != nil {
+		p.markupParser.Reset()
+	}
+}
+
+// pushAudio pushes synthesized audio annotated with the text and the estimated word timestamps,
+// sample info is updated by the provider after synthesis
+func (p *TTSProcessor) pushAudio(audio []byte, text string) {
+	rate, channels, sampleWidth := p.provider.GetSampleInfo()
+	var words []types.WordTimestamp
+	if text != "" && rate > 0 && channels > 0 && sampleWidth > 0 {
+		words = utils.AlignTextWords(text, float64(len(audio))/float64(rate*channels*sampleWidth))
+	}
+	audioFrame := achatbot_frames.NewTTSAudioRawFrame(audio, rate, channels, sampleWidth, text, words)
+	p.PushDownstreamFrame(audioFrame)
 }
@@ -0,0 +1,54 @@
+package processors
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"achatbot/pkg/types"
+)
+
+// fakeOptionsTTSProvider 记录合成选项, 合成结果为文本字节
+type fakeOptionsTTSProvider struct {
+	options []*types.TTSOptions
+}
+
+func (p *fakeOptionsTTSProvider) Synthesize(text string) []byte { return []byte(text) }
+func (p *fakeOptionsTTSProvider) SynthesizeWithOptions(text string, options *types.TTSOptions) []byte {
+	p.options = append(p.options, options)
+	return []byte(text)
+}
+func (p *fakeOptionsTTSProvider) Warmup()                             {}
+func (p *fakeOptionsTTSProvider) GetSampleInfo() (int, int, int)      { return 24000, 1, 2 }
+func (p *fakeOptionsTTSProvider) SetPromptAudio(string, []byte) error { return nil }
+func (p *fakeOptionsTTSProvider) Name() string                        { return "fake" }
+func (p *fakeOptionsTTSProvider) Reset() error                        { return nil }
+func (p *fakeOptionsTTSProvider) Release() error                      { return nil }
+
+func TestTTSProcessorSegments(t *testing.T) {
+	provider := &fakeOptionsTTSProvider{}
+	p := NewTTSProcessor(provider).WithOptions(&types.TTSOptions{Speaker: "zf_xiaobei", Speed: 1.2})
+
+	// 未开启标记时整句作为一个片段
+	segments := p.parseSegments(`你好<break time="1s"/>`)
+	assert.Len(t, segments, 1)
+
+	p.WithMarkup(true)
+	segments = p.parseSegments(`你好<break time="1s"/><voice name="zm_yunjian">再见</voice>`)
+	assert.Equal(t, "你好再见", segmentsText(segments))
+	assert.Len(t, segments, 3)
+	assert.Equal(t, 1.0, segments[1].BreakSecs)
+
+	// 片段音色覆盖会话音色, 会话语速保留
+	options := p.options.Merge(&types.TTSOptions{Speaker: segments[2].Speaker})
+	assert.Equal(t, []byte("再见"), p.synthesizeText(segments[2].Text, "zh", options))
+	assert.Equal(t, "zm_yunjian", provider.options[0].Speaker)
+	assert.Equal(t, float32(1.2), provider.options[0].Speed)
+	assert.Equal(t, "zh", provider.options[0].Language)
+
+	// 未闭合的标记作用到后续句子, 直到 Reset
+	p.parseSegments(`<emphasis level="strong">重要`)
+	assert.Equal(t, float32(0.75), p.parseSegments("的事")[0].SpeedScale)
+	p.resetMarkup()
+	assert.Equal(t, float32(1), p.parseSegments("的事")[0].SpeedScale)
+}
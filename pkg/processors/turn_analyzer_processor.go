@@ -0,0 +1,260 @@
+package processors
+
+import (
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/weedge/pipeline-go/pkg/frames"
+	"github.com/weedge/pipeline-go/pkg/logger"
+	"github.com/weedge/pipeline-go/pkg/processors"
+
+	"achatbot/pkg/common"
+	"achatbot/pkg/types"
+	achatbot_frames "achatbot/pkg/types/frames"
+	"achatbot/pkg/utils"
+)
+
+// TurnAnalyzerProcessor 轮次分析处理器, 放在 ASR 与 LLM 之间:
+// 累计一轮内各 VAD 语音段的转录文本, 由轮次分析器判断用户说完后,
+// 推送整轮转录 TranscriptionFrame(ASR 只输出 TextFrame 时为 TextFrame) 和 UserEndOfTurnFrame; 未说完则按自适应超时继续等待
+type TurnAnalyzerProcessor struct {
+	*processors.AsyncFrameProcessor
+	analyzer common.ITurnAnalyzer
+
+	mu            sync.Mutex
+	transcript    string
+	last          *achatbot_frames.TranscriptionFrame // last transcription of the turn, turn transcription takes its tags
+	words         []types.WordTimestamp               // words of the turn, timestamps are relative to the first transcribed speech
+	firstSpeechAt time.Time                           // start of the first transcribed speech of the turn
+	audio         []byte
+	userSpeaking  bool
+	lastStoppedAt time.Time
+	// starts of the current and the previous speech, the transcription belongs to the last stopped speech
+	speechStartedAt     time.Time
+	prevSpeechStartedAt time.Time
+	timer               *time.Timer
+	now                 func() time.Time
+}
+
+func NewTurnAnalyzerProcessor(analyzer common.ITurnAnalyzer) *TurnAnalyzerProcessor {
+	return &TurnAnalyzerProcessor{
+		AsyncFrameProcessor: processors.NewAsyncFrameProcessor("TurnAnalyzerProcessor"),
+		analyzer:            analyzer,
+		now:                 time.Now,
+	}
+}
+
+func (p *TurnAnalyzerProcessor) Start(frame *frames.StartFrame) {
+	logger.Info("TurnAnalyzerProcessor Start")
+}
+
+func (p *TurnAnalyzerProcessor) Stop(frame *frames.EndFrame) {
+	p.stopTimer()
+	logger.Info("TurnAnalyzerProcessor Stop")
+}
+
+func (p *TurnAnalyzerProcessor) Cancel(frame *frames.CancelFrame) {
+	p.stopTimer()
+	logger.Info("TurnAnalyzerProcessor Cancel")
+}
+
+// ProcessFrame processes a frame
+func (p *TurnAnalyzerProcessor) ProcessFrame(frame frames.Frame, direction processors.FrameDirection) {
+	// call frame processor to init star frame init
+	p.AsyncFrameProcessor.WithPorcessFrameAllowPush(false).ProcessFrame(frame, direction)
+
+	switch f := frame.(type) {
+	case *frames.StartFrame:
+		p.PushFrame(f, direction)
+		p.Start(f)
+	case *frames.EndFrame:
+		p.PushFrame(f, direction)
+		p.Stop(f)
+	case *frames.CancelFrame:
+		p.PushFrame(f, direction)
+		p.Cancel(f)
+	case *achatbot_frames.UserStartedSpeakingFrame:
+		p.userStartedSpeaking()
+		p.QueueFrame(f, direction)
+	case *achatbot_frames.UserStoppedSpeakingFrame:
+		p.userStoppedSpeaking()
+		p.QueueFrame(f, direction)
+	case *frames.TextFrame:
+		if direction != processors.FrameDirectionDownstream {
+			p.QueueFrame(f, direction)
+			return
+		}
+		p.appendTranscript(f.Text)
+		p.analyze()
+	case *achatbot_frames.TranscriptionFrame:
+		if direction != processors.FrameDirectionDownstream {
+			p.QueueFrame(f, direction)
+			return
+		}
+		p.mu.Lock()
+		p.last = f
+		p.appendWords(f.Words)
+		p.mu.Unlock()
+		p.appendTranscript(f.Text)
+		p.analyze()
+	default:
+		if audioFrame := utils.GetAudioRawFrame(frame); audioFrame != nil && direction == processors.FrameDirectionDownstream {
+			p.mu.Lock()
+			p.audio = append(p.audio, audioFrame.Audio...)
+			p.mu.Unlock()
+		}
+		p.QueueFrame(f, direction)
+	}
+}
+
+// userStartedSpeaking user continue speaking in the same turn, record the pause
+func (p *TurnAnalyzerProcessor) userStartedSpeaking() {
+	p.stopTimer()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.userSpeaking = true
+	p.prevSpeechStartedAt, p.speechStartedAt = p.speechStartedAt, p.now()
+	if p.transcript != "" && !p.lastStoppedAt.IsZero() {
+		p.analyzer.ObservePause(p.now().Sub(p.lastStoppedAt).Seconds())
+	}
+}
+
+// userStoppedSpeaking re-arms the silence timeout of the pending transcript,
+// the speech may produce no transcription (e.g. noise dropped by ASR, unverified speaker)
+func (p *TurnAnalyzerProcessor) userStoppedSpeaking() {
+	p.mu.Lock()
+	p.userSpeaking = false
+	p.lastStoppedAt = p.now()
+	pending := p.transcript != ""
+	p.mu.Unlock()
+	if pending {
+		p.analyze()
+	}
+}
+
+// appendWords offsets the word timestamps of the speech (relative to the speech) by the speech start within the turn,
+// keeps the turn words in time order; called with p.mu held
+func (p *TurnAnalyzerProcessor) appendWords(words []types.WordTimestamp) {
+	// the transcription arrives after its speech stopped, the user may be speaking the next one
+	speechStartedAt := p.speechStartedAt
+	if p.userSpeaking {
+		speechStartedAt = p.prevSpeechStartedAt
+	}
+	if p.transcript == "" {
+		p.firstSpeechAt = speechStartedAt
+	}
+
+	var offset float32
+	if !speechStartedAt.IsZero() && !p.firstSpeechAt.IsZero() {
+		offset = float32(speechStartedAt.Sub(p.firstSpeechAt).Seconds())
+	}
+	if n := len(p.words); n > 0 {
+		offset = max(offset, p.words[n-1].End)
+	}
+	for _, word := range words {
+		word.Start += offset
+		word.End += offset
+		p.words = append(p.words, word)
+	}
+}
+
+// appendTranscript joins the segment transcript, add space between latin words
+func (p *TurnAnalyzerProcessor) appendTranscript(text string) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.transcript != "" {
+		last, _ := utf8.DecodeLastRuneInString(p.transcript)
+		first, _ := utf8.DecodeRuneInString(text)
+		if !unicode.Is(unicode.Han, last) || !unicode.Is(unicode.Han, first) {
+			p.transcript += " "
+		}
+	}
+	p.transcript += text
+}
+
+// analyze decides end of turn, or wait the adaptive silence timeout
+func (p *TurnAnalyzerProcessor) analyze() {
+	p.mu.Lock()
+	transcript, audio := p.transcript, p.audio
+	silenceSecs := 0.0
+	if !p.userSpeaking && !p.lastStoppedAt.IsZero() {
+		silenceSecs = p.now().Sub(p.lastStoppedAt).Seconds()
+	}
+	p.mu.Unlock()
+	if transcript == "" {
+		return
+	}
+
+	result := p.analyzer.AnalyzeEndOfTurn(transcript, audio, silenceSecs)
+	logger.Debugf("%s %q %s", p.Name(), transcript, result)
+	if result.State == types.TurnComplete {
+		p.endTurn(result, silenceSecs)
+		return
+	}
+
+	p.stopTimer()
+	delay := time.Duration((result.WaitSecs - silenceSecs) * float64(time.Second))
+	p.mu.Lock()
+	p.timer = time.AfterFunc(delay, func() {
+		p.mu.Lock()
+		speaking := p.userSpeaking
+		p.mu.Unlock()
+		if speaking {
+			return
+		}
+		result.Reason += ", silence timeout"
+		p.endTurn(result, result.WaitSecs)
+	})
+	p.mu.Unlock()
+}
+
+// endTurn pushes the turn transcript and the explicit end of turn frame
+func (p *TurnAnalyzerProcessor) endTurn(result *types.EndOfTurnResult, silenceSecs float64) {
+	p.stopTimer()
+
+	p.mu.Lock()
+	transcript, last, words := p.transcript, p.last, p.words
+	p.transcript = ""
+	p.last = nil
+	p.words = nil
+	p.firstSpeechAt = time.Time{}
+	p.audio = nil
+	p.mu.Unlock()
+	if transcript == "" {
+		return
+	}
+
+	logger.Infof("%s end of turn: %q probability: %.2f reason: %s", p.Name(), transcript, result.Probability, result.Reason)
+	endOfTurnFrame := achatbot_frames.NewUserEndOfTurnFrame(transcript, result.Probability, silenceSecs, result.Reason)
+	if last != nil {
+		frame := achatbot_frames.NewTranscriptionFrame(transcript, last.SpeakerID, last.SpeakerScore)
+		frame.Words = words
+		frame.Language, frame.Emotion, frame.Event = last.Language, last.Emotion, last.Event
+		frame.SpeechID, frame.UserID = last.SpeechID, last.UserID
+		// propagate the trace context of the last transcription
+		achatbot_frames.CopyFrameMetadata(frame, last)
+		achatbot_frames.CopyFrameMetadata(endOfTurnFrame, last)
+		p.QueueFrame(frame, processors.FrameDirectionDownstream)
+	} else {
+		p.QueueFrame(frames.NewTextFrame(transcript), processors.FrameDirectionDownstream)
+	}
+	p.QueueFrame(endOfTurnFrame, processors.FrameDirectionDownstream)
+}
+
+func (p *TurnAnalyzerProcessor) stopTimer() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.timer != nil {
+		p.timer.Stop()
+		p.timer = nil
+	}
+}
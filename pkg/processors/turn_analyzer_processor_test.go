@@ -0,0 +1,144 @@
+package processors
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weedge/pipeline-go/pkg/frames"
+	"github.com/weedge/pipeline-go/pkg/pipeline"
+	"github.com/weedge/pipeline-go/pkg/processors"
+
+	"achatbot/pkg/types"
+	achatbot_frames "achatbot/pkg/types/frames"
+)
+
+// fakeTurnAnalyzer 转录以 completeSuffix 结尾时判断说完, 否则等待固定静音超时
+type fakeTurnAnalyzer struct {
+	waitSecs       float64
+	completeSuffix string
+}
+
+func (a *fakeTurnAnalyzer) AnalyzeEndOfTurn(transcript string, audio []byte, silenceSecs float64) *types.EndOfTurnResult {
+	if a.completeSuffix != "" && strings.HasSuffix(transcript, a.completeSuffix) {
+		return &types.EndOfTurnResult{State: types.TurnComplete}
+	}
+	return &types.EndOfTurnResult{State: types.TurnIncomplete, WaitSecs: a.waitSecs}
+}
+func (a *fakeTurnAnalyzer) ObservePause(pauseSecs float64) {}
+func (a *fakeTurnAnalyzer) Reset() error                   { return nil }
+
+// turnCaptureProcessor 记录轮次分析器下游收到的帧
+type turnCaptureProcessor struct {
+	*processors.FrameProcessor
+	mu     sync.Mutex
+	frames []frames.Frame
+}
+
+func (p *turnCaptureProcessor) ProcessFrame(frame frames.Frame, direction processors.FrameDirection) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.frames = append(p.frames, frame)
+}
+
+func (p *turnCaptureProcessor) endOfTurn() *achatbot_frames.UserEndOfTurnFrame {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, frame := range p.frames {
+		if f, ok := frame.(*achatbot_frames.UserEndOfTurnFrame); ok {
+			return f
+		}
+	}
+	return nil
+}
+
+func (p *turnCaptureProcessor) transcription() *achatbot_frames.TranscriptionFrame {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, frame := range p.frames {
+		if f, ok := frame.(*achatbot_frames.TranscriptionFrame); ok {
+			return f
+		}
+	}
+	return nil
+}
+
+// fakeTurnClock 由测试推进的时钟
+type fakeTurnClock struct {
+	t time.Time
+}
+
+func (c *fakeTurnClock) Now() time.Time          { return c.t }
+func (c *fakeTurnClock) Advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTurnAnalyzerTestPipeline(analyzer *fakeTurnAnalyzer) (*pipeline.Pipeline, *turnCaptureProcessor, *fakeTurnClock) {
+	clock := &fakeTurnClock{t: time.Unix(1700000000, 0)}
+	p := NewTurnAnalyzerProcessor(analyzer)
+	p.now = clock.Now
+	sink := &turnCaptureProcessor{FrameProcessor: processors.NewFrameProcessor("TurnCaptureProcessor")}
+	pipe := pipeline.NewPipelineWithVerbose([]processors.IFrameProcessor{p, sink}, nil, nil, false)
+	pipe.ProcessFrame(frames.NewStartFrame(), processors.FrameDirectionDownstream)
+	return pipe, sink, clock
+}
+
+func TestTurnAnalyzerProcessorSpeechWithoutTranscript(t *testing.T) {
+	pipe, sink, clock := newTurnAnalyzerTestPipeline(&fakeTurnAnalyzer{waitSecs: 0.05})
+	defer pipe.ProcessFrame(frames.NewEndFrame(), processors.FrameDirectionDownstream)
+	process := func(frame frames.Frame) {
+		pipe.ProcessFrame(frame, processors.FrameDirectionDownstream)
+	}
+
+	process(achatbot_frames.NewUserStartedSpeakingFrame())
+	clock.Advance(time.Second)
+	process(achatbot_frames.NewUserStoppedSpeakingFrame())
+	process(frames.NewTextFrame("我想问一下"))
+
+	// 停顿后用户继续说话, 但该语音段没有转录(噪声被 ASR 丢弃)
+	clock.Advance(500 * time.Millisecond)
+	process(achatbot_frames.NewUserStartedSpeakingFrame())
+	time.Sleep(100 * time.Millisecond)
+	assert.Nil(t, sink.endOfTurn(), "the turn doesn't end while the user is speaking")
+	clock.Advance(time.Second)
+	process(achatbot_frames.NewUserStoppedSpeakingFrame())
+
+	// 静音超时后仍结束该轮
+	assert.Eventually(t, func() bool { return sink.endOfTurn() != nil }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, "我想问一下", sink.endOfTurn().Transcript)
+}
+
+func TestTurnAnalyzerProcessorWordTimestamps(t *testing.T) {
+	pipe, sink, clock := newTurnAnalyzerTestPipeline(&fakeTurnAnalyzer{waitSecs: 10, completeSuffix: "world"})
+	defer pipe.ProcessFrame(frames.NewEndFrame(), processors.FrameDirectionDownstream)
+	process := func(frame frames.Frame) {
+		pipe.ProcessFrame(frame, processors.FrameDirectionDownstream)
+	}
+	transcription := func(text string, words ...types.WordTimestamp) {
+		frame := achatbot_frames.NewTranscriptionFrame(text, "", 0)
+		frame.Words = words
+		process(frame)
+	}
+
+	// 第一段语音 [0, 1s), 停顿后第二段语音 [1.5s, 2.5s)
+	process(achatbot_frames.NewUserStartedSpeakingFrame())
+	clock.Advance(time.Second)
+	process(achatbot_frames.NewUserStoppedSpeakingFrame())
+	transcription("hello", types.WordTimestamp{Word: "hello", Start: 0.1, End: 0.6})
+	clock.Advance(500 * time.Millisecond)
+	process(achatbot_frames.NewUserStartedSpeakingFrame())
+	clock.Advance(time.Second)
+	process(achatbot_frames.NewUserStoppedSpeakingFrame())
+	// 第二段的转录在用户说第三段时到达
+	clock.Advance(100 * time.Millisecond)
+	process(achatbot_frames.NewUserStartedSpeakingFrame())
+	transcription("world", types.WordTimestamp{Word: "world", Start: 0.2, End: 0.7})
+
+	assert.Eventually(t, func() bool { return sink.transcription() != nil }, time.Second, 10*time.Millisecond)
+	frame := sink.transcription()
+	assert.Equal(t, "hello world", frame.Text)
+	assert.Equal(t, []types.WordTimestamp{
+		{Word: "hello", Start: 0.1, End: 0.6},
+		{Word: "world", Start: 1.7, End: 2.2},
+	}, frame.Words)
+}
@@ -0,0 +1,153 @@
+package processors
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/weedge/pipeline-go/pkg/frames"
+	"github.com/weedge/pipeline-go/pkg/logger"
+	"github.com/weedge/pipeline-go/pkg/processors"
+
+	"achatbot/pkg/common"
+	"achatbot/pkg/types"
+	achatbot_frames "achatbot/pkg/types/frames"
+	"achatbot/pkg/utils"
+)
+
+// TurnMetricsProcessor 一轮对话耗时分解的记录点, 放在阶段对应的处理器之后, 记录阶段输出帧经过的时间点, 帧原样透传:
+// - vad_stop: 输入处理器之后, UserStoppedSpeakingFrame
+// - aggregator_flush: 用户语音聚合器之后, 聚合后的音频帧
+// - asr_done: ASRProcessor 之后, TranscriptionFrame
+// - llm_first_token: LLM 处理器之后, TextFrame/ThinkTextFrame
+// - first_sentence: 句子聚合器之后, TextFrame
+// - tts_first_audio: TTSProcessor 之后, 音频帧
+// first_audio_written 由 TurnMetricsTransportWriter 记录; 同一会话的记录点共享一个 TurnMetricsTracker,
+// 其中一个记录点(一般是输出处理器之前的 tts_first_audio)开启 WithReport 输出每轮的耗时分解
+type TurnMetricsProcessor struct {
+	*processors.AsyncFrameProcessor
+	tracker *utils.TurnMetricsTracker
+	stage   types.TurnStage
+
+	report      bool
+	clientEvent bool
+}
+
+func NewTurnMetricsProcessor(tracker *utils.TurnMetricsTracker, stage types.TurnStage) *TurnMetricsProcessor {
+	return &TurnMetricsProcessor{
+		AsyncFrameProcessor: processors.NewAsyncFrameProcessor("TurnMetricsProcessor"),
+		tracker:             tracker,
+		stage:               stage,
+	}
+}
+
+// WithReport 每轮完成时记录日志并下发 TurnMetricsFrame, clientEvent 为 true 时同时以 json 消息发送给客户端;
+// 会话结束时记录各阶段耗时分位数
+func (p *TurnMetricsProcessor) WithReport(clientEvent bool) *TurnMetricsProcessor {
+	if !p.report {
+		p.tracker.OnTurnMetrics(p.reportTurn)
+	}
+	p.report = true
+	p.clientEvent = clientEvent
+	return p
+}
+
+func (p *TurnMetricsProcessor) Start(frame *frames.StartFrame) {
+	logger.Info("TurnMetricsProcessor Start", "stage", p.stage)
+}
+
+func (p *TurnMetricsProcessor) Stop(frame *frames.EndFrame) {
+	p.reportSummary()
+	logger.Info("TurnMetricsProcessor Stop", "stage", p.stage)
+}
+
+func (p *TurnMetricsProcessor) Cancel(frame *frames.CancelFrame) {
+	p.reportSummary()
+	logger.Info("TurnMetricsProcessor Cancel", "stage", p.stage)
+}
+
+// ProcessFrame processes a frame
+func (p *TurnMetricsProcessor) ProcessFrame(frame frames.Frame, direction processors.FrameDirection) {
+	// call frame processor to init star frame init
+	p.AsyncFrameProcessor.WithPorcessFrameAllowPush(false).ProcessFrame(frame, direction)
+
+	switch f := frame.(type) {
+	case *frames.StartFrame:
+		p.PushFrame(f, direction)
+		p.Start(f)
+	case *frames.EndFrame:
+		p.PushFrame(f, direction)
+		p.Stop(f)
+	case *frames.CancelFrame:
+		p.PushFrame(f, direction)
+		p.Cancel(f)
+	default:
+		if direction == processors.FrameDirectionDownstream && p.isStageFrame(frame) {
+			p.tracker.Mark(p.stage)
+		}
+		p.QueueFrame(f, direction)
+	}
+}
+
+// isStageFrame reports whether the frame is the output of the stage
+func (p *TurnMetricsProcessor) isStageFrame(frame frames.Frame) bool {
+	switch frame.(type) {
+	case *achatbot_frames.UserStoppedSpeakingFrame:
+		return p.stage == types.TurnStageVADStop
+	case *achatbot_frames.VADStateAudioRawFrame:
+		return p.stage == types.TurnStageAggregatorFlush
+	case *achatbot_frames.TranscriptionFrame:
+		return p.stage == types.TurnStageASRDone
+	case *achatbot_frames.ThinkTextFrame:
+		return p.stage == types.TurnStageLLMFirstToken
+	case *frames.TextFrame:
+		return p.stage == types.TurnStageLLMFirstToken || p.stage == types.TurnStageFirstSentence
+	case *achatbot_frames.TTSAudioRawFrame:
+		return p.stage == types.TurnStageTTSFirstAudio
+	case *frames.AudioRawFrame:
+		return p.stage == types.TurnStageAggregatorFlush || p.stage == types.TurnStageTTSFirstAudio
+	}
+	return false
+}
+
+// reportTurn is called by the tracker when the first audio of the turn is written
+func (p *TurnMetricsProcessor) reportTurn(metrics *types.TurnMetrics) {
+	logger.Info(metrics.String())
+	p.QueueFrame(achatbot_frames.NewTurnMetricsFrame(metrics), processors.FrameDirectionDownstream)
+	if !p.clientEvent {
+		return
+	}
+	message, err := json.Marshal(types.NewTurnMetricsEvent(metrics))
+	if err != nil {
+		logger.Error(fmt.Sprintf("%s marshal turn metrics event error", p.Name()), "error", err)
+		return
+	}
+	p.QueueFrame(achatbot_frames.NewTransportMessageFrame(message), processors.FrameDirectionDownstream)
+}
+
+func (p *TurnMetricsProcessor) reportSummary() {
+	if !p.report {
+		return
+	}
+	logger.Info(p.tracker.Summary().String())
+}
+
+// TurnMetricsTransportWriter 包装输出处理器的 ITransportWriter, 记录每轮第一段音频写入客户端的时间点
+type TurnMetricsTransportWriter struct {
+	common.ITransportWriter
+	tracker *utils.TurnMetricsTracker
+}
+
+func NewTurnMetricsTransportWriter(writer common.ITransportWriter, tracker *utils.TurnMetricsTracker) *TurnMetricsTransportWriter {
+	return &TurnMetricsTransportWriter{
+		ITransportWriter: writer,
+		tracker:          tracker,
+	}
+}
+
+func (w *TurnMetricsTransportWriter) WriteRawAudio(data []byte) error {
+	if err := w.ITransportWriter.WriteRawAudio(data); err != nil {
+		return err
+	}
+	w.tracker.Mark(types.TurnStageFirstAudioWritten)
+	return nil
+}
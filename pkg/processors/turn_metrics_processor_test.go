@@ -0,0 +1,49 @@
+package processors
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weedge/pipeline-go/pkg/frames"
+	"github.com/weedge/pipeline-go/pkg/processors"
+
+	"achatbot/pkg/types"
+	achatbot_frames "achatbot/pkg/types/frames"
+	"achatbot/pkg/utils"
+)
+
+func TestTurnMetricsProcessor(t *testing.T) {
+	tracker := utils.NewTurnMetricsTracker("session")
+	var reported []*types.TurnMetrics
+	tracker.OnTurnMetrics(func(metrics *types.TurnMetrics) { reported = append(reported, metrics) })
+
+	taps := []struct {
+		processor *TurnMetricsProcessor
+		frame     frames.Frame
+	}{
+		{NewTurnMetricsProcessor(tracker, types.TurnStageVADStop), achatbot_frames.NewUserStoppedSpeakingFrame()},
+		{NewTurnMetricsProcessor(tracker, types.TurnStageASRDone), achatbot_frames.NewTranscriptionFrame("hi", "user", 0)},
+		{NewTurnMetricsProcessor(tracker, types.TurnStageLLMFirstToken), frames.NewTextFrame("hello")},
+		{NewTurnMetricsProcessor(tracker, types.TurnStageTTSFirstAudio), achatbot_frames.NewTTSAudioRawFrame([]byte{0, 0}, 16000, 1, 2, "hello", nil)},
+	}
+	for _, tap := range taps {
+		// upstream frames and frames of other stages are not marked
+		tap.processor.ProcessFrame(tap.frame, processors.FrameDirectionUpstream)
+		tap.processor.ProcessFrame(frames.NewAudioRawFrame([]byte{0, 0}, 16000, 1, 2), processors.FrameDirectionDownstream)
+		tap.processor.ProcessFrame(tap.frame, processors.FrameDirectionDownstream)
+	}
+
+	writer := NewTurnMetricsTransportWriter(&fakeTransportWriter{}, tracker)
+	assert.NoError(t, writer.WriteRawAudio([]byte{0, 0}))
+	assert.NoError(t, writer.WriteRawAudio([]byte{0, 0}))
+	assert.Len(t, reported, 1)
+
+	stages := make([]types.TurnStage, 0)
+	for _, latency := range reported[0].Stages {
+		stages = append(stages, latency.Stage)
+	}
+	assert.Equal(t, []types.TurnStage{
+		types.TurnStageVADStop, types.TurnStageASRDone, types.TurnStageLLMFirstToken,
+		types.TurnStageTTSFirstAudio, types.TurnStageFirstAudioWritten,
+	}, stages)
+}
@@ -0,0 +1,76 @@
+package processors
+
+import (
+	"github.com/weedge/pipeline-go/pkg/frames"
+	"github.com/weedge/pipeline-go/pkg/logger"
+	"github.com/weedge/pipeline-go/pkg/processors"
+
+	"achatbot/pkg/services/tracing"
+	achatbot_frames "achatbot/pkg/types/frames"
+)
+
+// TurnTracingProcessor 会话追踪的起点, 放在输入处理器之后: 用户开始说话时开始新的 turn span,
+// 将当前 turn 的 trace context 写入下游控制帧和文本帧的元数据, 后续处理器的 span 以此为父 span; 会话结束时结束 trace.
+// 音频帧不写入: 每秒数十帧会占满全局帧元数据环, 淘汰 audio_path 等其他元数据, 没有元数据的帧使用当前 turn
+type TurnTracingProcessor struct {
+	*processors.AsyncFrameProcessor
+	tracer *tracing.Tracer
+}
+
+func NewTurnTracingProcessor(tracer *tracing.Tracer) *TurnTracingProcessor {
+	return &TurnTracingProcessor{
+		AsyncFrameProcessor: processors.NewAsyncFrameProcessor("TurnTracingProcessor"),
+		tracer:              tracer,
+	}
+}
+
+func (p *TurnTracingProcessor) Start(frame *frames.StartFrame) {
+	logger.Info("TurnTracingProcessor Start", "trace_id", p.tracer.TraceID())
+}
+
+func (p *TurnTracingProcessor) Stop(frame *frames.EndFrame) {
+	p.tracer.End()
+	logger.Info("TurnTracingProcessor Stop", "trace_id", p.tracer.TraceID())
+}
+
+func (p *TurnTracingProcessor) Cancel(frame *frames.CancelFrame) {
+	p.tracer.End()
+	logger.Info("TurnTracingProcessor Cancel", "trace_id", p.tracer.TraceID())
+}
+
+// ProcessFrame processes a frame
+func (p *TurnTracingProcessor) ProcessFrame(frame frames.Frame, direction processors.FrameDirection) {
+	// call frame processor to init star frame init
+	p.AsyncFrameProcessor.WithPorcessFrameAllowPush(false).ProcessFrame(frame, direction)
+
+	switch f := frame.(type) {
+	case *frames.StartFrame:
+		p.PushFrame(f, direction)
+		p.Start(f)
+	case *frames.EndFrame:
+		p.PushFrame(f, direction)
+		p.Stop(f)
+	case *frames.CancelFrame:
+		p.PushFrame(f, direction)
+		p.Cancel(f)
+	case *achatbot_frames.UserStartedSpeakingFrame:
+		turn := p.tracer.StartUserTurn()
+		tracing.InjectFrame(f, turn.Context())
+		p.QueueFrame(f, direction)
+	default:
+		if direction == processors.FrameDirectionDownstream && tracedFrame(f) {
+			tracing.InjectFrame(f, p.tracer.CurrentContext())
+		}
+		p.QueueFrame(f, direction)
+	}
+}
+
+// tracedFrame reports whether the turn trace context is propagated by the frame
+func tracedFrame(frame frames.Frame) bool {
+	switch frame.(type) {
+	case *achatbot_frames.UserStoppedSpeakingFrame, *achatbot_frames.UserEndOfTurnFrame,
+		*achatbot_frames.TranscriptionFrame, *frames.TextFrame, *achatbot_frames.TurnEndFrame:
+		return true
+	}
+	return false
+}
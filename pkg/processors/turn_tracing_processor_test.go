@@ -0,0 +1,39 @@
+package processors
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weedge/pipeline-go/pkg/frames"
+	"github.com/weedge/pipeline-go/pkg/processors"
+
+	"achatbot/pkg/services/tracing"
+	achatbot_frames "achatbot/pkg/types/frames"
+)
+
+func TestTurnTracingProcessorInjectsControlAndTextFrames(t *testing.T) {
+	tracer := tracing.NewSessionTracer("session-1", nil)
+	p := NewTurnTracingProcessor(tracer)
+
+	started := achatbot_frames.NewUserStartedSpeakingFrame()
+	p.ProcessFrame(started, processors.FrameDirectionDownstream)
+	turnContext, ok := tracing.ExtractFrame(started)
+	assert.True(t, ok)
+
+	audio := frames.NewAudioRawFrame(make([]byte, 640), 16000, 1, 2)
+	stopped := achatbot_frames.NewUserStoppedSpeakingFrame()
+	transcription := achatbot_frames.NewTranscriptionFrame("hello", "", 0)
+	for _, frame := range []frames.Frame{audio, stopped, transcription} {
+		p.ProcessFrame(frame, processors.FrameDirectionDownstream)
+	}
+
+	// audio frames are not tagged, so they don't evict other frame metadata
+	_, ok = tracing.ExtractFrame(audio)
+	assert.False(t, ok)
+	for _, frame := range []frames.Frame{stopped, transcription} {
+		sc, ok := tracing.ExtractFrame(frame)
+		assert.True(t, ok)
+		assert.Equal(t, turnContext, sc)
+	}
+	assert.Equal(t, turnContext, tracer.ParentContext(audio), "untagged frames use the current turn")
+}
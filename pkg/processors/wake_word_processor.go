@@ -0,0 +1,250 @@
+package processors
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/weedge/pipeline-go/pkg/frames"
+	"github.com/weedge/pipeline-go/pkg/logger"
+	"github.com/weedge/pipeline-go/pkg/processors"
+
+	"achatbot/pkg/common"
+	"achatbot/pkg/consts"
+	"achatbot/pkg/params"
+	"achatbot/pkg/types"
+	achatbot_frames "achatbot/pkg/types/frames"
+	"achatbot/pkg/utils"
+)
+
+// WakeWordProcessor 唤醒词门控处理器, 放在 AudioVADInputProcessor 之后, 用户语音聚合器之前:
+// 休眠时只将用户语音送入 KWS 检测唤醒词, 用户语音(及打断)不进入后续处理;
+// 检测到唤醒词后打开监听窗口, 用户说完一轮或超时未说话时回到休眠;
+// 休眠/唤醒状态变化以 json 消息(TransportMessageFrame)发送给客户端;
+// spotter 为 nil 时不做门控, 直接透传
+type WakeWordProcessor struct {
+	*processors.AsyncFrameProcessor
+	args    *params.WakeWordArgs
+	spotter common.IKeywordSpotter
+
+	mu    sync.Mutex
+	state types.WakeState
+	// 用户正在说话(VAD UserStartedSpeakingFrame ~ UserStoppedSpeakingFrame)
+	userSpeaking bool
+	// 当前语音段是唤醒词所在的语音段, 以及唤醒词之后放行的音频字节数
+	inWakeSpeech bool
+	commandBytes int
+	// 监听超时定时器, timerID 用于忽略已取消定时器的回调
+	timer   *time.Timer
+	timerID int
+}
+
+func NewWakeWordProcessor(args *params.WakeWordArgs, spotter common.IKeywordSpotter) *WakeWordProcessor {
+	return &WakeWordProcessor{
+		AsyncFrameProcessor: processors.NewAsyncFrameProcessor("WakeWordProcessor"),
+		args:                args,
+		spotter:             spotter,
+		state:               types.WakeStateSleeping,
+	}
+}
+
+// GetAudioInFormat returns the audio format which keyword spotting expects
+func (p *WakeWordProcessor) GetAudioInFormat() *types.AudioFormat {
+	if declarer, ok := p.spotter.(common.IAudioFormatDeclarer); ok {
+		return declarer.GetAudioInFormat()
+	}
+	return types.NewAudioFormat(p.args.SampleRate, consts.DefaultChannels, p.args.SampleWidth)
+}
+
+// GetAudioOutFormat wake word gate pass raw audio through
+func (p *WakeWordProcessor) GetAudioOutFormat() *types.AudioFormat {
+	return nil
+}
+
+// GetState returns the current wake state
+func (p *WakeWordProcessor) GetState() types.WakeState {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.state
+}
+
+func (p *WakeWordProcessor) Start(frame *frames.StartFrame) {
+	logger.Infof("WakeWordProcessor Start %s", p.args)
+}
+
+func (p *WakeWordProcessor) Stop(frame *frames.EndFrame) {
+	p.mu.Lock()
+	p.stopTimer()
+	p.mu.Unlock()
+	logger.Info("WakeWordProcessor Stop")
+}
+
+func (p *WakeWordProcessor) Cancel(frame *frames.CancelFrame) {
+	p.mu.Lock()
+	p.stopTimer()
+	p.mu.Unlock()
+	logger.Info("WakeWordProcessor Cancel")
+}
+
+// ProcessFrame processes a frame
+func (p *WakeWordProcessor) ProcessFrame(frame frames.Frame, direction processors.FrameDirection) {
+	// call frame processor to init star frame init
+	p.AsyncFrameProcessor.WithPorcessFrameAllowPush(false).ProcessFrame(frame, direction)
+
+	switch f := frame.(type) {
+	case *frames.StartFrame:
+		p.PushFrame(f, direction)
+		p.Start(f)
+	case *frames.EndFrame:
+		p.PushFrame(f, direction)
+		p.Stop(f)
+	case *frames.CancelFrame:
+		p.PushFrame(f, direction)
+		p.Cancel(f)
+	default:
+		if p.spotter == nil || direction != processors.FrameDirectionDownstream {
+			p.QueueFrame(f, direction)
+			return
+		}
+		for _, outFrame := range p.gate(f) {
+			p.QueueFrame(outFrame, direction)
+		}
+	}
+}
+
+// gate returns the frames to push downstream for the input frame
+func (p *WakeWordProcessor) gate(frame frames.Frame) []frames.Frame {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	switch f := frame.(type) {
+	case *achatbot_frames.UserStartedSpeakingFrame:
+		p.userSpeaking = true
+		if p.state == types.WakeStateSleeping {
+			return nil
+		}
+		p.stopTimer()
+		return []frames.Frame{f}
+	case *achatbot_frames.UserStoppedSpeakingFrame:
+		p.userSpeaking = false
+		if p.state == types.WakeStateSleeping {
+			// 新的语音段重新检测唤醒词
+			p.resetSpotter()
+			return nil
+		}
+		return append([]frames.Frame{f}, p.endTurn()...)
+	case *frames.StartInterruptionFrame, *frames.StopInterruptionFrame:
+		// 休眠时用户说话不打断机器人
+		if p.state == types.WakeStateSleeping {
+			return nil
+		}
+		return []frames.Frame{f}
+	}
+
+	audioFrame := utils.GetAudioRawFrame(frame)
+	if audioFrame == nil || len(audioFrame.Audio) == 0 {
+		return []frames.Frame{frame}
+	}
+	if p.state == types.WakeStateAwake {
+		if p.inWakeSpeech {
+			p.commandBytes += len(audioFrame.Audio)
+		}
+		return []frames.Frame{frame}
+	}
+
+	keyword, err := p.spotter.DetectKeyword(audioFrame.Audio)
+	if err != nil {
+		logger.Warnf("%s detect keyword err: %v", p.Name(), err)
+		return nil
+	}
+	if keyword == "" {
+		return nil
+	}
+	// 唤醒词所在的音频不放行
+	return p.wake(keyword)
+}
+
+// wake opens the listening window; if the user is still speaking, the rest of the speech is passed as a new user speech
+func (p *WakeWordProcessor) wake(keyword string) []frames.Frame {
+	p.state = types.WakeStateAwake
+	res := []frames.Frame{p.eventFrame(types.WakeReasonKeyword, keyword)}
+	if !p.userSpeaking {
+		p.startTimer()
+		return res
+	}
+
+	p.inWakeSpeech = true
+	p.commandBytes = 0
+	if p.InterruptionsAllowed() {
+		res = append(res, frames.NewStartInterruptionFrame())
+	}
+	return append(res, achatbot_frames.NewUserStartedSpeakingFrame())
+}
+
+// endTurn closes the listening window after the user turn, or waits the next turn until timeout;
+// the wake word speech without command (e.g. only "小爱同学") doesn't close the window
+func (p *WakeWordProcessor) endTurn() []frames.Frame {
+	isCommand := true
+	if p.inWakeSpeech {
+		bytesPerSec := float64(p.args.SampleRate * p.args.SampleWidth)
+		isCommand = float64(p.commandBytes)/bytesPerSec >= p.args.MinCommandSecs
+		p.inWakeSpeech = false
+		p.commandBytes = 0
+	}
+
+	if p.args.CloseAfterTurn && isCommand {
+		return []frames.Frame{p.sleep(types.WakeReasonTurnEnd)}
+	}
+	p.startTimer()
+	return nil
+}
+
+// sleep closes the listening window
+func (p *WakeWordProcessor) sleep(reason string) frames.Frame {
+	p.state = types.WakeStateSleeping
+	p.stopTimer()
+	p.resetSpotter()
+	return p.eventFrame(reason, "")
+}
+
+// timeout closes the listening window if the user doesn't start speaking in time
+func (p *WakeWordProcessor) timeout(timerID int) frames.Frame {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if timerID != p.timerID || p.state != types.WakeStateAwake || p.userSpeaking {
+		return nil
+	}
+	return p.sleep(types.WakeReasonTimeout)
+}
+
+func (p *WakeWordProcessor) startTimer() {
+	p.stopTimer()
+	timerID := p.timerID
+	p.timer = time.AfterFunc(time.Duration(p.args.ListenTimeoutSecs*float64(time.Second)), func() {
+		if frame := p.timeout(timerID); frame != nil {
+			p.QueueFrame(frame, processors.FrameDirectionDownstream)
+		}
+	})
+}
+
+func (p *WakeWordProcessor) stopTimer() {
+	p.timerID++
+	if p.timer != nil {
+		p.timer.Stop()
+		p.timer = nil
+	}
+}
+
+func (p *WakeWordProcessor) resetSpotter() {
+	if err := p.spotter.Reset(); err != nil {
+		logger.Warnf("%s reset spotter err: %v", p.Name(), err)
+	}
+}
+
+// eventFrame builds the wake state json message sent to the client
+func (p *WakeWordProcessor) eventFrame(reason, keyword string) frames.Frame {
+	event := types.NewWakeEvent(p.state, reason, keyword)
+	logger.Infof("%s %s", p.Name(), event)
+	message, _ := json.Marshal(event)
+	return achatbot_frames.NewTransportMessageFrame(message)
+}
@@ -0,0 +1,125 @@
+package processors
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weedge/pipeline-go/pkg/frames"
+
+	"achatbot/pkg/params"
+	"achatbot/pkg/types"
+	achatbot_frames "achatbot/pkg/types/frames"
+)
+
+// fakeKeywordSpotter 音频首字节为 9 时检测到唤醒词
+type fakeKeywordSpotter struct {
+	resets int
+}
+
+func (s *fakeKeywordSpotter) DetectKeyword(audio []byte) (string, error) {
+	if audio[0] == 9 {
+		return "小爱同学", nil
+	}
+	return "", nil
+}
+func (s *fakeKeywordSpotter) Warmup()        {}
+func (s *fakeKeywordSpotter) Name() string   { return "fake" }
+func (s *fakeKeywordSpotter) Reset() error   { s.resets++; return nil }
+func (s *fakeKeywordSpotter) Release() error { return nil }
+
+func wakeAudio(b byte, secs float64) frames.Frame {
+	buf := make([]byte, int(secs*16000*2))
+	buf[0] = b
+	return frames.NewAudioRawFrame(buf, 16000, 1, 2)
+}
+
+func wakeEvent(t *testing.T, frame frames.Frame) *types.WakeEvent {
+	msg, ok := frame.(*achatbot_frames.TransportMessageFrame)
+	if !assert.True(t, ok, "%T", frame) {
+		return nil
+	}
+	event := &types.WakeEvent{}
+	assert.NoError(t, json.Unmarshal(msg.Message, event))
+	return event
+}
+
+func TestWakeWordProcessorGate(t *testing.T) {
+	args := params.NewWakeWordArgs()
+	assert.NoError(t, args.Validate())
+	spotter := &fakeKeywordSpotter{}
+	p := NewWakeWordProcessor(args, spotter)
+	defer p.Cancel(nil)
+
+	// 休眠时用户语音和打断被丢弃
+	assert.Empty(t, p.gate(achatbot_frames.NewUserStartedSpeakingFrame()))
+	assert.Empty(t, p.gate(frames.NewStartInterruptionFrame()))
+	assert.Empty(t, p.gate(wakeAudio(0, 0.5)))
+	assert.Empty(t, p.gate(achatbot_frames.NewUserStoppedSpeakingFrame()))
+	assert.Equal(t, 1, spotter.resets)
+	// 其他帧透传
+	assert.Len(t, p.gate(frames.NewTextFrame("hi")), 1)
+
+	// 语音段中检测到唤醒词, 唤醒词之后的语音作为新的用户语音放行
+	p.gate(achatbot_frames.NewUserStartedSpeakingFrame())
+	res := p.gate(wakeAudio(9, 0.5))
+	assert.Len(t, res, 3)
+	event := wakeEvent(t, res[0])
+	assert.Equal(t, "awake", event.State)
+	assert.Equal(t, types.WakeReasonKeyword, event.Reason)
+	assert.Equal(t, "小爱同学", event.Keyword)
+	assert.IsType(t, &frames.StartInterruptionFrame{}, res[1])
+	assert.IsType(t, &achatbot_frames.UserStartedSpeakingFrame{}, res[2])
+	assert.Equal(t, types.WakeStateAwake, p.GetState())
+
+	// 唤醒词之后的指令足够长, 一轮结束后回到休眠
+	assert.Len(t, p.gate(wakeAudio(0, 1)), 1)
+	res = p.gate(achatbot_frames.NewUserStoppedSpeakingFrame())
+	assert.Len(t, res, 2)
+	assert.IsType(t, &achatbot_frames.UserStoppedSpeakingFrame{}, res[0])
+	event = wakeEvent(t, res[1])
+	assert.Equal(t, "sleeping", event.State)
+	assert.Equal(t, types.WakeReasonTurnEnd, event.Reason)
+	assert.Equal(t, types.WakeStateSleeping, p.GetState())
+}
+
+func TestWakeWordProcessorWakeOnly(t *testing.T) {
+	p := NewWakeWordProcessor(params.NewWakeWordArgs(), &fakeKeywordSpotter{})
+	defer p.Cancel(nil)
+
+	// 只说了唤醒词, 不关闭监听窗口, 等待下一轮
+	p.gate(achatbot_frames.NewUserStartedSpeakingFrame())
+	p.gate(wakeAudio(9, 0.5))
+	assert.Len(t, p.gate(achatbot_frames.NewUserStoppedSpeakingFrame()), 1)
+	assert.Equal(t, types.WakeStateAwake, p.GetState())
+
+	// 说话中不超时
+	assert.Len(t, p.gate(achatbot_frames.NewUserStartedSpeakingFrame()), 1)
+	assert.Nil(t, p.timeout(p.timerID))
+	res := p.gate(achatbot_frames.NewUserStoppedSpeakingFrame())
+	assert.Len(t, res, 2)
+	assert.Equal(t, types.WakeStateSleeping, p.GetState())
+}
+
+func TestWakeWordProcessorTimeout(t *testing.T) {
+	args := params.NewWakeWordArgs().WithCloseAfterTurn(false)
+	p := NewWakeWordProcessor(args, &fakeKeywordSpotter{})
+	defer p.Cancel(nil)
+
+	// 未在语音段中唤醒(如 VAD 未开启), 只发送唤醒事件
+	res := p.gate(wakeAudio(9, 0.5))
+	assert.Len(t, res, 1)
+	assert.Equal(t, types.WakeStateAwake, p.GetState())
+
+	// 不在一轮结束后关闭, 等待超时
+	p.gate(achatbot_frames.NewUserStartedSpeakingFrame())
+	assert.Len(t, p.gate(achatbot_frames.NewUserStoppedSpeakingFrame()), 1)
+	assert.Equal(t, types.WakeStateAwake, p.GetState())
+
+	// 过期定时器的回调被忽略
+	assert.Nil(t, p.timeout(p.timerID-1))
+	event := wakeEvent(t, p.timeout(p.timerID))
+	assert.Equal(t, "sleeping", event.State)
+	assert.Equal(t, types.WakeReasonTimeout, event.Reason)
+	assert.Equal(t, types.WakeStateSleeping, p.GetState())
+}
@@ -34,7 +34,7 @@ func (p *WebsocketServerOutputProcessor) ProcessFrame(frame frames.Frame, direct
 	// Handle specific frame types
 	switch f := frame.(type) {
 	case *achatbot_frames.VADStateAudioRawFrame:
-		p.handleAudio(f.AudioRawFrame)
+		p.handleAudio(f.AudioRawFrame, nil)
 	case *frames.StartInterruptionFrame:
 		err := p.transportWriter.WriteFrame(f)
 		if err != nil {
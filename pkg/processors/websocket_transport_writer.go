@@ -77,10 +77,32 @@ func (p *WebsocketTransportWriter) WriteFrame(frame frames.Frame) error {
 		err = p.SendPayload(f)
 	case *achatbot_frames.AnimationAudioRawFrame:
 		err = p.WriteAnimationAudioFrame(f)
+	case *achatbot_frames.TransportMessageFrame:
+		err = p.SendMessage(f)
 	}
 	return err
 }
 
+// SendMessage sends the transport message (e.g. json event) to the WebSocket as is
+func (p *WebsocketTransportWriter) SendMessage(frame *achatbot_frames.TransportMessageFrame) error {
+	if len(frame.Message) == 0 {
+		return nil
+	}
+
+	messageType := consts.BinaryMessage
+	if isStringPayload(frame.Message) {
+		messageType = consts.TextMessage
+	}
+
+	err := p.websocket.WriteMessage(messageType, frame.Message)
+	if err != nil {
+		logger.Error("send_message error", "error", err)
+		return err
+	}
+
+	return nil
+}
+
 // WriteAnimationAudioFrame writes an animation audio frame to the WebSocket
 func (p *WebsocketTransportWriter) WriteAnimationAudioFrame(frame *achatbot_frames.AnimationAudioRawFrame) error {
 	if p.params.AudioOutAddWavHeader && len(frame.Audio) > 0 {
@@ -0,0 +1,182 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifierHMACToken(t *testing.T) {
+	key := &Key{ID: "k1", Secret: "secret1"}
+	keys, err := NewStaticKeyProvider(key)
+	assert.NoError(t, err)
+	verifier := NewVerifier(keys)
+
+	token, err := SignHMACToken(key, map[string]any{"sub": "alice", "bot_profile": "tutor", "exp": time.Now().Add(time.Hour).Unix()})
+	assert.NoError(t, err)
+	identity, err := verifier.Verify(token)
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", identity.UserID)
+	assert.Equal(t, "tutor", identity.BotProfile)
+	assert.False(t, identity.ExpiresAt.IsZero())
+
+	// 默认必须有 exp, 可关闭(不过期)
+	token, _ = SignHMACToken(key, map[string]any{"sub": "alice"})
+	_, err = verifier.Verify(token)
+	assert.ErrorIs(t, err, ErrInvalidClaims)
+	identity, err = NewVerifier(keys).WithRequireExpiry(false).Verify(token)
+	assert.NoError(t, err)
+	assert.True(t, identity.ExpiresAt.IsZero())
+
+	// 篡改 payload
+	forged, _ := SignHMACToken(&Key{ID: "k1", Secret: "other"}, map[string]any{"sub": "bob"})
+	_, err = verifier.Verify(forged)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+
+	_, err = verifier.Verify("")
+	assert.ErrorIs(t, err, ErrMissingToken)
+	_, err = verifier.Verify("not-a-token")
+	assert.ErrorIs(t, err, ErrMalformedToken)
+
+	// 未知 kid
+	unknown, _ := SignHMACToken(&Key{ID: "k2", Secret: "secret1"}, map[string]any{"sub": "alice"})
+	_, err = verifier.Verify(unknown)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+
+	// 缺少用户
+	token, _ = SignHMACToken(key, map[string]any{"bot_profile": "tutor", "exp": time.Now().Add(time.Hour).Unix()})
+	_, err = verifier.Verify(token)
+	assert.ErrorIs(t, err, ErrInvalidClaims)
+}
+
+func TestVerifierJWT(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	assert.NoError(t, err)
+	publicKey := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+
+	hsKey := &Key{ID: "hs", Secret: "secret"}
+	keys, err := NewStaticKeyProvider(hsKey, &Key{ID: "rs", PublicKey: publicKey})
+	assert.NoError(t, err)
+	verifier := NewVerifier(keys).WithIssuer("achatbot").WithAudience("websocket")
+	now := time.Unix(1700000000, 0)
+	verifier.now = func() time.Time { return now }
+	claims := func() map[string]any {
+		return map[string]any{"sub": "alice", "iss": "achatbot", "aud": []string{"websocket"}, "exp": now.Add(time.Hour).Unix()}
+	}
+
+	token, err := SignJWT(hsKey, claims())
+	assert.NoError(t, err)
+	identity, err := verifier.Verify(token)
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", identity.UserID)
+	// 有效截止时间包含时钟偏差容忍
+	assert.Equal(t, now.Add(time.Hour+30*time.Second), identity.ExpiresAt)
+
+	// RS256
+	segment := func(v any) string {
+		data, _ := json.Marshal(v)
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	signed := segment(map[string]any{"alg": "RS256", "kid": "rs"}) + "." + segment(claims())
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:])
+	assert.NoError(t, err)
+	identity, err = verifier.Verify(signed + "." + base64.RawURLEncoding.EncodeToString(signature))
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", identity.UserID)
+
+	// alg none
+	_, err = verifier.Verify(segment(map[string]any{"alg": "none"}) + "." + segment(claims()) + ".")
+	assert.ErrorIs(t, err, ErrUnsupportedAlg)
+
+	// 过期, 未生效, iss, aud
+	expired := claims()
+	expired["exp"] = now.Add(-time.Minute).Unix()
+	token, _ = SignJWT(hsKey, expired)
+	_, err = verifier.Verify(token)
+	assert.ErrorIs(t, err, ErrTokenExpired)
+
+	// 容忍范围内过期的 token 接受, 截止时间不早于当前时间
+	skewed := claims()
+	skewed["exp"] = now.Add(-10 * time.Second).Unix()
+	token, _ = SignJWT(hsKey, skewed)
+	identity, err = verifier.Verify(token)
+	assert.NoError(t, err)
+	assert.Equal(t, now.Add(20*time.Second), identity.ExpiresAt)
+
+	notYet := claims()
+	notYet["nbf"] = now.Add(time.Minute).Unix()
+	token, _ = SignJWT(hsKey, notYet)
+	_, err = verifier.Verify(token)
+	assert.ErrorIs(t, err, ErrTokenNotYetValid)
+
+	wrongAud := claims()
+	wrongAud["aud"] = "api"
+	token, _ = SignJWT(hsKey, wrongAud)
+	_, err = verifier.Verify(token)
+	assert.ErrorIs(t, err, ErrInvalidClaims)
+
+	wrongIss := claims()
+	wrongIss["iss"] = "other"
+	token, _ = SignJWT(hsKey, wrongIss)
+	_, err = verifier.Verify(token)
+	assert.ErrorIs(t, err, ErrInvalidClaims)
+}
+
+func TestFileKeyProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	writeKeys := func(modTime time.Time, keys ...*Key) {
+		data, _ := json.Marshal(&KeysFile{Keys: keys})
+		assert.NoError(t, os.WriteFile(path, data, 0o600))
+		assert.NoError(t, os.Chtimes(path, modTime, modTime))
+	}
+	oldKey, newKey := &Key{ID: "old", Secret: "old-secret"}, &Key{ID: "new", Secret: "new-secret"}
+	start := time.Now()
+	writeKeys(start, oldKey)
+
+	provider, err := NewFileKeyProvider(path, 0)
+	assert.NoError(t, err)
+	defer provider.Close()
+	verifier := NewVerifier(provider)
+	exp := time.Now().Add(time.Hour).Unix()
+	oldToken, _ := SignJWT(oldKey, map[string]any{"sub": "alice", "exp": exp})
+	newToken, _ := SignJWT(newKey, map[string]any{"sub": "alice", "exp": exp})
+	_, err = verifier.Verify(oldToken)
+	assert.NoError(t, err)
+	_, err = verifier.Verify(newToken)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+
+	// 轮换: 新旧密钥同时有效
+	writeKeys(start.Add(time.Second), oldKey, newKey)
+	reloaded, err := provider.Reload()
+	assert.NoError(t, err)
+	assert.True(t, reloaded)
+	_, err = verifier.Verify(newToken)
+	assert.NoError(t, err)
+
+	// 无效文件保留上一次的密钥
+	assert.NoError(t, os.WriteFile(path, []byte("{"), 0o600))
+	_, err = provider.Reload()
+	assert.Error(t, err)
+	assert.Equal(t, 2, provider.KeySet().Len())
+
+	// 移除旧密钥
+	writeKeys(start.Add(2*time.Second), newKey)
+	_, err = provider.Reload()
+	assert.NoError(t, err)
+	_, err = verifier.Verify(oldToken)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+}
@@ -0,0 +1,218 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/weedge/pipeline-go/pkg/logger"
+)
+
+// Key 验证 token 签名的密钥, Secret(HMAC) 和 PublicKey(RSA) 二选一
+type Key struct {
+	// ID 密钥 ID, 对应 JWT 头部的 kid; 轮换时新旧密钥使用不同 ID 同时配置
+	ID string `json:"kid"`
+	// Secret HMAC 密钥, 验证 HMAC 签名 token 和 HS256/HS384/HS512 JWT
+	Secret string `json:"secret,omitempty"`
+	// PublicKey PEM 格式的 RSA 公钥, 验证 RS256 JWT
+	PublicKey string `json:"public_key,omitempty"`
+
+	rsaKey *rsa.PublicKey
+}
+
+// KeySet 一组验证密钥, 不可变; 轮换时替换整个 KeySet
+type KeySet struct {
+	keys []*Key
+	byID map[string]*Key
+}
+
+// NewKeySet validates the keys and parses the rsa public keys
+func NewKeySet(keys ...*Key) (*KeySet, error) {
+	ks := &KeySet{byID: make(map[string]*Key, len(keys))}
+	for _, key := range keys {
+		if key == nil {
+			continue
+		}
+		k := *key
+		switch {
+		case k.Secret != "" && k.PublicKey != "":
+			return nil, fmt.Errorf("key %q: secret and public_key are exclusive", k.ID)
+		case k.PublicKey != "":
+			rsaKey, err := parseRSAPublicKey(k.PublicKey)
+			if err != nil {
+				return nil, fmt.Errorf("key %q: %w", k.ID, err)
+			}
+			k.rsaKey = rsaKey
+		case k.Secret == "":
+			return nil, fmt.Errorf("key %q: secret or public_key is required", k.ID)
+		}
+		if _, ok := ks.byID[k.ID]; ok {
+			return nil, fmt.Errorf("duplicate key id %q", k.ID)
+		}
+		ks.byID[k.ID] = &k
+		ks.keys = append(ks.keys, &k)
+	}
+	return ks, nil
+}
+
+// Len returns the number of keys
+func (ks *KeySet) Len() int {
+	if ks == nil {
+		return 0
+	}
+	return len(ks.keys)
+}
+
+// candidates returns the key of the kid, all keys if the kid is empty (e.g. tokens without kid during rotation)
+func (ks *KeySet) candidates(kid string) []*Key {
+	if ks == nil {
+		return nil
+	}
+	if kid == "" {
+		return ks.keys
+	}
+	if key, ok := ks.byID[kid]; ok {
+		return []*Key{key}
+	}
+	return nil
+}
+
+func parseRSAPublicKey(data string) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, errors.New("invalid pem public key")
+	}
+	if pub, err := x509.ParsePKIXPublicKey(block.Bytes); err == nil {
+		rsaKey, ok := pub.(*rsa.PublicKey)
+		if !ok {
+			return nil, errors.New("public key is not rsa")
+		}
+		return rsaKey, nil
+	}
+	return x509.ParsePKCS1PublicKey(block.Bytes)
+}
+
+// IKeyProvider 提供当前的验证密钥, 每次验证时读取, 实现可在运行时轮换密钥
+type IKeyProvider interface {
+	KeySet() *KeySet
+}
+
+// StaticKeyProvider 内存中的密钥, 可通过 SetKeys 轮换
+type StaticKeyProvider struct {
+	keySet atomic.Pointer[KeySet]
+}
+
+func NewStaticKeyProvider(keys ...*Key) (*StaticKeyProvider, error) {
+	p := &StaticKeyProvider{}
+	if err := p.SetKeys(keys...); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *StaticKeyProvider) KeySet() *KeySet {
+	return p.keySet.Load()
+}
+
+// SetKeys replaces the keys, the old keys are kept if the new ones are invalid
+func (p *StaticKeyProvider) SetKeys(keys ...*Key) error {
+	ks, err := NewKeySet(keys...)
+	if err != nil {
+		return err
+	}
+	p.keySet.Store(ks)
+	return nil
+}
+
+// KeysFile 密钥文件格式: {"keys": [{"kid": "k1", "secret": "..."}, {"kid": "k2", "public_key": "-----BEGIN PUBLIC KEY-----..."}]}
+type KeysFile struct {
+	Keys []*Key `json:"keys"`
+}
+
+// FileKeyProvider 从 JSON 密钥文件加载密钥, 定期检查文件修改时间并重新加载, 无需重启即可轮换密钥;
+// 加载失败时保留上一次有效的密钥
+type FileKeyProvider struct {
+	path     string
+	interval time.Duration
+	keySet   atomic.Pointer[KeySet]
+
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+
+	closeOnce sync.Once
+	stopCh    chan struct{}
+}
+
+// NewFileKeyProvider loads the keys file and reloads it when modified, checked every interval (0 disables)
+func NewFileKeyProvider(path string, interval time.Duration) (*FileKeyProvider, error) {
+	p := &FileKeyProvider{path: path, interval: interval, stopCh: make(chan struct{})}
+	if _, err := p.Reload(); err != nil {
+		return nil, err
+	}
+	if interval > 0 {
+		go p.watch()
+	}
+	return p, nil
+}
+
+func (p *FileKeyProvider) KeySet() *KeySet {
+	return p.keySet.Load()
+}
+
+// Reload reloads the keys file if it is modified, returns whether the keys are reloaded
+func (p *FileKeyProvider) Reload() (bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	info, err := os.Stat(p.path)
+	if err != nil {
+		return false, fmt.Errorf("failed to stat keys file %s: %w", p.path, err)
+	}
+	if p.keySet.Load() != nil && info.ModTime().Equal(p.modTime) && info.Size() == p.size {
+		return false, nil
+	}
+
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return false, fmt.Errorf("failed to read keys file %s: %w", p.path, err)
+	}
+	keysFile := &KeysFile{}
+	if err := json.Unmarshal(data, keysFile); err != nil {
+		return false, fmt.Errorf("failed to parse keys file %s: %w", p.path, err)
+	}
+	ks, err := NewKeySet(keysFile.Keys...)
+	if err != nil {
+		return false, fmt.Errorf("invalid keys file %s: %w", p.path, err)
+	}
+	p.keySet.Store(ks)
+	p.modTime, p.size = info.ModTime(), info.Size()
+	logger.Info("auth keys loaded", "path", p.path, "keys", ks.Len())
+	return true, nil
+}
+
+func (p *FileKeyProvider) watch() {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := p.Reload(); err != nil {
+				logger.Warn("reload auth keys error, keep the previous keys", "error", err)
+			}
+		case <-p.stopCh:
+			return
+		}
+	}
+}
+
+// Close stops watching the keys file
+func (p *FileKeyProvider) Close() {
+	p.closeOnce.Do(func() { close(p.stopCh) })
+}
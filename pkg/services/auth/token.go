@@ -0,0 +1,333 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"strings"
+	"time"
+
+	"achatbot/pkg/types"
+)
+
+var (
+	ErrMissingToken     = errors.New("missing token")
+	ErrMalformedToken   = errors.New("malformed token")
+	ErrUnsupportedAlg   = errors.New("unsupported token algorithm")
+	ErrUnknownKey       = errors.New("unknown token key")
+	ErrInvalidSignature = errors.New("invalid token signature")
+	ErrTokenExpired     = errors.New("token expired")
+	ErrTokenNotYetValid = errors.New("token not yet valid")
+	ErrInvalidClaims    = errors.New("invalid token claims")
+)
+
+// ClaimsMapper 将 token claims 映射为会话身份(用户, 机器人配置)
+type ClaimsMapper func(claims map[string]any) (*types.Identity, error)
+
+// DefaultClaimsMapper user id from "sub" (or "user_id"), bot profile from "bot_profile"
+func DefaultClaimsMapper(claims map[string]any) (*types.Identity, error) {
+	identity := &types.Identity{}
+	identity.UserID, _ = claims["sub"].(string)
+	if identity.UserID == "" {
+		identity.UserID, _ = claims["user_id"].(string)
+	}
+	if identity.UserID == "" {
+		return nil, fmt.Errorf("%w: missing sub", ErrInvalidClaims)
+	}
+	identity.BotProfile, _ = claims["bot_profile"].(string)
+	return identity, nil
+}
+
+// Verifier 验证 bearer token 并映射为会话身份, 支持两种 token:
+//   - JWT(header.payload.signature): HS256/HS384/HS512 使用 Secret 密钥, RS256 使用 PublicKey 密钥, 按头部 kid 选择密钥
+//   - HMAC 签名 token(payload.signature): payload 为 base64url 编码的 JSON claims, 签名为 HMAC-SHA256(Secret, payload),
+//     按 claims 中的 kid 选择密钥
+//
+// 没有 kid 时依次尝试所有密钥(轮换期间新旧密钥同时有效); 校验 exp(默认必须有), nbf 以及配置的 iss, aud
+type Verifier struct {
+	keys          IKeyProvider
+	issuer        string
+	audience      string
+	leeway        time.Duration
+	requireExpiry bool
+	claimsMapper  ClaimsMapper
+	now           func() time.Time
+}
+
+func NewVerifier(keys IKeyProvider) *Verifier {
+	return &Verifier{
+		keys:          keys,
+		leeway:        30 * time.Second,
+		requireExpiry: true,
+		claimsMapper:  DefaultClaimsMapper,
+		now:           time.Now,
+	}
+}
+
+// WithIssuer requires the "iss" claim
+func (v *Verifier) WithIssuer(issuer string) *Verifier {
+	v.issuer = issuer
+	return v
+}
+
+// WithAudience requires the "aud" claim (string or array) contains the audience
+func (v *Verifier) WithAudience(audience string) *Verifier {
+	v.audience = audience
+	return v
+}
+
+// WithLeeway tolerates the clock skew of exp and nbf
+func (v *Verifier) WithLeeway(leeway time.Duration) *Verifier {
+	v.leeway = leeway
+	return v
+}
+
+// WithRequireExpiry requires the "exp" claim (default), false accepts tokens without exp that never expire
+func (v *Verifier) WithRequireExpiry(requireExpiry bool) *Verifier {
+	v.requireExpiry = requireExpiry
+	return v
+}
+
+func (v *Verifier) WithClaimsMapper(claimsMapper ClaimsMapper) *Verifier {
+	v.claimsMapper = claimsMapper
+	return v
+}
+
+// Verify verifies the token signature and claims, returns the mapped identity
+func (v *Verifier) Verify(token string) (*types.Identity, error) {
+	if token == "" {
+		return nil, ErrMissingToken
+	}
+	var claims map[string]any
+	var err error
+	switch parts := strings.Split(token, "."); len(parts) {
+	case 3:
+		claims, err = v.verifyJWT(parts)
+	case 2:
+		claims, err = v.verifyHMACToken(parts)
+	default:
+		return nil, ErrMalformedToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt, err := v.validateClaims(claims)
+	if err != nil {
+		return nil, err
+	}
+	identity, err := v.claimsMapper(claims)
+	if err != nil {
+		return nil, err
+	}
+	identity.ExpiresAt = expiresAt
+	identity.Claims = claims
+	return identity, nil
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+func (v *Verifier) verifyJWT(parts []string) (map[string]any, error) {
+	header := &jwtHeader{}
+	if err := decodeSegment(parts[0], header); err != nil {
+		return nil, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrMalformedToken
+	}
+	signed := []byte(parts[0] + "." + parts[1])
+
+	var verify func(key *Key) bool
+	switch header.Alg {
+	case "HS256", "HS384", "HS512":
+		newHash := hmacHash(header.Alg)
+		verify = func(key *Key) bool {
+			return key.Secret != "" && hmac.Equal(signature, hmacSign(newHash, key.Secret, signed))
+		}
+	case "RS256":
+		digest := sha256.Sum256(signed)
+		verify = func(key *Key) bool {
+			return key.rsaKey != nil && rsa.VerifyPKCS1v15(key.rsaKey, crypto.SHA256, digest[:], signature) == nil
+		}
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedAlg, header.Alg)
+	}
+
+	if err := v.verifySignature(header.Kid, verify); err != nil {
+		return nil, err
+	}
+	claims := map[string]any{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func (v *Verifier) verifyHMACToken(parts []string) (map[string]any, error) {
+	claims := map[string]any{}
+	if err := decodeSegment(parts[0], &claims); err != nil {
+		return nil, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrMalformedToken
+	}
+	kid, _ := claims["kid"].(string)
+	err = v.verifySignature(kid, func(key *Key) bool {
+		return key.Secret != "" && hmac.Equal(signature, hmacSign(sha256.New, key.Secret, []byte(parts[0])))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func (v *Verifier) verifySignature(kid string, verify func(key *Key) bool) error {
+	candidates := v.keys.KeySet().candidates(kid)
+	if len(candidates) == 0 {
+		return ErrUnknownKey
+	}
+	for _, key := range candidates {
+		if verify(key) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
+
+// validateClaims checks exp, nbf, iss and aud, returns the time until which the token is accepted:
+// exp plus the leeway, so a token accepted within the leeway after exp never gets a deadline in the past (zero if no exp)
+func (v *Verifier) validateClaims(claims map[string]any) (time.Time, error) {
+	now := v.now()
+	var expiresAt time.Time
+	if exp, ok := claims["exp"]; ok {
+		secs, ok := exp.(float64)
+		if !ok {
+			return expiresAt, fmt.Errorf("%w: exp", ErrInvalidClaims)
+		}
+		expiresAt = time.Unix(int64(secs), 0).Add(v.leeway)
+		if !now.Before(expiresAt) {
+			return expiresAt, ErrTokenExpired
+		}
+	} else if v.requireExpiry {
+		return expiresAt, fmt.Errorf("%w: missing exp", ErrInvalidClaims)
+	}
+	if nbf, ok := claims["nbf"]; ok {
+		secs, ok := nbf.(float64)
+		if !ok {
+			return expiresAt, fmt.Errorf("%w: nbf", ErrInvalidClaims)
+		}
+		if now.Add(v.leeway).Before(time.Unix(int64(secs), 0)) {
+			return expiresAt, ErrTokenNotYetValid
+		}
+	}
+	if v.issuer != "" {
+		if iss, _ := claims["iss"].(string); iss != v.issuer {
+			return expiresAt, fmt.Errorf("%w: iss", ErrInvalidClaims)
+		}
+	}
+	if v.audience != "" && !hasAudience(claims["aud"], v.audience) {
+		return expiresAt, fmt.Errorf("%w: aud", ErrInvalidClaims)
+	}
+	return expiresAt, nil
+}
+
+func hasAudience(aud any, audience string) bool {
+	switch aud := aud.(type) {
+	case string:
+		return aud == audience
+	case []any:
+		for _, a := range aud {
+			if a == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// SignHMACToken signs the claims as the hmac signed token (payload.signature) with the secret key,
+// e.g. issued by the api server for the websocket client
+func SignHMACToken(key *Key, claims map[string]any) (string, error) {
+	if key == nil || key.Secret == "" {
+		return "", errors.New("hmac token requires a secret key")
+	}
+	if key.ID != "" {
+		claims["kid"] = key.ID
+	}
+	payload, err := encodeSegment(claims)
+	if err != nil {
+		return "", err
+	}
+	signature := hmacSign(sha256.New, key.Secret, []byte(payload))
+	return payload + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// SignJWT signs the claims as the HS256 jwt with the secret key
+func SignJWT(key *Key, claims map[string]any) (string, error) {
+	if key == nil || key.Secret == "" {
+		return "", errors.New("HS256 jwt requires a secret key")
+	}
+	header := map[string]any{"alg": "HS256", "typ": "JWT"}
+	if key.ID != "" {
+		header["kid"] = key.ID
+	}
+	headerSegment, err := encodeSegment(header)
+	if err != nil {
+		return "", err
+	}
+	payloadSegment, err := encodeSegment(claims)
+	if err != nil {
+		return "", err
+	}
+	signed := headerSegment + "." + payloadSegment
+	signature := hmacSign(sha256.New, key.Secret, []byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+func hmacHash(alg string) func() hash.Hash {
+	switch alg {
+	case "HS384":
+		return sha512.New384
+	case "HS512":
+		return sha512.New
+	default:
+		return sha256.New
+	}
+}
+
+func hmacSign(newHash func() hash.Hash, secret string, data []byte) []byte {
+	mac := hmac.New(newHash, []byte(secret))
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+func encodeSegment(v any) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return ErrMalformedToken
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return ErrMalformedToken
+	}
+	return nil
+}
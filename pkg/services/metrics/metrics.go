@@ -0,0 +1,119 @@
+package metrics
+
+import (
+	"time"
+)
+
+// DefaultRegistry 默认注册表, 处理器耗时和错误计数记录在此, 服务通过 DefaultRegistry.Handler() 暴露 /metrics
+var DefaultRegistry = NewRegistry()
+
+var (
+	// ProcessingSeconds 处理器单次处理耗时(ASR 转录, LLM 请求, TTS 合成)
+	ProcessingSeconds = DefaultRegistry.NewHistogram("achatbot_processing_seconds",
+		"Processing time of the processor in seconds.", DefaultBuckets, "processor")
+	// ProcessorErrors 处理器错误数
+	ProcessorErrors = DefaultRegistry.NewCounter("achatbot_processor_errors_total",
+		"Number of errors of the processor.", "processor")
+)
+
+// 处理器标签值
+const (
+	ProcessorASR = "asr"
+	ProcessorLLM = "llm"
+	ProcessorTTS = "tts"
+)
+
+// ObserveProcessing 记录处理器从 start 开始的处理耗时, 如 defer metrics.ObserveProcessing(metrics.ProcessorASR, time.Now())
+func ObserveProcessing(processor string, start time.Time) {
+	ProcessingSeconds.Observe(time.Since(start).Seconds(), processor)
+}
+
+// IncError 处理器错误数 +1
+func IncError(processor string) {
+	ProcessorErrors.Inc(processor)
+}
+
+// StatsMetric GetStats 返回的统计项与指标的映射
+type StatsMetric struct {
+	Key  string
+	Name string
+	Help string
+	Type MetricType
+}
+
+// RegisterStats 将组件的 GetStats (如池, 限速器) 注册为抓取时读取的指标, 样本带 labelName=labelValue 标签;
+// 同一指标可注册多个组件(如多个池按 pool 标签区分), 不存在或非数值的统计项跳过
+func (r *Registry) RegisterStats(labelName, labelValue string, getStats func() map[string]any, statsMetrics []StatsMetric) {
+	for _, m := range statsMetrics {
+		key := m.Key
+		collect := func(emit EmitFunc) {
+			if value, ok := toFloat(getStats()[key]); ok {
+				emit(value, labelValue)
+			}
+		}
+		switch m.Type {
+		case CounterType:
+			r.NewCounterFunc(m.Name, m.Help, []string{labelName}, collect)
+		default:
+			r.NewGaugeFunc(m.Name, m.Help, []string{labelName}, collect)
+		}
+	}
+}
+
+func toFloat(value any) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// PoolStatsMetrics 池(common.Pool GetStats)的指标
+var PoolStatsMetrics = []StatsMetric{
+	{Key: "max_size", Name: "achatbot_pool_max_size", Help: "Max number of instances of the pool.", Type: GaugeType},
+	{Key: "num_instances", Name: "achatbot_pool_instances", Help: "Number of live instances of the pool.", Type: GaugeType},
+	{Key: "total_instances", Name: "achatbot_pool_idle_instances", Help: "Number of idle instances of the pool.", Type: GaugeType},
+	{Key: "active_count", Name: "achatbot_pool_active_instances", Help: "Number of instances in use of the pool.", Type: GaugeType},
+	{Key: "total_created", Name: "achatbot_pool_created_total", Help: "Number of instances created by the pool.", Type: CounterType},
+	{Key: "total_gets", Name: "achatbot_pool_gets_total", Help: "Number of instances got from the pool.", Type: CounterType},
+	{Key: "total_timeouts", Name: "achatbot_pool_get_timeouts_total", Help: "Number of gets canceled or timed out while waiting for the pool.", Type: CounterType},
+	{Key: "total_wait_secs", Name: "achatbot_pool_get_wait_seconds_total", Help: "Total time spent waiting for instances of the pool in seconds.", Type: CounterType},
+	{Key: "total_reaped", Name: "achatbot_pool_reaped_total", Help: "Number of idle instances released by the pool.", Type: CounterType},
+	{Key: "total_unhealthy", Name: "achatbot_pool_unhealthy_total", Help: "Number of unhealthy instances replaced by the pool.", Type: CounterType},
+}
+
+// RateLimiterStatsMetrics 限速器(middleware.RateLimiter GetStats)的指标
+var RateLimiterStatsMetrics = []StatsMetric{
+	{Key: "current_connections", Name: "achatbot_ratelimit_connections", Help: "Number of current connections.", Type: GaugeType},
+	{Key: "active_limiters", Name: "achatbot_ratelimit_active_limiters", Help: "Number of client ip limiters.", Type: GaugeType},
+	{Key: "total_accepted", Name: "achatbot_ratelimit_accepted_total", Help: "Number of requests accepted by the rate limiter.", Type: CounterType},
+	{Key: "total_rejected_conns", Name: "achatbot_ratelimit_rejected_connections_total", Help: "Number of requests rejected by the max connections.", Type: CounterType},
+	{Key: "total_rejected_rates", Name: "achatbot_ratelimit_rejected_requests_total", Help: "Number of requests rejected by the request rate.", Type: CounterType},
+	{Key: "total_rejected_sessions", Name: "achatbot_ratelimit_rejected_sessions_total", Help: "Number of requests rejected by the max sessions per key.", Type: CounterType},
+	{Key: "total_rejected_auths", Name: "achatbot_ratelimit_rejected_auths_total", Help: "Number of requests rejected by too many failed authentications.", Type: CounterType},
+	{Key: "total_rejected_bans", Name: "achatbot_ratelimit_rejected_bans_total", Help: "Number of requests rejected by the temporary bans.", Type: CounterType},
+	{Key: "total_bans", Name: "achatbot_ratelimit_bans_total", Help: "Number of temporary bans after repeated violations.", Type: CounterType},
+	{Key: "active_bans", Name: "achatbot_ratelimit_active_bans", Help: "Number of keys temporarily banned.", Type: GaugeType},
+}
+
+// AuthStatsMetrics 认证(middleware.Authenticator GetStats)的指标
+var AuthStatsMetrics = []StatsMetric{
+	{Key: "total_accepted", Name: "achatbot_auth_accepted_total", Help: "Number of requests authenticated.", Type: CounterType},
+	{Key: "total_rejected_tokens", Name: "achatbot_auth_rejected_tokens_total", Help: "Number of requests rejected by the invalid token.", Type: CounterType},
+	{Key: "total_rejected_origins", Name: "achatbot_auth_rejected_origins_total", Help: "Number of requests rejected by the origin allowlist.", Type: CounterType},
+}
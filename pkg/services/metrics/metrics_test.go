@@ -0,0 +1,81 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistryWriteText(t *testing.T) {
+	r := NewRegistry()
+	requests := r.NewCounter("test_requests_total", "Number of requests.", "code")
+	requests.Inc("200")
+	requests.Add(2, "200")
+	requests.Inc(`5"0\0`)
+	assert.Same(t, requests.f, r.NewCounter("test_requests_total", "Number of requests.", "code").f)
+
+	sessions := r.NewGauge("test_sessions", "Number of sessions.")
+	sessions.Set(3)
+	sessions.Add(-1)
+
+	latency := r.NewHistogram("test_latency_seconds", "Latency.", []float64{0.1, 1}, "processor")
+	latency.Observe(0.05, "asr")
+	latency.Observe(0.5, "asr")
+	latency.Observe(2, "asr")
+
+	stats := map[string]any{"num_instances": 2, "total_created": int64(5), "name": "asr"}
+	r.RegisterStats("pool", "asr", func() map[string]any { return stats }, []StatsMetric{
+		{Key: "num_instances", Name: "test_pool_instances", Help: "Instances.", Type: GaugeType},
+		{Key: "total_created", Name: "test_pool_created_total", Help: "Created.", Type: CounterType},
+		{Key: "name", Name: "test_pool_name", Help: "Not a number.", Type: GaugeType},
+	})
+	r.RegisterStats("pool", "tts", func() map[string]any { return map[string]any{"num_instances": 1} }, []StatsMetric{
+		{Key: "num_instances", Name: "test_pool_instances", Help: "Instances.", Type: GaugeType},
+	})
+
+	server := httptest.NewServer(r.Handler())
+	defer server.Close()
+	resp, err := http.Get(server.URL)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", resp.Header.Get("Content-Type"))
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	text := string(body)
+
+	for _, line := range []string{
+		"# HELP test_requests_total Number of requests.",
+		"# TYPE test_requests_total counter",
+		`test_requests_total{code="200"} 3`,
+		`test_requests_total{code="5\"0\\0"} 1`,
+		"# TYPE test_sessions gauge",
+		"test_sessions 2",
+		"# TYPE test_latency_seconds histogram",
+		`test_latency_seconds_bucket{processor="asr",le="0.1"} 1`,
+		`test_latency_seconds_bucket{processor="asr",le="1"} 2`,
+		`test_latency_seconds_bucket{processor="asr",le="+Inf"} 3`,
+		`test_latency_seconds_sum{processor="asr"} 2.55`,
+		`test_latency_seconds_count{processor="asr"} 3`,
+		`test_pool_instances{pool="asr"} 2`,
+		`test_pool_instances{pool="tts"} 1`,
+		"# TYPE test_pool_created_total counter",
+		`test_pool_created_total{pool="asr"} 5`,
+	} {
+		assert.Contains(t, text, line+"\n")
+	}
+	assert.NotContains(t, text, "test_pool_name{")
+	// families are sorted by name
+	assert.Less(t, strings.Index(text, "test_latency_seconds"), strings.Index(text, "test_pool_created_total"))
+	assert.Less(t, strings.Index(text, "test_requests_total"), strings.Index(text, "test_sessions"))
+}
+
+func TestRegistryLabelMismatch(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounter("test_total", "Test.", "a")
+	assert.Panics(t, func() { r.NewGauge("test_total", "Test.", "a") })
+	assert.Panics(t, func() { r.NewCounter("test_total", "Test.").Inc() })
+}
@@ -0,0 +1,284 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// MetricType Prometheus 指标类型
+type MetricType string
+
+const (
+	CounterType   MetricType = "counter"
+	GaugeType     MetricType = "gauge"
+	HistogramType MetricType = "histogram"
+)
+
+// DefaultBuckets 处理耗时(秒)直方图默认分桶
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// EmitFunc 抓取时输出一个样本, labelValues 与指标的标签名一一对应
+type EmitFunc func(value float64, labelValues ...string)
+
+type series struct {
+	labelValues  []string
+	value        float64
+	bucketCounts []uint64
+	sum          float64
+	count        uint64
+}
+
+// family 同名指标, 样本来自直接记录的 series 和抓取时调用的 collectors
+type family struct {
+	name       string
+	help       string
+	metricType MetricType
+	labelNames []string
+	buckets    []float64
+
+	mu         sync.Mutex
+	series     map[string]*series
+	collectors []func(emit EmitFunc)
+}
+
+func (f *family) getSeries(labelValues []string) *series {
+	if len(labelValues) != len(f.labelNames) {
+		panic(fmt.Sprintf("metric %s expects %d label values, got %d", f.name, len(f.labelNames), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{labelValues: append([]string(nil), labelValues...)}
+		if f.metricType == HistogramType {
+			s.bucketCounts = make([]uint64, len(f.buckets))
+		}
+		f.series[key] = s
+	}
+	return s
+}
+
+// Registry 指标注册表, 以 Prometheus 文本格式导出, 并发安全;
+// 同名指标重复注册返回已注册的指标(类型或标签不一致时 panic)
+type Registry struct {
+	mu       sync.RWMutex
+	families map[string]*family
+}
+
+func NewRegistry() *Registry {
+	return &Registry{families: make(map[string]*family)}
+}
+
+func (r *Registry) register(name, help string, metricType MetricType, buckets []float64, labelNames []string) *family {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if f, ok := r.families[name]; ok {
+		if f.metricType != metricType || strings.Join(f.labelNames, ",") != strings.Join(labelNames, ",") {
+			panic(fmt.Sprintf("metric %s already registered as %s%v", name, f.metricType, f.labelNames))
+		}
+		return f
+	}
+	f := &family{
+		name:       name,
+		help:       help,
+		metricType: metricType,
+		labelNames: labelNames,
+		buckets:    buckets,
+		series:     make(map[string]*series),
+	}
+	r.families[name] = f
+	return f
+}
+
+// Counter 单调递增计数
+type Counter struct{ f *family }
+
+// NewCounter 注册计数指标
+func (r *Registry) NewCounter(name, help string, labelNames ...string) *Counter {
+	return &Counter{f: r.register(name, help, CounterType, nil, labelNames)}
+}
+
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add 增加计数, v < 0 忽略
+func (c *Counter) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		return
+	}
+	c.f.mu.Lock()
+	defer c.f.mu.Unlock()
+	c.f.getSeries(labelValues).value += v
+}
+
+// Gauge 可增减的瞬时值
+type Gauge struct{ f *family }
+
+// NewGauge 注册瞬时值指标
+func (r *Registry) NewGauge(name, help string, labelNames ...string) *Gauge {
+	return &Gauge{f: r.register(name, help, GaugeType, nil, labelNames)}
+}
+
+func (g *Gauge) Set(v float64, labelValues ...string) {
+	g.f.mu.Lock()
+	defer g.f.mu.Unlock()
+	g.f.getSeries(labelValues).value = v
+}
+
+func (g *Gauge) Add(v float64, labelValues ...string) {
+	g.f.mu.Lock()
+	defer g.f.mu.Unlock()
+	g.f.getSeries(labelValues).value += v
+}
+
+// Histogram 分桶统计(如处理耗时)
+type Histogram struct{ f *family }
+
+// NewHistogram 注册直方图指标, buckets 为升序的桶上界
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labelNames ...string) *Histogram {
+	return &Histogram{f: r.register(name, help, HistogramType, buckets, labelNames)}
+}
+
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	h.f.mu.Lock()
+	defer h.f.mu.Unlock()
+	s := h.f.getSeries(labelValues)
+	for i, upper := range h.f.buckets {
+		if v <= upper {
+			s.bucketCounts[i]++
+		}
+	}
+	s.sum += v
+	s.count++
+}
+
+// NewCounterFunc 注册抓取时读取的计数指标(如池的累计创建数), 同名指标可注册多个来源(如多个池)
+func (r *Registry) NewCounterFunc(name, help string, labelNames []string, collect func(emit EmitFunc)) {
+	r.addCollector(r.register(name, help, CounterType, nil, labelNames), collect)
+}
+
+// NewGaugeFunc 注册抓取时读取的瞬时值指标(如活跃会话数)
+func (r *Registry) NewGaugeFunc(name, help string, labelNames []string, collect func(emit EmitFunc)) {
+	r.addCollector(r.register(name, help, GaugeType, nil, labelNames), collect)
+}
+
+func (r *Registry) addCollector(f *family, collect func(emit EmitFunc)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.collectors = append(f.collectors, collect)
+}
+
+// WriteText 以 Prometheus 文本格式(0.0.4)输出所有指标, 按名称排序
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.RLock()
+	families := make([]*family, 0, len(r.families))
+	for _, f := range r.families {
+		families = append(families, f)
+	}
+	r.mu.RUnlock()
+	sort.Slice(families, func(i, j int) bool { return families[i].name < families[j].name })
+
+	bw := bufio.NewWriter(w)
+	for _, f := range families {
+		f.write(bw)
+	}
+	return bw.Flush()
+}
+
+func (f *family) write(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", f.name, escapeHelp(f.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", f.name, f.metricType)
+
+	f.mu.Lock()
+	seriesList := make([]*series, 0, len(f.series))
+	for _, s := range f.series {
+		seriesList = append(seriesList, s)
+	}
+	sort.Slice(seriesList, func(i, j int) bool {
+		return strings.Join(seriesList[i].labelValues, "\xff") < strings.Join(seriesList[j].labelValues, "\xff")
+	})
+	for _, s := range seriesList {
+		if f.metricType != HistogramType {
+			writeSample(w, f.name, f.labelNames, s.labelValues, s.value)
+			continue
+		}
+		bucketLabels := append(append([]string(nil), f.labelNames...), "le")
+		for i, upper := range f.buckets {
+			writeSample(w, f.name+"_bucket", bucketLabels, append(append([]string(nil), s.labelValues...), formatFloat(upper)), float64(s.bucketCounts[i]))
+		}
+		writeSample(w, f.name+"_bucket", bucketLabels, append(append([]string(nil), s.labelValues...), "+Inf"), float64(s.count))
+		writeSample(w, f.name+"_sum", f.labelNames, s.labelValues, s.sum)
+		writeSample(w, f.name+"_count", f.labelNames, s.labelValues, float64(s.count))
+	}
+	collectors := append([]func(emit EmitFunc){}, f.collectors...)
+	f.mu.Unlock()
+
+	// collectors read other components' stats, call them without holding the lock
+	for _, collect := range collectors {
+		collect(func(value float64, labelValues ...string) {
+			if len(labelValues) != len(f.labelNames) {
+				return
+			}
+			writeSample(w, f.name, f.labelNames, labelValues, value)
+		})
+	}
+}
+
+func writeSample(w *bufio.Writer, name string, labelNames, labelValues []string, value float64) {
+	w.WriteString(name)
+	if len(labelNames) > 0 {
+		w.WriteByte('{')
+		for i, labelName := range labelNames {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			fmt.Fprintf(w, "%s=\"%s\"", labelName, escapeLabelValue(labelValues[i]))
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(value))
+	w.WriteByte('\n')
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(help string) string {
+	return helpEscaper.Replace(help)
+}
+
+func escapeLabelValue(value string) string {
+	return labelEscaper.Replace(value)
+}
+
+// Handler 返回 /metrics 的 http handler
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		if err := r.WriteText(w); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
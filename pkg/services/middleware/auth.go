@@ -0,0 +1,190 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+
+	"github.com/weedge/pipeline-go/pkg/logger"
+
+	"achatbot/pkg/services/auth"
+	"achatbot/pkg/types"
+)
+
+// Authenticator 会话认证: 在 websocket 升级前校验 Origin 白名单和 bearer token(header 或 query 参数),
+// 通过后将身份(用户, 机器人配置)写入请求 context, 拒绝时返回结构化 JSON 错误
+type Authenticator struct {
+	enabled        bool
+	verifier       *auth.Verifier
+	header         string       // token header, 值可带 "Bearer " 前缀
+	queryParam     string       // token query 参数, 浏览器 websocket 无法设置 header
+	allowedOrigins []string     // 允许的 Origin(如 https://app.example.com, https://*.example.com, *), 为空不限制
+	failureLimiter *RateLimiter // 认证失败按客户端 IP 限流和封禁, 在校验 token 前检查, nil 不限制
+
+	totalAccepted        int64 // 认证通过的请求数
+	totalRejectedTokens  int64 // token 无效被拒绝的请求数
+	totalRejectedOrigins int64 // Origin 不在白名单被拒绝的请求数
+}
+
+// NewAuthenticator reads the token from the Authorization header or the "token" query parameter
+func NewAuthenticator(verifier *auth.Verifier) *Authenticator {
+	return &Authenticator{
+		enabled:    true,
+		verifier:   verifier,
+		header:     "Authorization",
+		queryParam: "token",
+	}
+}
+
+func (a *Authenticator) WithEnable(enabled bool) *Authenticator {
+	a.enabled = enabled
+	return a
+}
+
+// WithHeader sets the token header, empty disables
+func (a *Authenticator) WithHeader(header string) *Authenticator {
+	a.header = header
+	return a
+}
+
+// WithQueryParam sets the token query parameter, empty disables
+func (a *Authenticator) WithQueryParam(queryParam string) *Authenticator {
+	a.queryParam = queryParam
+	return a
+}
+
+// WithAllowedOrigins sets the origin allowlist, e.g. https://app.example.com, https://*.example.com, * allows all
+func (a *Authenticator) WithAllowedOrigins(origins ...string) *Authenticator {
+	a.allowedOrigins = a.allowedOrigins[:0]
+	for _, origin := range origins {
+		if origin = strings.ToLower(strings.TrimRight(strings.TrimSpace(origin), "/")); origin != "" {
+			a.allowedOrigins = append(a.allowedOrigins, origin)
+		}
+	}
+	return a
+}
+
+// WithFailureLimiter limits the failed authentications (invalid origin or token) by the client ip of the limiter:
+// checked before the token check, so invalid token floods are rate limited and banned (WithBan) before reaching
+// the limiter of the authenticated requests, e.g. the same RateLimiter whose Middleware is after the authenticator
+func (a *Authenticator) WithFailureLimiter(limiter *RateLimiter) *Authenticator {
+	a.failureLimiter = limiter
+	return a
+}
+
+// CheckOrigin reports whether the origin of the request is allowed, e.g. websocket.Upgrader.CheckOrigin;
+// requests without Origin (non-browser clients) are allowed, they are authenticated by the token
+func (a *Authenticator) CheckOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if len(a.allowedOrigins) == 0 || origin == "" {
+		return true
+	}
+	u, err := url.Parse(strings.ToLower(origin))
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return false
+	}
+	for _, allowed := range a.allowedOrigins {
+		if allowed == "*" || allowed == u.Scheme+"://"+u.Host {
+			return true
+		}
+		// wildcard subdomain: https://*.example.com
+		if prefix := u.Scheme + "://*."; strings.HasPrefix(allowed, prefix) &&
+			strings.HasSuffix(u.Host, "."+strings.TrimPrefix(allowed, prefix)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Token returns the bearer token of the header, else the query parameter
+func (a *Authenticator) Token(r *http.Request) string {
+	if a.header != "" {
+		value := strings.TrimSpace(r.Header.Get(a.header))
+		if len(value) > len("Bearer ") && strings.EqualFold(value[:len("Bearer ")], "Bearer ") {
+			value = strings.TrimSpace(value[len("Bearer "):])
+		}
+		if value != "" {
+			return value
+		}
+	}
+	if a.queryParam != "" {
+		return r.URL.Query().Get(a.queryParam)
+	}
+	return ""
+}
+
+// Middleware rejects the request before the upgrade if the origin or the token is invalid,
+// otherwise adds the identity (ContextWithIdentity) and user id (ContextWithUserID) to the request context
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	if !a.enabled {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if a.failureLimiter != nil && a.failureLimiter.rejectAuthAttempt(w, r) {
+			return
+		}
+		if !a.CheckOrigin(r) {
+			a.authFailed(r)
+			atomic.AddInt64(&a.totalRejectedOrigins, 1)
+			logger.Warn("auth origin not allowed", "origin", r.Header.Get("Origin"), "remote", r.RemoteAddr)
+			WriteJSONError(w, http.StatusForbidden, ErrCodeForbiddenOrigin, "Origin not allowed", 0)
+			return
+		}
+
+		identity, err := a.verifier.Verify(a.Token(r))
+		if err != nil {
+			a.authFailed(r)
+			atomic.AddInt64(&a.totalRejectedTokens, 1)
+			logger.Warn("auth token rejected", "remote", r.RemoteAddr, "error", err)
+			w.Header().Set("WWW-Authenticate", bearerChallenge(err))
+			WriteJSONError(w, http.StatusUnauthorized, ErrCodeUnauthorized, err.Error(), 0)
+			return
+		}
+
+		atomic.AddInt64(&a.totalAccepted, 1)
+		ctx := ContextWithIdentity(r.Context(), identity)
+		ctx = ContextWithUserID(ctx, identity.UserID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// GetStats returns the authenticator statistics
+func (a *Authenticator) GetStats() map[string]any {
+	return map[string]any{
+		"enabled":                a.enabled,
+		"allowed_origins":        len(a.allowedOrigins),
+		"total_accepted":         atomic.LoadInt64(&a.totalAccepted),
+		"total_rejected_tokens":  atomic.LoadInt64(&a.totalRejectedTokens),
+		"total_rejected_origins": atomic.LoadInt64(&a.totalRejectedOrigins),
+	}
+}
+
+func (a *Authenticator) authFailed(r *http.Request) {
+	if a.failureLimiter != nil {
+		a.failureLimiter.authFailed(r)
+	}
+}
+
+// bearerChallenge RFC 6750 WWW-Authenticate challenge
+func bearerChallenge(err error) string {
+	if errors.Is(err, auth.ErrMissingToken) {
+		return `Bearer realm="achatbot"`
+	}
+	return `Bearer realm="achatbot", error="invalid_token"`
+}
+
+type identityContextKey struct{}
+
+// ContextWithIdentity returns the context with the authenticated identity
+func ContextWithIdentity(ctx context.Context, identity *types.Identity) context.Context {
+	return context.WithValue(ctx, identityContextKey{}, identity)
+}
+
+// IdentityFromContext returns the authenticated identity of the request context, nil if not authenticated
+func IdentityFromContext(ctx context.Context) *types.Identity {
+	identity, _ := ctx.Value(identityContextKey{}).(*types.Identity)
+	return identity
+}
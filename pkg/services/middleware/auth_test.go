@@ -0,0 +1,135 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"achatbot/pkg/services/auth"
+)
+
+func TestAuthenticatorCheckOrigin(t *testing.T) {
+	a := NewAuthenticator(nil).WithAllowedOrigins("https://app.example.com/", "https://*.example.org")
+	check := func(origin string) bool {
+		req := httptest.NewRequest("GET", "/", nil)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		return a.CheckOrigin(req)
+	}
+	assert.True(t, check(""))
+	assert.True(t, check("https://APP.example.com"))
+	assert.True(t, check("https://chat.example.org"))
+	assert.False(t, check("https://example.org"))
+	assert.False(t, check("http://app.example.com"))
+	assert.False(t, check("https://evil.com"))
+	assert.False(t, check("https://app.example.com.evil.com"))
+
+	assert.True(t, NewAuthenticator(nil).CheckOrigin(httptest.NewRequest("GET", "/", nil)))
+}
+
+func TestAuthenticatorMiddleware(t *testing.T) {
+	key := &auth.Key{ID: "k1", Secret: "secret"}
+	keys, err := auth.NewStaticKeyProvider(key)
+	assert.NoError(t, err)
+	a := NewAuthenticator(auth.NewVerifier(keys)).WithAllowedOrigins("https://app.example.com")
+	handler := a.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity := IdentityFromContext(r.Context())
+		assert.Equal(t, identity.UserID, UserIDFromContext(r.Context()))
+		w.Write([]byte(identity.UserID + "/" + identity.BotProfile))
+	}))
+	token, err := auth.SignHMACToken(key, map[string]any{"sub": "alice", "bot_profile": "tutor", "exp": time.Now().Add(time.Hour).Unix()})
+	assert.NoError(t, err)
+
+	// header token
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "alice/tutor", resp.Body.String())
+
+	// query token
+	req = httptest.NewRequest("GET", "/?token="+token, nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	resp = httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	// 拒绝: Origin 不在白名单
+	req = httptest.NewRequest("GET", "/?token="+token, nil)
+	req.Header.Set("Origin", "https://evil.com")
+	resp = httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+	assert.Contains(t, resp.Body.String(), ErrCodeForbiddenOrigin)
+
+	// 拒绝: 缺少 token
+	resp = httptest.NewRecorder()
+	handler.ServeHTTP(resp, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	assert.Equal(t, `Bearer realm="achatbot"`, resp.Header().Get("WWW-Authenticate"))
+	body := map[string]ErrorBody{}
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+	assert.Equal(t, ErrCodeUnauthorized, body["error"].Code)
+	assert.Equal(t, auth.ErrMissingToken.Error(), body["error"].Message)
+
+	// 拒绝: 无效 token
+	resp = httptest.NewRecorder()
+	handler.ServeHTTP(resp, httptest.NewRequest("GET", "/?token="+token+"x", nil))
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	assert.Contains(t, resp.Header().Get("WWW-Authenticate"), "invalid_token")
+
+	stats := a.GetStats()
+	assert.Equal(t, int64(2), stats["total_accepted"])
+	assert.Equal(t, int64(2), stats["total_rejected_tokens"])
+	assert.Equal(t, int64(1), stats["total_rejected_origins"])
+}
+
+func TestAuthenticatorFailureLimiter(t *testing.T) {
+	key := &auth.Key{ID: "k1", Secret: "secret"}
+	keys, err := auth.NewStaticKeyProvider(key)
+	assert.NoError(t, err)
+	// the request budget (1024 rps) doesn't apply to the failed authentications
+	rl := NewDefaultRateLimiter().WithBan(2, time.Minute, time.Minute)
+	a := NewAuthenticator(auth.NewVerifier(keys)).WithFailureLimiter(rl)
+	handler := a.Middleware(rl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+	token, err := auth.SignHMACToken(key, map[string]any{"sub": "alice", "exp": time.Now().Add(time.Hour).Unix()})
+	assert.NoError(t, err)
+
+	serve := func(remoteAddr, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/?token="+token, nil)
+		req.RemoteAddr = remoteAddr
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, req)
+		return resp
+	}
+
+	// invalid tokens use up the failure budget (5 per minute) of the client ip
+	for range defaultAuthFailureBurst {
+		assert.Equal(t, http.StatusUnauthorized, serve("192.0.2.1:1234", "invalid").Code)
+	}
+
+	// rejected before the token check, counted as violations, then banned
+	resp := serve("192.0.2.1:1234", "invalid")
+	assert.Equal(t, http.StatusTooManyRequests, resp.Code)
+	assert.Contains(t, resp.Body.String(), ErrCodeRateLimited)
+	serve("192.0.2.1:1234", token)
+	resp = serve("192.0.2.1:1234", token)
+	assert.Equal(t, http.StatusTooManyRequests, resp.Code)
+	assert.Contains(t, resp.Body.String(), ErrCodeBanned)
+
+	// other clients are not affected
+	assert.Equal(t, http.StatusOK, serve("192.0.2.2:1234", token).Code)
+
+	assert.Equal(t, int64(defaultAuthFailureBurst), a.GetStats()["total_rejected_tokens"])
+	stats := rl.GetStats()
+	assert.Equal(t, int64(2), stats["total_rejected_auths"])
+	assert.Equal(t, int64(1), stats["total_bans"])
+}
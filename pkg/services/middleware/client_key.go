@@ -0,0 +1,155 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/weedge/pipeline-go/pkg/logger"
+)
+
+// KeyFunc 从请求中提取限流键(如 API Key, 认证用户), 返回空时使用客户端 IP
+type KeyFunc func(r *http.Request) string
+
+type userIDContextKey struct{}
+
+// ContextWithUserID returns the context with the authenticated user id, e.g. set by the auth middleware
+func ContextWithUserID(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, userIDContextKey{}, userID)
+}
+
+// UserIDFromContext returns the authenticated user id of the request context
+func UserIDFromContext(ctx context.Context) string {
+	userID, _ := ctx.Value(userIDContextKey{}).(string)
+	return userID
+}
+
+// UserIDKeyFunc limits by the authenticated user id (ContextWithUserID)
+func UserIDKeyFunc() KeyFunc {
+	return func(r *http.Request) string {
+		if userID := UserIDFromContext(r.Context()); userID != "" {
+			return "user:" + userID
+		}
+		return ""
+	}
+}
+
+// APIKeyValidator 校验 API Key 是否有效, 未校验的 Key 由客户端任意生成, 不能作为限流键
+type APIKeyValidator func(apiKey string) bool
+
+// APIKeyFunc limits by the api key of the header (e.g. X-API-Key) or the query parameter (e.g. api_key)
+// accepted by the validator, invalid keys (or nil validator) fall back to the client ip so forged keys can't
+// bypass the limits; the key is hashed to avoid keeping the secret in the limiter keys
+func APIKeyFunc(header, query string, validate APIKeyValidator) KeyFunc {
+	return func(r *http.Request) string {
+		apiKey := ""
+		if header != "" {
+			apiKey = strings.TrimSpace(strings.TrimPrefix(r.Header.Get(header), "Bearer "))
+		}
+		if apiKey == "" && query != "" {
+			apiKey = r.URL.Query().Get(query)
+		}
+		if apiKey == "" || validate == nil || !validate(apiKey) {
+			return ""
+		}
+		sum := sha256.Sum256([]byte(apiKey))
+		return "api_key:" + hex.EncodeToString(sum[:8])
+	}
+}
+
+// FirstKeyFunc uses the first non-empty key of the key funcs, e.g. user id then api key
+func FirstKeyFunc(keyFuncs ...KeyFunc) KeyFunc {
+	return func(r *http.Request) string {
+		for _, keyFunc := range keyFuncs {
+			if key := keyFunc(r); key != "" {
+				return key
+			}
+		}
+		return ""
+	}
+}
+
+// parseTrustedProxies parses the CIDRs (or single IPs) of the trusted proxies, invalid ones are skipped
+func parseTrustedProxies(cidrs []string) []*net.IPNet {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, entry := range cidrs {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		cidr := entry
+		if !strings.Contains(cidr, "/") {
+			if ip := net.ParseIP(cidr); ip != nil && ip.To4() != nil {
+				cidr += "/32"
+			} else {
+				cidr += "/128"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			logger.Warn("invalid trusted proxy", "cidr", entry, "error", err)
+			continue
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets
+}
+
+// ClientIP returns the client ip of the request: X-Forwarded-For is only used when the request comes from
+// a trusted proxy, the client is the rightmost address not of a trusted proxy
+func (rl *RateLimiter) ClientIP(r *http.Request) string {
+	remoteIP := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		remoteIP = host
+	}
+	if !rl.isTrustedProxy(remoteIP) {
+		return remoteIP
+	}
+
+	var hops []string
+	for _, forwarded := range r.Header.Values("X-Forwarded-For") {
+		for _, hop := range strings.Split(forwarded, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				hops = append(hops, hop)
+			}
+		}
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		if !rl.isTrustedProxy(hops[i]) {
+			return hops[i]
+		}
+	}
+	if len(hops) > 0 {
+		return hops[0]
+	}
+	return remoteIP
+}
+
+func (rl *RateLimiter) isTrustedProxy(ip string) bool {
+	if len(rl.trustedProxies) == 0 {
+		return false
+	}
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, ipNet := range rl.trustedProxies {
+		if ipNet.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// key returns the limiter key of the request, the client ip if the key func returns empty
+func (rl *RateLimiter) key(r *http.Request) string {
+	if rl.keyFunc != nil {
+		if key := rl.keyFunc(r); key != "" {
+			return key
+		}
+	}
+	return rl.ClientIP(r)
+}
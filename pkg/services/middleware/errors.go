@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/weedge/pipeline-go/pkg/logger"
+)
+
+// 拒绝请求的错误码
+const (
+	ErrCodeTooManyConnections = "too_many_connections"
+	ErrCodeTooManySessions    = "too_many_sessions"
+	ErrCodeRateLimited        = "rate_limited"
+	ErrCodeBanned             = "banned"
+	ErrCodeUnauthorized       = "unauthorized"
+	ErrCodeForbiddenOrigin    = "forbidden_origin"
+)
+
+// ErrorBody 中间件拒绝请求时返回的结构化错误, 响应体为 {"error": {...}}
+type ErrorBody struct {
+	Code           string `json:"code"`
+	Message        string `json:"message"`
+	RetryAfterSecs int    `json:"retry_after_secs,omitempty"`
+}
+
+// WriteJSONError writes the json error response, retryAfter > 0 sets the Retry-After header
+func WriteJSONError(w http.ResponseWriter, status int, code, message string, retryAfter time.Duration) {
+	body := ErrorBody{Code: code, Message: message}
+	if retryAfter > 0 {
+		body.RetryAfterSecs = int(math.Ceil(retryAfter.Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(body.RetryAfterSecs))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(map[string]ErrorBody{"error": body}); err != nil {
+		logger.Warn("write json error response error", "code", code, "error", err)
+	}
+}
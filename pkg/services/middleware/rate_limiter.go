@@ -1,16 +1,19 @@
 package middleware
 
 import (
+	"context"
+	"net"
 	"net/http"
-	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/weedge/pipeline-go/pkg/logger"
 	"golang.org/x/time/rate"
 )
 
-// RateLimiter Token Bucket 速率限制器
+// RateLimiter Token Bucket 速率限制器: 按键(默认客户端 IP)限制连接请求速率和并发会话数,
+// 全局限制最大连接数; 长连接(websocket)只在建立时计一次请求, 会话时长可限制; 多次违规的键临时封禁
 type RateLimiter struct {
 	enabled              bool
 	limiters             map[string]*rate.Limiter
@@ -19,7 +22,45 @@ type RateLimiter struct {
 	b                    int        // token桶容量大小
 	maxConns             int
 	connCount            int32
-	cleanupIntervalTimeS int // 清理间隔时间,检查是否token桶是满的，满则有段时间未用，可删除释放对应ip limiter
+	totalAccepted        int64 // 通过限制的请求数
+	totalRejectedConns   int64 // 超过最大连接数被拒绝的请求数
+	totalRejectedRates   int64 // 超过请求速率被拒绝的请求数
+	cleanupIntervalTimeS int   // 清理间隔时间,检查是否token桶是满的，满则有段时间未用，可删除释放对应ip limiter
+
+	// 认证失败按客户端 IP 单独限流, 预算远小于请求速率, 用于防暴力破解
+	authFailureLimiters map[string]*rate.Limiter
+	authFailureR        rate.Limit // 每秒恢复的认证失败次数
+	authFailureB        int        // 认证失败的突发次数, 0 不限制
+
+	trustedProxies []*net.IPNet // 可信代理, 只有来自可信代理的请求才使用 X-Forwarded-For
+	keyFunc        KeyFunc      // 限流键, nil 或返回空时使用客户端 IP
+
+	stateMu            sync.Mutex
+	maxSessionsPerKey  int            // 每个键的最大并发会话数, 0 不限制
+	sessions           map[string]int // 每个键的当前会话数
+	maxSessionDuration time.Duration  // 会话最长时长, 0 不限制
+	maxViolations      int            // 窗口内违规(超速率/超会话数)次数达到后临时封禁, 0 不封禁
+	violationWindow    time.Duration
+	banDuration        time.Duration
+	violations         map[string]*violation
+
+	totalRejectedSessions int64 // 超过每个键的并发会话数被拒绝的请求数
+	totalRejectedAuths    int64 // 认证失败过多, 在校验 token 前被拒绝的请求数
+	totalRejectedBans     int64 // 封禁期间被拒绝的请求数
+	totalBans             int64 // 封禁次数
+	now                   func() time.Time
+}
+
+const (
+	defaultAuthFailuresPerMinute = 5
+	defaultAuthFailureBurst      = 5
+)
+
+// violation 键的违规计数和封禁截止时间
+type violation struct {
+	count       int
+	windowStart time.Time
+	bannedUntil time.Time
 }
 
 // NewRateLimiter 创建新的速率限制器
@@ -32,8 +73,14 @@ func NewRateLimiter(
 		limiters:             make(map[string]*rate.Limiter),
 		r:                    rate.Limit(requestsPerSecond),
 		b:                    burstSize,
+		authFailureLimiters:  make(map[string]*rate.Limiter),
+		authFailureR:         rate.Limit(float64(defaultAuthFailuresPerMinute) / 60),
+		authFailureB:         defaultAuthFailureBurst,
 		maxConns:             maxConnections,
 		cleanupIntervalTimeS: 3,
+		sessions:             make(map[string]int),
+		violations:           make(map[string]*violation),
+		now:                  time.Now,
 	}
 }
 
@@ -43,8 +90,14 @@ func NewDefaultRateLimiter() *RateLimiter {
 		limiters:             make(map[string]*rate.Limiter),
 		r:                    rate.Limit(1024),
 		b:                    1024,
+		authFailureLimiters:  make(map[string]*rate.Limiter),
+		authFailureR:         rate.Limit(float64(defaultAuthFailuresPerMinute) / 60),
+		authFailureB:         defaultAuthFailureBurst,
 		maxConns:             1024,
 		cleanupIntervalTimeS: 3,
+		sessions:             make(map[string]int),
+		violations:           make(map[string]*violation),
+		now:                  time.Now,
 	}
 }
 
@@ -73,10 +126,62 @@ func (rl *RateLimiter) WithCleanupIntervalTimeS(cleanupIntervalTimeS int) *RateL
 	return rl
 }
 
+// WithTrustedProxies sets the CIDRs (or IPs) of the trusted proxies (e.g. the load balancer),
+// X-Forwarded-For of other requests is ignored
+func (rl *RateLimiter) WithTrustedProxies(cidrs ...string) *RateLimiter {
+	rl.trustedProxies = parseTrustedProxies(cidrs)
+	return rl
+}
+
+// WithKeyFunc sets the limiter key of the request, e.g. APIKeyFunc, UserIDKeyFunc
+func (rl *RateLimiter) WithKeyFunc(keyFunc KeyFunc) *RateLimiter {
+	rl.keyFunc = keyFunc
+	return rl
+}
+
+// WithMaxSessionsPerKey limits the concurrent sessions of each key, 0 disables
+func (rl *RateLimiter) WithMaxSessionsPerKey(maxSessions int) *RateLimiter {
+	rl.maxSessionsPerKey = maxSessions
+	return rl
+}
+
+// WithMaxSessionDuration cancels the request context after the duration, the handler (e.g. websocket session)
+// should end the session when the context is done; 0 disables
+func (rl *RateLimiter) WithMaxSessionDuration(duration time.Duration) *RateLimiter {
+	rl.maxSessionDuration = duration
+	return rl
+}
+
+// WithBan bans the key for the duration after maxViolations denials (rate limited, too many sessions)
+// within the window, 0 maxViolations disables
+func (rl *RateLimiter) WithBan(maxViolations int, window, duration time.Duration) *RateLimiter {
+	rl.maxViolations = maxViolations
+	rl.violationWindow = window
+	rl.banDuration = duration
+	return rl
+}
+
+// WithAuthFailureLimit limits the failed authentications of each client ip to perMinute with the burst,
+// separately from the request rate; 0 burst disables
+func (rl *RateLimiter) WithAuthFailureLimit(perMinute, burst int) *RateLimiter {
+	rl.authFailureR = rate.Limit(float64(perMinute) / 60)
+	rl.authFailureB = burst
+	return rl
+}
+
 // getLimiter 获取或创建IP的限制器
 func (rl *RateLimiter) getLimiter(ip string) *rate.Limiter {
+	return rl.getOrCreateLimiter(rl.limiters, ip, rl.r, rl.b)
+}
+
+// getAuthFailureLimiter 获取或创建客户端 IP 的认证失败限制器
+func (rl *RateLimiter) getAuthFailureLimiter(ip string) *rate.Limiter {
+	return rl.getOrCreateLimiter(rl.authFailureLimiters, ip, rl.authFailureR, rl.authFailureB)
+}
+
+func (rl *RateLimiter) getOrCreateLimiter(limiters map[string]*rate.Limiter, key string, r rate.Limit, b int) *rate.Limiter {
 	rl.mu.RLock()
-	limiter, exists := rl.limiters[ip]
+	limiter, exists := limiters[key]
 	rl.mu.RUnlock()
 	if exists {
 		return limiter
@@ -86,12 +191,12 @@ func (rl *RateLimiter) getLimiter(ip string) *rate.Limiter {
 	defer rl.mu.Unlock()
 
 	// Double-check in case another goroutine created it while we were waiting for the lock.
-	if limiter, exists = rl.limiters[ip]; exists {
+	if limiter, exists = limiters[key]; exists {
 		return limiter
 	}
 
-	limiter = rate.NewLimiter(rl.r, rl.b)
-	rl.limiters[ip] = limiter
+	limiter = rate.NewLimiter(r, b)
+	limiters[key] = limiter
 	return limiter
 }
 
@@ -113,11 +218,130 @@ func (rl *RateLimiter) cleanupLimiters() {
 					delete(rl.limiters, ip)
 				}
 			}
+			for ip, limiter := range rl.authFailureLimiters {
+				if limiter.Tokens() >= float64(rl.authFailureB) {
+					delete(rl.authFailureLimiters, ip)
+				}
+			}
 			rl.mu.Unlock()
+			rl.cleanupViolations()
 		}
 	}()
 }
 
+// cleanupViolations 清理窗口过期且未封禁的违规记录
+func (rl *RateLimiter) cleanupViolations() {
+	rl.stateMu.Lock()
+	defer rl.stateMu.Unlock()
+	now := rl.now()
+	for key, v := range rl.violations {
+		if now.After(v.bannedUntil) && now.Sub(v.windowStart) > rl.violationWindow {
+			delete(rl.violations, key)
+		}
+	}
+}
+
+// bannedFor returns the remaining ban duration of the key, 0 if not banned
+func (rl *RateLimiter) bannedFor(key string) time.Duration {
+	rl.stateMu.Lock()
+	defer rl.stateMu.Unlock()
+	if v, ok := rl.violations[key]; ok {
+		if remaining := v.bannedUntil.Sub(rl.now()); remaining > 0 {
+			return remaining
+		}
+	}
+	return 0
+}
+
+// violate counts the violation of the key, bans the key if too many violations within the window
+func (rl *RateLimiter) violate(key string) {
+	if rl.maxViolations <= 0 {
+		return
+	}
+	rl.stateMu.Lock()
+	defer rl.stateMu.Unlock()
+	now := rl.now()
+	v, ok := rl.violations[key]
+	if !ok || now.Sub(v.windowStart) > rl.violationWindow {
+		v = &violation{windowStart: now, bannedUntil: time.Time{}}
+		rl.violations[key] = v
+	}
+	v.count++
+	if v.count >= rl.maxViolations {
+		v.count = 0
+		v.windowStart = now
+		v.bannedUntil = now.Add(rl.banDuration)
+		atomic.AddInt64(&rl.totalBans, 1)
+		logger.Warn("rate limiter ban", "key", key, "until", v.bannedUntil)
+	}
+}
+
+// authFailureKey 认证失败按客户端 IP 计数, 与认证后的限流键(用户)分开
+func (rl *RateLimiter) authFailureKey(r *http.Request) string {
+	return "auth_failure:" + rl.ClientIP(r)
+}
+
+// rejectAuthAttempt checks the client ip before the token check (Authenticator.WithFailureLimiter):
+// rejects if banned or the failure budget (WithAuthFailureLimit) is used up, returns whether rejected
+func (rl *RateLimiter) rejectAuthAttempt(w http.ResponseWriter, r *http.Request) bool {
+	if !rl.enabled || rl.authFailureB <= 0 {
+		return false
+	}
+	key := rl.authFailureKey(r)
+	if retryAfter := rl.bannedFor(key); retryAfter > 0 {
+		atomic.AddInt64(&rl.totalRejectedBans, 1)
+		WriteJSONError(w, http.StatusTooManyRequests, ErrCodeBanned, "Temporarily banned for repeated violations", retryAfter)
+		return true
+	}
+	if rl.getAuthFailureLimiter(key).Tokens() < 1 {
+		atomic.AddInt64(&rl.totalRejectedAuths, 1)
+		rl.violate(key)
+		var retryAfter time.Duration
+		if rl.authFailureR > 0 {
+			retryAfter = time.Duration(float64(time.Second) / float64(rl.authFailureR))
+		}
+		WriteJSONError(w, http.StatusTooManyRequests, ErrCodeRateLimited, "Too many failed authentications", retryAfter)
+		return true
+	}
+	return false
+}
+
+// authFailed counts the failed authentication of the client ip, uses up its failure budget
+func (rl *RateLimiter) authFailed(r *http.Request) {
+	if !rl.enabled || rl.authFailureB <= 0 {
+		return
+	}
+	key := rl.authFailureKey(r)
+	if !rl.getAuthFailureLimiter(key).Allow() {
+		rl.violate(key)
+	}
+}
+
+// acquireSession counts the session of the key, false if the key has too many concurrent sessions
+func (rl *RateLimiter) acquireSession(key string) bool {
+	if rl.maxSessionsPerKey <= 0 {
+		return true
+	}
+	rl.stateMu.Lock()
+	defer rl.stateMu.Unlock()
+	if rl.sessions[key] >= rl.maxSessionsPerKey {
+		return false
+	}
+	rl.sessions[key]++
+	return true
+}
+
+func (rl *RateLimiter) releaseSession(key string) {
+	if rl.maxSessionsPerKey <= 0 {
+		return
+	}
+	rl.stateMu.Lock()
+	defer rl.stateMu.Unlock()
+	if rl.sessions[key]--; rl.sessions[key] <= 0 {
+		delete(rl.sessions, key)
+	}
+}
+
 // Middleware 速率限制中间件
 func (rl *RateLimiter) Middleware(next http.Handler) http.Handler {
 	// 如果限速器未启用，直接跳过
@@ -129,32 +353,58 @@ func (rl *RateLimiter) Middleware(next http.Handler) http.Handler {
 	rl.cleanupLimiters()
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 获取限流键(默认客户端IP)
+		key := rl.key(r)
+
+		// 检查是否临时封禁
+		if retryAfter := rl.bannedFor(key); retryAfter > 0 {
+			atomic.AddInt64(&rl.totalRejectedBans, 1)
+			WriteJSONError(w, http.StatusTooManyRequests, ErrCodeBanned, "Temporarily banned for repeated violations", retryAfter)
+			return
+		}
+
 		// 检查连接数限制
 		currentConns := atomic.AddInt32(&rl.connCount, 1)
 		if currentConns > int32(rl.maxConns) {
 			atomic.AddInt32(&rl.connCount, -1) // Decrement back as we are rejecting this connection.
-			http.Error(w, "Too many connections", http.StatusTooManyRequests)
+			atomic.AddInt64(&rl.totalRejectedConns, 1)
+			WriteJSONError(w, http.StatusTooManyRequests, ErrCodeTooManyConnections, "Too many connections", 0)
 			return
 		}
 
 		// 连接结束时减少计数
 		defer atomic.AddInt32(&rl.connCount, -1)
 
-		// 获取客户端IP
-		ip := r.RemoteAddr
-		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
-			// X-Forwarded-For can be a comma-separated list of IPs. The first one is the original client.
-			parts := strings.Split(forwarded, ",")
-			ip = strings.TrimSpace(parts[0])
+		// 检查连接请求速率限制
+		limiter := rl.getLimiter(key)
+		if !limiter.Allow() {
+			atomic.AddInt64(&rl.totalRejectedRates, 1)
+			rl.violate(key)
+			var retryAfter time.Duration
+			if rl.r > 0 {
+				retryAfter = time.Duration(float64(time.Second) / float64(rl.r))
+			}
+			WriteJSONError(w, http.StatusTooManyRequests, ErrCodeRateLimited, "Rate limit exceeded", retryAfter)
+			return
 		}
 
-		// 检查客户端IP请求速率限制
-		limiter := rl.getLimiter(ip)
-		if !limiter.Allow() {
-			http.Error(w, "Rate limit exceeded", http.StatusTooManyRequests)
+		// 检查每个键的并发会话数
+		if !rl.acquireSession(key) {
+			atomic.AddInt64(&rl.totalRejectedSessions, 1)
+			rl.violate(key)
+			WriteJSONError(w, http.StatusTooManyRequests, ErrCodeTooManySessions, "Too many concurrent sessions", 0)
 			return
 		}
+		defer rl.releaseSession(key)
+
+		// 会话最长时长, 到期取消请求 context
+		if rl.maxSessionDuration > 0 {
+			ctx, cancel := context.WithTimeout(r.Context(), rl.maxSessionDuration)
+			defer cancel()
+			r = r.WithContext(ctx)
+		}
 
+		atomic.AddInt64(&rl.totalAccepted, 1)
 		next.ServeHTTP(w, r)
 	})
 }
@@ -176,12 +426,33 @@ func (rl *RateLimiter) GetStats() map[string]any {
 	activeLimiters := len(rl.limiters)
 	rl.mu.RUnlock()
 
+	rl.stateMu.Lock()
+	activeBans := 0
+	now := rl.now()
+	for _, v := range rl.violations {
+		if v.bannedUntil.After(now) {
+			activeBans++
+		}
+	}
+	rl.stateMu.Unlock()
+
 	return map[string]any{
-		"enabled":             rl.enabled,
-		"active_limiters":     activeLimiters,
-		"current_connections": currentConns,
-		"max_connections":     rl.maxConns,
-		"requests_per_second": float64(rl.r),
-		"burst_size":          rl.b,
+		"enabled":              rl.enabled,
+		"active_limiters":      activeLimiters,
+		"current_connections":  currentConns,
+		"max_connections":      rl.maxConns,
+		"requests_per_second":  float64(rl.r),
+		"burst_size":           rl.b,
+		"total_accepted":       atomic.LoadInt64(&rl.totalAccepted),
+		"total_rejected_conns": atomic.LoadInt64(&rl.totalRejectedConns),
+		"total_rejected_rates": atomic.LoadInt64(&rl.totalRejectedRates),
+
+		"max_sessions_per_key":      rl.maxSessionsPerKey,
+		"max_session_duration_secs": rl.maxSessionDuration.Seconds(),
+		"active_bans":               activeBans,
+		"total_rejected_sessions":   atomic.LoadInt64(&rl.totalRejectedSessions),
+		"total_rejected_auths":      atomic.LoadInt64(&rl.totalRejectedAuths),
+		"total_rejected_bans":       atomic.LoadInt64(&rl.totalRejectedBans),
+		"total_bans":                atomic.LoadInt64(&rl.totalBans),
 	}
 }
@@ -168,10 +168,16 @@ func TestMiddlewareRateLimit(t *testing.T) {
 	resp2 := httptest.NewRecorder()
 	handler.ServeHTTP(resp2, req)
 	assert.Equal(t, http.StatusTooManyRequests, resp2.Code)
+
+	stats := rl.GetStats()
+	assert.Equal(t, int64(1), stats["total_accepted"])
+	assert.Equal(t, int64(1), stats["total_rejected_rates"])
+	assert.Equal(t, int64(0), stats["total_rejected_conns"])
 }
 
 func TestMiddlewareWithXForwardedFor(t *testing.T) {
-	rl := NewRateLimiter(true, 1, 1, 100)
+	// httptest 请求的 RemoteAddr 为 192.0.2.1:1234
+	rl := NewRateLimiter(true, 1, 1, 100).WithTrustedProxies("192.0.2.0/24")
 
 	nextHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -196,7 +202,8 @@ func TestMiddlewareWithXForwardedFor(t *testing.T) {
 // 测试 cleanupLimiters 函数
 func TestMiddlewareWithCleanupIntervalTimeS(t *testing.T) {
 	cleanupIntervalTimeS := 3
-	rl := NewRateLimiter(true, 1, 1, 100).WithCleanupIntervalTimeS(cleanupIntervalTimeS)
+	rl := NewRateLimiter(true, 1, 1, 100).WithTrustedProxies("192.0.2.0/24").
+		WithCleanupIntervalTimeS(cleanupIntervalTimeS)
 
 	nextHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -224,7 +231,8 @@ func TestMiddlewareWithCleanupIntervalTimeS(t *testing.T) {
 func TestMultiCleanupLimiters(t *testing.T) {
 	// 创建一个速率限制器，设置较小的限制以便测试, 每秒放一个token,token桶容量为1, 最大并发3个请求
 	cleanupIntervalTimeS := 3
-	rl := NewRateLimiter(true, 1, 1, 3).WithCleanupIntervalTimeS(cleanupIntervalTimeS)
+	rl := NewRateLimiter(true, 1, 1, 3).WithTrustedProxies("192.0.2.0/24").
+		WithCleanupIntervalTimeS(cleanupIntervalTimeS)
 
 	nextHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -264,7 +272,8 @@ func TestMultiConcurrentCleanupLimiters(t *testing.T) {
 	// 创建一个速率限制器，设置较小的限制以便测试, 每秒放一个token,token桶容量为1, 最大并发3个请求
 	cleanupIntervalTimeS := 3
 	maxConns := 3
-	rl := NewRateLimiter(true, 1, 1, maxConns).WithCleanupIntervalTimeS(cleanupIntervalTimeS)
+	rl := NewRateLimiter(true, 1, 1, maxConns).WithTrustedProxies("192.0.2.0/24").
+		WithCleanupIntervalTimeS(cleanupIntervalTimeS)
 
 	nextHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		//slow process (e.g.: slow sql/kv(RAG) io or local asr/llm/tts DNN model inference)
@@ -329,3 +338,147 @@ func TestMultiConcurrentCleanupLimiters(t *testing.T) {
 	time.Sleep((time.Duration(cleanupIntervalTimeS) + 1) * time.Second)
 	assert.Equal(t, 0, len(rl.GetClientIPs()))
 }
+
+func TestClientIPTrustedProxies(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "203.0.113.7:4321"
+	req.Header.Set("X-Forwarded-For", "1.2.3.4")
+
+	// 不可信来源伪造的 X-Forwarded-For 被忽略
+	rl := NewRateLimiter(true, 1, 1, 100)
+	assert.Equal(t, "203.0.113.7", rl.ClientIP(req))
+
+	// 可信代理追加的最右侧非可信地址为客户端
+	rl = NewRateLimiter(true, 1, 1, 100).WithTrustedProxies("203.0.113.0/24", "10.0.0.1", "bad")
+	req.Header.Set("X-Forwarded-For", "1.2.3.4, 5.6.7.8, 10.0.0.1")
+	assert.Equal(t, "5.6.7.8", rl.ClientIP(req))
+}
+
+func TestKeyFunc(t *testing.T) {
+	rl := NewRateLimiter(true, 1, 1, 100).
+		WithKeyFunc(FirstKeyFunc(UserIDKeyFunc(), APIKeyFunc("Authorization", "api_key", func(apiKey string) bool {
+			return apiKey == "secret"
+		})))
+	handler := rl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func(req *http.Request) int {
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, req)
+		return resp.Code
+	}
+
+	// 同一 API Key (header 或 query) 共享限流
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	assert.Equal(t, http.StatusOK, serve(req))
+	assert.Equal(t, http.StatusTooManyRequests, serve(httptest.NewRequest("GET", "/?api_key=secret", nil)))
+
+	// 认证用户优先, 不受 API Key 限流影响
+	req = req.WithContext(ContextWithUserID(req.Context(), "alice"))
+	assert.Equal(t, http.StatusOK, serve(req))
+
+	// 无键或无效的 API Key 使用客户端 IP, 伪造的 Key 不能绕过限流
+	forged := httptest.NewRequest("GET", "/?api_key=forged", nil)
+	assert.Equal(t, http.StatusOK, serve(forged))
+	assert.Equal(t, http.StatusTooManyRequests, serve(httptest.NewRequest("GET", "/", nil)))
+	assert.Equal(t, http.StatusTooManyRequests, serve(httptest.NewRequest("GET", "/?api_key=forged2", nil)))
+
+	keys := rl.GetClientIPs()
+	assert.Len(t, keys, 3)
+	assert.Contains(t, keys, "user:alice")
+	assert.Contains(t, keys, "192.0.2.1")
+	for _, key := range keys {
+		assert.NotContains(t, key, "secret")
+	}
+}
+
+func TestMiddlewareSessionLimit(t *testing.T) {
+	rl := NewRateLimiter(true, 100, 100, 100).WithMaxSessionsPerKey(1)
+	started, release := make(chan struct{}), make(chan struct{})
+	handler := rl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	done := make(chan int)
+	go func() {
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, httptest.NewRequest("GET", "/", nil))
+		done <- resp.Code
+	}()
+	<-started
+
+	// 同一键的第二个并发会话被拒绝, 其他键不受影响
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, http.StatusTooManyRequests, resp.Code)
+	assert.Contains(t, resp.Body.String(), ErrCodeTooManySessions)
+
+	close(release)
+	assert.Equal(t, http.StatusOK, <-done)
+
+	// 会话结束后释放配额
+	go func() { <-started }()
+	resp = httptest.NewRecorder()
+	handler.ServeHTTP(resp, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, int64(1), rl.GetStats()["total_rejected_sessions"])
+}
+
+func TestMiddlewareMaxSessionDuration(t *testing.T) {
+	rl := NewRateLimiter(true, 100, 100, 100).WithMaxSessionDuration(50 * time.Millisecond)
+	handler := rl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+			w.WriteHeader(http.StatusRequestTimeout)
+		case <-time.After(time.Second):
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, http.StatusRequestTimeout, resp.Code)
+}
+
+func TestMiddlewareBan(t *testing.T) {
+	rl := NewRateLimiter(true, 1, 1, 100).WithBan(2, time.Minute, time.Minute)
+	now := time.Now()
+	rl.now = func() time.Time { return now }
+	handler := rl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func() *httptest.ResponseRecorder {
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, httptest.NewRequest("GET", "/", nil))
+		return resp
+	}
+
+	assert.Equal(t, http.StatusOK, serve().Code)
+	resp := serve()
+	assert.Equal(t, http.StatusTooManyRequests, resp.Code)
+	assert.Equal(t, "application/json", resp.Header().Get("Content-Type"))
+	assert.Equal(t, "1", resp.Header().Get("Retry-After"))
+	assert.JSONEq(t, `{"error":{"code":"rate_limited","message":"Rate limit exceeded","retry_after_secs":1}}`, resp.Body.String())
+
+	// 第二次违规后封禁
+	assert.Contains(t, serve().Body.String(), ErrCodeRateLimited)
+	resp = serve()
+	assert.Equal(t, http.StatusTooManyRequests, resp.Code)
+	assert.Equal(t, "60", resp.Header().Get("Retry-After"))
+	assert.Contains(t, resp.Body.String(), ErrCodeBanned)
+
+	// 封禁到期后恢复(等待令牌桶补充令牌)
+	now = now.Add(2 * time.Minute)
+	time.Sleep(time.Second)
+	assert.Equal(t, http.StatusOK, serve().Code)
+
+	stats := rl.GetStats()
+	assert.Equal(t, int64(1), stats["total_bans"])
+	assert.Equal(t, int64(1), stats["total_rejected_bans"])
+	assert.Equal(t, 0, stats["active_bans"])
+}
@@ -0,0 +1,276 @@
+package recording
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/weedge/pipeline-go/pkg/logger"
+
+	"achatbot/pkg/common"
+	"achatbot/pkg/types"
+	"achatbot/pkg/utils"
+)
+
+const (
+	// DefaultFlushInterval 录音写入文件并更新 WAV 头部的间隔
+	DefaultFlushInterval = time.Second
+	// DefaultFlushDelay 只写入早于当前时间该延迟的时间轴, 等待晚到的用户音频
+	DefaultFlushDelay = time.Second
+	// DefaultJitter 音频到达时间的抖动, 小于该间隔的空隙不补静音
+	DefaultJitter = 100 * time.Millisecond
+)
+
+// ConversationRecorder 会话双声道录音: 用户输入音频(左声道)和机器人输出音频(右声道)按墙钟时间对齐,
+// 空隙补静音, 写入立体声 WAV; 定期写入并更新头部(崩溃后文件仍可播放), 可按时长切分文件
+type ConversationRecorder struct {
+	dir    string
+	prefix string
+	// mono 16 bit track format of the recording
+	format *types.AudioFormat
+	user   *track
+	bot    *track
+
+	flushInterval  time.Duration
+	flushDelay     time.Duration
+	jitter         time.Duration
+	rotateInterval time.Duration
+
+	mu    sync.Mutex
+	start time.Time
+	// frames of the timeline written to files
+	flushed int
+	// timeline frame where the current file starts
+	fileStart int
+	writer    *utils.WAVFileWriter
+	files     []string
+	closed    bool
+
+	startOnce sync.Once
+	stopCh    chan struct{}
+	doneCh    chan struct{}
+	now       func() time.Time
+}
+
+// track pending audio of a channel, starts at the flushed frame of the timeline
+type track struct {
+	name   string
+	format *types.AudioFormat
+	buf    []byte
+	// the audio is tapped when its capture ends (user input), otherwise when its playback starts (bot output)
+	atEnd bool
+}
+
+// NewConversationRecorder records to <dir>/<prefix>_<time>.wav at the sample rate,
+// userFormat is the client input audio format, botFormat is the output audio format written to the client
+func NewConversationRecorder(dir, prefix string, sampleRate int, userFormat, botFormat *types.AudioFormat) *ConversationRecorder {
+	return &ConversationRecorder{
+		dir:           dir,
+		prefix:        prefix,
+		format:        types.NewAudioFormat(sampleRate, 1, 2),
+		user:          &track{name: "user", format: userFormat, atEnd: true},
+		bot:           &track{name: "bot", format: botFormat},
+		flushInterval: DefaultFlushInterval,
+		flushDelay:    DefaultFlushDelay,
+		jitter:        DefaultJitter,
+		stopCh:        make(chan struct{}),
+		doneCh:        make(chan struct{}),
+		now:           time.Now,
+	}
+}
+
+// WithRotateInterval starts a new file every interval of the recording, 0 disables rotation
+func (r *ConversationRecorder) WithRotateInterval(interval time.Duration) *ConversationRecorder {
+	r.rotateInterval = interval
+	return r
+}
+
+func (r *ConversationRecorder) WithFlushInterval(interval time.Duration) *ConversationRecorder {
+	r.flushInterval = interval
+	return r
+}
+
+// UserTap returns the tap of the user input audio, e.g. params.AudioVADParams.WithAudioInTap
+func (r *ConversationRecorder) UserTap() common.IAudioTap {
+	return &trackTap{recorder: r, track: r.user}
+}
+
+// BotTap returns the tap of the bot output audio, e.g. processors.NewAudioTapTransportWriter
+func (r *ConversationRecorder) BotTap() common.IAudioTap {
+	return &trackTap{recorder: r, track: r.bot}
+}
+
+// Files returns the recorded files
+func (r *ConversationRecorder) Files() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.files...)
+}
+
+// Close writes the buffered audio of both tracks and finalizes the file
+func (r *ConversationRecorder) Close() error {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return nil
+	}
+	r.closed = true
+	started := !r.start.IsZero()
+	r.mu.Unlock()
+	if !started {
+		return nil
+	}
+
+	close(r.stopCh)
+	<-r.doneCh
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	err := r.flush(max(r.user.frames(), r.bot.frames()) + r.flushed)
+	if r.writer != nil {
+		if closeErr := r.writer.Close(); err == nil {
+			err = closeErr
+		}
+		r.writer = nil
+	}
+	return err
+}
+
+type trackTap struct {
+	recorder *ConversationRecorder
+	track    *track
+}
+
+func (t *trackTap) WriteAudio(audio []byte) {
+	t.recorder.write(t.track, audio)
+}
+
+// write places the audio on the timeline by the wall clock: a gap after the track audio is filled with silence,
+// audio ahead of the timeline (e.g. bot audio written faster than played) follows the track audio
+func (r *ConversationRecorder) write(t *track, audio []byte) {
+	if len(audio) == 0 {
+		return
+	}
+	pcm, err := utils.ConvertAudioFormat(audio, t.format, r.format)
+	if err != nil {
+		logger.Warn("conversation recorder convert audio error", "track", t.name, "error", err)
+		return
+	}
+	pcm = pcm[:len(pcm)-len(pcm)%2]
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return
+	}
+	now := r.now()
+	if r.start.IsZero() {
+		r.start = now
+		r.startOnce.Do(func() { go r.run() })
+	}
+
+	pos := r.frameAt(now)
+	if t.atEnd {
+		pos -= len(pcm) / 2
+	}
+	if cursor := r.flushed + t.frames(); pos-cursor > r.framesOf(r.jitter) {
+		t.buf = append(t.buf, make([]byte, (pos-cursor)*2)...)
+	}
+	t.buf = append(t.buf, pcm...)
+}
+
+func (r *ConversationRecorder) run() {
+	defer close(r.doneCh)
+	ticker := time.NewTicker(r.flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.mu.Lock()
+			if err := r.flush(r.frameAt(r.now()) - r.framesOf(r.flushDelay)); err != nil {
+				logger.Warn("conversation recorder flush error", "error", err)
+			}
+			r.mu.Unlock()
+		case <-r.stopCh:
+			return
+		}
+	}
+}
+
+// flush writes the timeline up to the target frame as stereo (user left, bot right),
+// then updates the wav header
+func (r *ConversationRecorder) flush(target int) error {
+	for target > r.flushed {
+		if r.writer == nil {
+			if err := r.openFile(); err != nil {
+				return err
+			}
+		}
+		n := target - r.flushed
+		if r.rotateInterval > 0 {
+			n = min(n, r.fileStart+r.framesOf(r.rotateInterval)-r.flushed)
+		}
+		if _, err := r.writer.Write(interleave(r.user.pop(n), r.bot.pop(n))); err != nil {
+			return fmt.Errorf("failed to write %s: %w", r.writer.Path(), err)
+		}
+		r.flushed += n
+
+		if r.rotateInterval > 0 && r.flushed-r.fileStart >= r.framesOf(r.rotateInterval) {
+			err := r.writer.Close()
+			r.writer = nil
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if r.writer == nil {
+		return nil
+	}
+	return r.writer.Sync()
+}
+
+func (r *ConversationRecorder) openFile() error {
+	formattedTime := r.start.Add(time.Duration(r.flushed) * time.Second / time.Duration(r.format.SampleRate)).
+		Format("2006-01-02_15-04-05.000")
+	path := filepath.Join(r.dir, fmt.Sprintf("%s_%s.wav", r.prefix, formattedTime))
+	writer, err := utils.NewWAVFileWriter(path, 2, r.format.SampleWidth, r.format.SampleRate)
+	if err != nil {
+		return err
+	}
+	logger.Info("conversation recording", "path", path)
+	r.writer = writer
+	r.fileStart = r.flushed
+	r.files = append(r.files, path)
+	return nil
+}
+
+func (r *ConversationRecorder) frameAt(t time.Time) int {
+	return r.framesOf(t.Sub(r.start))
+}
+
+func (r *ConversationRecorder) framesOf(d time.Duration) int {
+	return int(d * time.Duration(r.format.SampleRate) / time.Second)
+}
+
+func (t *track) frames() int {
+	return len(t.buf) / 2
+}
+
+// pop takes n frames of the track, silence if not enough
+func (t *track) pop(n int) []byte {
+	out := make([]byte, n*2)
+	m := copy(out, t.buf)
+	t.buf = t.buf[m:]
+	return out
+}
+
+// interleave 16 bit mono left and right into stereo
+func interleave(left, right []byte) []byte {
+	out := make([]byte, len(left)*2)
+	for i := 0; i < len(left)/2; i++ {
+		copy(out[i*4:i*4+2], left[i*2:i*2+2])
+		copy(out[i*4+2:i*4+4], right[i*2:i*2+2])
+	}
+	return out
+}
@@ -0,0 +1,79 @@
+package recording
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"achatbot/pkg/types"
+	"achatbot/pkg/utils"
+)
+
+func pcm(frames int, value int16) []byte {
+	audio := make([]byte, frames*2)
+	for i := range frames {
+		binary.LittleEndian.PutUint16(audio[i*2:], uint16(value))
+	}
+	return audio
+}
+
+// channels returns the left and right samples of the stereo wav
+func channels(t *testing.T, path string) ([]int16, []int16) {
+	data, sampleRate, err := utils.ReadWAVToBytes(path)
+	assert.NoError(t, err)
+	assert.Equal(t, 1000, sampleRate)
+	left, right := make([]int16, len(data)/4), make([]int16, len(data)/4)
+	for i := range left {
+		left[i] = int16(binary.LittleEndian.Uint16(data[i*4:]))
+		right[i] = int16(binary.LittleEndian.Uint16(data[i*4+2:]))
+	}
+	return left, right
+}
+
+func TestConversationRecorder(t *testing.T) {
+	format := types.NewAudioFormat(1000, 1, 2)
+	recorder := NewConversationRecorder(t.TempDir(), "conversation", 1000, format, format).
+		WithRotateInterval(600 * time.Millisecond).
+		WithFlushInterval(time.Hour)
+	start := time.Now()
+	at := func(ms int) {
+		recorder.now = func() time.Time { return start.Add(time.Duration(ms) * time.Millisecond) }
+	}
+	user, bot := recorder.UserTap(), recorder.BotTap()
+
+	// user audio captured in [0, 100ms)
+	at(0)
+	user.WriteAudio(pcm(100, 1))
+	// bot audio written faster than played follows the previous bot audio
+	at(500)
+	bot.WriteAudio(pcm(50, 2))
+	at(510)
+	bot.WriteAudio(pcm(50, 3))
+	// user audio captured in [900ms, 1000ms), the gap is filled with silence
+	at(1000)
+	user.WriteAudio(pcm(100, 4))
+
+	assert.NoError(t, recorder.Close())
+	assert.NoError(t, recorder.Close())
+	files := recorder.Files()
+	assert.Len(t, files, 2)
+
+	left, right := channels(t, files[0])
+	assert.Len(t, left, 600)
+	assert.Equal(t, int16(1), left[0])
+	assert.Equal(t, int16(1), left[99])
+	assert.Equal(t, int16(0), left[100])
+	assert.Equal(t, int16(0), right[499])
+	assert.Equal(t, int16(2), right[500])
+	assert.Equal(t, int16(3), right[550])
+	assert.Equal(t, int16(3), right[599])
+
+	left, right = channels(t, files[1])
+	assert.Len(t, left, 400)
+	assert.Equal(t, int16(0), left[299])
+	assert.Equal(t, int16(4), left[300])
+	assert.Equal(t, int16(4), left[399])
+	assert.Equal(t, int16(0), right[0])
+}
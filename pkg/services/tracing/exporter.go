@@ -0,0 +1,391 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/weedge/pipeline-go/pkg/logger"
+)
+
+// SpanExporter 导出结束的 span
+type SpanExporter interface {
+	// ExportSpans 导出一批 span
+	ExportSpans(spans []*SpanData) error
+
+	// Shutdown 刷新并释放资源
+	Shutdown() error
+}
+
+// ------------------------------------------------------------
+
+// JSONFileExporter 将 span 以 JSON Lines 追加写入本地文件, 用于离线调试
+type JSONFileExporter struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func NewJSONFileExporter(path string) (*JSONFileExporter, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create trace dir: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open trace file %s: %w", path, err)
+	}
+	return &JSONFileExporter{file: file}, nil
+}
+
+func (e *JSONFileExporter) ExportSpans(spans []*SpanData) error {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, span := range spans {
+		if err := encoder.Encode(span); err != nil {
+			return fmt.Errorf("failed to encode span %s: %w", span.Name, err)
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_, err := e.file.Write(buf.Bytes())
+	return err
+}
+
+func (e *JSONFileExporter) Shutdown() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.file.Close()
+}
+
+// ------------------------------------------------------------
+
+// DefaultOTLPTimeout OTLP 导出请求超时
+const DefaultOTLPTimeout = 10 * time.Second
+
+// OTLPHTTPExporter 以 OTLP/HTTP JSON 编码导出 span (如 http://localhost:4318/v1/traces),
+// 兼容 OpenTelemetry Collector, Jaeger, Tempo 等
+type OTLPHTTPExporter struct {
+	endpoint    string
+	serviceName string
+	headers     map[string]string
+	client      *http.Client
+}
+
+func NewOTLPHTTPExporter(endpoint, serviceName string) *OTLPHTTPExporter {
+	return &OTLPHTTPExporter{
+		endpoint:    endpoint,
+		serviceName: serviceName,
+		headers:     make(map[string]string),
+		client:      &http.Client{Timeout: DefaultOTLPTimeout},
+	}
+}
+
+// WithHeader sets the request header, e.g. authorization of the collector
+func (e *OTLPHTTPExporter) WithHeader(key, value string) *OTLPHTTPExporter {
+	e.headers[key] = value
+	return e
+}
+
+func (e *OTLPHTTPExporter) WithTimeout(timeout time.Duration) *OTLPHTTPExporter {
+	e.client.Timeout = timeout
+	return e
+}
+
+func (e *OTLPHTTPExporter) ExportSpans(spans []*SpanData) error {
+	body, err := json.Marshal(newOTLPTraceRequest(e.serviceName, spans))
+	if err != nil {
+		return fmt.Errorf("failed to encode otlp request: %w", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range e.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to export spans to %s: %w", e.endpoint, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("failed to export spans to %s: %s %s", e.endpoint, resp.Status, msg)
+	}
+	return nil
+}
+
+func (e *OTLPHTTPExporter) Shutdown() error {
+	e.client.CloseIdleConnections()
+	return nil
+}
+
+// otlp json encoding, see opentelemetry-proto trace/v1/trace.proto
+type otlpTraceRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string         `json:"key"`
+	Value map[string]any `json:"value"`
+}
+
+// otlp span kind internal
+const otlpSpanKindInternal = 1
+
+// newOTLPTraceRequest encodes the spans as the otlp json export request
+func newOTLPTraceRequest(serviceName string, spans []*SpanData) *otlpTraceRequest {
+	otlpSpans := make([]otlpSpan, 0, len(spans))
+	for _, span := range spans {
+		attributes := make([]otlpKeyValue, 0, len(span.Attributes))
+		for key, value := range span.Attributes {
+			attributes = append(attributes, otlpKeyValue{Key: key, Value: otlpAnyValue(value)})
+		}
+		otlpSpans = append(otlpSpans, otlpSpan{
+			TraceID:           span.TraceID,
+			SpanID:            span.SpanID,
+			ParentSpanID:      span.ParentSpanID,
+			Name:              span.Name,
+			Kind:              otlpSpanKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(span.StartTime.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.EndTime.UnixNano(), 10),
+			Attributes:        attributes,
+			Status:            otlpStatus{Code: int(span.Status), Message: span.StatusMessage},
+		})
+	}
+	return &otlpTraceRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource: otlpResource{Attributes: []otlpKeyValue{
+			{Key: "service.name", Value: otlpAnyValue(serviceName)},
+		}},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: "achatbot"}, Spans: otlpSpans}},
+	}}}
+}
+
+func otlpAnyValue(value any) map[string]any {
+	switch v := value.(type) {
+	case string:
+		return map[string]any{"stringValue": v}
+	case bool:
+		return map[string]any{"boolValue": v}
+	case int:
+		return map[string]any{"intValue": strconv.Itoa(v)}
+	case int64:
+		return map[string]any{"intValue": strconv.FormatInt(v, 10)}
+	case float32:
+		return map[string]any{"doubleValue": float64(v)}
+	case float64:
+		return map[string]any{"doubleValue": v}
+	default:
+		return map[string]any{"stringValue": fmt.Sprint(v)}
+	}
+}
+
+// ------------------------------------------------------------
+
+// MultiExporter 导出到多个导出器(如同时导出到 collector 和本地文件)
+type MultiExporter []SpanExporter
+
+func (m MultiExporter) ExportSpans(spans []*SpanData) error {
+	var errs []error
+	for _, exporter := range m {
+		errs = append(errs, exporter.ExportSpans(spans))
+	}
+	return errors.Join(errs...)
+}
+
+func (m MultiExporter) Shutdown() error {
+	var errs []error
+	for _, exporter := range m {
+		errs = append(errs, exporter.Shutdown())
+	}
+	return errors.Join(errs...)
+}
+
+// ------------------------------------------------------------
+
+const (
+	DefaultSpanQueueSize      = 2048
+	DefaultSpanBatchSize      = 256
+	DefaultSpanExportInterval = 2 * time.Second
+)
+
+// BatchSpanProcessor 结束的 span 入队, 后台按批量或间隔导出, 不阻塞处理器; 队列满时丢弃
+type BatchSpanProcessor struct {
+	exporter  SpanExporter
+	queue     chan *SpanData
+	batchSize int
+	interval  time.Duration
+	flushCh   chan chan struct{}
+	stopCh    chan struct{}
+	doneCh    chan struct{}
+	startOnce sync.Once
+	stopOnce  sync.Once
+
+	totalDropped int64
+}
+
+func NewBatchSpanProcessor(exporter SpanExporter) *BatchSpanProcessor {
+	return &BatchSpanProcessor{
+		exporter:  exporter,
+		queue:     make(chan *SpanData, DefaultSpanQueueSize),
+		batchSize: DefaultSpanBatchSize,
+		interval:  DefaultSpanExportInterval,
+		flushCh:   make(chan chan struct{}),
+		stopCh:    make(chan struct{}),
+		doneCh:    make(chan struct{}),
+	}
+}
+
+func (p *BatchSpanProcessor) WithQueueSize(queueSize int) *BatchSpanProcessor {
+	p.queue = make(chan *SpanData, queueSize)
+	return p
+}
+
+func (p *BatchSpanProcessor) WithBatchSize(batchSize int) *BatchSpanProcessor {
+	p.batchSize = batchSize
+	return p
+}
+
+func (p *BatchSpanProcessor) WithInterval(interval time.Duration) *BatchSpanProcessor {
+	p.interval = interval
+	return p
+}
+
+// OnEnd enqueues the ended span
+func (p *BatchSpanProcessor) OnEnd(span *SpanData) {
+	p.startOnce.Do(func() { go p.run() })
+	select {
+	case <-p.stopCh:
+		atomic.AddInt64(&p.totalDropped, 1)
+		return
+	default:
+	}
+	select {
+	case p.queue <- span:
+	default:
+		if atomic.AddInt64(&p.totalDropped, 1)%100 == 1 {
+			logger.Warnf("span queue is full, dropped %d spans", atomic.LoadInt64(&p.totalDropped))
+		}
+	}
+}
+
+// ForceFlush exports the queued spans and waits until done
+func (p *BatchSpanProcessor) ForceFlush() {
+	p.startOnce.Do(func() { go p.run() })
+	done := make(chan struct{})
+	select {
+	case p.flushCh <- done:
+		<-done
+	case <-p.doneCh:
+	}
+}
+
+// Shutdown exports the queued spans and shuts down the exporter
+func (p *BatchSpanProcessor) Shutdown() error {
+	p.startOnce.Do(func() { go p.run() })
+	p.stopOnce.Do(func() { close(p.stopCh) })
+	<-p.doneCh
+	return p.exporter.Shutdown()
+}
+
+func (p *BatchSpanProcessor) run() {
+	defer close(p.doneCh)
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	batch := make([]*SpanData, 0, p.batchSize)
+	export := func() {
+		for len(batch) > 0 {
+			n := min(len(batch), p.batchSize)
+			if err := p.exporter.ExportSpans(batch[:n]); err != nil {
+				logger.Warn("export spans error", "spans", n, "error", err)
+			}
+			batch = batch[n:]
+		}
+		batch = make([]*SpanData, 0, p.batchSize)
+	}
+	drain := func() {
+		for {
+			select {
+			case span := <-p.queue:
+				batch = append(batch, span)
+			default:
+				return
+			}
+		}
+	}
+
+	for {
+		select {
+		case span := <-p.queue:
+			batch = append(batch, span)
+			if len(batch) >= p.batchSize {
+				export()
+			}
+		case <-ticker.C:
+			export()
+		case done := <-p.flushCh:
+			drain()
+			export()
+			close(done)
+		case <-p.stopCh:
+			drain()
+			export()
+			return
+		}
+	}
+}
+
+// GetStats 获取统计信息
+func (p *BatchSpanProcessor) GetStats() map[string]any {
+	return map[string]any{
+		"queued_spans":  len(p.queue),
+		"dropped_spans": atomic.LoadInt64(&p.totalDropped),
+	}
+}
@@ -0,0 +1,131 @@
+package tracing
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// SpanContext 跨处理器传递的 span 标识(W3C/OTLP 格式的十六进制 trace id 和 span id)
+type SpanContext struct {
+	TraceID string `json:"trace_id"`
+	SpanID  string `json:"span_id"`
+}
+
+// IsValid reports whether the span context is set
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != "" && sc.SpanID != ""
+}
+
+func (sc SpanContext) String() string {
+	return fmt.Sprintf("%s/%s", sc.TraceID, sc.SpanID)
+}
+
+// SpanStatus span 状态, 与 OTLP status code 一致
+type SpanStatus int
+
+const (
+	SpanStatusUnset SpanStatus = iota
+	SpanStatusOK
+	SpanStatusError
+)
+
+func (s SpanStatus) String() string {
+	switch s {
+	case SpanStatusOK:
+		return "OK"
+	case SpanStatusError:
+		return "ERROR"
+	default:
+		return "UNSET"
+	}
+}
+
+// SpanData 结束的 span, 导出器导出的数据
+type SpanData struct {
+	Name          string         `json:"name"`
+	TraceID       string         `json:"trace_id"`
+	SpanID        string         `json:"span_id"`
+	ParentSpanID  string         `json:"parent_span_id,omitempty"`
+	StartTime     time.Time      `json:"start_time"`
+	EndTime       time.Time      `json:"end_time"`
+	DurationMS    float64        `json:"duration_ms"`
+	Attributes    map[string]any `json:"attributes,omitempty"`
+	Status        SpanStatus     `json:"status"`
+	StatusMessage string         `json:"status_message,omitempty"`
+}
+
+// Span 一段处理(会话, 轮次, ASR, LLM 请求, 工具调用, TTS 合成), nil span 的方法为空操作(未开启追踪)
+type Span struct {
+	mu     sync.Mutex
+	tracer *Tracer
+	data   SpanData
+	ended  bool
+}
+
+// Context returns the span context to propagate to the child spans
+func (s *Span) Context() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return SpanContext{TraceID: s.data.TraceID, SpanID: s.data.SpanID}
+}
+
+// SetAttribute sets the attribute of the span
+func (s *Span) SetAttribute(key string, value any) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.data.Attributes == nil {
+		s.data.Attributes = make(map[string]any)
+	}
+	s.data.Attributes[key] = value
+}
+
+// RecordError sets the span status to error, nil error is ignored
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Status = SpanStatusError
+	s.data.StatusMessage = err.Error()
+}
+
+// End ends the span and exports it, only the first call takes effect
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.EndTime = time.Now()
+	s.data.DurationMS = float64(s.data.EndTime.Sub(s.data.StartTime).Microseconds()) / 1000
+	data := s.data
+	s.mu.Unlock()
+
+	s.tracer.export(&data)
+}
+
+func newTraceID() string {
+	return randomHex(16)
+}
+
+func newSpanID() string {
+	return randomHex(8)
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
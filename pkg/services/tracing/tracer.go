@@ -0,0 +1,198 @@
+package tracing
+
+import (
+	"sync"
+	"time"
+
+	pipelineframes "github.com/weedge/pipeline-go/pkg/frames"
+
+	achatbot_frames "achatbot/pkg/types/frames"
+)
+
+// FrameMetadataKey 帧元数据中 trace context 的键
+const FrameMetadataKey = "trace_context"
+
+// span 名称
+const (
+	SpanSession    = "session"
+	SpanTurn       = "turn"
+	SpanASR        = "asr.transcribe"
+	SpanLLMChat    = "llm.chat"
+	SpanLLMRequest = "llm.request"
+	SpanLLMStream  = "llm.stream"
+	SpanTool       = "tool.execute"
+	SpanTTS        = "tts.synthesize"
+)
+
+// Tracer 会话追踪: 一个会话一个 trace(根 span 为 session), 每轮对话一个 turn span,
+// 处理器的 ASR, LLM 请求, 工具调用, TTS 合成为 turn 的子 span;
+// 父 span 从输入帧元数据读取(InjectFrame 写入), 没有时使用当前 turn; nil tracer 的方法为空操作(未开启追踪)
+type Tracer struct {
+	sessionID   string
+	processor   *BatchSpanProcessor
+	sessionSpan *Span
+
+	mu     sync.Mutex
+	turn   *Span
+	turnID int
+	// the bot started to respond (llm chat) in the current turn
+	responded bool
+}
+
+// NewSessionTracer starts the session trace, spans are exported by the processor (shared by sessions)
+func NewSessionTracer(sessionID string, processor *BatchSpanProcessor) *Tracer {
+	t := &Tracer{sessionID: sessionID, processor: processor}
+	t.sessionSpan = t.newSpan(SpanSession, SpanContext{TraceID: newTraceID()}, "")
+	t.sessionSpan.SetAttribute("session.id", sessionID)
+	return t
+}
+
+// TraceID returns the trace id of the session
+func (t *Tracer) TraceID() string {
+	if t == nil {
+		return ""
+	}
+	return t.sessionSpan.data.TraceID
+}
+
+// StartSpan starts a child span of the parent, invalid parent uses the current turn (or session) span
+func (t *Tracer) StartSpan(name string, parent SpanContext) *Span {
+	if t == nil {
+		return nil
+	}
+	if !parent.IsValid() {
+		parent = t.CurrentContext()
+	}
+	if name == SpanLLMChat {
+		t.mu.Lock()
+		t.responded = true
+		t.mu.Unlock()
+	}
+	span := t.newSpan(name, parent, parent.SpanID)
+	span.SetAttribute("session.id", t.sessionID)
+	return span
+}
+
+// StartTurn ends the previous turn span and starts a new one
+func (t *Tracer) StartTurn() *Span {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	previous, turn := t.turn, t.startTurn()
+	t.mu.Unlock()
+
+	previous.End()
+	return turn
+}
+
+// StartUserTurn starts a new turn when the user starts speaking, unless the bot has not responded
+// in the current turn (the user paused and continues speaking the same turn)
+func (t *Tracer) StartUserTurn() *Span {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	if t.turn != nil && !t.responded {
+		turn := t.turn
+		t.mu.Unlock()
+		return turn
+	}
+	previous, turn := t.turn, t.startTurn()
+	t.mu.Unlock()
+
+	previous.End()
+	return turn
+}
+
+func (t *Tracer) startTurn() *Span {
+	t.turnID++
+	t.responded = false
+	t.turn = t.newSpan(SpanTurn, t.sessionSpan.Context(), t.sessionSpan.data.SpanID)
+	t.turn.SetAttribute("session.id", t.sessionID)
+	t.turn.SetAttribute("turn.id", t.turnID)
+	return t.turn
+}
+
+// EndTurn ends the current turn span
+func (t *Tracer) EndTurn() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	turn := t.turn
+	t.turn = nil
+	t.mu.Unlock()
+	turn.End()
+}
+
+// CurrentContext returns the current turn span context, the session span context if no turn
+func (t *Tracer) CurrentContext() SpanContext {
+	if t == nil {
+		return SpanContext{}
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.turn != nil {
+		return t.turn.Context()
+	}
+	return t.sessionSpan.Context()
+}
+
+// ParentContext returns the span context propagated by the frame metadata, otherwise the current context
+func (t *Tracer) ParentContext(frame pipelineframes.Frame) SpanContext {
+	if t == nil {
+		return SpanContext{}
+	}
+	if sc, ok := ExtractFrame(frame); ok && sc.TraceID == t.TraceID() {
+		return sc
+	}
+	return t.CurrentContext()
+}
+
+// End ends the turn and session spans
+func (t *Tracer) End() {
+	if t == nil {
+		return
+	}
+	t.EndTurn()
+	t.sessionSpan.End()
+}
+
+func (t *Tracer) newSpan(name string, sc SpanContext, parentSpanID string) *Span {
+	return &Span{
+		tracer: t,
+		data: SpanData{
+			Name:         name,
+			TraceID:      sc.TraceID,
+			SpanID:       newSpanID(),
+			ParentSpanID: parentSpanID,
+			StartTime:    time.Now(),
+		},
+	}
+}
+
+func (t *Tracer) export(data *SpanData) {
+	if t == nil || t.processor == nil {
+		return
+	}
+	t.processor.OnEnd(data)
+}
+
+// InjectFrame propagates the span context to the downstream processors by the frame metadata
+func InjectFrame(frame pipelineframes.Frame, sc SpanContext) {
+	if !sc.IsValid() {
+		return
+	}
+	achatbot_frames.SetFrameMetadata(frame, FrameMetadataKey, sc)
+}
+
+// ExtractFrame returns the span context propagated by the frame metadata
+func ExtractFrame(frame pipelineframes.Frame) (SpanContext, bool) {
+	value, ok := achatbot_frames.GetFrameMetadata(frame, FrameMetadataKey)
+	if !ok {
+		return SpanContext{}, false
+	}
+	sc, ok := value.(SpanContext)
+	return sc, ok && sc.IsValid()
+}
@@ -0,0 +1,162 @@
+package tracing
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weedge/pipeline-go/pkg/frames"
+)
+
+type memoryExporter struct {
+	mu    sync.Mutex
+	spans []*SpanData
+}
+
+func (e *memoryExporter) ExportSpans(spans []*SpanData) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+func (e *memoryExporter) Shutdown() error { return nil }
+
+func (e *memoryExporter) byName(name string) []*SpanData {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var spans []*SpanData
+	for _, span := range e.spans {
+		if span.Name == name {
+			spans = append(spans, span)
+		}
+	}
+	return spans
+}
+
+func TestTracerSpans(t *testing.T) {
+	exporter := &memoryExporter{}
+	processor := NewBatchSpanProcessor(exporter)
+	tracer := NewSessionTracer("session-1", processor)
+
+	turn := tracer.StartUserTurn()
+	assert.Equal(t, turn.Context(), tracer.CurrentContext())
+	// the user continues speaking before the bot responds, same turn
+	assert.Equal(t, turn, tracer.StartUserTurn())
+
+	// context propagated by the frame metadata
+	frame := frames.NewTextFrame("hello")
+	InjectFrame(frame, turn.Context())
+	assert.Equal(t, turn.Context(), tracer.ParentContext(frame))
+
+	asrSpan := tracer.StartSpan(SpanASR, tracer.ParentContext(frame))
+	asrSpan.End()
+	chatSpan := tracer.StartSpan(SpanLLMChat, SpanContext{})
+	toolSpan := tracer.StartSpan(SpanTool, chatSpan.Context())
+	toolSpan.RecordError(errors.New("tool failed"))
+	toolSpan.End()
+	chatSpan.End()
+
+	// the bot responded, a new turn starts
+	nextTurn := tracer.StartUserTurn()
+	assert.NotEqual(t, turn.Context(), nextTurn.Context())
+	tracer.End()
+	assert.NoError(t, processor.Shutdown())
+
+	sessions := exporter.byName(SpanSession)
+	turns := exporter.byName(SpanTurn)
+	assert.Len(t, sessions, 1)
+	assert.Len(t, turns, 2)
+	for _, span := range exporter.spans {
+		assert.Equal(t, tracer.TraceID(), span.TraceID)
+	}
+	assert.Equal(t, sessions[0].SpanID, turns[0].ParentSpanID)
+	assert.Equal(t, turn.Context().SpanID, exporter.byName(SpanASR)[0].ParentSpanID)
+	assert.Equal(t, turn.Context().SpanID, exporter.byName(SpanLLMChat)[0].ParentSpanID)
+	tool := exporter.byName(SpanTool)[0]
+	assert.Equal(t, chatSpan.Context().SpanID, tool.ParentSpanID)
+	assert.Equal(t, SpanStatusError, tool.Status)
+	assert.Equal(t, "tool failed", tool.StatusMessage)
+}
+
+func TestTracerNil(t *testing.T) {
+	var tracer *Tracer
+	span := tracer.StartSpan(SpanTTS, tracer.ParentContext(frames.NewTextFrame("hi")))
+	span.SetAttribute("tts.text", "hi")
+	span.End()
+	tracer.StartUserTurn()
+	tracer.End()
+	assert.False(t, tracer.CurrentContext().IsValid())
+}
+
+func TestJSONFileExporter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces.jsonl")
+	exporter, err := NewJSONFileExporter(path)
+	assert.NoError(t, err)
+	processor := NewBatchSpanProcessor(exporter)
+	tracer := NewSessionTracer("session-1", processor)
+	span := tracer.StartSpan(SpanTTS, SpanContext{})
+	span.SetAttribute("tts.text", "hello")
+	span.End()
+	processor.ForceFlush()
+
+	file, err := os.Open(path)
+	assert.NoError(t, err)
+	defer file.Close()
+	var spans []SpanData
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var span SpanData
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &span))
+		spans = append(spans, span)
+	}
+	assert.Len(t, spans, 1)
+	assert.Equal(t, SpanTTS, spans[0].Name)
+	assert.Equal(t, "hello", spans[0].Attributes["tts.text"])
+	assert.NoError(t, processor.Shutdown())
+}
+
+func TestOTLPHTTPExporter(t *testing.T) {
+	var body map[string]any
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		data, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(data, &body)
+	}))
+	defer server.Close()
+
+	exporter := NewOTLPHTTPExporter(server.URL+"/v1/traces", "achatbot").WithHeader("Authorization", "Bearer token")
+	tracer := NewSessionTracer("session-1", nil)
+	span := tracer.StartSpan(SpanASR, SpanContext{})
+	span.SetAttribute("asr.audio_bytes", 3200)
+	span.End()
+	data := span.data
+	assert.NoError(t, exporter.ExportSpans([]*SpanData{&data}))
+
+	assert.Equal(t, "Bearer token", auth)
+	resourceSpans := body["resourceSpans"].([]any)[0].(map[string]any)
+	resource := resourceSpans["resource"].(map[string]any)["attributes"].([]any)[0].(map[string]any)
+	assert.Equal(t, "service.name", resource["key"])
+	otlpSpan := resourceSpans["scopeSpans"].([]any)[0].(map[string]any)["spans"].([]any)[0].(map[string]any)
+	assert.Equal(t, SpanASR, otlpSpan["name"])
+	assert.Equal(t, tracer.TraceID(), otlpSpan["traceId"])
+	assert.Equal(t, tracer.sessionSpan.data.SpanID, otlpSpan["parentSpanId"])
+	attribute := otlpSpan["attributes"].([]any)
+	assert.Contains(t, attribute, map[string]any{
+		"key": "asr.audio_bytes", "value": map[string]any{"intValue": "3200"},
+	})
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad request", http.StatusBadRequest)
+	})
+	assert.Error(t, exporter.ExportSpans([]*SpanData{&data}))
+}
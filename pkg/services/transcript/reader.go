@@ -0,0 +1,141 @@
+package transcript
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxEntryBytes 单行记录的最大字节数(工具结果可能较长)
+const maxEntryBytes = 4 * 1024 * 1024
+
+// Session 从 JSONL 文件加载的会话记录, 供回看/标注等工具使用
+type Session struct {
+	SessionID string
+	Path      string
+	StartTime time.Time
+	EndTime   time.Time
+	// user, bot, think and tool entries in recording order, interruptions are applied to the bot replies
+	Entries []*Entry
+}
+
+// Turn user speech and the bot reply of a turn
+type Turn struct {
+	ID      int
+	Entries []*Entry
+}
+
+// UserText returns the user transcriptions of the turn
+func (t *Turn) UserText() string {
+	return t.joinText(EntryUser)
+}
+
+// BotText returns the bot reply of the turn, the spoken text if interrupted
+func (t *Turn) BotText() string {
+	return t.joinText(EntryBot)
+}
+
+func (t *Turn) joinText(entryType EntryType) string {
+	texts := make([]string, 0, len(t.Entries))
+	for _, entry := range t.Entries {
+		if entry.Type != entryType {
+			continue
+		}
+		if entry.Interrupted {
+			texts = append(texts, entry.SpokenText)
+		} else {
+			texts = append(texts, entry.Text)
+		}
+	}
+	return strings.Join(texts, " ")
+}
+
+// Turns groups the entries by turn
+func (s *Session) Turns() []*Turn {
+	turns := make([]*Turn, 0)
+	for _, entry := range s.Entries {
+		if len(turns) == 0 || turns[len(turns)-1].ID != entry.Turn {
+			turns = append(turns, &Turn{ID: entry.Turn})
+		}
+		turns[len(turns)-1].Entries = append(turns[len(turns)-1].Entries, entry)
+	}
+	return turns
+}
+
+// Load loads the session transcript file
+func Load(path string) (*Session, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	session := &Session{Path: path}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxEntryBytes)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		entry := &Entry{}
+		if err := json.Unmarshal(scanner.Bytes(), entry); err != nil {
+			return nil, fmt.Errorf("failed to decode %s line %d: %w", path, line, err)
+		}
+		if session.SessionID == "" {
+			session.SessionID = entry.SessionID
+		}
+		if session.StartTime.IsZero() {
+			session.StartTime = entry.Time
+		}
+		session.EndTime = entry.Time
+
+		switch entry.Type {
+		case EntrySessionStart, EntrySessionEnd:
+		case EntryInterruption:
+			session.applyInterruption(entry)
+		default:
+			session.Entries = append(session.Entries, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return session, nil
+}
+
+// applyInterruption marks the last bot reply of the turn interrupted
+func (s *Session) applyInterruption(interruption *Entry) {
+	for i := len(s.Entries) - 1; i >= 0; i-- {
+		entry := s.Entries[i]
+		if entry.Turn != interruption.Turn {
+			return
+		}
+		if entry.Type == EntryBot {
+			entry.Interrupted = true
+			entry.SpokenText = interruption.SpokenText
+			return
+		}
+	}
+}
+
+// LoadSession loads the transcript of the session recorded in the dir
+func LoadSession(dir, sessionID string) (*Session, error) {
+	return Load(filepath.Join(dir, SessionFileName(sessionID)))
+}
+
+// ListSessionFiles returns the transcript files in the dir, sorted by name
+func ListSessionFiles(dir string) ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
@@ -0,0 +1,280 @@
+package transcript
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/weedge/pipeline-go/pkg/logger"
+)
+
+// EntryType 对话记录的类型
+type EntryType string
+
+const (
+	EntrySessionStart EntryType = "session_start"
+	EntrySessionEnd   EntryType = "session_end"
+	EntryUser         EntryType = "user"
+	EntryBot          EntryType = "bot"
+	EntryThink        EntryType = "think"
+	EntryToolCall     EntryType = "tool_call"
+	EntryToolResult   EntryType = "tool_result"
+	// the bot reply of the turn is interrupted after it is recorded
+	EntryInterruption EntryType = "interruption"
+)
+
+// Entry 会话 JSONL 记录的一行
+type Entry struct {
+	Type      EntryType `json:"type"`
+	SessionID string    `json:"session_id"`
+	// turn number of the session, a turn is the user speech and the bot reply
+	Turn int `json:"turn"`
+	// when the entry is recorded
+	Time time.Time `json:"time"`
+	// when the user starts speaking or the bot starts replying
+	StartTime  time.Time `json:"start_time"`
+	DurationMS float64   `json:"duration_ms,omitempty"`
+
+	Text      string `json:"text,omitempty"`
+	Language  string `json:"language,omitempty"`
+	Emotion   string `json:"emotion,omitempty"`
+	SpeakerID string `json:"speaker_id,omitempty"`
+	SpeechID  int    `json:"speech_id,omitempty"`
+
+	// the bot reply is interrupted by the user, only the spoken text was heard
+	Interrupted bool   `json:"interrupted,omitempty"`
+	SpokenText  string `json:"spoken_text,omitempty"`
+
+	ToolCallID string         `json:"tool_call_id,omitempty"`
+	ToolName   string         `json:"tool_name,omitempty"`
+	Arguments  map[string]any `json:"arguments,omitempty"`
+	Result     string         `json:"result,omitempty"`
+	Error      string         `json:"error,omitempty"`
+
+	// audio files saved by AudioSaveProcessor
+	AudioPaths []string `json:"audio_paths,omitempty"`
+}
+
+var unsafeFileNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// SessionFileName returns the transcript file name of the session
+func SessionFileName(sessionID string) string {
+	return unsafeFileNameChars.ReplaceAllString(sessionID, "_") + ".jsonl"
+}
+
+// Recorder 记录会话的用户转写, 机器人回复(含打断), 思考文本, 工具调用和结果, 以 JSON Lines 追加写入
+// <dir>/<session_id>.jsonl; 机器人回复按 turn 聚合, 在 TurnEndFrame / 打断 / 关闭时写入
+type Recorder struct {
+	sessionID string
+	path      string
+
+	mu      sync.Mutex
+	file    *os.File
+	encoder *json.Encoder
+	closed  bool
+	turn    int
+	// the bot replied in the current turn
+	responded bool
+	userStart time.Time
+	// pending bot reply and think text of the current turn
+	bot   *Entry
+	think *Entry
+	now   func() time.Time
+}
+
+func NewRecorder(dir, sessionID string) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create transcript dir: %w", err)
+	}
+	path := filepath.Join(dir, SessionFileName(sessionID))
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open transcript file %s: %w", path, err)
+	}
+	r := &Recorder{
+		sessionID: sessionID,
+		path:      path,
+		file:      file,
+		encoder:   json.NewEncoder(file),
+		now:       time.Now,
+	}
+	now := r.now()
+	r.write(&Entry{Type: EntrySessionStart, Time: now, StartTime: now})
+	return r, nil
+}
+
+// Path returns the transcript file path
+func (r *Recorder) Path() string {
+	return r.path
+}
+
+// UserStartedSpeaking marks the start time of the user speech
+func (r *Recorder) UserStartedSpeaking() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.userStart.IsZero() {
+		r.userStart = r.now()
+	}
+}
+
+// RecordUser records the user transcription, Text, Language, Emotion, SpeakerID, SpeechID and AudioPaths
+// are set by the caller; a new turn starts if the bot replied in the current turn
+func (r *Recorder) RecordUser(entry *Entry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.turn == 0 || r.responded {
+		r.flushBot()
+		r.turn++
+		r.responded = false
+	}
+	entry.Type = EntryUser
+	entry.Turn = r.turn
+	entry.Time = r.now()
+	entry.StartTime = entry.Time
+	if !r.userStart.IsZero() {
+		entry.StartTime = r.userStart
+		entry.DurationMS = durationMS(entry.StartTime, entry.Time)
+	}
+	r.userStart = time.Time{}
+	r.write(entry)
+}
+
+// AppendBotText appends the text to the bot reply of the current turn
+func (r *Recorder) AppendBotText(text string) {
+	if text == "" {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pendingBot().Text += text
+}
+
+// AppendBotAudio links the saved audio file of the bot reply
+func (r *Recorder) AppendBotAudio(path string) {
+	if path == "" {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	bot := r.pendingBot()
+	bot.AudioPaths = append(bot.AudioPaths, path)
+}
+
+// AppendThink appends the think text of the current turn
+func (r *Recorder) AppendThink(text string) {
+	if text == "" {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.responded = true
+	if r.think == nil {
+		now := r.now()
+		r.think = &Entry{Type: EntryThink, Turn: r.turn, StartTime: now}
+	}
+	r.think.Text += text
+}
+
+// RecordToolCall records the tool call of the llm
+func (r *Recorder) RecordToolCall(toolCallID, toolName string, arguments map[string]any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.responded = true
+	now := r.now()
+	r.write(&Entry{
+		Type: EntryToolCall, Turn: r.turn, Time: now, StartTime: now,
+		ToolCallID: toolCallID, ToolName: toolName, Arguments: arguments,
+	})
+}
+
+// RecordToolResult records the tool result, errMsg is set if the tool failed
+func (r *Recorder) RecordToolResult(toolCallID, toolName, result, errMsg string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.responded = true
+	now := r.now()
+	r.write(&Entry{
+		Type: EntryToolResult, Turn: r.turn, Time: now, StartTime: now,
+		ToolCallID: toolCallID, ToolName: toolName, Result: result, Error: errMsg,
+	})
+}
+
+// EndBotReply writes the think text and the bot reply of the current turn
+func (r *Recorder) EndBotReply() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.flushBot()
+}
+
+// RecordInterruption marks the bot reply interrupted with the spoken text heard by the user,
+// the reply already written is marked by an interruption entry (applied by Load)
+func (r *Recorder) RecordInterruption(spokenText string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.bot != nil {
+		r.bot.Interrupted = true
+		r.bot.SpokenText = spokenText
+		r.flushBot()
+		return
+	}
+	now := r.now()
+	r.write(&Entry{Type: EntryInterruption, Turn: r.turn, Time: now, StartTime: now, SpokenText: spokenText})
+}
+
+// Close writes the pending bot reply and the session end, then closes the file
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return nil
+	}
+	r.flushBot()
+	now := r.now()
+	r.write(&Entry{Type: EntrySessionEnd, Turn: r.turn, Time: now, StartTime: now})
+	r.closed = true
+	return r.file.Close()
+}
+
+func (r *Recorder) pendingBot() *Entry {
+	r.responded = true
+	if r.bot == nil {
+		r.bot = &Entry{Type: EntryBot, Turn: r.turn, StartTime: r.now()}
+	}
+	return r.bot
+}
+
+func (r *Recorder) flushBot() {
+	if r.think == nil && r.bot == nil {
+		return
+	}
+	now := r.now()
+	for _, entry := range []*Entry{r.think, r.bot} {
+		if entry == nil {
+			continue
+		}
+		entry.Text = strings.TrimSpace(entry.Text)
+		entry.Time = now
+		entry.DurationMS = durationMS(entry.StartTime, now)
+		r.write(entry)
+	}
+	r.think, r.bot = nil, nil
+}
+
+func (r *Recorder) write(entry *Entry) {
+	if r.closed {
+		return
+	}
+	entry.SessionID = r.sessionID
+	if err := r.encoder.Encode(entry); err != nil {
+		logger.Warn("write transcript entry error", "session_id", r.sessionID, "type", entry.Type, "error", err)
+	}
+}
+
+func durationMS(start, end time.Time) float64 {
+	return float64(end.Sub(start).Microseconds()) / 1000
+}
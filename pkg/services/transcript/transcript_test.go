@@ -0,0 +1,72 @@
+package transcript
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecorderLoad(t *testing.T) {
+	dir := t.TempDir()
+	recorder, err := NewRecorder(dir, "tcp_127.0.0.1:5000")
+	assert.NoError(t, err)
+	now := time.Unix(1700000000, 0)
+	recorder.now = func() time.Time {
+		now = now.Add(100 * time.Millisecond)
+		return now
+	}
+
+	// turn 1: tool call, think and reply
+	recorder.UserStartedSpeaking()
+	recorder.RecordUser(&Entry{Text: "北京天气怎么样", Language: "zh", AudioPaths: []string{"user_speak.wav"}})
+	recorder.RecordToolCall("call_1", "weather", map[string]any{"city": "北京"})
+	recorder.RecordToolResult("call_1", "weather", "晴", "")
+	recorder.AppendThink("查询天气")
+	recorder.AppendBotText("北京今天晴。")
+	recorder.AppendBotAudio("bot_speak.wav")
+	recorder.EndBotReply()
+	// interrupted after the reply is written
+	recorder.RecordInterruption("北京")
+
+	// turn 2: interrupted while replying, the pending reply is written on close
+	recorder.RecordUser(&Entry{Text: "明天呢"})
+	recorder.AppendBotText("明天")
+	recorder.RecordInterruption("")
+	recorder.RecordUser(&Entry{Text: "算了"})
+	recorder.AppendBotText("好的")
+	assert.NoError(t, recorder.Close())
+	assert.NoError(t, recorder.Close())
+
+	paths, err := ListSessionFiles(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{recorder.Path()}, paths)
+
+	session, err := LoadSession(dir, "tcp_127.0.0.1:5000")
+	assert.NoError(t, err)
+	assert.Equal(t, "tcp_127.0.0.1:5000", session.SessionID)
+	assert.False(t, session.StartTime.IsZero())
+
+	turns := session.Turns()
+	assert.Len(t, turns, 3)
+	types := make([]EntryType, 0)
+	for _, entry := range turns[0].Entries {
+		types = append(types, entry.Type)
+	}
+	assert.Equal(t, []EntryType{EntryUser, EntryToolCall, EntryToolResult, EntryThink, EntryBot}, types)
+
+	user := turns[0].Entries[0]
+	assert.Equal(t, []string{"user_speak.wav"}, user.AudioPaths)
+	assert.Equal(t, 100.0, user.DurationMS)
+	assert.Equal(t, map[string]any{"city": "北京"}, turns[0].Entries[1].Arguments)
+	bot := turns[0].Entries[4]
+	assert.Equal(t, "北京今天晴。", bot.Text)
+	assert.Equal(t, []string{"bot_speak.wav"}, bot.AudioPaths)
+	assert.True(t, bot.Interrupted)
+	assert.Equal(t, "北京", turns[0].BotText())
+
+	assert.Equal(t, "明天呢", turns[1].UserText())
+	assert.True(t, turns[1].Entries[1].Interrupted)
+	assert.Equal(t, "", turns[1].BotText())
+	assert.Equal(t, "好的", turns[2].BotText())
+}
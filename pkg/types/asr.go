@@ -0,0 +1,34 @@
+package types
+
+import "fmt"
+
+// SenseVoice 等模型识别的情绪和音频事件(小写, 去掉 <| |> 标记)
+const (
+	EmotionNeutral = "neutral"
+	EventSpeech    = "speech"
+)
+
+// WordTimestamp 词级时间戳(相对语音段开始, 单位秒)
+type WordTimestamp struct {
+	Word  string  `json:"word"`
+	Start float32 `json:"start"`
+	End   float32 `json:"end"`
+}
+
+// ASRResult 语音识别结果, 除 Text 外的字段取决于模型是否支持
+type ASRResult struct {
+	Text string `json:"text"`
+	// token 及其开始时间(秒)
+	Tokens     []string  `json:"tokens"`
+	Timestamps []float32 `json:"timestamps"`
+	// 由 token 合并的词及其时间
+	Words    []WordTimestamp `json:"words"`
+	Language string          `json:"language"`
+	Emotion  string          `json:"emotion"`
+	Event    string          `json:"event"`
+}
+
+func (r *ASRResult) String() string {
+	return fmt.Sprintf("ASRResult(text: %s words: %d language: %s emotion: %s event: %s)",
+		r.Text, len(r.Words), r.Language, r.Emotion, r.Event)
+}
@@ -0,0 +1,57 @@
+package types
+
+import "fmt"
+
+// AudioFormat PCM 音频格式: 采样率, 通道数, 采样宽度(字节)
+type AudioFormat struct {
+	SampleRate  int `json:"sample_rate"`
+	NumChannels int `json:"num_channels"`
+	SampleWidth int `json:"sample_width"`
+}
+
+// NewAudioFormat creates a new AudioFormat
+func NewAudioFormat(sampleRate, numChannels, sampleWidth int) *AudioFormat {
+	return &AudioFormat{
+		SampleRate:  sampleRate,
+		NumChannels: numChannels,
+		SampleWidth: sampleWidth,
+	}
+}
+
+// Equal 判断两个音频格式是否一致
+func (f *AudioFormat) Equal(other *AudioFormat) bool {
+	if f == nil || other == nil {
+		return f == other
+	}
+	return f.SampleRate == other.SampleRate &&
+		f.NumChannels == other.NumChannels &&
+		f.SampleWidth == other.SampleWidth
+}
+
+// Validate 校验音频格式是否合法(支持 8/16/32 bit PCM)
+func (f *AudioFormat) Validate() error {
+	if f.SampleRate <= 0 {
+		return fmt.Errorf("sample_rate must be positive, got %d", f.SampleRate)
+	}
+	if f.NumChannels <= 0 {
+		return fmt.Errorf("num_channels must be positive, got %d", f.NumChannels)
+	}
+	switch f.SampleWidth {
+	case 1, 2, 4:
+	default:
+		return fmt.Errorf("sample_width must be 1, 2 or 4, got %d", f.SampleWidth)
+	}
+	return nil
+}
+
+// BytesPerSecond 每秒音频字节数
+func (f *AudioFormat) BytesPerSecond() int {
+	return f.SampleRate * f.NumChannels * f.SampleWidth
+}
+
+func (f *AudioFormat) String() string {
+	if f == nil {
+		return "AudioFormat{any}"
+	}
+	return fmt.Sprintf("AudioFormat{SampleRate: %d, NumChannels: %d, SampleWidth: %d}", f.SampleRate, f.NumChannels, f.SampleWidth)
+}
@@ -0,0 +1,30 @@
+package types
+
+// EchoStrategy 表示没有客户端 AEC 时处理机器人自身回声(TTS 经扬声器回到麦克风)的策略
+type EchoStrategy int
+
+const (
+	// EchoStrategyNone 不做回声处理
+	EchoStrategyNone EchoStrategy = iota
+	// EchoStrategyHalfDuplex 半双工: 机器人说话期间丢弃麦克风音频
+	EchoStrategyHalfDuplex
+	// EchoStrategyGated 门控: 机器人说话期间需持续足够长且能量高于参考信号的语音才触发打断
+	EchoStrategyGated
+	// EchoStrategyFullDuplex 全双工: 使用机器人输出音频作为参考信号做回声消除后再做 VAD
+	EchoStrategyFullDuplex
+)
+
+func (s EchoStrategy) String() string {
+	switch s {
+	case EchoStrategyNone:
+		return "NONE"
+	case EchoStrategyHalfDuplex:
+		return "HALF_DUPLEX"
+	case EchoStrategyGated:
+		return "GATED"
+	case EchoStrategyFullDuplex:
+		return "FULL_DUPLEX"
+	default:
+		return "UNKNOWN"
+	}
+}
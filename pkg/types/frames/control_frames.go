@@ -1,6 +1,10 @@
 package frames
 
-import pipelineframes "github.com/weedge/pipeline-go/pkg/frames"
+import (
+	"fmt"
+
+	pipelineframes "github.com/weedge/pipeline-go/pkg/frames"
+)
 
 // Emitted by VAD to indicate that a user has started speaking. This can be
 // used for interruptions or other times when detecting that someone is
@@ -113,3 +117,103 @@ func NewTTSStoppedFrame() *TTSStoppedFrame {
 		},
 	}
 }
+
+// UserEndOfTurnFrame is emitted by the turn analyzer to indicate that the user finished the turn,
+// the turn transcript is pushed as TextFrame before it
+type UserEndOfTurnFrame struct {
+	*pipelineframes.ControlFrame
+	Transcript  string  `json:"transcript"`
+	Probability float64 `json:"probability"`
+	SilenceSecs float64 `json:"silence_secs"`
+	Reason      string  `json:"reason"`
+}
+
+// NewUserEndOfTurnFrame creates a new UserEndOfTurnFrame
+func NewUserEndOfTurnFrame(transcript string, probability, silenceSecs float64, reason string) *UserEndOfTurnFrame {
+	return &UserEndOfTurnFrame{
+		ControlFrame: &pipelineframes.ControlFrame{
+			BaseFrame: pipelineframes.NewBaseFrameWithName("UserEndOfTurnFrame"),
+		},
+		Transcript:  transcript,
+		Probability: probability,
+		SilenceSecs: silenceSecs,
+		Reason:      reason,
+	}
+}
+
+// String implements string representation of UserEndOfTurnFrame
+func (f *UserEndOfTurnFrame) String() string {
+	return fmt.Sprintf("%s (transcript: %s probability: %.2f silence_secs: %.2f reason: %s)",
+		f.ControlFrame.String(), f.Transcript, f.Probability, f.SilenceSecs, f.Reason)
+}
+
+// SpeakerVerifiedFrame is emitted by the speaker verification processor before the verified user audio,
+// ASR attaches the speaker to the transcription of the following audio
+type SpeakerVerifiedFrame struct {
+	*pipelineframes.ControlFrame
+	SpeakerID string  `json:"speaker_id"`
+	Score     float32 `json:"score"`
+	Verified  bool    `json:"verified"`
+}
+
+// NewSpeakerVerifiedFrame creates a new SpeakerVerifiedFrame
+func NewSpeakerVerifiedFrame(speakerID string, score float32, verified bool) *SpeakerVerifiedFrame {
+	return &SpeakerVerifiedFrame{
+		ControlFrame: &pipelineframes.ControlFrame{
+			BaseFrame: pipelineframes.NewBaseFrameWithName("SpeakerVerifiedFrame"),
+		},
+		SpeakerID: speakerID,
+		Score:     score,
+		Verified:  verified,
+	}
+}
+
+// String implements string representation of SpeakerVerifiedFrame
+func (f *SpeakerVerifiedFrame) String() string {
+	return fmt.Sprintf("%s (speaker_id: %s score: %.3f verified: %t)",
+		f.ControlFrame.String(), f.SpeakerID, f.Score, f.Verified)
+}
+
+// LanguageDetectedFrame is emitted by the language identification processor before the user audio,
+// ASR routes the following audio to the provider of the language
+type LanguageDetectedFrame struct {
+	*pipelineframes.ControlFrame
+	Language string `json:"language"`
+}
+
+// NewLanguageDetectedFrame creates a new LanguageDetectedFrame
+func NewLanguageDetectedFrame(language string) *LanguageDetectedFrame {
+	return &LanguageDetectedFrame{
+		ControlFrame: &pipelineframes.ControlFrame{
+			BaseFrame: pipelineframes.NewBaseFrameWithName("LanguageDetectedFrame"),
+		},
+		Language: language,
+	}
+}
+
+// String implements string representation of LanguageDetectedFrame
+func (f *LanguageDetectedFrame) String() string {
+	return fmt.Sprintf("%s (language: %s)", f.ControlFrame.String(), f.Language)
+}
+
+// BotSpeechInterruptedFrame is pushed upstream by the output processor when the user interrupts the bot speech,
+// SpokenText is the text of the bot reply which was really played (heard by the user) before the interruption
+type BotSpeechInterruptedFrame struct {
+	*pipelineframes.ControlFrame
+	SpokenText string `json:"spoken_text"`
+}
+
+// NewBotSpeechInterruptedFrame creates a new BotSpeechInterruptedFrame
+func NewBotSpeechInterruptedFrame(spokenText string) *BotSpeechInterruptedFrame {
+	return &BotSpeechInterruptedFrame{
+		ControlFrame: &pipelineframes.ControlFrame{
+			BaseFrame: pipelineframes.NewBaseFrameWithName("BotSpeechInterruptedFrame"),
+		},
+		SpokenText: spokenText,
+	}
+}
+
+// String implements string representation of BotSpeechInterruptedFrame
+func (f *BotSpeechInterruptedFrame) String() string {
+	return fmt.Sprintf("%s (spoken_text: %s)", f.ControlFrame.String(), f.SpokenText)
+}
@@ -18,6 +18,8 @@ type VADStateAudioRawFrame struct {
 	StartAtS float64        `json:"start_at_s"`
 	CurAtS   float64        `json:"cur_at_s"`
 	EndAtS   float64        `json:"end_at_s"`
+	// Confidence 当前窗口的语音置信度, 进入 Speaking 的帧为触发窗口的置信度
+	Confidence float32 `json:"confidence"`
 }
 
 // NewVADStateAudioRawFrame creates a new VADStateAudioRawFrame with default values
@@ -34,7 +36,7 @@ func NewVADStateAudioRawFrame() *VADStateAudioRawFrame {
 
 // String implements string representation of VADStateAudioRawFrame
 func (f *VADStateAudioRawFrame) String() string {
-	return fmt.Sprintf("%s (state: %v speech_id: %d is_final: %t start_at_s: %.2f cur_at_s: %.2f end_at_s: %.2f)",
+	return fmt.Sprintf("%s (state: %v speech_id: %d is_final: %t start_at_s: %.2f cur_at_s: %.2f end_at_s: %.2f confidence: %.2f)",
 		f.AudioRawFrame.String(),
 		f.State,
 		f.SpeechID,
@@ -42,6 +44,7 @@ func (f *VADStateAudioRawFrame) String() string {
 		f.StartAtS,
 		f.CurAtS,
 		f.EndAtS,
+		f.Confidence,
 	)
 }
 
@@ -97,6 +100,40 @@ func NewTransportMessageFrame(message []byte) *TransportMessageFrame {
 	}
 }
 
+// TTSAudioRawFrame represents the synthesized bot audio annotated with the text it speaks
+type TTSAudioRawFrame struct {
+	*pipelineframes.AudioRawFrame
+	Text string `json:"text"`
+	// word timestamps relative to the start of the audio, words joined are the text
+	Words []types.WordTimestamp `json:"words"`
+}
+
+// NewTTSAudioRawFrame creates a new TTSAudioRawFrame
+func NewTTSAudioRawFrame(audio []byte, sampleRate, numChannels, sampleWidth int, text string, words []types.WordTimestamp) *TTSAudioRawFrame {
+	return &TTSAudioRawFrame{
+		AudioRawFrame: pipelineframes.NewAudioRawFrame(audio, sampleRate, numChannels, sampleWidth),
+		Text:          text,
+		Words:         words,
+	}
+}
+
+// String implements string representation of TTSAudioRawFrame
+func (f *TTSAudioRawFrame) String() string {
+	return fmt.Sprintf("%s text: %s words: %d", f.AudioRawFrame.String(), f.Text, len(f.Words))
+}
+
+// BotSpokenTextFrame is emitted by the output processor when the bot audio of the text is played
+type BotSpokenTextFrame struct {
+	*pipelineframes.TextFrame
+}
+
+// NewBotSpokenTextFrame creates a new BotSpokenTextFrame
+func NewBotSpokenTextFrame(text string) *BotSpokenTextFrame {
+	return &BotSpokenTextFrame{
+		TextFrame: pipelineframes.NewTextFrame(text),
+	}
+}
+
 type PathAudioRawFrame struct {
 	*pipelineframes.AudioRawFrame
 	Path string `json:"path"`
@@ -134,6 +171,36 @@ func (f *ThinkTextFrame) String() string {
 	return fmt.Sprintf("%s think_start_tag: %s think_end_tag: %s", f.TextFrame.String(), f.ThinkStartTag, f.ThinkEndTag)
 }
 
+// TranscriptionFrame represents a user speech transcription emitted by ASR,
+// with word timestamps, language/emotion/event tags of the model, speech id of VAD and the verified speaker
+type TranscriptionFrame struct {
+	*pipelineframes.TextFrame
+	// word timestamps relative to the start of the speech (the first speech of the turn for the turn transcription)
+	Words        []types.WordTimestamp `json:"words"`
+	Language     string                `json:"language"`
+	Emotion      string                `json:"emotion"`
+	Event        string                `json:"event"`
+	SpeechID     int                   `json:"speech_id"`
+	UserID       string                `json:"user_id"`
+	SpeakerID    string                `json:"speaker_id"`
+	SpeakerScore float32               `json:"speaker_score"`
+}
+
+// NewTranscriptionFrame creates a new TranscriptionFrame
+func NewTranscriptionFrame(text, speakerID string, speakerScore float32) *TranscriptionFrame {
+	return &TranscriptionFrame{
+		TextFrame:    pipelineframes.NewTextFrame(text),
+		SpeakerID:    speakerID,
+		SpeakerScore: speakerScore,
+	}
+}
+
+// String implements string representation of TranscriptionFrame
+func (f *TranscriptionFrame) String() string {
+	return fmt.Sprintf("%s words: %d language: %s emotion: %s event: %s speech_id: %d user_id: %s speaker_id: %s speaker_score: %.3f",
+		f.TextFrame.String(), len(f.Words), f.Language, f.Emotion, f.Event, f.SpeechID, f.UserID, f.SpeakerID, f.SpeakerScore)
+}
+
 // FunctionCallFrame represents a function call frame generated by LLM
 type FunctionCallFrame struct {
 	*pipelineframes.DataFrame
@@ -161,3 +228,32 @@ func (f *FunctionCallFrame) String() string {
 	return fmt.Sprintf("%s(function_name: %s, tool_call_id: %s, arguments: %+v index: %d type: %s)",
 		f.DataFrame.Name(), f.FunctionName, f.ToolCallID, f.Arguments, f.Index, f.Type)
 }
+
+// FunctionCallResultFrame represents the result of the function call executed for LLM, Error is set if failed
+type FunctionCallResultFrame struct {
+	*pipelineframes.DataFrame
+	ToolCallID   string `json:"tool_call_id"`
+	FunctionName string `json:"function_name"`
+	Result       string `json:"result"`
+	Error        string `json:"error"`
+}
+
+// NewFunctionCallResultFrame creates a new FunctionCallResultFrame
+func NewFunctionCallResultFrame(toolCallID, functionName, result string, err error) *FunctionCallResultFrame {
+	frame := &FunctionCallResultFrame{
+		DataFrame:    pipelineframes.NewDataFrameWithName("FunctionCallResultFrame"),
+		ToolCallID:   toolCallID,
+		FunctionName: functionName,
+		Result:       result,
+	}
+	if err != nil {
+		frame.Error = err.Error()
+	}
+	return frame
+}
+
+// String implements string representation of FunctionCallResultFrame
+func (f *FunctionCallResultFrame) String() string {
+	return fmt.Sprintf("%s(function_name: %s, tool_call_id: %s, result: %s error: %s)",
+		f.DataFrame.Name(), f.FunctionName, f.ToolCallID, f.Result, f.Error)
+}
@@ -0,0 +1,79 @@
+package frames
+
+import (
+	"sync"
+
+	pipelineframes "github.com/weedge/pipeline-go/pkg/frames"
+)
+
+// AudioPathMetadataKey 帧元数据中保存的音频文件路径的键(AudioSaveProcessor 写入)
+const AudioPathMetadataKey = "audio_path"
+
+// DefaultMaxFrameMetadata 最多保留元数据的帧数, 超出时丢弃最早设置的(帧一般在数秒内处理完)
+const DefaultMaxFrameMetadata = 65536
+
+// frameMetadataStore pipeline-go 的帧没有元数据字段, 按帧 ID 关联元数据(如 trace context);
+// 处理器从输入帧读取元数据, 复制到输出帧, 在处理器间传递
+type frameMetadataStore struct {
+	mu       sync.Mutex
+	metadata map[uint64]map[string]any
+	// ring of frame ids in setting order
+	order []uint64
+	next  int
+}
+
+var frameMetadata = &frameMetadataStore{
+	metadata: make(map[uint64]map[string]any),
+	order:    make([]uint64, DefaultMaxFrameMetadata),
+}
+
+// SetFrameMetadata sets the metadata value of the frame
+func SetFrameMetadata(frame pipelineframes.Frame, key string, value any) {
+	if frame == nil {
+		return
+	}
+	s := frameMetadata
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := frame.ID()
+	metadata, ok := s.metadata[id]
+	if !ok {
+		metadata = make(map[string]any)
+		delete(s.metadata, s.order[s.next])
+		s.metadata[id] = metadata
+		s.order[s.next] = id
+		s.next = (s.next + 1) % len(s.order)
+	}
+	metadata[key] = value
+}
+
+// GetFrameMetadata returns the metadata value of the frame
+func GetFrameMetadata(frame pipelineframes.Frame, key string) (any, bool) {
+	if frame == nil {
+		return nil, false
+	}
+	s := frameMetadata
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	value, ok := s.metadata[frame.ID()][key]
+	return value, ok
+}
+
+// CopyFrameMetadata copies all metadata of the src frame to the dst frame (e.g. input frame -> output frame)
+func CopyFrameMetadata(dst, src pipelineframes.Frame) {
+	if src == nil {
+		return
+	}
+	s := frameMetadata
+	s.mu.Lock()
+	metadata := make(map[string]any, len(s.metadata[src.ID()]))
+	for key, value := range s.metadata[src.ID()] {
+		metadata[key] = value
+	}
+	s.mu.Unlock()
+
+	for key, value := range metadata {
+		SetFrameMetadata(dst, key, value)
+	}
+}
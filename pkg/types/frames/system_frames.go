@@ -1,6 +1,12 @@
 package frames
 
-import pipelineframes "github.com/weedge/pipeline-go/pkg/frames"
+import (
+	"fmt"
+
+	pipelineframes "github.com/weedge/pipeline-go/pkg/frames"
+
+	"achatbot/pkg/types"
+)
 
 // Emitted by when the bot should be interrupted. This will mainly cause the
 // same actions as if the user interrupted except that the
@@ -8,3 +14,25 @@ import pipelineframes "github.com/weedge/pipeline-go/pkg/frames"
 type BotInterruptionFrame struct {
 	*pipelineframes.SystemFrame
 }
+
+// TurnMetricsFrame is emitted when the first bot audio of a turn is written to the client,
+// with the latency breakdown of the turn stages
+type TurnMetricsFrame struct {
+	*pipelineframes.SystemFrame
+	Metrics *types.TurnMetrics `json:"metrics"`
+}
+
+// NewTurnMetricsFrame creates a new TurnMetricsFrame
+func NewTurnMetricsFrame(metrics *types.TurnMetrics) *TurnMetricsFrame {
+	return &TurnMetricsFrame{
+		SystemFrame: &pipelineframes.SystemFrame{
+			BaseFrame: pipelineframes.NewBaseFrameWithName("TurnMetricsFrame"),
+		},
+		Metrics: metrics,
+	}
+}
+
+// String implements string representation of TurnMetricsFrame
+func (f *TurnMetricsFrame) String() string {
+	return fmt.Sprintf("%s %s", f.SystemFrame.String(), f.Metrics)
+}
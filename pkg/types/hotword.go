@@ -0,0 +1,24 @@
+package types
+
+import "fmt"
+
+// Hotword ASR 热词, Boost 为解码时的加分(<= 0 使用识别器默认分数)
+type Hotword struct {
+	Phrase string  `json:"phrase"`
+	Boost  float32 `json:"boost"`
+}
+
+func (h Hotword) String() string {
+	return fmt.Sprintf("%s:%.2f", h.Phrase, h.Boost)
+}
+
+// ASRContext 会话的 ASR 上下文偏置: 热词和识别后替换词典
+type ASRContext struct {
+	Hotwords []Hotword `json:"hotwords"`
+	// 识别结果中的错词 -> 正确词
+	Replacements map[string]string `json:"replacements"`
+}
+
+func (c *ASRContext) String() string {
+	return fmt.Sprintf("ASRContext(hotwords: %v replacements: %d)", c.Hotwords, len(c.Replacements))
+}
@@ -0,0 +1,43 @@
+package types
+
+import (
+	"fmt"
+	"maps"
+	"time"
+)
+
+// Identity 认证后的会话身份: 由 token claims 映射得到的用户和机器人配置(bot profile)
+type Identity struct {
+	UserID string `json:"user_id"`
+	// BotProfile 机器人配置名称(如系统提示词, 音色), 为空使用默认配置
+	BotProfile string `json:"bot_profile"`
+	// ExpiresAt token 有效截止时间(exp 加时钟偏差容忍), 零值表示不过期
+	ExpiresAt time.Time `json:"expires_at,omitzero"`
+	// Claims token 的全部 claims
+	Claims map[string]any `json:"claims,omitempty"`
+}
+
+func (i *Identity) String() string {
+	return fmt.Sprintf("user_id: %s bot_profile: %s expires_at: %s", i.UserID, i.BotProfile, i.ExpiresAt)
+}
+
+// AdminClaim 管理员 claim, 值为 true 时可管理其他用户的会话和数据
+const AdminClaim = "admin"
+
+// IsAdmin reports whether the token has the admin claim ("admin": true)
+func (i *Identity) IsAdmin() bool {
+	if i == nil {
+		return false
+	}
+	admin, _ := i.Claims[AdminClaim].(bool)
+	return admin
+}
+
+func (i *Identity) Copy() *Identity {
+	if i == nil {
+		return nil
+	}
+	cp := *i
+	cp.Claims = maps.Clone(i.Claims)
+	return &cp
+}
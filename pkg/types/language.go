@@ -0,0 +1,13 @@
+package types
+
+// 语种代码, 与 whisper 语种识别结果一致
+const (
+	LanguageZh = "zh"
+	LanguageEn = "en"
+)
+
+// LanguageSegment 文本中单一语种的片段
+type LanguageSegment struct {
+	Text     string `json:"text"`
+	Language string `json:"language"`
+}
@@ -0,0 +1,38 @@
+package types
+
+import "fmt"
+
+// SpeakerVerifyAction 说话人验证未通过(非注册声纹)时的处理方式
+type SpeakerVerifyAction int
+
+const (
+	// SpeakerVerifyActionDrop 丢弃未通过验证的语音, 机器人不响应
+	SpeakerVerifyActionDrop SpeakerVerifyAction = iota
+	// SpeakerVerifyActionTag 保留语音, 仅标记说话人(未通过验证时说话人为空)
+	SpeakerVerifyActionTag
+)
+
+func (a SpeakerVerifyAction) String() string {
+	switch a {
+	case SpeakerVerifyActionDrop:
+		return "DROP"
+	case SpeakerVerifyActionTag:
+		return "TAG"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// SpeakerVerifyResult 说话人验证结果
+type SpeakerVerifyResult struct {
+	// 最相似的注册说话人 ID, 未通过验证时为空
+	SpeakerID string `json:"speaker_id"`
+	// 与最相似注册声纹的余弦相似度
+	Score    float32 `json:"score"`
+	Verified bool    `json:"verified"`
+	Reason   string  `json:"reason"`
+}
+
+func (r *SpeakerVerifyResult) String() string {
+	return fmt.Sprintf("speaker_id: %s score: %.3f verified: %t reason: %s", r.SpeakerID, r.Score, r.Verified, r.Reason)
+}
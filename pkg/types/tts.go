@@ -0,0 +1,72 @@
+package types
+
+import "fmt"
+
+// TTSOptions 单次合成选项, 零值字段使用提供者默认值
+type TTSOptions struct {
+	// Speaker 音色, 说话人 ID(如 "49")或名称(如 "zm_yunjian"), 为空使用默认音色
+	Speaker string `json:"speaker"`
+	// Speed 语速, 越大越快, <= 0 使用默认语速
+	Speed float32 `json:"speed"`
+	// Pitch 音高偏移(半音), 0 不变
+	Pitch float32 `json:"pitch"`
+	// Volume 音量增益倍数, <= 0 不变
+	Volume float32 `json:"volume"`
+	// Language 文本语种, 多语种提供者未指定音色时按语种选择音色
+	Language string `json:"language"`
+}
+
+// Merge returns a copy of the options overridden by the non-zero fields of override
+func (o *TTSOptions) Merge(override *TTSOptions) *TTSOptions {
+	merged := &TTSOptions{}
+	if o != nil {
+		*merged = *o
+	}
+	if override == nil {
+		return merged
+	}
+	if override.Speaker != "" {
+		merged.Speaker = override.Speaker
+	}
+	if override.Speed > 0 {
+		merged.Speed = override.Speed
+	}
+	if override.Pitch != 0 {
+		merged.Pitch = override.Pitch
+	}
+	if override.Volume > 0 {
+		merged.Volume = override.Volume
+	}
+	if override.Language != "" {
+		merged.Language = override.Language
+	}
+	return merged
+}
+
+func (o *TTSOptions) String() string {
+	return fmt.Sprintf("TTSOptions(speaker: %s speed: %.2f pitch: %.2f volume: %.2f language: %s)",
+		o.Speaker, o.Speed, o.Pitch, o.Volume, o.Language)
+}
+
+// TTSSegment 标记文本解析出的合成片段, BreakSecs > 0 时为停顿片段(Text 为空)
+type TTSSegment struct {
+	Text string `json:"text"`
+	// 相对会话语速的倍数(如强调时放慢), 1 不变
+	SpeedScale float32 `json:"speed_scale"`
+	// 切换的音色, 为空使用会话音色
+	Speaker   string  `json:"speaker"`
+	BreakSecs float64 `json:"break_secs"`
+}
+
+// BotTranscriptEvent 机器人语音播放进度事件, 以 json 消息发送给客户端:
+// 播放到的文本逐段发送; 被打断时发送实际播放的完整文本, Interrupted 为 true
+type BotTranscriptEvent struct {
+	Type        string `json:"type"`
+	Text        string `json:"text"`
+	Interrupted bool   `json:"interrupted,omitempty"`
+}
+
+// NewBotTranscriptEvent 创建机器人语音播放进度事件
+func NewBotTranscriptEvent(text string, interrupted bool) *BotTranscriptEvent {
+	return &BotTranscriptEvent{Type: "bot_transcript", Text: text, Interrupted: interrupted}
+}
@@ -0,0 +1,37 @@
+package types
+
+import "fmt"
+
+// TurnState 表示用户轮次状态
+type TurnState int
+
+const (
+	TurnIncomplete TurnState = iota
+	TurnComplete
+)
+
+func (s TurnState) String() string {
+	switch s {
+	case TurnIncomplete:
+		return "INCOMPLETE"
+	case TurnComplete:
+		return "COMPLETE"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// EndOfTurnResult 轮次结束判断结果
+type EndOfTurnResult struct {
+	State TurnState `json:"state"`
+	// 用户说完的概率(0~1)
+	Probability float64 `json:"probability"`
+	// 未结束时建议继续等待的静音时长(秒)
+	WaitSecs float64 `json:"wait_secs"`
+	Reason   string  `json:"reason"`
+}
+
+func (r *EndOfTurnResult) String() string {
+	return fmt.Sprintf("EndOfTurnResult{State: %s, Probability: %.2f, WaitSecs: %.2f, Reason: %s}",
+		r.State, r.Probability, r.WaitSecs, r.Reason)
+}
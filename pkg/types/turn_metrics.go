@@ -0,0 +1,142 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TurnStage 一轮对话的处理阶段, 按处理顺序
+type TurnStage string
+
+const (
+	// TurnStageVADStop VAD 检测到用户停止说话, 一轮的起点
+	TurnStageVADStop TurnStage = "vad_stop"
+	// TurnStageAggregatorFlush 用户语音聚合输出
+	TurnStageAggregatorFlush TurnStage = "aggregator_flush"
+	// TurnStageASRDone 语音识别完成
+	TurnStageASRDone TurnStage = "asr_done"
+	// TurnStageLLMFirstToken LLM 输出第一个 token
+	TurnStageLLMFirstToken TurnStage = "llm_first_token"
+	// TurnStageFirstSentence 第一个句子聚合完成
+	TurnStageFirstSentence TurnStage = "first_sentence"
+	// TurnStageTTSFirstAudio TTS 合成第一段音频
+	TurnStageTTSFirstAudio TurnStage = "tts_first_audio"
+	// TurnStageFirstAudioWritten 第一段音频写入客户端, 一轮的终点
+	TurnStageFirstAudioWritten TurnStage = "first_audio_written"
+)
+
+// TurnStages 所有阶段, 按处理顺序
+var TurnStages = []TurnStage{
+	TurnStageVADStop,
+	TurnStageAggregatorFlush,
+	TurnStageASRDone,
+	TurnStageLLMFirstToken,
+	TurnStageFirstSentence,
+	TurnStageTTSFirstAudio,
+	TurnStageFirstAudioWritten,
+}
+
+// Index 返回阶段的处理顺序, 未知阶段返回 -1
+func (s TurnStage) Index() int {
+	for i, stage := range TurnStages {
+		if stage == s {
+			return i
+		}
+	}
+	return -1
+}
+
+// TurnStageLatency 阶段耗时
+type TurnStageLatency struct {
+	Stage TurnStage `json:"stage"`
+	// 距 VAD 停止的耗时(毫秒)
+	SinceVADStopMS float64 `json:"since_vad_stop_ms"`
+	// 距上一个阶段的耗时(毫秒)
+	SincePrevMS float64 `json:"since_prev_ms"`
+}
+
+// TurnMetrics 一轮对话各阶段的耗时分解, 未经过的阶段(如 pipeline 中没有该处理器)不包含在内
+type TurnMetrics struct {
+	TurnID    int                `json:"turn_id"`
+	StartTime time.Time          `json:"start_time"`
+	Stages    []TurnStageLatency `json:"stages"`
+}
+
+// NewTurnMetrics 根据各阶段的时间点创建耗时分解
+func NewTurnMetrics(turnID int, marks map[TurnStage]time.Time) *TurnMetrics {
+	start := marks[TurnStageVADStop]
+	metrics := &TurnMetrics{TurnID: turnID, StartTime: start, Stages: make([]TurnStageLatency, 0, len(marks))}
+	prev := start
+	for _, stage := range TurnStages {
+		at, ok := marks[stage]
+		if !ok {
+			continue
+		}
+		metrics.Stages = append(metrics.Stages, TurnStageLatency{
+			Stage:          stage,
+			SinceVADStopMS: durationMS(at.Sub(start)),
+			SincePrevMS:    durationMS(at.Sub(prev)),
+		})
+		prev = at
+	}
+	return metrics
+}
+
+// Latency 返回阶段距 VAD 停止的耗时(毫秒)
+func (m *TurnMetrics) Latency(stage TurnStage) (float64, bool) {
+	for _, latency := range m.Stages {
+		if latency.Stage == stage {
+			return latency.SinceVADStopMS, true
+		}
+	}
+	return 0, false
+}
+
+func (m *TurnMetrics) String() string {
+	parts := make([]string, 0, len(m.Stages))
+	for _, latency := range m.Stages {
+		parts = append(parts, fmt.Sprintf("%s: %.0fms(+%.0fms)", latency.Stage, latency.SinceVADStopMS, latency.SincePrevMS))
+	}
+	return fmt.Sprintf("TurnMetrics{TurnID: %d, %s}", m.TurnID, strings.Join(parts, ", "))
+}
+
+func durationMS(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// TurnLatencyPercentiles 会话内阶段距 VAD 停止耗时(毫秒)的分位数
+type TurnLatencyPercentiles struct {
+	Stage TurnStage `json:"stage"`
+	Count int       `json:"count"`
+	P50MS float64   `json:"p50_ms"`
+	P90MS float64   `json:"p90_ms"`
+	P99MS float64   `json:"p99_ms"`
+	MaxMS float64   `json:"max_ms"`
+}
+
+// TurnMetricsSummary 会话结束时的耗时汇总
+type TurnMetricsSummary struct {
+	SessionID string                   `json:"session_id"`
+	Turns     int                      `json:"turns"`
+	Stages    []TurnLatencyPercentiles `json:"stages"`
+}
+
+func (s *TurnMetricsSummary) String() string {
+	parts := make([]string, 0, len(s.Stages))
+	for _, p := range s.Stages {
+		parts = append(parts, fmt.Sprintf("%s: p50 %.0fms p90 %.0fms p99 %.0fms", p.Stage, p.P50MS, p.P90MS, p.P99MS))
+	}
+	return fmt.Sprintf("TurnMetricsSummary{SessionID: %s, Turns: %d, %s}", s.SessionID, s.Turns, strings.Join(parts, ", "))
+}
+
+// TurnMetricsEvent 一轮对话耗时分解事件, 以 json 消息发送给客户端
+type TurnMetricsEvent struct {
+	Type string `json:"type"`
+	*TurnMetrics
+}
+
+// NewTurnMetricsEvent 创建一轮对话耗时分解事件
+func NewTurnMetricsEvent(metrics *TurnMetrics) *TurnMetricsEvent {
+	return &TurnMetricsEvent{Type: "turn_metrics", TurnMetrics: metrics}
+}
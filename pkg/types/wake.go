@@ -0,0 +1,53 @@
+package types
+
+import "fmt"
+
+// WakeState 唤醒词门控状态
+type WakeState int
+
+const (
+	// WakeStateSleeping 休眠: 只做唤醒词检测, 用户语音不进入后续处理
+	WakeStateSleeping WakeState = iota
+	// WakeStateAwake 唤醒: 监听窗口打开, 用户语音正常进入后续处理
+	WakeStateAwake
+)
+
+func (s WakeState) String() string {
+	switch s {
+	case WakeStateSleeping:
+		return "sleeping"
+	case WakeStateAwake:
+		return "awake"
+	default:
+		return "unknown"
+	}
+}
+
+// 唤醒状态变化原因
+const (
+	WakeReasonKeyword = "keyword"
+	WakeReasonTurnEnd = "turn_end"
+	WakeReasonTimeout = "timeout"
+)
+
+// WakeEvent 唤醒状态变化事件, 以 json 消息发送给客户端
+type WakeEvent struct {
+	Type    string `json:"type"`
+	State   string `json:"state"`
+	Reason  string `json:"reason"`
+	Keyword string `json:"keyword,omitempty"`
+}
+
+// NewWakeEvent 创建唤醒状态变化事件
+func NewWakeEvent(state WakeState, reason, keyword string) *WakeEvent {
+	return &WakeEvent{
+		Type:    "wake_state",
+		State:   state.String(),
+		Reason:  reason,
+		Keyword: keyword,
+	}
+}
+
+func (e *WakeEvent) String() string {
+	return fmt.Sprintf("WakeEvent(state: %s reason: %s keyword: %s)", e.State, e.Reason, e.Keyword)
+}
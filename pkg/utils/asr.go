@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+
+	"achatbot/pkg/types"
+)
+
+// IsMeaningfulText 文本是否包含文字或数字, 只有标点/空白(如噪声识别出的 "。")视为无意义
+func IsMeaningfulText(text string) bool {
+	for _, r := range text {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeASRTag 去掉 SenseVoice 等模型标签的 <| |> 标记并转为小写, 如 <|HAPPY|> -> happy; 未知标签返回空
+func NormalizeASRTag(tag string) string {
+	tag = strings.ToLower(strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(tag), "<|"), "|>"))
+	if tag == "emo_unknown" || tag == "unknown" || tag == "nospeech" {
+		return ""
+	}
+	return tag
+}
+
+// TokensToWords 将 token 及其开始时间合并为词:
+// 汉字每个字为一个词, BPE token 以 "▁" 或空格开始新词, 其余 token 接到上一个词;
+// 词的结束时间为下一个词的开始时间, 最后一个词结束于 endSecs
+func TokensToWords(tokens []string, timestamps []float32, endSecs float32) []types.WordTimestamp {
+	if len(tokens) == 0 || len(tokens) != len(timestamps) {
+		return nil
+	}
+
+	words := make([]types.WordTimestamp, 0, len(tokens))
+	newWord := true
+	for i, token := range tokens {
+		text := strings.TrimLeft(token, "▁ ")
+		startsWord := text != token
+		if text == "" {
+			newWord = true
+			continue
+		}
+		if !IsMeaningfulText(text) {
+			// 标点不计为词, 其后开始新词
+			newWord = true
+			continue
+		}
+
+		isHan := unicode.Is(unicode.Han, []rune(text)[0])
+		if newWord || startsWord || isHan || len(words) == 0 {
+			words = append(words, types.WordTimestamp{Word: text, Start: timestamps[i]})
+		} else {
+			words[len(words)-1].Word += text
+		}
+		newWord = isHan
+	}
+
+	for i := range words {
+		if i+1 < len(words) {
+			words[i].End = words[i+1].Start
+		} else {
+			words[i].End = max(endSecs, words[i].Start)
+		}
+	}
+	return words
+}
@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"achatbot/pkg/types"
+)
+
+func TestTokensToWords(t *testing.T) {
+	tokens := []string{"你", "好", "，", "▁open", "ai", "▁is", "."}
+	timestamps := []float32{0.1, 0.3, 0.5, 0.6, 0.8, 1.0, 1.2}
+	words := TokensToWords(tokens, timestamps, 1.5)
+	assert.Equal(t, []types.WordTimestamp{
+		{Word: "你", Start: 0.1, End: 0.3},
+		{Word: "好", Start: 0.3, End: 0.6},
+		{Word: "openai", Start: 0.6, End: 1.0},
+		{Word: "is", Start: 1.0, End: 1.5},
+	}, words)
+
+	assert.Nil(t, TokensToWords(tokens, timestamps[:2], 1))
+}
+
+func TestNormalizeASRTag(t *testing.T) {
+	assert.Equal(t, "happy", NormalizeASRTag("<|HAPPY|>"))
+	assert.Equal(t, "zh", NormalizeASRTag("<|zh|>"))
+	assert.Equal(t, "", NormalizeASRTag("<|EMO_UNKNOWN|>"))
+	assert.True(t, IsMeaningfulText("嗯"))
+	assert.False(t, IsMeaningfulText(" 。, "))
+}
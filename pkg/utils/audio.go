@@ -45,3 +45,11 @@ func SamplesFloatToInt16(inSamples []float32) []byte {
 
 	return outSamples
 }
+
+// ScaleSamples multiplies float32 samples by the gain in place, clamped to [-1.0, 1.0]
+func ScaleSamples(samples []float32, gain float32) []float32 {
+	for i, s := range samples {
+		samples[i] = float32(math.Max(-1, math.Min(1, float64(s*gain))))
+	}
+	return samples
+}
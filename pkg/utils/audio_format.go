@@ -0,0 +1,139 @@
+package utils
+
+import (
+	"fmt"
+
+	"achatbot/pkg/types"
+)
+
+// SamplesToFloat 将 8/16/32 bit PCM 字节解码为交错(interleaved)的 float32 样本
+func SamplesToFloat(data []byte, sampleWidth int) []float32 {
+	switch sampleWidth {
+	case 1:
+		// 8bit PCM 为无符号, 128 为零点
+		out := make([]float32, len(data))
+		for i, b := range data {
+			out[i] = (float32(b) - 128) / 128
+		}
+		return out
+	case 2:
+		return SamplesInt16ToFloat(data)
+	case 4:
+		numSamples := len(data) / 4
+		out := make([]float32, numSamples)
+		for i := range numSamples {
+			s32 := int32(data[4*i]) | int32(data[4*i+1])<<8 | int32(data[4*i+2])<<16 | int32(data[4*i+3])<<24
+			out[i] = float32(float64(s32) / 2147483648)
+		}
+		return out
+	default:
+		return []float32{}
+	}
+}
+
+// SamplesFloatTo 将交错的 float32 样本编码为 8/16/32 bit PCM 字节
+func SamplesFloatTo(samples []float32, sampleWidth int) []byte {
+	switch sampleWidth {
+	case 1:
+		out := make([]byte, len(samples))
+		for i, s := range samples {
+			s = min(max(s, -1), 127.0/128)
+			out[i] = byte(int(s*128) + 128)
+		}
+		return out
+	case 2:
+		return SamplesFloatToInt16(samples)
+	case 4:
+		out := make([]byte, len(samples)*4)
+		for i, s := range samples {
+			var s32 int32
+			if s >= 1.0 {
+				s32 = 2147483647
+			} else if s <= -1.0 {
+				s32 = -2147483648
+			} else {
+				s32 = int32(float64(s) * 2147483648)
+			}
+			out[4*i] = byte(s32)
+			out[4*i+1] = byte(s32 >> 8)
+			out[4*i+2] = byte(s32 >> 16)
+			out[4*i+3] = byte(s32 >> 24)
+		}
+		return out
+	default:
+		return []byte{}
+	}
+}
+
+// RemixChannels 交错样本的通道转换: 多->少 取平均下混, 少->多 复制上混
+func RemixChannels(samples []float32, inChannels, outChannels int) []float32 {
+	if inChannels == outChannels || inChannels <= 0 || outChannels <= 0 {
+		return samples
+	}
+
+	numFrames := len(samples) / inChannels
+	out := make([]float32, numFrames*outChannels)
+	for i := range numFrames {
+		frame := samples[i*inChannels : (i+1)*inChannels]
+		if outChannels < inChannels {
+			var sum float32
+			for _, s := range frame {
+				sum += s
+			}
+			mono := sum / float32(inChannels)
+			for c := range outChannels {
+				out[i*outChannels+c] = mono
+			}
+			continue
+		}
+		for c := range outChannels {
+			out[i*outChannels+c] = frame[c%inChannels]
+		}
+	}
+	return out
+}
+
+// ConvertAudioFormat 将 PCM 音频从 from 格式转换为 to 格式(位深, 通道, 采样率)
+func ConvertAudioFormat(audio []byte, from, to *types.AudioFormat) ([]byte, error) {
+	if from == nil || to == nil {
+		return nil, fmt.Errorf("convert audio format: from/to format is nil")
+	}
+	if err := from.Validate(); err != nil {
+		return nil, fmt.Errorf("convert audio from %s: %w", from, err)
+	}
+	if err := to.Validate(); err != nil {
+		return nil, fmt.Errorf("convert audio to %s: %w", to, err)
+	}
+	if from.Equal(to) {
+		return audio, nil
+	}
+
+	samples := SamplesToFloat(audio, from.SampleWidth)
+	samples = RemixChannels(samples, from.NumChannels, to.NumChannels)
+
+	if from.SampleRate != to.SampleRate {
+		if to.NumChannels == 1 {
+			samples = Resample(samples, from.SampleRate, to.SampleRate)
+		} else {
+			// 按通道拆分重采样后再交错
+			numFrames := len(samples) / to.NumChannels
+			var resampled [][]float32
+			for c := range to.NumChannels {
+				channel := make([]float32, numFrames)
+				for i := range numFrames {
+					channel[i] = samples[i*to.NumChannels+c]
+				}
+				resampled = append(resampled, Resample(channel, from.SampleRate, to.SampleRate))
+			}
+			outFrames := len(resampled[0])
+			samples = make([]float32, outFrames*to.NumChannels)
+			for i := range outFrames {
+				for c := range to.NumChannels {
+					samples[i*to.NumChannels+c] = resampled[c][i]
+				}
+			}
+		}
+	}
+
+	return SamplesFloatTo(samples, to.SampleWidth), nil
+}
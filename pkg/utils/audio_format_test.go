@@ -0,0 +1,143 @@
+package utils
+
+import (
+	"math"
+	"testing"
+
+	"achatbot/pkg/types"
+)
+
+func TestRemixChannels(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       []float32
+		inChannels  int
+		outChannels int
+		expected    []float32
+	}{
+		{
+			name:        "Same channels",
+			input:       []float32{0.1, 0.2},
+			inChannels:  1,
+			outChannels: 1,
+			expected:    []float32{0.1, 0.2},
+		},
+		{
+			name:        "Stereo to mono",
+			input:       []float32{0.2, 0.4, -0.5, 0.5},
+			inChannels:  2,
+			outChannels: 1,
+			expected:    []float32{0.3, 0},
+		},
+		{
+			name:        "Mono to stereo",
+			input:       []float32{0.1, -0.2},
+			inChannels:  1,
+			outChannels: 2,
+			expected:    []float32{0.1, 0.1, -0.2, -0.2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := RemixChannels(tt.input, tt.inChannels, tt.outChannels)
+			if len(result) != len(tt.expected) {
+				t.Fatalf("RemixChannels() length = %d, expected %d", len(result), len(tt.expected))
+			}
+			for i := range result {
+				if math.Abs(float64(result[i]-tt.expected[i])) > 1e-6 {
+					t.Errorf("RemixChannels()[%d] = %v, expected %v", i, result[i], tt.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestConvertAudioFormat(t *testing.T) {
+	// 24k/16bit/mono 的 6 个样本
+	audio := SamplesFloatToInt16([]float32{0, 0.25, 0.5, 0.25, 0, -0.25})
+
+	tests := []struct {
+		name        string
+		from        *types.AudioFormat
+		to          *types.AudioFormat
+		expectedLen int
+		expectErr   bool
+	}{
+		{
+			name:        "Same format",
+			from:        types.NewAudioFormat(24000, 1, 2),
+			to:          types.NewAudioFormat(24000, 1, 2),
+			expectedLen: 12,
+		},
+		{
+			name:        "Resample 24k to 16k",
+			from:        types.NewAudioFormat(24000, 1, 2),
+			to:          types.NewAudioFormat(16000, 1, 2),
+			expectedLen: 8,
+		},
+		{
+			name:        "Mono to stereo",
+			from:        types.NewAudioFormat(24000, 1, 2),
+			to:          types.NewAudioFormat(24000, 2, 2),
+			expectedLen: 24,
+		},
+		{
+			name:        "16bit to 32bit",
+			from:        types.NewAudioFormat(24000, 1, 2),
+			to:          types.NewAudioFormat(24000, 1, 4),
+			expectedLen: 24,
+		},
+		{
+			name:        "16bit to 8bit",
+			from:        types.NewAudioFormat(24000, 1, 2),
+			to:          types.NewAudioFormat(24000, 1, 1),
+			expectedLen: 6,
+		},
+		{
+			name:      "Unsupported sample width",
+			from:      types.NewAudioFormat(24000, 1, 2),
+			to:        types.NewAudioFormat(24000, 1, 3),
+			expectErr: true,
+		},
+		{
+			name:      "Nil format",
+			from:      types.NewAudioFormat(24000, 1, 2),
+			to:        nil,
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ConvertAudioFormat(audio, tt.from, tt.to)
+			if tt.expectErr {
+				if err == nil {
+					t.Errorf("ConvertAudioFormat() expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ConvertAudioFormat() error = %v", err)
+			}
+			if len(result) != tt.expectedLen {
+				t.Errorf("ConvertAudioFormat() length = %d, expected %d", len(result), tt.expectedLen)
+			}
+		})
+	}
+}
+
+func TestSamplesWidthRoundTrip(t *testing.T) {
+	input := []float32{0, 0.5, -0.5}
+	for _, width := range []int{1, 2, 4} {
+		result := SamplesToFloat(SamplesFloatTo(input, width), width)
+		if len(result) != len(input) {
+			t.Fatalf("width %d length = %d, expected %d", width, len(result), len(input))
+		}
+		for i := range result {
+			if math.Abs(float64(result[i]-input[i])) > 1e-2 {
+				t.Errorf("width %d [%d] = %v, expected %v", width, i, result[i], input[i])
+			}
+		}
+	}
+}
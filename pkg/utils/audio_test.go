@@ -133,3 +133,14 @@ func TestSamplesFloatToInt16(t *testing.T) {
 		})
 	}
 }
+
+func TestScaleSamples(t *testing.T) {
+	samples := ScaleSamples([]float32{0.1, -0.3, 0.8}, 2)
+	expected := []float32{0.2, -0.6, 1.0}
+	for i := range expected {
+		if !floatEquals(samples[i], expected[i]) {
+			t.Errorf("ScaleSamples() = %v, expected %v", samples, expected)
+			break
+		}
+	}
+}
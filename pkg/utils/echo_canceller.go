@@ -0,0 +1,118 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+)
+
+// NLMSEchoCanceller 基于归一化最小均方(NLMS)自适应滤波的单通道回声消除,
+// 用参考信号(机器人输出音频)估计回声路径, 从麦克风信号中减去估计的回声
+type NLMSEchoCanceller struct {
+	weights  []float64
+	history  []float64 // 参考信号历史(环形)
+	pos      int
+	energy   float64 // history 能量, 增量维护
+	stepSize float64
+	// PCM 采样宽度(字节), ProcessBytes 按此解码/编码, 默认 16bit
+	sampleWidth int
+}
+
+// NewNLMSEchoCanceller 创建回声消除器, filterLen 为滤波器阶数(覆盖回声路径长度), stepSize 取 (0, 2)
+func NewNLMSEchoCanceller(filterLen int, stepSize float64) *NLMSEchoCanceller {
+	return &NLMSEchoCanceller{
+		weights:  make([]float64, filterLen),
+		history:  make([]float64, filterLen),
+		stepSize: stepSize,
+
+		sampleWidth: 2,
+	}
+}
+
+// NewPCMEchoCanceller 创建处理 8/16/32 bit PCM 字节的回声消除器, 其他采样宽度返回错误
+func NewPCMEchoCanceller(filterLen int, stepSize float64, sampleWidth int) (*NLMSEchoCanceller, error) {
+	switch sampleWidth {
+	case 1, 2, 4:
+	default:
+		return nil, fmt.Errorf("echo canceller only support 8/16/32 bit PCM, got sample_width %d", sampleWidth)
+	}
+	c := NewNLMSEchoCanceller(filterLen, stepSize)
+	c.sampleWidth = sampleWidth
+	return c, nil
+}
+
+// Process 对等长的麦克风样本和参考样本做回声消除, 返回残差(近端语音)样本
+func (c *NLMSEchoCanceller) Process(mic, ref []float32) []float32 {
+	const eps = 1e-6
+	n := len(c.weights)
+	out := make([]float32, len(mic))
+	for i := range mic {
+		var x float64
+		if i < len(ref) {
+			x = float64(ref[i])
+		}
+
+		// 更新参考信号历史, 增量维护能量
+		old := c.history[c.pos]
+		c.energy += x*x - old*old
+		if c.energy < 0 {
+			c.energy = 0
+		}
+		c.history[c.pos] = x
+
+		// 估计回声 y = w·x
+		var y float64
+		for k := range n {
+			y += c.weights[k] * c.history[c.index(k)]
+		}
+		e := float64(mic[i]) - y
+		out[i] = float32(math.Max(-1, math.Min(1, e)))
+
+		// 权重更新 w += mu * e * x / (x·x + eps)
+		if c.energy > eps {
+			g := c.stepSize * e / (c.energy + eps)
+			for k := range n {
+				c.weights[k] += g * c.history[c.index(k)]
+			}
+		}
+
+		c.pos = (c.pos + 1) % n
+	}
+	return out
+}
+
+// index 返回 k 个样本之前的参考信号在环形历史中的位置
+func (c *NLMSEchoCanceller) index(k int) int {
+	idx := c.pos - k
+	if idx < 0 {
+		idx += len(c.history)
+	}
+	return idx
+}
+
+// ProcessBytes 对单声道 PCM(采样宽度见 NewPCMEchoCanceller, 默认 16bit)麦克风音频和参考音频做回声消除
+func (c *NLMSEchoCanceller) ProcessBytes(mic, ref []byte) []byte {
+	return SamplesFloatTo(c.Process(SamplesToFloat(mic, c.sampleWidth), SamplesToFloat(ref, c.sampleWidth)), c.sampleWidth)
+}
+
+// Reset 重置滤波器状态
+func (c *NLMSEchoCanceller) Reset() {
+	for i := range c.weights {
+		c.weights[i] = 0
+		c.history[i] = 0
+	}
+	c.pos = 0
+	c.energy = 0
+}
+
+// RMS 计算 PCM 音频均方根(0~1)
+func RMS(audio []byte, sampleWidth int) float64 {
+	samples := SamplesToFloat(audio, sampleWidth)
+	if len(samples) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, s := range samples {
+		sum += float64(s) * float64(s)
+	}
+	return math.Sqrt(sum / float64(len(samples)))
+}
@@ -0,0 +1,130 @@
+package utils
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestNLMSEchoCanceller(t *testing.T) {
+	// 参考信号为白噪声, 麦克风为参考信号延迟 5 个样本并衰减的回声
+	rng := rand.New(rand.NewSource(1))
+	numSamples := 16000
+	ref := make([]float32, numSamples)
+	mic := make([]float32, numSamples)
+	for i := range ref {
+		ref[i] = float32(rng.Float64()*0.5 - 0.25)
+		if i >= 5 {
+			mic[i] = 0.6 * ref[i-5]
+		}
+	}
+
+	canceller := NewNLMSEchoCanceller(32, 0.5)
+	out := canceller.Process(mic, ref)
+
+	energy := func(samples []float32) float64 {
+		var sum float64
+		for _, s := range samples {
+			sum += float64(s) * float64(s)
+		}
+		return sum
+	}
+	tail := numSamples / 2
+	micEnergy := energy(mic[tail:])
+	outEnergy := energy(out[tail:])
+	if outEnergy > micEnergy*1e-3 {
+		t.Errorf("echo not cancelled, mic energy %v, residual energy %v", micEnergy, outEnergy)
+	}
+
+	canceller.Reset()
+	silence := canceller.Process(make([]float32, 10), make([]float32, 10))
+	for i, s := range silence {
+		if s != 0 {
+			t.Errorf("Process() silence [%d] = %v, expected 0", i, s)
+		}
+	}
+}
+
+func TestPCMEchoCanceller(t *testing.T) {
+	if _, err := NewPCMEchoCanceller(32, 0.5, 3); err == nil {
+		t.Errorf("NewPCMEchoCanceller() sample_width 3, expected error")
+	}
+
+	// 8/32 bit PCM 按采样宽度解码, 与 float 样本的结果一致
+	rng := rand.New(rand.NewSource(1))
+	ref := make([]float32, 1600)
+	mic := make([]float32, len(ref))
+	for i := range ref {
+		ref[i] = float32(rng.Float64()*0.5 - 0.25)
+		if i >= 5 {
+			mic[i] = 0.6 * ref[i-5]
+		}
+	}
+	for _, sampleWidth := range []int{1, 2, 4} {
+		canceller, err := NewPCMEchoCanceller(32, 0.5, sampleWidth)
+		if err != nil {
+			t.Fatalf("NewPCMEchoCanceller(%d) err: %v", sampleWidth, err)
+		}
+		micBytes, refBytes := SamplesFloatTo(mic, sampleWidth), SamplesFloatTo(ref, sampleWidth)
+		out := canceller.ProcessBytes(micBytes, refBytes)
+		if len(out) != len(micBytes) {
+			t.Fatalf("ProcessBytes(%d) len = %d, expected %d", sampleWidth, len(out), len(micBytes))
+		}
+		expected := NewNLMSEchoCanceller(32, 0.5).Process(SamplesToFloat(micBytes, sampleWidth), SamplesToFloat(refBytes, sampleWidth))
+		got := SamplesToFloat(out, sampleWidth)
+		tolerance := 2.0 / math.Pow(2, float64(8*sampleWidth))
+		for i := range got {
+			if math.Abs(float64(got[i]-expected[i])) > tolerance {
+				t.Errorf("ProcessBytes(%d) [%d] = %v, expected %v", sampleWidth, i, got[i], expected[i])
+				break
+			}
+		}
+	}
+}
+
+func TestEchoReferenceBuffer(t *testing.T) {
+	buf := NewEchoReferenceBuffer(8, 2, 2)
+
+	// 无参考信号时补静音
+	if got := buf.ReadReference(4); len(got) != 4 || got[0] != 0 {
+		t.Errorf("ReadReference() empty = %v, expected 4 zero bytes", got)
+	}
+
+	// 新一段语音前补延迟静音
+	buf.WriteReference([]byte{1, 2, 3, 4})
+	if buf.Size() != 6 {
+		t.Errorf("Size() = %d, expected 6", buf.Size())
+	}
+	got := buf.ReadReference(4)
+	expected := []byte{0, 0, 1, 2}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("ReadReference() = %v, expected %v", got, expected)
+			break
+		}
+	}
+
+	// 超出最大长度丢弃最旧的
+	buf.WriteReference([]byte{5, 6, 7, 8, 9, 10, 11, 12})
+	if buf.Size() != 8 {
+		t.Errorf("Size() = %d, expected 8", buf.Size())
+	}
+	if got := buf.ReadReference(2); got[0] != 5 {
+		t.Errorf("ReadReference() = %v, expected start with 5", got)
+	}
+
+	buf.Reset()
+	if buf.Size() != 0 {
+		t.Errorf("Size() after Reset = %d, expected 0", buf.Size())
+	}
+}
+
+func TestRMS(t *testing.T) {
+	audio := SamplesFloatToInt16([]float32{0.5, -0.5, 0.5, -0.5})
+	if got := RMS(audio, 2); math.Abs(got-0.5) > 1e-3 {
+		t.Errorf("RMS() = %v, expected 0.5", got)
+	}
+	if got := RMS(nil, 2); got != 0 {
+		t.Errorf("RMS() empty = %v, expected 0", got)
+	}
+}
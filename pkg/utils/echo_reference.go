@@ -0,0 +1,76 @@
+package utils
+
+import "sync"
+
+// EchoReferenceBuffer 机器人输出音频参考信号 FIFO, 实现 common.IEchoReference
+// 输出处理器和输入处理器在不同 goroutine 中读写, 需加锁
+type EchoReferenceBuffer struct {
+	mu         sync.Mutex
+	buffer     []byte
+	maxBytes   int
+	delayBytes int
+	primed     bool
+	frameBytes int
+}
+
+// NewEchoReferenceBuffer 创建参考信号缓冲区
+// maxBytes 最大缓存字节数(超出丢弃最旧的); delayBytes 每段机器人语音开始前补齐的静音字节数(对齐播放延迟);
+// frameBytes 单个样本帧字节数(通道数*采样宽度), 用于对齐
+func NewEchoReferenceBuffer(maxBytes, delayBytes, frameBytes int) *EchoReferenceBuffer {
+	if frameBytes <= 0 {
+		frameBytes = 1
+	}
+	return &EchoReferenceBuffer{
+		buffer:     make([]byte, 0, maxBytes),
+		maxBytes:   maxBytes,
+		delayBytes: delayBytes - delayBytes%frameBytes,
+		frameBytes: frameBytes,
+	}
+}
+
+// WriteReference 写入机器人输出音频
+func (b *EchoReferenceBuffer) WriteReference(audio []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.primed {
+		// 一段新的机器人语音, 先补延迟静音
+		b.buffer = append(b.buffer, make([]byte, b.delayBytes)...)
+		b.primed = true
+	}
+	b.buffer = append(b.buffer, audio...)
+	if over := len(b.buffer) - b.maxBytes; b.maxBytes > 0 && over > 0 {
+		over += (b.frameBytes - over%b.frameBytes) % b.frameBytes
+		b.buffer = b.buffer[min(over, len(b.buffer)):]
+	}
+}
+
+// ReadReference 读取 n 字节参考信号, 不足补静音
+func (b *EchoReferenceBuffer) ReadReference(n int) []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	out := make([]byte, n)
+	copied := copy(out, b.buffer)
+	b.buffer = b.buffer[copied:]
+	if len(b.buffer) == 0 {
+		// 参考信号已消费完, 下一段机器人语音重新对齐延迟
+		b.primed = false
+	}
+	return out
+}
+
+// Size 当前缓存的参考信号字节数
+func (b *EchoReferenceBuffer) Size() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.buffer)
+}
+
+// Reset 清空参考信号
+func (b *EchoReferenceBuffer) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buffer = b.buffer[:0]
+	b.primed = false
+}
@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"math"
+	"math/bits"
+	"math/cmplx"
+)
+
+// NextPowerOfTwo 返回不小于 n 的最小 2 的幂
+func NextPowerOfTwo(n int) int {
+	if n <= 1 {
+		return 1
+	}
+	return 1 << bits.Len(uint(n-1))
+}
+
+// FFT 原地迭代 radix-2 快速傅里叶变换, len(x) 必须为 2 的幂; inverse 为 true 时做逆变换(含 1/N 归一化)
+func FFT(x []complex128, inverse bool) {
+	n := len(x)
+	if n <= 1 {
+		return
+	}
+
+	// 位反转重排
+	shift := bits.Len(uint(n)) - 1
+	for i := range n {
+		j := int(bits.Reverse(uint(i)) >> (bits.UintSize - shift))
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+
+	sign := -1.0
+	if inverse {
+		sign = 1.0
+	}
+	for size := 2; size <= n; size <<= 1 {
+		half := size >> 1
+		step := cmplx.Exp(complex(0, sign*2*math.Pi/float64(size)))
+		for start := 0; start < n; start += size {
+			w := complex(1, 0)
+			for k := range half {
+				u := x[start+k]
+				v := x[start+k+half] * w
+				x[start+k] = u + v
+				x[start+k+half] = u - v
+				w *= step
+			}
+		}
+	}
+
+	if inverse {
+		scale := complex(1/float64(n), 0)
+		for i := range x {
+			x[i] *= scale
+		}
+	}
+}
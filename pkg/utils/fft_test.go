@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+func TestNextPowerOfTwo(t *testing.T) {
+	tests := []struct {
+		input    int
+		expected int
+	}{
+		{0, 1}, {1, 1}, {2, 2}, {3, 4}, {320, 512}, {512, 512},
+	}
+	for _, tt := range tests {
+		if got := NextPowerOfTwo(tt.input); got != tt.expected {
+			t.Errorf("NextPowerOfTwo(%d) = %d, expected %d", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestFFT(t *testing.T) {
+	n := 16
+	x := make([]complex128, n)
+	for i := range x {
+		x[i] = complex(math.Cos(2*math.Pi*2*float64(i)/float64(n)), 0)
+	}
+	orig := append([]complex128{}, x...)
+
+	FFT(x, false)
+	// 余弦信号能量集中在第 2 和第 n-2 个频点
+	for k := range x {
+		expected := 0.0
+		if k == 2 || k == n-2 {
+			expected = float64(n) / 2
+		}
+		if math.Abs(cmplx.Abs(x[k])-expected) > 1e-9 {
+			t.Errorf("FFT()[%d] = %v, expected magnitude %v", k, cmplx.Abs(x[k]), expected)
+		}
+	}
+
+	FFT(x, true)
+	for i := range x {
+		if cmplx.Abs(x[i]-orig[i]) > 1e-9 {
+			t.Errorf("inverse FFT()[%d] = %v, expected %v", i, x[i], orig[i])
+		}
+	}
+}
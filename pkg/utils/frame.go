@@ -14,6 +14,8 @@ func GetAudioRawFrame(frame frames.Frame) *frames.AudioRawFrame {
 		return f.AudioRawFrame
 	case *achatbot_frames.AnimationAudioRawFrame:
 		return f.AudioRawFrame
+	case *achatbot_frames.TTSAudioRawFrame:
+		return f.AudioRawFrame
 	default:
 		return nil
 	}
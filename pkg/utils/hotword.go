@@ -0,0 +1,122 @@
+package utils
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+
+	"achatbot/pkg/types"
+)
+
+// minFuzzyHotwordRunes 模糊匹配的中文热词最短字数, 过短的词模糊匹配误纠太多
+const minFuzzyHotwordRunes = 2
+
+// CorrectHotwords 识别后纠错:
+// 1. 替换词典中的错词替换为正确词(长词优先, 只替换一次);
+// 2. 英文热词忽略大小写匹配, 统一为热词的写法;
+// 3. 中文热词按拼音模糊匹配(dict 为 nil 时跳过), 读音相近的同长度片段替换为热词
+func CorrectHotwords(text string, hotwords []types.Hotword, replacements map[string]string, dict *PinyinDict) string {
+	if text == "" {
+		return text
+	}
+
+	if len(replacements) > 0 {
+		froms := make([]string, 0, len(replacements))
+		for from := range replacements {
+			if from != "" {
+				froms = append(froms, from)
+			}
+		}
+		// 同一位置长词优先, 一次替换, 替换结果不会再被替换
+		slices.SortFunc(froms, func(a, b string) int { return len(b) - len(a) })
+		oldnew := make([]string, 0, 2*len(froms))
+		for _, from := range froms {
+			oldnew = append(oldnew, from, replacements[from])
+		}
+		text = strings.NewReplacer(oldnew...).Replace(text)
+	}
+
+	for _, hotword := range hotwords {
+		phrase := strings.TrimSpace(hotword.Phrase)
+		if phrase == "" {
+			continue
+		}
+		if isHanPhrase(phrase) {
+			if dict != nil {
+				text = fuzzyReplaceHan(text, phrase, dict)
+			}
+			continue
+		}
+		text = replaceFold(text, phrase)
+	}
+	return text
+}
+
+func isHanPhrase(phrase string) bool {
+	for _, r := range phrase {
+		if !unicode.Is(unicode.Han, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// fuzzyReplaceHan 将与热词逐字模糊音相同的中文片段替换为热词
+func fuzzyReplaceHan(text, phrase string, dict *PinyinDict) string {
+	target := []rune(phrase)
+	if len(target) < minFuzzyHotwordRunes {
+		return text
+	}
+	runes := []rune(text)
+	for i := 0; i+len(target) <= len(runes); i++ {
+		matched := true
+		for j, r := range target {
+			if !dict.FuzzyEqual(runes[i+j], r) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			copy(runes[i:], target)
+			i += len(target) - 1
+		}
+	}
+	return string(runes)
+}
+
+// replaceFold 忽略大小写按单词边界匹配英文热词, 统一为热词的写法(如 openai -> OpenAI)
+func replaceFold(text, phrase string) string {
+	lowerText, lowerPhrase := strings.ToLower(text), strings.ToLower(phrase)
+	// 仅大小写不变的文本(ASCII)才能按字节位置替换
+	if len(lowerText) != len(text) || len(lowerPhrase) != len(phrase) {
+		return text
+	}
+
+	var sb strings.Builder
+	start := 0
+	for {
+		idx := strings.Index(lowerText[start:], lowerPhrase)
+		if idx < 0 {
+			break
+		}
+		idx += start
+		end := idx + len(phrase)
+		sb.WriteString(text[start:idx])
+		if isWordBoundary(text, idx-1) && isWordBoundary(text, end) {
+			sb.WriteString(phrase)
+		} else {
+			sb.WriteString(text[idx:end])
+		}
+		start = end
+	}
+	sb.WriteString(text[start:])
+	return sb.String()
+}
+
+func isWordBoundary(text string, i int) bool {
+	if i < 0 || i >= len(text) {
+		return true
+	}
+	c := text[i]
+	return !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9')
+}
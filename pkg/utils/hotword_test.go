@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"achatbot/pkg/types"
+)
+
+func TestPinyinDictLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pinyin.txt")
+	content := "# pinyin-data\nU+4E2D: zhōng,zhòng  # 中\nU+5B97: zōng  # 宗\n"
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+
+	dict := NewPinyinDict()
+	assert.NoError(t, dict.Load(path))
+	assert.Equal(t, 2, dict.Len())
+	assert.Equal(t, []string{"zhong"}, dict.Readings('中'))
+	// 平翘舌模糊音
+	assert.True(t, dict.FuzzyEqual('中', '宗'))
+
+	assert.NoError(t, os.WriteFile(path, []byte("bad line\n"), 0o644))
+	assert.Error(t, dict.Load(path))
+}
+
+func TestFuzzyPinyin(t *testing.T) {
+	assert.Equal(t, "lv", StripPinyinTone("lǜ"))
+	assert.Equal(t, "zhong", StripPinyinTone("zhong1"))
+	assert.Equal(t, FuzzyPinyin("shan"), FuzzyPinyin("sang"))
+	assert.Equal(t, FuzzyPinyin("nan"), FuzzyPinyin("lan"))
+	assert.NotEqual(t, FuzzyPinyin("ba"), FuzzyPinyin("pa"))
+}
+
+func TestCorrectHotwords(t *testing.T) {
+	dict := NewPinyinDict()
+	dict.Add('智', "zhì")
+	dict.Add('谱', "pǔ")
+	dict.Add('至', "zhì")
+	dict.Add('普', "pǔ")
+	dict.Add('八', "bā")
+
+	hotwords := []types.Hotword{{Phrase: "智谱", Boost: 2}, {Phrase: "OpenAI"}}
+	replacements := map[string]string{"蓝天": "Lan Tian", "蓝": "澜"}
+
+	// 拼音模糊匹配
+	assert.Equal(t, "智谱的模型", CorrectHotwords("至普的模型", hotwords, nil, dict))
+	// 读音不同不替换
+	assert.Equal(t, "八普的模型", CorrectHotwords("八普的模型", hotwords, nil, dict))
+	// 没有拼音词典只做替换词典和英文热词
+	assert.Equal(t, "至普的模型", CorrectHotwords("至普的模型", hotwords, nil, nil))
+	// 英文热词按单词边界统一写法
+	assert.Equal(t, "用 OpenAI 的接口, openaix", CorrectHotwords("用 openai 的接口, openaix", hotwords, nil, nil))
+	// 替换词典长词优先
+	assert.Equal(t, "Lan Tian和澜色", CorrectHotwords("蓝天和蓝色", hotwords, replacements, dict))
+}
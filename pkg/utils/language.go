@@ -0,0 +1,85 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+
+	"achatbot/pkg/types"
+)
+
+// runeLanguage 返回字符所属语种, 数字/空白/标点等中性字符返回空
+func runeLanguage(r rune) string {
+	switch {
+	case unicode.Is(unicode.Han, r):
+		return types.LanguageZh
+	case r < unicode.MaxLatin1 && unicode.IsLetter(r):
+		return types.LanguageEn
+	default:
+		return ""
+	}
+}
+
+// DetectTextLanguage 按音节数估计文本主要语种(一个汉字约一个音节, 一个英文单词约 1.5 个音节), 无法判断返回空
+func DetectTextLanguage(text string) string {
+	zh, en := 0.0, 0.0
+	prev := ""
+	for _, r := range text {
+		lang := runeLanguage(r)
+		switch {
+		case lang == types.LanguageZh:
+			zh++
+		case lang == types.LanguageEn && prev != types.LanguageEn:
+			en += 1.5
+		}
+		prev = lang
+	}
+	switch {
+	case zh == 0 && en == 0:
+		return ""
+	case zh >= en:
+		return types.LanguageZh
+	default:
+		return types.LanguageEn
+	}
+}
+
+// SplitTextByLanguage 将中英混合文本按语种切分为连续片段, 中性字符归入当前片段;
+// 夹在中文中的少于 minWords 个英文单词(如 "用 iPhone 拍照")并入中文片段, 避免频繁切换音色
+func SplitTextByLanguage(text string, minWords int) []types.LanguageSegment {
+	segments := make([]types.LanguageSegment, 0)
+	var cur strings.Builder
+	curLang := ""
+	for _, r := range text {
+		lang := runeLanguage(r)
+		if lang != "" && curLang != "" && lang != curLang {
+			segments = append(segments, types.LanguageSegment{Text: cur.String(), Language: curLang})
+			cur.Reset()
+		}
+		if lang != "" {
+			curLang = lang
+		}
+		cur.WriteRune(r)
+	}
+	if cur.Len() > 0 {
+		segments = append(segments, types.LanguageSegment{Text: cur.String(), Language: curLang})
+	}
+
+	// 合并短英文片段到相邻中文片段
+	merged := make([]types.LanguageSegment, 0, len(segments))
+	for i, seg := range segments {
+		short := seg.Language == types.LanguageEn && len(strings.Fields(seg.Text)) < minWords
+		nextZh := i+1 < len(segments) && segments[i+1].Language == types.LanguageZh
+		prevZh := len(merged) > 0 && merged[len(merged)-1].Language == types.LanguageZh
+		switch {
+		case short && prevZh:
+			merged[len(merged)-1].Text += seg.Text
+		case short && nextZh:
+			segments[i+1].Text = seg.Text + segments[i+1].Text
+		case len(merged) > 0 && merged[len(merged)-1].Language == seg.Language:
+			merged[len(merged)-1].Text += seg.Text
+		default:
+			merged = append(merged, seg)
+		}
+	}
+	return merged
+}
@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"achatbot/pkg/types"
+)
+
+func TestDetectTextLanguage(t *testing.T) {
+	assert.Equal(t, types.LanguageZh, DetectTextLanguage("你好, world"))
+	assert.Equal(t, types.LanguageEn, DetectTextLanguage("hello world, 你好"))
+	assert.Equal(t, "", DetectTextLanguage("123, ..."))
+}
+
+func TestSplitTextByLanguage(t *testing.T) {
+	segments := SplitTextByLanguage("今天天气不错. The weather is nice today! 我们出去走走吧", 2)
+	assert.Equal(t, []types.LanguageSegment{
+		{Text: "今天天气不错. ", Language: types.LanguageZh},
+		{Text: "The weather is nice today! ", Language: types.LanguageEn},
+		{Text: "我们出去走走吧", Language: types.LanguageZh},
+	}, segments)
+
+	// 短英文单词并入中文
+	segments = SplitTextByLanguage("我用 iPhone 拍照", 2)
+	assert.Equal(t, []types.LanguageSegment{{Text: "我用 iPhone 拍照", Language: types.LanguageZh}}, segments)
+	segments = SplitTextByLanguage("OK 我知道了", 2)
+	assert.Equal(t, []types.LanguageSegment{{Text: "OK 我知道了", Language: types.LanguageZh}}, segments)
+
+	segments = SplitTextByLanguage("hello", 2)
+	assert.Equal(t, []types.LanguageSegment{{Text: "hello", Language: types.LanguageEn}}, segments)
+	assert.Empty(t, SplitTextByLanguage("", 2))
+}
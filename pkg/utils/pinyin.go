@@ -0,0 +1,134 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// PinyinDict 汉字 -> 拼音(无声调, 多音字有多个读音)
+type PinyinDict struct {
+	readings map[rune][]string
+}
+
+// NewPinyinDict 创建空的拼音词典, 通过 Add/Load 添加读音
+func NewPinyinDict() *PinyinDict {
+	return &PinyinDict{readings: make(map[rune][]string)}
+}
+
+// Add 添加汉字读音, 读音可带声调符号或数字声调(如 zhōng, zhong1)
+func (d *PinyinDict) Add(r rune, readings ...string) {
+	for _, reading := range readings {
+		syllable := StripPinyinTone(reading)
+		if syllable == "" {
+			continue
+		}
+		exists := false
+		for _, s := range d.readings[r] {
+			if s == syllable {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			d.readings[r] = append(d.readings[r], syllable)
+		}
+	}
+}
+
+// Load 加载 pinyin-data 格式的拼音文件, 每行如: U+4E2D: zhōng,zhòng  # 中
+// https://github.com/mozillazg/pinyin-data
+func (d *PinyinDict) Load(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := scanner.Text()
+		if idx := strings.Index(line, "#"); idx >= 0 {
+			line = line[:idx]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		code, readings, ok := strings.Cut(line, ":")
+		if !ok || !strings.HasPrefix(code, "U+") {
+			return fmt.Errorf("invalid pinyin line %d: %s", lineNo, line)
+		}
+		codePoint, err := strconv.ParseUint(strings.TrimSpace(code[2:]), 16, 32)
+		if err != nil {
+			return fmt.Errorf("invalid pinyin line %d: %w", lineNo, err)
+		}
+		d.Add(rune(codePoint), strings.Split(strings.TrimSpace(readings), ",")...)
+	}
+	return scanner.Err()
+}
+
+// Len 返回收录的汉字数
+func (d *PinyinDict) Len() int {
+	return len(d.readings)
+}
+
+// Readings 返回汉字的无声调读音, 未收录返回 nil
+func (d *PinyinDict) Readings(r rune) []string {
+	return d.readings[r]
+}
+
+// FuzzyEqual 两个汉字是否有模糊音相同的读音, 未收录的字只与自身相同
+func (d *PinyinDict) FuzzyEqual(a, b rune) bool {
+	if a == b {
+		return true
+	}
+	for _, x := range d.readings[a] {
+		for _, y := range d.readings[b] {
+			if FuzzyPinyin(x) == FuzzyPinyin(y) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+var pinyinToneReplacer = strings.NewReplacer(
+	"ā", "a", "á", "a", "ǎ", "a", "à", "a",
+	"ō", "o", "ó", "o", "ǒ", "o", "ò", "o",
+	"ē", "e", "é", "e", "ě", "e", "è", "e", "ê", "e",
+	"ī", "i", "í", "i", "ǐ", "i", "ì", "i",
+	"ū", "u", "ú", "u", "ǔ", "u", "ù", "u",
+	"ǖ", "v", "ǘ", "v", "ǚ", "v", "ǜ", "v", "ü", "v",
+	"ń", "n", "ň", "n", "ǹ", "n", "ḿ", "m",
+)
+
+// StripPinyinTone 去掉拼音的声调符号和数字声调, 转为小写, ü 记为 v
+func StripPinyinTone(syllable string) string {
+	syllable = pinyinToneReplacer.Replace(strings.ToLower(strings.TrimSpace(syllable)))
+	return strings.TrimRightFunc(syllable, unicode.IsDigit)
+}
+
+// 常见模糊音: 平翘舌 z/zh c/ch s/sh, 鼻边音 n/l, 唇齿音 f/h; 前后鼻音 an/ang en/eng in/ing
+var fuzzyInitials = [][2]string{{"zh", "z"}, {"ch", "c"}, {"sh", "s"}, {"l", "n"}, {"h", "f"}}
+var fuzzyFinals = [][2]string{{"ang", "an"}, {"eng", "en"}, {"ing", "in"}}
+
+// FuzzyPinyin 将无声调拼音归一化为模糊音, 模糊音相同的拼音视为相同
+func FuzzyPinyin(syllable string) string {
+	for _, pair := range fuzzyInitials {
+		if strings.HasPrefix(syllable, pair[0]) {
+			syllable = pair[1] + syllable[len(pair[0]):]
+			break
+		}
+	}
+	for _, pair := range fuzzyFinals {
+		if strings.HasSuffix(syllable, pair[0]) {
+			syllable = syllable[:len(syllable)-len(pair[0])] + pair[1]
+			break
+		}
+	}
+	return syllable
+}
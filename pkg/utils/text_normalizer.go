@@ -0,0 +1,609 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"achatbot/pkg/types"
+)
+
+// TextNormalizer 合成前的文本规整: 去掉 markdown, 链接, emoji, 按自定义发音词典替换,
+// 将数字, 货币, 日期, 时间, 单位和缩写展开为可读的文字。
+// TTS 标记(见 TTSMarkupParser)原样保留, say-as/spell 中的文本不规整;
+// 代码块可跨多个句子(TextFrame), 代码块中的文本整体丢弃, 直到闭合或 Reset
+type TextNormalizer struct {
+	// 固定语种(zh/en), 为空时按文本自动判断, 中英混合文本分段规整
+	language string
+	// 自定义发音词典, 如 achatbot -> a chat bot
+	lexicon       map[string]string
+	lexiconRegexp *regexp.Regexp
+
+	inCodeBlock  bool
+	spellDepth   int
+	lastLanguage string
+}
+
+func NewTextNormalizer(language string) *TextNormalizer {
+	return &TextNormalizer{language: language, lexicon: make(map[string]string)}
+}
+
+// WithLexicon 添加自定义发音, 已有的词覆盖
+func (n *TextNormalizer) WithLexicon(lexicon map[string]string) *TextNormalizer {
+	for word, pronunciation := range lexicon {
+		if word = strings.TrimSpace(word); word != "" {
+			n.lexicon[word] = strings.TrimSpace(pronunciation)
+		}
+	}
+	n.lexiconRegexp = wordsRegexp(n.lexicon)
+	return n
+}
+
+// LoadLexicon 加载发音词典文件, 每行如: achatbot=a chat bot, # 开头为注释
+func (n *TextNormalizer) LoadLexicon(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	lexicon := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		word, pronunciation, ok := strings.Cut(line, "=")
+		if !ok || strings.TrimSpace(word) == "" {
+			return fmt.Errorf("invalid lexicon line %d: %s", lineNo, line)
+		}
+		lexicon[word] = pronunciation
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	n.WithLexicon(lexicon)
+	return nil
+}
+
+// Reset 清除跨句子的状态(如一轮回复结束或被打断)
+func (n *TextNormalizer) Reset() {
+	n.inCodeBlock = false
+	n.spellDepth = 0
+	n.lastLanguage = ""
+}
+
+// Normalize 规整一段文本, 返回的文本可能为空(如整句都是代码或 emoji)
+func (n *TextNormalizer) Normalize(text string) string {
+	language := n.language
+	if language == "" {
+		language = DetectTextLanguage(ttsMarkupTagRegexp.ReplaceAllString(text, ""))
+		if language == "" {
+			language = n.lastLanguage
+		}
+		if language == "" {
+			language = types.LanguageZh
+		}
+		n.lastLanguage = language
+	}
+
+	var sb strings.Builder
+	pos := 0
+	for _, loc := range ttsMarkupTagRegexp.FindAllStringSubmatchIndex(text, -1) {
+		sb.WriteString(n.normalizePiece(text[pos:loc[0]], language))
+		pos = loc[1]
+		if n.inCodeBlock {
+			continue
+		}
+		sb.WriteString(text[loc[0]:loc[1]])
+
+		tag := strings.ToLower(text[loc[4]:loc[5]])
+		isClose, isSelfClose := loc[3] > loc[2], loc[9] > loc[8]
+		if (tag != "say-as" && tag != "spell") || isSelfClose {
+			continue
+		}
+		if isClose {
+			n.spellDepth = max(n.spellDepth-1, 0)
+		} else {
+			n.spellDepth++
+		}
+	}
+	sb.WriteString(n.normalizePiece(text[pos:], language))
+	return strings.TrimSpace(sb.String())
+}
+
+// normalizePiece 规整两个标记之间的文本
+func (n *TextNormalizer) normalizePiece(text, language string) string {
+	text = n.stripCodeBlocks(text)
+	if text == "" || n.spellDepth > 0 {
+		return text
+	}
+
+	text = StripMarkdown(text)
+	text = textURLRegexp.ReplaceAllStringFunc(text, func(url string) string {
+		return spokenURL(url, language)
+	})
+	text = StripEmoji(text)
+	if n.lexiconRegexp != nil {
+		text = n.lexiconRegexp.ReplaceAllStringFunc(text, func(word string) string {
+			return n.lexicon[word]
+		})
+	}
+
+	if n.language != "" {
+		text = normalizeLanguageText(text, n.language)
+	} else {
+		var sb strings.Builder
+		for _, segment := range SplitTextByLanguage(text, 2) {
+			segmentLanguage := segment.Language
+			if segmentLanguage == "" {
+				segmentLanguage = language
+			}
+			sb.WriteString(normalizeLanguageText(segment.Text, segmentLanguage))
+		}
+		text = sb.String()
+	}
+	text = textSpacesRegexp.ReplaceAllString(text, " ")
+	return textPunctSpacesRegexp.ReplaceAllString(text, "$1")
+}
+
+// stripCodeBlocks 丢弃 ``` 代码块中的文本
+func (n *TextNormalizer) stripCodeBlocks(text string) string {
+	var sb strings.Builder
+	for {
+		idx := strings.Index(text, "```")
+		if !n.inCodeBlock {
+			if idx < 0 {
+				sb.WriteString(text)
+				return sb.String()
+			}
+			sb.WriteString(text[:idx])
+		} else if idx < 0 {
+			return sb.String()
+		}
+		n.inCodeBlock = !n.inCodeBlock
+		text = text[idx+3:]
+	}
+}
+
+// ------------------------------------------------------------
+
+var (
+	markdownRuleRegexp       = regexp.MustCompile(`(?m)^[\s:|-]*-{3,}[\s:|-]*$|^\s*(?:\*{3,}|_{3,})\s*$`)
+	markdownImageRegexp      = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	markdownLinkRegexp       = regexp.MustCompile(`\[([^\]]+)\]\([^)]*\)`)
+	markdownInlineCodeRegexp = regexp.MustCompile("`([^`]*)`")
+	markdownHeaderRegexp     = regexp.MustCompile(`(?m)^\s{0,3}#{1,6}\s+`)
+	markdownQuoteRegexp      = regexp.MustCompile(`(?m)^\s*>\s?`)
+	markdownListRegexp       = regexp.MustCompile(`(?m)^\s*(?:[-*+]|\d+[.)])\s+`)
+	markdownItalicRegexp     = regexp.MustCompile(`(^|[^\w*])\*([^*\n]+)\*`)
+	markdownEmphasisReplacer = strings.NewReplacer("**", "", "__", "", "~~", "", "|", " ", "`", "")
+
+	textURLRegexp    = regexp.MustCompile(`(?i)\b(?:https?://|www\.)[^\s<>()（）"'，。]+`)
+	textSpacesRegexp = regexp.MustCompile(`\s+`)
+	// 展开后标点前多余的空格
+	textPunctSpacesRegexp = regexp.MustCompile(`\s+([,.!?;:，。！？；：、])`)
+)
+
+// StripMarkdown 去掉 markdown 格式, 保留文字: 标题, 引用, 列表符号, 加粗/斜体/删除线, 表格线;
+// 链接和图片保留文字, 行内代码保留内容
+func StripMarkdown(text string) string {
+	text = markdownRuleRegexp.ReplaceAllString(text, "")
+	text = markdownImageRegexp.ReplaceAllString(text, "$1")
+	text = markdownLinkRegexp.ReplaceAllString(text, "$1")
+	text = markdownInlineCodeRegexp.ReplaceAllString(text, "$1")
+	text = markdownHeaderRegexp.ReplaceAllString(text, "")
+	text = markdownQuoteRegexp.ReplaceAllString(text, "")
+	text = markdownListRegexp.ReplaceAllString(text, "")
+	text = markdownEmphasisReplacer.Replace(text)
+	return markdownItalicRegexp.ReplaceAllString(text, "$1$2")
+}
+
+// StripEmoji 去掉 emoji 及其变体选择符, 连接符
+func StripEmoji(text string) string {
+	return strings.Map(func(r rune) rune {
+		if isEmoji(r) {
+			return -1
+		}
+		return r
+	}, text)
+}
+
+func isEmoji(r rune) bool {
+	switch {
+	case r >= 0x1F000 && r <= 0x1FAFF, // emoticons, symbols & pictographs, flags
+		r >= 0x2600 && r <= 0x27BF, // misc symbols, dingbats
+		r >= 0x2B00 && r <= 0x2BFF, // arrows, stars
+		r >= 0xFE00 && r <= 0xFE0F, // variation selectors
+		r == 0x200D, r == 0x20E3:   // zero width joiner, keycap
+		return true
+	}
+	return false
+}
+
+// spokenURL 链接只读出域名, 如 https://www.example.com/a -> example dot com
+func spokenURL(url, language string) string {
+	// 句末标点不属于链接
+	host := strings.TrimRight(url, ".,;:!?")
+	punct := url[len(host):]
+	if _, rest, ok := strings.Cut(host, "://"); ok {
+		host = rest
+	}
+	host, _, _ = strings.Cut(host, "/")
+	host, _, _ = strings.Cut(host, "?")
+	host = strings.TrimPrefix(strings.ToLower(host), "www.")
+	dot := " dot "
+	if language == types.LanguageZh {
+		dot = "点"
+	}
+	return " " + strings.ReplaceAll(host, ".", dot) + punct + " "
+}
+
+// wordsRegexp 匹配词典中的词(长词优先), 英文词按单词边界匹配
+func wordsRegexp(words map[string]string) *regexp.Regexp {
+	if len(words) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(words))
+	for word := range words {
+		keys = append(keys, word)
+	}
+	slices.SortFunc(keys, func(a, b string) int { return len(b) - len(a) })
+
+	patterns := make([]string, 0, len(keys))
+	for _, word := range keys {
+		pattern := regexp.QuoteMeta(word)
+		if !isWordBoundary(word, 0) {
+			pattern = `\b` + pattern
+		}
+		if !isWordBoundary(word, len(word)-1) {
+			pattern += `\b`
+		}
+		patterns = append(patterns, pattern)
+	}
+	return regexp.MustCompile(strings.Join(patterns, "|"))
+}
+
+// ------------------------------------------------------------
+
+// textRule 按语种展开匹配的文本, 返回 match[0] 表示不处理
+type textRule struct {
+	regexp *regexp.Regexp
+	zh     func(match []string) string
+	en     func(match []string) string
+}
+
+const (
+	textNumberExpr    = `(?:\d{1,3}(?:,\d{3})+(?:\.\d+)?|\d+(?:\.\d+)?)`
+	textNumberPattern = `(` + textNumberExpr + `)`
+)
+
+type textUnit struct {
+	zh, en, enPlural string
+}
+
+var textCurrencies = map[string]textUnit{
+	"$": {"美元", "dollar", "dollars"},
+	"¥": {"元", "yuan", "yuan"},
+	"￥": {"元", "yuan", "yuan"},
+	"€": {"欧元", "euro", "euros"},
+	"£": {"英镑", "pound", "pounds"},
+}
+
+var textUnits = map[string]textUnit{
+	"km/h": {"公里每小时", "kilometer per hour", "kilometers per hour"},
+	"km":   {"公里", "kilometer", "kilometers"},
+	"kg":   {"千克", "kilogram", "kilograms"},
+	"cm":   {"厘米", "centimeter", "centimeters"},
+	"mm":   {"毫米", "millimeter", "millimeters"},
+	"mg":   {"毫克", "milligram", "milligrams"},
+	"ml":   {"毫升", "milliliter", "milliliters"},
+	"kHz":  {"千赫兹", "kilohertz", "kilohertz"},
+	"Hz":   {"赫兹", "hertz", "hertz"},
+	"kW":   {"千瓦", "kilowatt", "kilowatts"},
+	"TB":   {"T", "terabyte", "terabytes"},
+	"GB":   {"G", "gigabyte", "gigabytes"},
+	"MB":   {"兆", "megabyte", "megabytes"},
+	"KB":   {"K", "kilobyte", "kilobytes"},
+	"°C":   {"摄氏度", "degree Celsius", "degrees Celsius"},
+	"℃":    {"摄氏度", "degree Celsius", "degrees Celsius"},
+	"°F":   {"华氏度", "degree Fahrenheit", "degrees Fahrenheit"},
+}
+
+var textAbbreviations = map[string]map[string]string{
+	types.LanguageZh: {
+		"e.g.": "例如", "i.e.": "即", "etc.": "等等", "vs.": "对", "&": "和",
+		"+": "加", "×": "乘", "÷": "除以", "=": "等于", "≈": "约等于",
+	},
+	types.LanguageEn: {
+		"Dr.": "Doctor", "Mr.": "Mister", "Mrs.": "Missus", "Ms.": "Miss", "Prof.": "Professor",
+		"e.g.": "for example", "i.e.": "that is", "etc.": "et cetera", "vs.": "versus",
+		"approx.": "approximately", "&": "and", "w/": "with",
+		"+": "plus", "×": "times", "÷": "divided by", "=": "equals", "≈": "approximately equals",
+	},
+}
+
+var textAbbreviationRegexps = map[string]*regexp.Regexp{
+	types.LanguageZh: wordsRegexp(textAbbreviations[types.LanguageZh]),
+	types.LanguageEn: wordsRegexp(textAbbreviations[types.LanguageEn]),
+}
+
+// 量词前的 2 读作 两
+var zhLiangRegexp = regexp.MustCompile(`(^|[^\d.,])2(个|只|本|位|次|天|种|条|张|件|份|台|辆|家|岁|倍|年|周|小时|分钟)`)
+
+var textRules []textRule
+
+func init() {
+	unitNames := make([]string, 0, len(textUnits))
+	for unit := range textUnits {
+		unitNames = append(unitNames, regexp.QuoteMeta(unit))
+	}
+	slices.SortFunc(unitNames, func(a, b string) int { return len(b) - len(a) })
+
+	textRules = []textRule{
+		// 日期 2024-05-01, 2024/5/1, 2024.05.01
+		{
+			regexp: regexp.MustCompile(`\b(\d{4})[-/.](\d{1,2})[-/.](\d{1,2})\b`),
+			zh: func(m []string) string {
+				month, day, ok := parseMonthDay(m[2], m[3])
+				if !ok {
+					return m[0]
+				}
+				return zhDigitString(m[1]) + "年" + zhInteger(month) + "月" + zhInteger(day) + "日"
+			},
+			en: func(m []string) string {
+				month, day, ok := parseMonthDay(m[2], m[3])
+				if !ok {
+					return m[0]
+				}
+				year, _ := strconv.ParseInt(m[1], 10, 64)
+				return enMonths[month] + " " + enOrdinal(day) + ", " + enYear(year)
+			},
+		},
+		// 年份 2024年
+		{
+			regexp: regexp.MustCompile(`(\d{4})年`),
+			zh:     func(m []string) string { return zhDigitString(m[1]) + "年" },
+			en:     func(m []string) string { return m[0] },
+		},
+		// 时间 10:30, 8:05:30 pm
+		{
+			regexp: regexp.MustCompile(`\b(\d{1,2}):(\d{2})(?::(\d{2}))?(?:\s?([aApP])\.?[mM]\b\.?)?`),
+			zh:     zhTime,
+			en:     enTime,
+		},
+		// 货币 $12.50, ￥100
+		{
+			regexp: regexp.MustCompile(`([$¥￥€£])\s?` + textNumberPattern),
+			zh: func(m []string) string {
+				amount := m[2]
+				if strings.Contains(amount, ".") {
+					amount = strings.TrimSuffix(strings.TrimRight(amount, "0"), ".")
+				}
+				return zhNumber(amount) + textCurrencies[m[1]].zh
+			},
+			en: enCurrency,
+		},
+		// 百分比 50%
+		{
+			regexp: regexp.MustCompile(textNumberPattern + `\s?[%％]`),
+			zh:     func(m []string) string { return "百分之" + zhNumber(m[1]) },
+			en:     func(m []string) string { return enNumber(m[1]) + " percent" },
+		},
+		// 单位 5km, 3-5km, -5 °C
+		{
+			regexp: regexp.MustCompile(`([-−]?` + textNumberExpr + `)(?:[-~～]` + textNumberPattern + `)?\s?(` +
+				strings.Join(unitNames, "|") + `)([^A-Za-z]|$)`),
+			zh: func(m []string) string {
+				words := zhNumber(m[1])
+				if m[2] != "" {
+					words += "到" + zhNumber(m[2])
+				}
+				return words + textUnits[m[3]].zh + m[4]
+			},
+			en: func(m []string) string {
+				unit, number := textUnits[m[3]], m[1]
+				words := enNumber(number)
+				if m[2] != "" {
+					words += " to " + enNumber(m[2])
+					number = m[2]
+				}
+				return words + " " + enPlural(number, unit.en, unit.enPlural) + m[4]
+			},
+		},
+		// 减法 5 - 3
+		{
+			regexp: regexp.MustCompile(textNumberPattern + `\s+[-−]\s+` + textNumberPattern),
+			zh:     func(m []string) string { return zhNumber(m[1]) + "减" + zhNumber(m[2]) },
+			en:     func(m []string) string { return enNumber(m[1]) + " minus " + enNumber(m[2]) },
+		},
+		// 范围 3-5, 10~20
+		{
+			regexp: regexp.MustCompile(textNumberPattern + `(?:-|\s?[~～–]\s?)` + textNumberPattern),
+			zh:     func(m []string) string { return zhNumber(m[1]) + "到" + zhNumber(m[2]) },
+			en:     func(m []string) string { return enNumber(m[1]) + " to " + enNumber(m[2]) },
+		},
+		// 序数 1st, 22nd
+		{
+			regexp: regexp.MustCompile(`\b(\d+)(?:st|nd|rd|th)\b`),
+			zh: func(m []string) string {
+				return "第" + zhNumber(m[1])
+			},
+			en: func(m []string) string {
+				n, err := strconv.ParseInt(m[1], 10, 64)
+				if err != nil || readDigitByDigit(m[1]) {
+					return m[0]
+				}
+				return enOrdinal(n)
+			},
+		},
+		// 编号 No. 5
+		{
+			regexp: regexp.MustCompile(`\bNo\.\s?(\d+)`),
+			zh:     func(m []string) string { return zhNumber(m[1]) + "号" },
+			en:     func(m []string) string { return "number " + enNumber(m[1]) },
+		},
+		// 负数 -5
+		{
+			regexp: regexp.MustCompile(`(^|[\s(（:：,，=])[-−]` + textNumberPattern),
+			zh:     func(m []string) string { return m[1] + "负" + zhNumber(m[2]) },
+			en:     func(m []string) string { return m[1] + "minus " + enNumber(m[2]) },
+		},
+		// 其他数字
+		{
+			regexp: regexp.MustCompile(textNumberPattern),
+			zh:     func(m []string) string { return zhNumber(m[1]) },
+			en:     func(m []string) string { return " " + enNumber(m[1]) + " " },
+		},
+	}
+}
+
+// normalizeLanguageText 按语种展开缩写, 数字, 货币, 日期, 时间和单位
+func normalizeLanguageText(text, language string) string {
+	if language == types.LanguageZh {
+		text = zhLiangRegexp.ReplaceAllString(text, "${1}两$2")
+	}
+	for _, rule := range textRules {
+		expand := rule.en
+		if language == types.LanguageZh {
+			expand = rule.zh
+		}
+		text = replaceAllSubmatchFunc(rule.regexp, text, expand)
+	}
+
+	if language != types.LanguageZh {
+		language = types.LanguageEn
+	}
+	abbreviations := textAbbreviations[language]
+	return textAbbreviationRegexps[language].ReplaceAllStringFunc(text, func(s string) string {
+		if language == types.LanguageEn {
+			return " " + abbreviations[s] + " "
+		}
+		return abbreviations[s]
+	})
+}
+
+// replaceAllSubmatchFunc 同 ReplaceAllStringFunc, 替换函数的参数为子匹配
+func replaceAllSubmatchFunc(re *regexp.Regexp, text string, repl func(match []string) string) string {
+	var sb strings.Builder
+	pos := 0
+	for _, loc := range re.FindAllStringSubmatchIndex(text, -1) {
+		match := make([]string, len(loc)/2)
+		for i := range match {
+			if loc[2*i] >= 0 {
+				match[i] = text[loc[2*i]:loc[2*i+1]]
+			}
+		}
+		sb.WriteString(text[pos:loc[0]])
+		sb.WriteString(repl(match))
+		pos = loc[1]
+	}
+	sb.WriteString(text[pos:])
+	return sb.String()
+}
+
+func parseMonthDay(monthStr, dayStr string) (int64, int64, bool) {
+	month, _ := strconv.ParseInt(monthStr, 10, 64)
+	day, _ := strconv.ParseInt(dayStr, 10, 64)
+	return month, day, month >= 1 && month <= 12 && day >= 1 && day <= 31
+}
+
+func parseClock(m []string) (hour, minute, second int64, ok bool) {
+	hour, _ = strconv.ParseInt(m[1], 10, 64)
+	minute, _ = strconv.ParseInt(m[2], 10, 64)
+	if m[3] != "" {
+		second, _ = strconv.ParseInt(m[3], 10, 64)
+	}
+	return hour, minute, second, hour <= 24 && minute < 60 && second < 60
+}
+
+func zhTime(m []string) string {
+	hour, minute, second, ok := parseClock(m)
+	if !ok {
+		return m[0]
+	}
+	var sb strings.Builder
+	switch strings.ToLower(m[4]) {
+	case "a":
+		sb.WriteString("上午")
+	case "p":
+		sb.WriteString("下午")
+	}
+	if hour == 2 {
+		sb.WriteString("两点")
+	} else {
+		sb.WriteString(zhInteger(hour) + "点")
+	}
+	switch {
+	case minute == 0 && second == 0:
+		sb.WriteString("整")
+	case minute < 10:
+		sb.WriteString("零" + zhInteger(minute) + "分")
+	default:
+		sb.WriteString(zhInteger(minute) + "分")
+	}
+	if second > 0 {
+		sb.WriteString(zhInteger(second) + "秒")
+	}
+	return sb.String()
+}
+
+// enCurrency 美元/欧元读作 dollars and cents, 其他货币按小数读出
+func enCurrency(m []string) string {
+	currency := textCurrencies[m[1]]
+	integer, fraction := splitNumber(m[2])
+	if fraction == "" {
+		return enNumber(integer) + " " + enPlural(integer, currency.en, currency.enPlural)
+	}
+	if m[1] != "$" && m[1] != "€" {
+		return enNumber(m[2]) + " " + currency.enPlural
+	}
+
+	words := enNumber(integer) + " " + enPlural(integer, currency.en, currency.enPlural)
+	cents, _ := strconv.ParseInt((fraction + "0")[:2], 10, 64)
+	if cents == 0 {
+		return words
+	}
+	centWords := enInteger(cents) + " " + enPlural(strconv.FormatInt(cents, 10), "cent", "cents")
+	if strings.TrimLeft(integer, "0") == "" {
+		return centWords
+	}
+	return words + " and " + centWords
+}
+
+func enTime(m []string) string {
+	hour, minute, second, ok := parseClock(m)
+	if !ok {
+		return m[0]
+	}
+	words := []string{enInteger(hour)}
+	switch {
+	case minute == 0 && m[4] == "":
+		words = append(words, "o'clock")
+	case minute == 0:
+	case minute < 10:
+		words = append(words, "oh", enOnes[minute])
+	default:
+		words = append(words, enInteger(minute))
+	}
+	if second > 0 {
+		words = append(words, "and", enInteger(second), enPlural(m[3], "second", "seconds"))
+	}
+	if m[4] != "" {
+		words = append(words, strings.ToUpper(m[4]), "M")
+	}
+	return " " + strings.Join(words, " ") + " "
+}
+
+// enPlural 数量为 1 时使用单数
+func enPlural(number, singular, plural string) string {
+	if strings.TrimLeft(number, "0") == "1" {
+		return singular
+	}
+	return plural
+}
@@ -0,0 +1,252 @@
+package utils
+
+import (
+	"strconv"
+	"strings"
+)
+
+// 超过此位数的整数(如电话, 订单号)逐位读出
+const maxSpokenIntegerDigits = 10
+
+var (
+	zhDigits       = []string{"零", "一", "二", "三", "四", "五", "六", "七", "八", "九"}
+	zhSmallUnits   = []string{"", "十", "百", "千"}
+	zhSectionUnits = []string{"", "万", "亿", "万亿"}
+
+	enOnes = []string{"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine",
+		"ten", "eleven", "twelve", "thirteen", "fourteen", "fifteen", "sixteen", "seventeen", "eighteen", "nineteen"}
+	enTens   = []string{"", "", "twenty", "thirty", "forty", "fifty", "sixty", "seventy", "eighty", "ninety"}
+	enScales = []struct {
+		value int64
+		name  string
+	}{{1e12, "trillion"}, {1e9, "billion"}, {1e6, "million"}, {1e3, "thousand"}}
+	enMonths = []string{"", "January", "February", "March", "April", "May", "June",
+		"July", "August", "September", "October", "November", "December"}
+	enIrregularOrdinals = map[string]string{
+		"one": "first", "two": "second", "three": "third", "five": "fifth",
+		"eight": "eighth", "nine": "ninth", "twelve": "twelfth",
+	}
+)
+
+// splitNumber 拆分数字字符串(可带千分位逗号)为整数部分和小数部分
+func splitNumber(s string) (string, string) {
+	s = strings.ReplaceAll(s, ",", "")
+	integer, fraction, _ := strings.Cut(s, ".")
+	return integer, fraction
+}
+
+// cutNumberSign 去掉负号, 返回是否为负数
+func cutNumberSign(s string) (string, bool) {
+	for _, minus := range []string{"-", "−"} {
+		if after, ok := strings.CutPrefix(s, minus); ok {
+			return after, true
+		}
+	}
+	return s, false
+}
+
+// readDigitByDigit 以 0 开头(如 007)或过长的整数逐位读出
+func readDigitByDigit(integer string) bool {
+	return len(integer) > maxSpokenIntegerDigits || (len(integer) > 1 && integer[0] == '0')
+}
+
+// ------------------------------------------------------------
+
+// zhDigitString 逐位读出, 如 2024 -> 二零二四
+func zhDigitString(s string) string {
+	var sb strings.Builder
+	for _, c := range s {
+		if c >= '0' && c <= '9' {
+			sb.WriteString(zhDigits[c-'0'])
+		}
+	}
+	return sb.String()
+}
+
+// zhSection 读出万以内的数, 如 1005 -> 一千零五
+func zhSection(n int64) string {
+	var sb strings.Builder
+	zero := false
+	for pos := 3; pos >= 0; pos-- {
+		d := n / int64Pow10(pos) % 10
+		if d == 0 {
+			zero = sb.Len() > 0
+			continue
+		}
+		if zero {
+			sb.WriteString("零")
+			zero = false
+		}
+		sb.WriteString(zhDigits[d] + zhSmallUnits[pos])
+	}
+	return sb.String()
+}
+
+// zhInteger 读出整数, 如 10 -> 十, 100010 -> 十万零一十
+func zhInteger(n int64) string {
+	if n == 0 {
+		return zhDigits[0]
+	}
+	if n < 0 {
+		return "负" + zhInteger(-n)
+	}
+
+	sections := make([]int64, 0, len(zhSectionUnits))
+	for ; n > 0 && len(sections) < len(zhSectionUnits); n /= 10000 {
+		sections = append(sections, n%10000)
+	}
+	var sb strings.Builder
+	zero := false
+	for i := len(sections) - 1; i >= 0; i-- {
+		section := sections[i]
+		if section == 0 {
+			zero = sb.Len() > 0
+			continue
+		}
+		if sb.Len() > 0 && (zero || section < 1000) {
+			sb.WriteString("零")
+		}
+		sb.WriteString(zhSection(section) + zhSectionUnits[i])
+		zero = false
+	}
+	// 一十五 -> 十五, 一十万 -> 十万
+	words := sb.String()
+	if strings.HasPrefix(words, "一十") {
+		words = strings.TrimPrefix(words, "一")
+	}
+	return words
+}
+
+// zhNumber 读出数字字符串, 如 1,234.5 -> 一千二百三十四点五, -5 -> 负五
+func zhNumber(s string) string {
+	if sign, ok := cutNumberSign(s); ok {
+		return "负" + zhNumber(sign)
+	}
+	integer, fraction := splitNumber(s)
+	words := zhDigitString(integer)
+	if !readDigitByDigit(integer) {
+		n, err := strconv.ParseInt(integer, 10, 64)
+		if err != nil {
+			return s
+		}
+		words = zhInteger(n)
+	}
+	if fraction != "" {
+		words += "点" + zhDigitString(fraction)
+	}
+	return words
+}
+
+// ------------------------------------------------------------
+
+// enDigitString 逐位读出, 如 007 -> zero zero seven
+func enDigitString(s string) string {
+	words := make([]string, 0, len(s))
+	for _, c := range s {
+		if c >= '0' && c <= '9' {
+			words = append(words, enOnes[c-'0'])
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// enHundreds 读出千以内的数, 如 121 -> one hundred twenty-one
+func enHundreds(n int64) string {
+	words := make([]string, 0, 3)
+	if n >= 100 {
+		words = append(words, enOnes[n/100], "hundred")
+		n %= 100
+	}
+	switch {
+	case n == 0:
+	case n < 20:
+		words = append(words, enOnes[n])
+	case n%10 == 0:
+		words = append(words, enTens[n/10])
+	default:
+		words = append(words, enTens[n/10]+"-"+enOnes[n%10])
+	}
+	return strings.Join(words, " ")
+}
+
+// enInteger 读出整数, 如 1200 -> one thousand two hundred
+func enInteger(n int64) string {
+	if n == 0 {
+		return enOnes[0]
+	}
+	if n < 0 {
+		return "minus " + enInteger(-n)
+	}
+
+	words := make([]string, 0, 5)
+	for _, scale := range enScales {
+		if n >= scale.value {
+			words = append(words, enInteger(n/scale.value)+" "+scale.name)
+			n %= scale.value
+		}
+	}
+	if n > 0 {
+		words = append(words, enHundreds(n))
+	}
+	return strings.Join(words, " ")
+}
+
+// enNumber 读出数字字符串, 如 3.14 -> three point one four, -5 -> minus five
+func enNumber(s string) string {
+	if sign, ok := cutNumberSign(s); ok {
+		return "minus " + enNumber(sign)
+	}
+	integer, fraction := splitNumber(s)
+	words := enDigitString(integer)
+	if !readDigitByDigit(integer) {
+		n, err := strconv.ParseInt(integer, 10, 64)
+		if err != nil {
+			return s
+		}
+		words = enInteger(n)
+	}
+	if fraction != "" {
+		words += " point " + enDigitString(fraction)
+	}
+	return words
+}
+
+// enOrdinal 读出序数词, 如 21 -> twenty-first
+func enOrdinal(n int64) string {
+	words := enInteger(n)
+	i := strings.LastIndexAny(words, " -") + 1
+	last := words[i:]
+	switch {
+	case enIrregularOrdinals[last] != "":
+		last = enIrregularOrdinals[last]
+	case strings.HasSuffix(last, "y"):
+		last = strings.TrimSuffix(last, "y") + "ieth"
+	default:
+		last += "th"
+	}
+	return words[:i] + last
+}
+
+// enYear 按年份习惯读出, 如 1999 -> nineteen ninety-nine, 2005 -> two thousand five, 2024 -> twenty twenty-four
+func enYear(year int64) string {
+	if year < 1000 || year >= 10000 || (year >= 2000 && year < 2010) {
+		return enInteger(year)
+	}
+	high, low := year/100, year%100
+	switch {
+	case low == 0:
+		return enInteger(high) + " hundred"
+	case low < 10:
+		return enInteger(high) + " oh " + enOnes[low]
+	default:
+		return enInteger(high) + " " + enInteger(low)
+	}
+}
+
+func int64Pow10(n int) int64 {
+	p := int64(1)
+	for range n {
+		p *= 10
+	}
+	return p
+}
@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"achatbot/pkg/types"
+)
+
+func TestNumberWords(t *testing.T) {
+	assert.Equal(t, "十", zhInteger(10))
+	assert.Equal(t, "一百一十五", zhInteger(115))
+	assert.Equal(t, "一千零五", zhInteger(1005))
+	assert.Equal(t, "十万零一十", zhInteger(100010))
+	assert.Equal(t, "一亿零二万", zhInteger(100020000))
+	assert.Equal(t, "一千二百三十四点五", zhNumber("1,234.5"))
+	assert.Equal(t, "零零七", zhNumber("007"))
+
+	assert.Equal(t, "one thousand two hundred thirty-four", enInteger(1234))
+	assert.Equal(t, "three point one four", enNumber("3.14"))
+	assert.Equal(t, "twenty-first", enOrdinal(21))
+	assert.Equal(t, "twelfth", enOrdinal(12))
+	assert.Equal(t, "twentieth", enOrdinal(20))
+	assert.Equal(t, "nineteen ninety-nine", enYear(1999))
+	assert.Equal(t, "two thousand five", enYear(2005))
+	assert.Equal(t, "twenty twenty-four", enYear(2024))
+}
+
+func TestTextNormalizerZh(t *testing.T) {
+	n := NewTextNormalizer(types.LanguageZh)
+	cases := map[string]string{
+		"**注意**: 今天气温 -5℃ 😀":             "注意: 今天气温 负五摄氏度",
+		"会议在 2024-05-01 10:30 开始":        "会议在 二零二四年五月一日 十点三十分 开始",
+		"价格是 ￥12.50, 打 8 折, 涨了 15%":      "价格是 十二点五元, 打 八 折, 涨了 百分之十五",
+		"买了2个苹果, 跑了 3-5km":               "买了两个苹果, 跑了 三到五公里",
+		"详见 [文档](https://example.com/a)": "详见 文档",
+		"访问 https://www.example.com/a。":  "访问 example点com。",
+	}
+	for text, expected := range cases {
+		assert.Equal(t, expected, n.Normalize(text), text)
+	}
+}
+
+func TestTextNormalizerEn(t *testing.T) {
+	n := NewTextNormalizer(types.LanguageEn)
+	cases := map[string]string{
+		"## It costs $12.50 today!":             "It costs twelve dollars and fifty cents today!",
+		"Meet Dr. Smith at 3:05 pm on the 1st.": "Meet Doctor Smith at three oh five P M on the first.",
+		"The date is 2024/05/01.":               "The date is May first, twenty twenty-four.",
+		"It weighs 1 kg, e.g. a book":           "It weighs one kilogram, for example a book",
+		"- Use `go test` for 2 packages":        "Use go test for two packages",
+	}
+	for text, expected := range cases {
+		assert.Equal(t, expected, n.Normalize(text), text)
+	}
+}
+
+func TestTextNormalizerMixed(t *testing.T) {
+	n := NewTextNormalizer("").WithLexicon(map[string]string{"achatbot": "a chat bot"})
+
+	// 中英混合按片段语种展开数字
+	assert.Equal(t, "我用 a chat bot 跑了 三 次 test", n.Normalize("我用 achatbot 跑了 3 次 test"))
+	assert.Equal(t, "I ran it three times", n.Normalize("I ran it 3 times"))
+
+	// 标记原样保留, say-as 中不展开
+	assert.Equal(t, `等 一秒<break time="1s"/>验证码<say-as interpret-as="characters">A12</say-as>`,
+		n.Normalize(`等 1秒<break time="1s"/>验证码<say-as interpret-as="characters">A12</say-as>`))
+
+	// 代码块跨句子丢弃
+	assert.Equal(t, "示例如下:", n.Normalize("示例如下: ```go"))
+	assert.Equal(t, "", n.Normalize("fmt.Println(1)"))
+	assert.Equal(t, "就这样。", n.Normalize("``` 就这样。"))
+	n.Normalize("```")
+	n.Reset()
+	assert.Equal(t, "好的", n.Normalize("好的"))
+}
+
+func TestTextNormalizerLoadLexicon(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lexicon.txt")
+	assert.NoError(t, os.WriteFile(path, []byte("# lexicon\nGPT=G P T\n智谱 = 智普\n"), 0o644))
+
+	n := NewTextNormalizer(types.LanguageEn)
+	assert.NoError(t, n.LoadLexicon(path))
+	assert.Equal(t, "G P T and GPTs", n.Normalize("GPT & GPTs"))
+
+	assert.NoError(t, os.WriteFile(path, []byte("bad line\n"), 0o644))
+	assert.Error(t, n.LoadLexicon(path))
+}
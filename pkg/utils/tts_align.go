@@ -0,0 +1,106 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+
+	"achatbot/pkg/types"
+)
+
+// 标点的停顿按音节计
+var punctPauseSyllables = map[rune]float64{
+	',': 0.5, '，': 0.5, '、': 0.5, ';': 0.5, '；': 0.5, ':': 0.5, '：': 0.5,
+	'.': 1, '。': 1, '!': 1, '！': 1, '?': 1, '？': 1,
+}
+
+// AlignTextWords 估计合成语音中每个词的时间戳(TTS 模型不输出时间戳时用于估计播放进度):
+// 汉字一个音节, 英文单词按元音组估计音节, 标点计为停顿, 按音节数比例分配语音时长;
+// 词之间的空白和标点归入前一个词, 所有词拼接为原文本
+func AlignTextWords(text string, durationSecs float64) []types.WordTimestamp {
+	words := make([]string, 0)
+	syllables := make([]float64, 0)
+	var cur strings.Builder
+	curSyllables := 0.0
+	inLatinWord := false
+	flush := func() {
+		if cur.Len() > 0 {
+			words = append(words, cur.String())
+			syllables = append(syllables, curSyllables)
+			cur.Reset()
+			curSyllables = 0
+		}
+	}
+
+	latinWord := make([]rune, 0)
+	for _, r := range text {
+		isLatin := r < unicode.MaxLatin1 && (unicode.IsLetter(r) || unicode.IsDigit(r)) || r == '\''
+		if isLatin {
+			if !inLatinWord {
+				// 新词开始, 前一个词(含其后的空白标点)结束
+				flush()
+				latinWord = latinWord[:0]
+			}
+			inLatinWord = true
+			latinWord = append(latinWord, r)
+			cur.WriteRune(r)
+			continue
+		}
+		if inLatinWord {
+			curSyllables += estimateSyllables(string(latinWord))
+			inLatinWord = false
+		}
+
+		switch {
+		case unicode.Is(unicode.Han, r):
+			flush()
+			cur.WriteRune(r)
+			curSyllables = 1
+		default:
+			cur.WriteRune(r)
+			curSyllables += punctPauseSyllables[r]
+		}
+	}
+	if inLatinWord {
+		curSyllables += estimateSyllables(string(latinWord))
+	}
+	flush()
+
+	total := 0.0
+	for _, s := range syllables {
+		total += s
+	}
+	res := make([]types.WordTimestamp, len(words))
+	start := 0.0
+	for i, word := range words {
+		end := durationSecs
+		if total > 0 {
+			end = start + durationSecs*syllables[i]/total
+		}
+		res[i] = types.WordTimestamp{Word: word, Start: float32(start), End: float32(end)}
+		start = end
+	}
+	return res
+}
+
+// estimateSyllables 按元音组估计英文单词音节数, 数字按位计, 至少一个音节
+func estimateSyllables(word string) float64 {
+	count := 0.0
+	prevVowel := false
+	for _, r := range strings.ToLower(word) {
+		if unicode.IsDigit(r) {
+			count++
+			prevVowel = false
+			continue
+		}
+		vowel := strings.ContainsRune("aeiouy", r)
+		if vowel && !prevVowel {
+			count++
+		}
+		prevVowel = vowel
+	}
+	// 词尾不发音的 e, 如 make
+	if count > 1 && strings.HasSuffix(strings.ToLower(word), "e") && !strings.HasSuffix(strings.ToLower(word), "le") {
+		count--
+	}
+	return max(count, 1)
+}
@@ -0,0 +1,29 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAlignTextWords(t *testing.T) {
+	words := AlignTextWords("你好, hello world!", 2.2)
+	texts := make([]string, len(words))
+	for i, w := range words {
+		texts[i] = w.Word
+	}
+	assert.Equal(t, []string{"你", "好, ", "hello ", "world!"}, texts)
+	assert.Equal(t, "你好, hello world!", strings.Join(texts, ""))
+
+	// 你(1) 好,(1.5) hello(2) world!(2), 共 6.5 音节
+	assert.InDelta(t, 0, words[0].Start, 1e-6)
+	assert.InDelta(t, 2.2*1/6.5, words[0].End, 1e-6)
+	assert.InDelta(t, words[1].End, words[2].Start, 1e-6)
+	assert.InDelta(t, 2.2, words[3].End, 1e-6)
+
+	assert.Empty(t, AlignTextWords("", 1))
+	assert.Equal(t, 1.0, estimateSyllables("make"))
+	// 每个数字按一个音节估算
+	assert.Equal(t, 4.0, estimateSyllables("2024"))
+}
@@ -0,0 +1,187 @@
+package utils
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"achatbot/pkg/types"
+)
+
+// 支持的轻量 SSML 标记(LLM 输出, 不区分大小写):
+//
+//	<break time="500ms"/>                          停顿, 支持 ms/s, 缺省 0.5s
+//	<emphasis level="strong|moderate|reduced">..</emphasis>  强调, 以放慢/加快语速表现
+//	<voice name="zm_yunjian">..</voice>, <speaker id="49">..</speaker>  切换音色
+//	<say-as interpret-as="characters">..</say-as>, <spell>..</spell>  逐字读出
+var (
+	ttsMarkupTagRegexp  = regexp.MustCompile(`(?i)<\s*(/?)\s*(break|emphasis|voice|speaker|say-as|spell)\b([^<>]*?)(/?)\s*>`)
+	ttsMarkupAttrRegexp = regexp.MustCompile(`([\w-]+)\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+)
+
+const defaultTTSBreakSecs = 0.5
+
+// MaxTTSBreakSecs 停顿时长上限, 停顿来自 LLM 输出, 防止超长停顿分配过大的静音音频
+const MaxTTSBreakSecs = 10.0
+
+// 强调等级 -> 语速倍数
+var emphasisSpeedScales = map[string]float32{
+	"strong":   0.75,
+	"moderate": 0.85,
+	"reduced":  1.15,
+}
+
+type ttsMarkupScope struct {
+	tag        string
+	speedScale float32
+	speaker    string
+	spell      bool
+}
+
+// TTSMarkupParser 解析 LLM 输出的轻量 SSML 标记为合成片段;
+// 标记可跨多个句子(TextFrame), 未闭合的标记作用到后续文本, 直到闭合或 Reset
+type TTSMarkupParser struct {
+	scopes []ttsMarkupScope
+}
+
+func NewTTSMarkupParser() *TTSMarkupParser {
+	return &TTSMarkupParser{}
+}
+
+// Reset 清除未闭合的标记(如一轮回复结束或被打断)
+func (p *TTSMarkupParser) Reset() {
+	p.scopes = nil
+}
+
+// Parse 解析文本为合成片段, 相同设置的相邻文本合并为一个片段, 不认识的标记保留为文本
+func (p *TTSMarkupParser) Parse(text string) []types.TTSSegment {
+	segments := make([]types.TTSSegment, 0)
+	pos := 0
+	for _, loc := range ttsMarkupTagRegexp.FindAllStringSubmatchIndex(text, -1) {
+		segments = p.appendText(segments, text[pos:loc[0]])
+		pos = loc[1]
+
+		isClose := loc[3] > loc[2]
+		tag := strings.ToLower(text[loc[4]:loc[5]])
+		attrs := parseTTSMarkupAttrs(text[loc[6]:loc[7]])
+		isSelfClose := loc[9] > loc[8]
+		switch {
+		case tag == "break":
+			if !isClose {
+				segments = append(segments, types.TTSSegment{BreakSecs: parseTTSBreakSecs(attrs["time"])})
+			}
+		case isClose:
+			p.close(tag)
+		case !isSelfClose:
+			p.open(tag, attrs)
+		}
+	}
+	return p.appendText(segments, text[pos:])
+}
+
+func (p *TTSMarkupParser) open(tag string, attrs map[string]string) {
+	scope := ttsMarkupScope{tag: tag, speedScale: 1}
+	switch tag {
+	case "emphasis":
+		scale, ok := emphasisSpeedScales[strings.ToLower(attrs["level"])]
+		if !ok {
+			scale = emphasisSpeedScales["moderate"]
+		}
+		scope.speedScale = scale
+	case "voice", "speaker":
+		scope.speaker = attrs["name"]
+		if scope.speaker == "" {
+			scope.speaker = attrs["id"]
+		}
+	case "say-as":
+		interpretAs := strings.ToLower(attrs["interpret-as"])
+		scope.spell = interpretAs == "characters" || interpretAs == "spell-out"
+	case "spell":
+		scope.spell = true
+	}
+	p.scopes = append(p.scopes, scope)
+}
+
+// close pops the scopes to the last opened tag, unmatched close tag is ignored
+func (p *TTSMarkupParser) close(tag string) {
+	for i := len(p.scopes) - 1; i >= 0; i-- {
+		if p.scopes[i].tag == tag {
+			p.scopes = p.scopes[:i]
+			return
+		}
+	}
+}
+
+func (p *TTSMarkupParser) appendText(segments []types.TTSSegment, text string) []types.TTSSegment {
+	if strings.TrimSpace(text) == "" {
+		return segments
+	}
+
+	segment := types.TTSSegment{SpeedScale: 1}
+	spell := false
+	for _, scope := range p.scopes {
+		segment.SpeedScale *= scope.speedScale
+		if scope.speaker != "" {
+			segment.Speaker = scope.speaker
+		}
+		spell = spell || scope.spell
+	}
+	if spell {
+		text = SpellOut(text)
+	}
+	segment.Text = text
+
+	if n := len(segments); n > 0 && segments[n-1].BreakSecs == 0 &&
+		segments[n-1].SpeedScale == segment.SpeedScale && segments[n-1].Speaker == segment.Speaker {
+		segments[n-1].Text += segment.Text
+		return segments
+	}
+	return append(segments, segment)
+}
+
+func parseTTSMarkupAttrs(s string) map[string]string {
+	attrs := make(map[string]string)
+	for _, match := range ttsMarkupAttrRegexp.FindAllStringSubmatch(s, -1) {
+		value := match[2]
+		if value == "" {
+			value = match[3]
+		}
+		attrs[strings.ToLower(match[1])] = strings.TrimSpace(value)
+	}
+	return attrs
+}
+
+// parseTTSBreakSecs parses break time like 500ms, 1s, 1.5 (secs), clamped to MaxTTSBreakSecs;
+// invalid, non-positive and non-finite (inf, nan) values use the default break
+func parseTTSBreakSecs(value string) float64 {
+	value = strings.ToLower(strings.TrimSpace(value))
+	scale := 1.0
+	switch {
+	case strings.HasSuffix(value, "ms"):
+		value, scale = strings.TrimSuffix(value, "ms"), 0.001
+	case strings.HasSuffix(value, "s"):
+		value = strings.TrimSuffix(value, "s")
+	}
+	secs, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(secs) || math.IsInf(secs, 0) || secs <= 0 {
+		return defaultTTSBreakSecs
+	}
+	return min(secs*scale, MaxTTSBreakSecs)
+}
+
+// SpellOut 字母和数字逐个以空格分开读出, 如 ABC123 -> A B C 1 2 3
+func SpellOut(text string) string {
+	var sb strings.Builder
+	prevSpelled := false
+	for _, r := range text {
+		spelled := r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r))
+		if spelled && prevSpelled {
+			sb.WriteRune(' ')
+		}
+		sb.WriteRune(r)
+		prevSpelled = spelled
+	}
+	return sb.String()
+}
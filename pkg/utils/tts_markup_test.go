@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"achatbot/pkg/types"
+)
+
+func TestTTSMarkupParser(t *testing.T) {
+	parser := NewTTSMarkupParser()
+
+	segments := parser.Parse(`你好<break time="300ms"/>我是<emphasis level="strong">小助手</emphasis>, 验证码是<say-as interpret-as="characters">A12</say-as>。`)
+	assert.Equal(t, []types.TTSSegment{
+		{Text: "你好", SpeedScale: 1},
+		{BreakSecs: 0.3},
+		{Text: "我是", SpeedScale: 1},
+		{Text: "小助手", SpeedScale: 0.75},
+		{Text: ", 验证码是A 1 2。", SpeedScale: 1},
+	}, segments)
+
+	// 音色切换跨句子, 直到闭合
+	segments = parser.Parse(`<voice name="zm_yunjian">第一句。`)
+	assert.Equal(t, []types.TTSSegment{{Text: "第一句。", SpeedScale: 1, Speaker: "zm_yunjian"}}, segments)
+	segments = parser.Parse(`第二句。</voice><BREAK/>结束 <unknown>`)
+	assert.Equal(t, []types.TTSSegment{
+		{Text: "第二句。", SpeedScale: 1, Speaker: "zm_yunjian"},
+		{BreakSecs: 0.5},
+		{Text: "结束 <unknown>", SpeedScale: 1},
+	}, segments)
+
+	// Reset 清除未闭合的标记
+	parser.Parse(`<speaker id="49">`)
+	parser.Reset()
+	assert.Equal(t, []types.TTSSegment{{Text: "hi", SpeedScale: 1}}, parser.Parse("hi"))
+}
+
+func TestParseTTSBreakSecs(t *testing.T) {
+	assert.Equal(t, 0.3, parseTTSBreakSecs("300ms"))
+	assert.Equal(t, 1.5, parseTTSBreakSecs("1.5"))
+	// 非法和非有限值使用默认停顿
+	for _, value := range []string{"inf", "NaN", "-inf", "-1s", "abc"} {
+		assert.Equal(t, defaultTTSBreakSecs, parseTTSBreakSecs(value), value)
+	}
+	// 超长停顿截断到上限
+	for _, value := range []string{"1e9s", "3600s", "1e12", "99999999ms"} {
+		assert.Equal(t, MaxTTSBreakSecs, parseTTSBreakSecs(value), value)
+	}
+
+	segments := NewTTSMarkupParser().Parse(`你好<break time="inf"/>再见<break time="1e9s"/>`)
+	assert.Equal(t, []types.TTSSegment{
+		{Text: "你好", SpeedScale: 1},
+		{BreakSecs: defaultTTSBreakSecs},
+		{Text: "再见", SpeedScale: 1},
+		{BreakSecs: MaxTTSBreakSecs},
+	}, segments)
+}
+
+func TestSpellOut(t *testing.T) {
+	assert.Equal(t, "A B C 1 2 3", SpellOut("ABC123"))
+	assert.Equal(t, "号码 1 3 8", SpellOut("号码 138"))
+}
@@ -0,0 +1,136 @@
+package utils
+
+import (
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"achatbot/pkg/types"
+)
+
+// DefaultMaxTurnMetrics 会话汇总最多保留的轮数(最近的)
+const DefaultMaxTurnMetrics = 1000
+
+// TurnMetricsTracker 记录会话每轮对话各阶段的时间点, 多个处理器(不同 goroutine)共享, 需加锁
+// - VAD 停止开始新的一轮(之前未完成的一轮丢弃, 如用户说话停顿后继续说)
+// - 其它阶段在一轮内只记录第一次, 且按处理顺序记录(已记录后面阶段时忽略), LLM 及之后的阶段需先识别完成
+// - 第一段音频写入客户端时一轮完成, 回调 OnTurnMetrics 注册的处理函数, 并计入会话汇总
+type TurnMetricsTracker struct {
+	mu        sync.Mutex
+	sessionID string
+	turnID    int
+	marks     map[types.TurnStage]time.Time
+	// 已完成轮的各阶段距 VAD 停止耗时(毫秒)
+	latencies map[types.TurnStage][]float64
+	turns     int
+	maxTurns  int
+	handlers  []func(*types.TurnMetrics)
+	now       func() time.Time
+}
+
+func NewTurnMetricsTracker(sessionID string) *TurnMetricsTracker {
+	return &TurnMetricsTracker{
+		sessionID: sessionID,
+		latencies: make(map[types.TurnStage][]float64),
+		maxTurns:  DefaultMaxTurnMetrics,
+		now:       time.Now,
+	}
+}
+
+// OnTurnMetrics 注册一轮完成时的处理函数(如记录日志, 发送给客户端), 在调用 Mark 的 goroutine 中执行
+func (t *TurnMetricsTracker) OnTurnMetrics(handler func(*types.TurnMetrics)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.handlers = append(t.handlers, handler)
+}
+
+// Mark 记录当前轮阶段的时间点, 一轮完成时返回耗时分解, 否则返回 nil
+func (t *TurnMetricsTracker) Mark(stage types.TurnStage) *types.TurnMetrics {
+	t.mu.Lock()
+	metrics := t.mark(stage)
+	handlers := slices.Clone(t.handlers)
+	t.mu.Unlock()
+
+	if metrics != nil {
+		for _, handler := range handlers {
+			handler(metrics)
+		}
+	}
+	return metrics
+}
+
+func (t *TurnMetricsTracker) mark(stage types.TurnStage) *types.TurnMetrics {
+	now := t.now()
+	if stage == types.TurnStageVADStop {
+		t.turnID++
+		t.marks = map[types.TurnStage]time.Time{stage: now}
+		return nil
+	}
+
+	index := stage.Index()
+	if t.marks == nil || index < 0 {
+		return nil
+	}
+	if _, ok := t.marks[stage]; ok {
+		return nil
+	}
+	for marked := range t.marks {
+		if marked.Index() > index {
+			return nil
+		}
+	}
+	if _, ok := t.marks[types.TurnStageASRDone]; !ok && index > types.TurnStageASRDone.Index() {
+		return nil
+	}
+	t.marks[stage] = now
+	if stage != types.TurnStageFirstAudioWritten {
+		return nil
+	}
+
+	metrics := types.NewTurnMetrics(t.turnID, t.marks)
+	t.marks = nil
+	t.turns++
+	for _, latency := range metrics.Stages {
+		values := append(t.latencies[latency.Stage], latency.SinceVADStopMS)
+		if len(values) > t.maxTurns {
+			values = values[len(values)-t.maxTurns:]
+		}
+		t.latencies[latency.Stage] = values
+	}
+	return metrics
+}
+
+// Summary 返回会话已完成轮的各阶段耗时分位数
+func (t *TurnMetricsTracker) Summary() *types.TurnMetricsSummary {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	summary := &types.TurnMetricsSummary{SessionID: t.sessionID, Turns: t.turns}
+	for _, stage := range types.TurnStages {
+		values := t.latencies[stage]
+		if stage == types.TurnStageVADStop || len(values) == 0 {
+			continue
+		}
+		sorted := slices.Clone(values)
+		slices.Sort(sorted)
+		summary.Stages = append(summary.Stages, types.TurnLatencyPercentiles{
+			Stage: stage,
+			Count: len(sorted),
+			P50MS: Percentile(sorted, 50),
+			P90MS: Percentile(sorted, 90),
+			P99MS: Percentile(sorted, 99),
+			MaxMS: sorted[len(sorted)-1],
+		})
+	}
+	return summary
+}
+
+// Percentile 返回升序数据的 p 分位数(nearest-rank), 空数据返回 0
+func Percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[min(max(rank, 1), len(sorted))-1]
+}
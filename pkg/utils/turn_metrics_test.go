@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"achatbot/pkg/types"
+)
+
+func TestTurnMetricsTracker(t *testing.T) {
+	tracker := NewTurnMetricsTracker("session")
+	now := time.Unix(0, 0)
+	tracker.now = func() time.Time { return now }
+	var reported []*types.TurnMetrics
+	tracker.OnTurnMetrics(func(metrics *types.TurnMetrics) { reported = append(reported, metrics) })
+
+	// bot audio before any user speech is not a turn
+	assert.Nil(t, tracker.Mark(types.TurnStageFirstAudioWritten))
+
+	for turn := 1; turn <= 10; turn++ {
+		// the user paused, the turn restarts from the last vad stop
+		tracker.Mark(types.TurnStageVADStop)
+		now = now.Add(time.Second)
+		tracker.Mark(types.TurnStageVADStop)
+		for _, stage := range types.TurnStages[1:] {
+			now = now.Add(time.Duration(turn*10) * time.Millisecond)
+			metrics := tracker.Mark(stage)
+			if stage != types.TurnStageFirstAudioWritten {
+				assert.Nil(t, metrics)
+				// marked once
+				tracker.Mark(stage)
+				continue
+			}
+			assert.Equal(t, turn, metrics.TurnID/2)
+			assert.Len(t, metrics.Stages, len(types.TurnStages))
+			latency, ok := metrics.Latency(types.TurnStageFirstAudioWritten)
+			assert.True(t, ok)
+			assert.Equal(t, float64(turn*60), latency)
+			assert.Equal(t, float64(turn*10), metrics.Stages[3].SincePrevMS)
+		}
+	}
+	assert.Len(t, reported, 10)
+
+	summary := tracker.Summary()
+	assert.Equal(t, "session", summary.SessionID)
+	assert.Equal(t, 10, summary.Turns)
+	assert.Len(t, summary.Stages, len(types.TurnStages)-1)
+	last := summary.Stages[len(summary.Stages)-1]
+	assert.Equal(t, types.TurnStageFirstAudioWritten, last.Stage)
+	assert.Equal(t, 10, last.Count)
+	assert.Equal(t, float64(300), last.P50MS)
+	assert.Equal(t, float64(540), last.P90MS)
+	assert.Equal(t, float64(600), last.P99MS)
+	assert.Equal(t, float64(600), last.MaxMS)
+}
+
+func TestTurnMetricsTrackerStageOrder(t *testing.T) {
+	tracker := NewTurnMetricsTracker("session")
+	tracker.Mark(types.TurnStageVADStop)
+	// stale llm text of the previous reply before the user speech is recognized
+	tracker.Mark(types.TurnStageLLMFirstToken)
+	tracker.Mark(types.TurnStageASRDone)
+	// earlier stage after a later one is ignored
+	tracker.Mark(types.TurnStageAggregatorFlush)
+	tracker.Mark(types.TurnStageTTSFirstAudio)
+	metrics := tracker.Mark(types.TurnStageFirstAudioWritten)
+	assert.NotNil(t, metrics)
+
+	stages := make([]types.TurnStage, 0)
+	for _, latency := range metrics.Stages {
+		stages = append(stages, latency.Stage)
+	}
+	assert.Equal(t, []types.TurnStage{
+		types.TurnStageVADStop, types.TurnStageASRDone, types.TurnStageTTSFirstAudio, types.TurnStageFirstAudioWritten,
+	}, stages)
+
+	// the turn is finished
+	assert.Nil(t, tracker.Mark(types.TurnStageFirstAudioWritten))
+	assert.Equal(t, 1, tracker.Summary().Turns)
+}
+
+func TestPercentile(t *testing.T) {
+	assert.Equal(t, float64(0), Percentile(nil, 50))
+	assert.Equal(t, float64(1), Percentile([]float64{1}, 99))
+	assert.Equal(t, float64(2), Percentile([]float64{1, 2, 3, 4}, 50))
+	assert.Equal(t, float64(4), Percentile([]float64{1, 2, 3, 4}, 90))
+}
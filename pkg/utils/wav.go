@@ -1,7 +1,9 @@
 package utils
 
 import (
+	"encoding/binary"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 
@@ -217,3 +219,98 @@ func parseSampleRateFromWAVHeader(header []byte) int {
 		int(header[26])<<16 |
 		int(header[27])<<24
 }
+
+// WAVFileWriter 流式写入 WAV 文件: 先写入占位头部, 每次 Sync 按已写入的数据长度更新头部并落盘,
+// 进程崩溃时文件至多丢失最后一次 Sync 之后的音频, 仍是合法的 WAV 文件
+type WAVFileWriter struct {
+	file        *os.File
+	path        string
+	channels    int
+	sampleWidth int
+	sampleRate  int
+	dataLen     int
+}
+
+// NewWAVFileWriter creates the wav file (and its directory)
+func NewWAVFileWriter(path string, channels, sampleWidth, sampleRate int) (*WAVFileWriter, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory %s: %w", filepath.Dir(path), err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file %s: %w", path, err)
+	}
+	w := &WAVFileWriter{file: file, path: path, channels: channels, sampleWidth: sampleWidth, sampleRate: sampleRate}
+	if _, err := file.Write(createWAVHeader(channels, sampleWidth, sampleRate, 0)); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to write WAV header: %w", err)
+	}
+	return w, nil
+}
+
+// Path returns the wav file path
+func (w *WAVFileWriter) Path() string {
+	return w.path
+}
+
+// DataLen returns the bytes of the audio data written
+func (w *WAVFileWriter) DataLen() int {
+	return w.dataLen
+}
+
+// Write appends the PCM audio data
+func (w *WAVFileWriter) Write(audio []byte) (int, error) {
+	n, err := w.file.Write(audio)
+	w.dataLen += n
+	return n, err
+}
+
+// Sync updates the header with the data length and flushes the file to disk
+func (w *WAVFileWriter) Sync() error {
+	header := createWAVHeader(w.channels, w.sampleWidth, w.sampleRate, w.dataLen)
+	if _, err := w.file.WriteAt(header, 0); err != nil {
+		return fmt.Errorf("failed to update WAV header: %w", err)
+	}
+	return w.file.Sync()
+}
+
+// Close finalizes the header and closes the file
+func (w *WAVFileWriter) Close() error {
+	err := w.Sync()
+	if closeErr := w.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// RepairWAVHeader 按文件实际长度修正 WAV 头部的数据长度(如写入过程中崩溃的录音文件),
+// 数据长度截断到整数个采样帧
+func RepairWAVHeader(filePath string) error {
+	file, err := os.OpenFile(filePath, os.O_RDWR, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open file %s: %w", filePath, err)
+	}
+	defer file.Close()
+
+	header := make([]byte, 44)
+	if _, err := io.ReadFull(file, header); err != nil {
+		return fmt.Errorf("invalid WAV file %s: %w", filePath, err)
+	}
+	if string(header[0:4]) != "RIFF" || string(header[8:12]) != "WAVE" {
+		return fmt.Errorf("invalid WAV file %s: not RIFF/WAVE", filePath)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	channels := int(binary.LittleEndian.Uint16(header[22:24]))
+	sampleWidth := int(binary.LittleEndian.Uint16(header[34:36])) / 8
+	dataLen := int(info.Size()) - 44
+	if blockAlign := channels * sampleWidth; blockAlign > 0 {
+		dataLen -= dataLen % blockAlign
+	}
+	if _, err := file.WriteAt(createWAVHeader(channels, sampleWidth, parseSampleRateFromWAVHeader(header), dataLen), 0); err != nil {
+		return fmt.Errorf("failed to update WAV header: %w", err)
+	}
+	return file.Sync()
+}
@@ -162,3 +162,32 @@ func TestParseSampleRateFromWAVHeader(t *testing.T) {
 	rate := parseSampleRateFromWAVHeader(header)
 	assert.Equal(t, 44100, rate)
 }
+
+func TestWAVFileWriter(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "stereo.wav")
+	w, err := NewWAVFileWriter(filePath, 2, 2, 16000)
+	assert.NoError(t, err)
+	_, err = w.Write(make([]byte, 8))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Sync())
+
+	// synced header is valid before close
+	data, sampleRate, err := ReadWAVToBytes(filePath)
+	assert.NoError(t, err)
+	assert.Equal(t, 16000, sampleRate)
+	assert.Len(t, data, 8)
+
+	// crash after write: the header is repaired by the file size (whole frames)
+	_, err = w.Write(make([]byte, 6))
+	assert.NoError(t, err)
+	assert.NoError(t, RepairWAVHeader(filePath))
+	header, err := os.ReadFile(filePath)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{12, 0, 0, 0}, header[40:44])
+
+	assert.NoError(t, w.Close())
+	header, err = os.ReadFile(filePath)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{14, 0, 0, 0}, header[40:44])
+	assert.Equal(t, []byte{2, 0}, header[22:24])
+}